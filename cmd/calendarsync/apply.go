@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/urfave/cli/v2"
+)
+
+const flagPlan = "plan"
+
+var applyCommand = &cli.Command{
+	Name:  "apply",
+	Usage: "executes the operations of a plan written by --plan-output",
+	Description: "Executes exactly the creates, updates and deletes of a reviewed plan file instead of computing the sync again. " +
+		"Operations whose sink event changed since the plan was made are skipped as stale.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     flagPlan,
+			Usage:    "path to the .json or .yaml plan file",
+			Required: true,
+		},
+	},
+	Action: Apply,
+}
+
+func Apply(c *cli.Context) error {
+	log.Infof("started calendarsync apply version %v", Version)
+
+	plans, err := readPlan(c.String(flagPlan))
+	if err != nil {
+		return err
+	}
+	if len(plans) == 0 {
+		log.Info("the plan contains no jobs")
+		return nil
+	}
+
+	var jobNames []string
+	for _, plan := range plans {
+		jobNames = append(jobNames, plan.Job)
+	}
+	jobs, err := loadJobs(c, jobNames)
+	if err != nil {
+		return err
+	}
+
+	var failedJobs []string
+	for i, job := range jobs {
+		summary, stale, err := job.controller.ApplyPlan(c.Context, *plans[i])
+		for _, op := range stale {
+			job.logger.Warn("skipped stale operation, the sink event changed since the plan was made",
+				"operation", op.Operation, "sink", op.Sink, "syncId", op.SyncID, "eventId", op.EventID, "title", op.Title)
+		}
+		job.logger.Info("job summary", "created", summary.Created, "updated", summary.Updated, "deleted", summary.Deleted, "stale", len(stale))
+		if err != nil {
+			job.logger.Errorf("we had some errors during the job:\n%v", err)
+			failedJobs = append(failedJobs, job.name)
+		}
+	}
+
+	if len(failedJobs) > 0 {
+		log.Fatalf("the following jobs had errors: %s", strings.Join(failedJobs, ", "))
+	}
+	log.Info("apply complete", "path", c.String(flagPlan))
+	return nil
+}
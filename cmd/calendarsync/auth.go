@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/inovex/CalendarSync/internal/auth"
+	"github.com/inovex/CalendarSync/internal/config"
+)
+
+var authCommand = &cli.Command{
+	Name:  "auth",
+	Usage: "manages the auth storage",
+	Subcommands: []*cli.Command{
+		{
+			Name:  "rekey",
+			Usage: "encrypts the yaml auth storage for the configured age recipients",
+			Description: "Decrypts the auth storage with the configured identity file or, if it is still encrypted with a passphrase, " +
+				"with $CALENDARSYNC_ENCRYPTION_KEY and encrypts it for the recipients configured in auth.config.recipients. " +
+				"Afterwards, the passphrase is no longer needed.",
+			Action: Rekey,
+		},
+	},
+}
+
+func Rekey(c *cli.Context) error {
+	cfg, err := config.NewFromFile(c.String(flagConfigFilePath))
+	if err != nil {
+		return err
+	}
+	if cfg.Auth.StorageMode != "yaml" {
+		return fmt.Errorf("only the yaml auth storage can be rekeyed, the storage mode is %s", cfg.Auth.StorageMode)
+	}
+
+	encryptionKey, err := storageEncryptionKey(c)
+	if err != nil {
+		return err
+	}
+	storage := new(auth.YamlStorage)
+	if err := storage.Setup(cfg.Auth, encryptionKey); err != nil {
+		return err
+	}
+
+	calendars, err := storage.Rekey()
+	if err != nil {
+		return fmt.Errorf("unable to rekey the auth storage: %w", err)
+	}
+	log.Info("encrypted the auth storage for the recipients", "path", storage.StoragePath, "calendars", calendars, "recipients", len(storage.Recipients))
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/inovex/CalendarSync/internal/config"
+	"github.com/inovex/CalendarSync/internal/scheduler"
+)
+
+const (
+	flagInterval = "interval"
+	flagCron     = "cron"
+	flagJitter   = "jitter"
+)
+
+var daemonCommand = &cli.Command{
+	Name:  "daemon",
+	Usage: "keeps running and synchronises the jobs on a schedule",
+	Description: "Loads the adapters once and re-runs the sync of every job on its schedule until SIGTERM or SIGINT is received. " +
+		"The schedule configured in the config file takes precedence over the flags.",
+	Flags: []cli.Flag{
+		&cli.DurationFlag{
+			Name:    flagInterval,
+			Usage:   "interval between the end of a run and the start of the next one",
+			Value:   15 * time.Minute,
+			EnvVars: []string{"CALENDARSYNC_INTERVAL"},
+		},
+		&cli.StringFlag{
+			Name:    flagCron,
+			Usage:   "cron expression (minute hour day-of-month month day-of-week) which is used instead of the interval",
+			EnvVars: []string{"CALENDARSYNC_CRON"},
+		},
+		&cli.DurationFlag{
+			Name:    flagJitter,
+			Usage:   "maximum random delay added to every scheduled run",
+			EnvVars: []string{"CALENDARSYNC_JITTER"},
+		},
+	},
+	Action: Daemon,
+}
+
+func Daemon(c *cli.Context) error {
+	log.Infof("started calendarsync daemon version %v", Version)
+
+	if c.Bool(flagClean) {
+		return fmt.Errorf("the daemon cannot be used together with --%s", flagClean)
+	}
+
+	jobs, err := loadJobs(c, nil)
+	if err != nil {
+		return err
+	}
+	warnUnpublishedFeeds(jobs)
+
+	s := scheduler.New(log.Default())
+	for _, job := range jobs {
+		schedule, jitter, err := jobSchedule(c, job.config.Schedule)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", job.name, err)
+		}
+		s.Add(job.name, schedule, jitter, func(ctx context.Context) error {
+			return job.run(ctx, false, c.Bool(flagDryRun))
+		})
+	}
+
+	ctx, stop := signal.NotifyContext(c.Context, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	s.Run(ctx)
+	log.Info("daemon stopped")
+	return nil
+}
+
+// jobSchedule returns the schedule configured for the job, falling back to the schedule set by the flags
+func jobSchedule(c *cli.Context, cfg config.Schedule) (scheduler.Schedule, time.Duration, error) {
+	jitter := cfg.Jitter
+	if jitter == 0 {
+		jitter = c.Duration(flagJitter)
+	}
+
+	switch {
+	case cfg.Cron != "":
+		schedule, err := scheduler.ParseCron(cfg.Cron)
+		return schedule, jitter, err
+	case cfg.Interval != 0:
+		schedule, err := scheduler.Every(cfg.Interval)
+		return schedule, jitter, err
+	case c.String(flagCron) != "":
+		schedule, err := scheduler.ParseCron(c.String(flagCron))
+		return schedule, jitter, err
+	default:
+		schedule, err := scheduler.Every(c.Duration(flagInterval))
+		return schedule, jitter, err
+	}
+}
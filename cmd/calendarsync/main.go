@@ -1,19 +1,22 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/inovex/CalendarSync/internal/auth"
-	"github.com/inovex/CalendarSync/internal/models"
 
 	"github.com/charmbracelet/log"
 	"github.com/urfave/cli/v2"
 
 	"github.com/inovex/CalendarSync/internal/adapter"
+	"github.com/inovex/CalendarSync/internal/adapter/feed"
 	"github.com/inovex/CalendarSync/internal/config"
+	"github.com/inovex/CalendarSync/internal/state"
 	"github.com/inovex/CalendarSync/internal/sync"
 )
 
@@ -26,6 +29,11 @@ const (
 	flagPort                     = "port"
 	flagOpenBrowserAutomatically = "open-browser"
 	flagVersion                  = "version"
+	flagJob                      = "job"
+	flagPlanOutput               = "plan-output"
+	flagForce                    = "force"
+	flagFrom                     = "from"
+	flagTo                       = "to"
 )
 
 var (
@@ -68,11 +76,32 @@ func main() {
 				Usage: "This flag helps you see which events would get created, updated or deleted without actually doing these operations",
 				Value: false,
 			},
+			&cli.BoolFlag{
+				Name:  flagForce,
+				Usage: "ignores the deleteLimit and executes all deletions",
+				Value: false,
+			},
 			&cli.BoolFlag{
 				Name:  flagVersion,
 				Usage: "shows the version of CalendarSync",
 				Value: false,
 			},
+			&cli.StringFlag{
+				Name:  flagPlanOutput,
+				Usage: "writes the planned creates, updates and deletes of all jobs to the given .json or .yaml file. Use together with --dry-run to review the changes before executing them",
+			},
+			&cli.StringFlag{
+				Name:  flagFrom,
+				Usage: "overrides the start of the sync window of all jobs, e.g. for a one-off backfill. Accepts an identifier like MonthStart, an ISO-8601 duration like -P90D or an RFC3339 date",
+			},
+			&cli.StringFlag{
+				Name:  flagTo,
+				Usage: "overrides the end of the sync window of all jobs, accepts the same values as --from",
+			},
+			&cli.StringSliceFlag{
+				Name:  flagJob,
+				Usage: "name of a job configured in the config file to run, can be repeated. Runs all jobs if not set",
+			},
 			&cli.UintFlag{
 				Name:   flagPort,
 				Usage:  "set manual free port for the authentication process",
@@ -93,7 +122,8 @@ func main() {
 			}
 			return nil
 		},
-		Action: Run,
+		Action:   Run,
+		Commands: []*cli.Command{daemonCommand, applyCommand, serveCommand, authCommand},
 	}
 
 	if err := app.Run(os.Args); err != nil {
@@ -110,109 +140,309 @@ func Run(c *cli.Context) error {
 		os.Exit(0)
 	}
 
-	cfg, err := config.NewFromFile(c.String(flagConfigFilePath))
+	planOutput := c.String(flagPlanOutput)
+	if planOutput != "" {
+		// fail before running any job if the plan cannot be written
+		if _, err := planMarshaller(planOutput); err != nil {
+			return err
+		}
+	}
+
+	jobs, err := loadJobs(c, nil)
 	if err != nil {
 		return err
 	}
-	log.Info("loaded config file", "path", cfg.Path)
 
-	if len(c.String(flagStorageEncryptionKey)) > 0 {
-		log.Warn("Parsing the encryption key using the flag is deprecated. Please use the environment variable $CALENDARSYNC_ENCRYPTION_KEY instead.")
-	} else {
-		if encKeyEnv, envSet := os.LookupEnv("CALENDARSYNC_ENCRYPTION_KEY"); envSet {
-			err := c.Set(flagStorageEncryptionKey, encKeyEnv)
-			if err != nil {
-				return err
-			}
+	warnUnpublishedFeeds(jobs)
+
+	var plans []*sync.Plan
+	var failedJobs []string
+	for _, job := range jobs {
+		if planOutput != "" {
+			plan := &sync.Plan{Job: job.name, Operations: []sync.Operation{}}
+			job.controller.SetPlan(plan)
+			plans = append(plans, plan)
+		}
+		if err := job.run(c.Context, c.Bool(flagClean), c.Bool(flagDryRun)); err != nil {
+			// a failing job does not stop the remaining jobs
+			job.logger.Errorf("we had some errors during the job:\n%v", err)
+			failedJobs = append(failedJobs, job.name)
+		}
+	}
+
+	if planOutput != "" {
+		if err := writePlan(planOutput, plans); err != nil {
+			return err
 		}
+		log.Info("wrote plan", "path", planOutput)
 	}
 
-	if len(c.String(flagStorageEncryptionKey)) == 0 {
-		return fmt.Errorf("storage encryption key needs to be set")
+	if len(failedJobs) > 0 {
+		log.Fatalf("the following jobs had errors: %s", strings.Join(failedJobs, ", "))
 	}
+	log.Info("sync complete")
+	return nil
+}
 
-	startTime, err := models.TimeFromConfig(cfg.Sync.StartTime)
+// loadJobs loads the config file and the adapters of the given jobs. If no jobs are given,
+// the jobs selected by --job or all configured jobs are loaded.
+func loadJobs(c *cli.Context, jobNames []string) ([]*job, error) {
+	cfg, err := config.NewFromFile(c.String(flagConfigFilePath))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	endTime, err := models.TimeFromConfig(cfg.Sync.EndTime)
+	log.Info("loaded config file", "path", cfg.Path)
+
+	encryptionKey, err := storageEncryptionKey(c)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	log.Debug("configured start and end time for sync", "start", startTime, "end", endTime)
+	if len(jobNames) == 0 {
+		jobNames = cfg.JobNames()
+		if c.IsSet(flagJob) {
+			jobNames = c.StringSlice(flagJob)
+		}
+	}
+	for _, name := range jobNames {
+		if _, ok := cfg.Jobs[name]; !ok {
+			return nil, fmt.Errorf("job %s is not configured, available jobs: %s", name, strings.Join(cfg.JobNames(), ", "))
+		}
+	}
 
-	var sourceBindAuthPort, sinkBindAuthPort uint
+	// every adapter needs its own port for the authentication process, the ports are assigned
+	// in the order in which the adapters of all jobs are loaded
+	var bindAuthPort uint
 	if c.IsSet("port") {
-		sourceBindAuthPort = c.Uint("port")
-		sinkBindAuthPort = c.Uint("port") + 1
+		bindAuthPort = c.Uint("port")
+	}
+	nextBindAuthPort := func() uint {
+		if bindAuthPort == 0 {
+			return 0
+		}
+		bindAuthPort++
+		return bindAuthPort - 1
 	}
 
-	storage, err := auth.NewStorageAdapterFromConfig(c.Context, cfg.Auth, c.String(flagStorageEncryptionKey))
+	storage, err := auth.NewStorageAdapterFromConfig(c.Context, cfg.Auth, encryptionKey)
 	if err != nil {
 		log.Fatal("error during storage adapter load", "error", err)
 	}
 
-	sourceLogger := log.With("adapter", cfg.Source.Adapter.Type, "type", "source")
+	// the state file is only needed if any of the jobs is synced incrementally or bidirectionally
+	var stateStore *state.FileStore
+	for _, name := range jobNames {
+		if cfg.Jobs[name].Incremental || cfg.Jobs[name].Mode == config.ModeBidirectional {
+			stateStore, err = state.NewFileStore(cfg.State.Path)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
 
-	sourceAdapter, err := adapter.NewSourceAdapterFromConfig(
-		c.Context,
-		sourceBindAuthPort,
-		c.Bool(flagOpenBrowserAutomatically),
-		config.NewAdapterConfig(cfg.Source.Adapter),
-		storage,
-		sourceLogger,
-	)
-	if err != nil {
-		return err
+	var jobs []*job
+	for _, name := range jobNames {
+		jobConfig := cfg.Jobs[name]
+		if err := overrideSyncWindow(c, &jobConfig); err != nil {
+			return nil, fmt.Errorf("job %s: %w", name, err)
+		}
+		j, err := loadJob(c, name, jobConfig, storage, stateStore, nextBindAuthPort)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", name, err)
+		}
+		jobs = append(jobs, j)
 	}
-	log.Info("loaded source adapter", "adapter", cfg.Source.Adapter.Type, "calendar", cfg.Source.Adapter.Calendar)
 
-	sinkLogger := log.With("adapter", cfg.Sink.Adapter.Type, "type", "sink")
+	// By default go runs a garbage collection once the memory usage doubles compared to the last GC run.
+	// Decrypting the storage with the passphrase in NewSourceAdapterFromConfig/NewSinkAdapterFromConfig requires
+	// a lot of memory for scrypt, such that the next GC only trigger once the memory usage double compared to that
+	// peak. Explicitly trigger a GC to reset the memory usage reference level.
+	if encryptionKey != "" {
+		runtime.GC()
+	}
+
+	return jobs, nil
+}
+
+// storageEncryptionKey returns the passphrase of the auth storage from the environment or the deprecated flag.
+// It's empty if the storage is protected otherwise, e.g. by age recipients or the keyring.
+func storageEncryptionKey(c *cli.Context) (string, error) {
+	if len(c.String(flagStorageEncryptionKey)) > 0 {
+		log.Warn("Parsing the encryption key using the flag is deprecated. Please use the environment variable $CALENDARSYNC_ENCRYPTION_KEY instead.")
+	} else {
+		if encKeyEnv, envSet := os.LookupEnv("CALENDARSYNC_ENCRYPTION_KEY"); envSet {
+			err := c.Set(flagStorageEncryptionKey, encKeyEnv)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	return c.String(flagStorageEncryptionKey), nil
+}
 
-	sinkAdapter, err := adapter.NewSinkAdapterFromConfig(
-		c.Context,
-		sinkBindAuthPort,
-		c.Bool(flagOpenBrowserAutomatically),
-		config.NewAdapterConfig(cfg.Sink.Adapter),
-		storage,
-		sinkLogger,
-	)
+// overrideSyncWindow replaces the configured sync window with the one given by --from and --to
+func overrideSyncWindow(c *cli.Context, cfg *config.Job) error {
+	if !c.IsSet(flagFrom) && !c.IsSet(flagTo) {
+		return nil
+	}
+	if c.IsSet(flagFrom) {
+		cfg.Sync.StartTime = config.SyncTime{Identifier: c.String(flagFrom)}
+	}
+	if c.IsSet(flagTo) {
+		cfg.Sync.EndTime = config.SyncTime{Identifier: c.String(flagTo)}
+	}
+	start, end, err := cfg.SyncWindow(time.Now())
 	if err != nil {
 		return err
 	}
-	log.Info("loaded sink adapter", "adapter", cfg.Sink.Adapter.Type, "calendar", cfg.Sink.Adapter.Calendar)
+	log.Info("overriding the sync window", "start", start, "end", end)
+	return nil
+}
+
+// job is a configured job with its loaded adapters
+type job struct {
+	name       string
+	config     config.Job
+	controller sync.Controller
+	// feeds are the sinks which are published by the serve command
+	feeds  []*feed.Calendar
+	logger *log.Logger
+}
+
+// loadJob loads the adapters of the given job and sets up its sync controller
+func loadJob(c *cli.Context, name string, cfg config.Job, storage auth.Storage, stateStore *state.FileStore, nextBindAuthPort func() uint) (*job, error) {
+	logger := log.With("job", name)
+
+	if len(cfg.Sources) == 0 {
+		return nil, fmt.Errorf("no source adapter configured")
+	}
+	if len(cfg.Sinks) == 0 {
+		return nil, fmt.Errorf("no sink adapter configured")
+	}
 
-	// By default go runs a garbage collection once the memory usage doubles compared to the last GC run.
-	// Decrypting the storage in NewSourceAdapterFromConfig/NewSinkAdapterFromConfig requires a lot of memory,
-	// such that the next GC only trigger once the memory usage double compared to that peak. Explicitly trigger
-	// a GC to reset the memory usage reference level.
-	runtime.GC()
+	var sourceAdapters []sync.Source
+	for _, source := range cfg.Sources {
+		sourceLogger := logger.With("adapter", source.Adapter.Type, "type", "source", "calendar", source.Adapter.Calendar)
+
+		var sourceAdapter sync.Source
+		var err error
+		if cfg.Mode == config.ModeBidirectional {
+			// in bidirectional mode, events are written into the source as well
+			sourceAdapter, err = adapter.NewSinkAdapterFromConfig(
+				c.Context,
+				nextBindAuthPort(),
+				c.Bool(flagOpenBrowserAutomatically),
+				config.NewAdapterConfig(source.Adapter).WithRecurrence(cfg.Recurrence),
+				storage,
+				sourceLogger,
+			)
+		} else {
+			sourceAdapter, err = adapter.NewSourceAdapterFromConfig(
+				c.Context,
+				nextBindAuthPort(),
+				c.Bool(flagOpenBrowserAutomatically),
+				config.NewAdapterConfig(source.Adapter).WithRecurrence(cfg.Recurrence),
+				storage,
+				sourceLogger,
+			)
+		}
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("loaded source adapter", "adapter", source.Adapter.Type, "calendar", source.Adapter.Calendar)
+		sourceAdapters = append(sourceAdapters, sourceAdapter)
+	}
+
+	var sinkAdapters []sync.Sink
+	var feeds []*feed.Calendar
+	for _, sink := range cfg.Sinks {
+		sinkLogger := logger.With("adapter", sink.Adapter.Type, "type", "sink", "calendar", sink.Adapter.Calendar)
+
+		sinkAdapter, err := adapter.NewSinkAdapterFromConfig(
+			c.Context,
+			nextBindAuthPort(),
+			c.Bool(flagOpenBrowserAutomatically),
+			config.NewAdapterConfig(sink.Adapter).WithRecurrence(cfg.Recurrence),
+			storage,
+			sinkLogger,
+		)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("loaded sink adapter", "adapter", sink.Adapter.Type, "calendar", sink.Adapter.Calendar)
+		sinkAdapters = append(sinkAdapters, sinkAdapter)
+		if f, ok := sinkAdapter.Client().(*feed.Calendar); ok {
+			feeds = append(feeds, f)
+		}
+	}
+	if len(feeds) > 0 && cfg.Incremental {
+		// the events of a feed are kept in memory, an incremental sync would not restore them after a restart
+		return nil, fmt.Errorf("%s sinks do not support incremental syncs", adapter.FeedCalendarType)
+	}
 
 	if log.GetLevel() == log.DebugLevel {
-		for _, transformation := range cfg.Transformations {
-			log.Debug("configured transformer", "name", transformation.Name, "config", transformation.Config)
+		for _, sink := range cfg.Sinks {
+			for _, transformation := range sink.Transformations {
+				logger.Debug("configured transformer", "sink", sink.Adapter.Calendar, "name", transformation.Name, "config", transformation.Config)
+			}
 		}
 	}
 
-	controller := sync.NewController(log.Default(), sourceAdapter, sinkAdapter, sync.TransformerFactory(cfg.Transformations), sync.FilterFactory(cfg.Filters))
+	location, err := cfg.Location()
+	if err != nil {
+		return nil, err
+	}
+	controller := sync.NewController(logger, sourceAdapters, sinkAdapters[0], sync.TransformerFactory(cfg.Sinks[0].Transformations), sync.FilterFactory(cfg.Sinks[0].Filters, location))
+	for i, sink := range cfg.Sinks[1:] {
+		controller.AddSink(sinkAdapters[i+1], sync.TransformerFactory(sink.Transformations), sync.FilterFactory(sink.Filters, location))
+	}
 	if cfg.UpdateConcurrency != 0 {
 		controller.SetConcurrency(cfg.UpdateConcurrency)
 	}
-	log.Debug("loaded sync controller")
+	diffOptions, err := sync.DiffOptionsFactory(cfg.Compare)
+	if err != nil {
+		return nil, err
+	}
+	controller.SetDiffOptions(diffOptions)
+	if c.Bool(flagForce) {
+		logger.Warn("the delete limit is ignored as --force is set")
+	} else {
+		controller.SetDeleteLimit(sync.DeleteLimit{Count: cfg.DeleteLimit.Count, Percent: cfg.DeleteLimit.Percent, MinCount: cfg.DeleteLimit.MinCount})
+	}
+	controller.SetBidirectional(cfg.Mode == config.ModeBidirectional)
+	if cfg.Mode == config.ModeBidirectional {
+		controller.SetReverseTransformation(sync.TransformerFactory(cfg.Sources[0].Transformations), sync.FilterFactory(cfg.Sources[0].Filters, location))
+	}
+	if cfg.Incremental || cfg.Mode == config.ModeBidirectional {
+		controller.SetStateStore(stateStore.Scoped(name), cfg.Sync.ID())
+	}
+	logger.Debug("loaded sync controller")
 
-	if c.Bool("clean") {
-		err = controller.CleanUp(c.Context, startTime, endTime)
-		if err != nil {
-			log.Fatalf("we had some errors during cleanup:\n%v", err)
-		}
+	return &job{
+		name:       name,
+		config:     cfg,
+		controller: controller,
+		feeds:      feeds,
+		logger:     logger,
+	}, nil
+}
+
+// run synchronises (or cleans) the job once and logs a summary. The sync window is computed on every run.
+func (j *job) run(ctx context.Context, clean bool, dryRun bool) error {
+	startTime, endTime, err := j.config.SyncWindow(time.Now())
+	if err != nil {
+		return err
+	}
 
+	j.logger.Debug("configured start and end time for sync", "start", startTime, "end", endTime)
+
+	var summary sync.Summary
+	if clean {
+		summary, err = j.controller.CleanUp(ctx, startTime, endTime)
 	} else {
-		err = controller.SynchroniseTimeframe(c.Context, startTime, endTime, c.Bool("dry-run"))
-		if err != nil {
-			log.Fatalf("we had some errors during synchronization:\n%v", err)
-		}
+		summary, err = j.controller.SynchroniseTimeframe(ctx, startTime, endTime, dryRun)
 	}
-	log.Info("sync complete")
-	return nil
+	j.logger.Info("job summary", "created", summary.Created, "updated", summary.Updated, "deleted", summary.Deleted, "changedFields", summary.FieldsReport())
+	return err
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/inovex/CalendarSync/internal/sync"
+)
+
+// planFile is the content of the file written by --plan-output
+type planFile struct {
+	Jobs []*sync.Plan `json:"jobs" yaml:"jobs"`
+}
+
+// planMarshaller returns the marshal func for the format of the plan file, which is derived from its extension
+func planMarshaller(path string) (func(any) ([]byte, error), error) {
+	switch filepath.Ext(path) {
+	case ".json":
+		return func(v any) ([]byte, error) {
+			return json.MarshalIndent(v, "", "  ")
+		}, nil
+	case ".yaml", ".yml":
+		return yaml.Marshal, nil
+	default:
+		return nil, fmt.Errorf("unknown plan output format %q, use a .json, .yaml or .yml file", filepath.Ext(path))
+	}
+}
+
+// planUnmarshaller returns the unmarshal func for the format of the plan file, which is derived from its extension
+func planUnmarshaller(path string) (func([]byte, any) error, error) {
+	switch filepath.Ext(path) {
+	case ".json":
+		return json.Unmarshal, nil
+	case ".yaml", ".yml":
+		return yaml.Unmarshal, nil
+	default:
+		return nil, fmt.Errorf("unknown plan format %q, use a .json, .yaml or .yml file", filepath.Ext(path))
+	}
+}
+
+func readPlan(path string) ([]*sync.Plan, error) {
+	unmarshal, err := planUnmarshaller(path)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read plan: %w", err)
+	}
+
+	var file planFile
+	if err := unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal plan: %w", err)
+	}
+	return file.Jobs, nil
+}
+
+func writePlan(path string, plans []*sync.Plan) error {
+	marshal, err := planMarshaller(path)
+	if err != nil {
+		return err
+	}
+
+	content, err := marshal(planFile{Jobs: plans})
+	if err != nil {
+		return fmt.Errorf("cannot marshal plan: %w", err)
+	}
+
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("cannot write plan: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/urfave/cli/v2"
+
+	"github.com/inovex/CalendarSync/internal/adapter"
+	"github.com/inovex/CalendarSync/internal/serve"
+)
+
+const (
+	flagListen = "listen"
+	flagCache  = "cache"
+)
+
+var serveCommand = &cli.Command{
+	Name:  "serve",
+	Usage: "publishes the feed sinks of the jobs as iCalendar feeds via HTTP",
+	Description: fmt.Sprintf("Keeps running and serves every sink of type %q at /<token>/<calendar>.ics, or at /<calendar>.ics if it has no token. ", adapter.FeedCalendarType) +
+		"A job is synchronised when one of its feeds is requested and the last sync is older than the cache duration.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:    flagListen,
+			Usage:   "address the HTTP server listens on",
+			Value:   ":8080",
+			EnvVars: []string{"CALENDARSYNC_LISTEN"},
+		},
+		&cli.DurationFlag{
+			Name:    flagCache,
+			Usage:   "minimum time between two syncs of a job, 0 syncs the job on every request",
+			Value:   5 * time.Minute,
+			EnvVars: []string{"CALENDARSYNC_CACHE"},
+		},
+	},
+	Action: Serve,
+}
+
+func Serve(c *cli.Context) error {
+	log.Infof("started calendarsync server version %v", Version)
+
+	if c.Bool(flagClean) || c.Bool(flagDryRun) {
+		return fmt.Errorf("the server cannot be used together with --%s or --%s", flagClean, flagDryRun)
+	}
+
+	jobs, err := loadJobs(c, nil)
+	if err != nil {
+		return err
+	}
+
+	server := serve.New(log.Default(), c.Duration(flagCache))
+	for _, job := range jobs {
+		if len(job.feeds) == 0 {
+			job.logger.Warn("the job has no feed sinks and is not synchronised by the server", "type", adapter.FeedCalendarType)
+			continue
+		}
+		var feeds []serve.Feed
+		for _, f := range job.feeds {
+			feeds = append(feeds, f)
+		}
+		if err := server.Add(job.name, func(ctx context.Context) error {
+			return job.run(ctx, false, false)
+		}, feeds...); err != nil {
+			return err
+		}
+	}
+	if len(server.Paths()) == 0 {
+		return fmt.Errorf("no sink of type %q is configured", adapter.FeedCalendarType)
+	}
+
+	httpServer := &http.Server{
+		Addr:              c.String(flagListen),
+		Handler:           server,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(c.Context, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Error("failed to stop the server", "error", err)
+		}
+	}()
+
+	log.Info("serving feeds", "address", httpServer.Addr, "paths", server.Paths())
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	// wait for the running requests
+	<-stopped
+	log.Info("server stopped")
+	return nil
+}
+
+// warnUnpublishedFeeds warns about feed sinks, as their events are only published by the serve command
+func warnUnpublishedFeeds(jobs []*job) {
+	for _, job := range jobs {
+		if len(job.feeds) > 0 {
+			job.logger.Warn("feed sinks are only published by the serve command", "type", adapter.FeedCalendarType)
+		}
+	}
+}
@@ -10,10 +10,17 @@ const (
 	GoogleCalendarType      Type = "google"
 	ZepCalendarType         Type = "zep"
 	OutlookHttpCalendarType Type = "outlook_http"
+	CalDavCalendarType      Type = "caldav"
+	ICSCalendarType         Type = "ics"
+	FeedCalendarType        Type = "feed"
+	EWSCalendarType         Type = "ews"
+	JMAPCalendarType        Type = "jmap"
 )
 
 // ConfigReader provides an interface for adapters to load their own configuration map.
 // It's the adapter's responsibility to validate that the map is valid.
 type ConfigReader interface {
 	Adapter() config.Adapter
+	// Series returns true if series are synchronised as recurring events
+	Series() bool
 }
@@ -0,0 +1,279 @@
+package caldav
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/caldav"
+
+	"github.com/inovex/CalendarSync/internal/adapter/icalendar"
+	"github.com/inovex/CalendarSync/internal/adapter/port"
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+const (
+	endpointKey = "endpoint"
+	usernameKey = "username"
+	passwordKey = "password"
+)
+
+// CalendarAPI reads and writes the events of a calendar on a CalDAV server like Nextcloud, Radicale, Fastmail
+// or iCloud. The metadata of CalendarSync is stored in X- properties of the events.
+type CalendarAPI struct {
+	username string
+	password string
+	endpoint *url.URL
+
+	httpClient webdav.HTTPClient
+	client     *caldav.Client
+
+	// calendarID is the configured calendar, either the URL or path of the calendar or its display name
+	calendarID   string
+	calendarPath string
+	series       bool
+
+	logger *log.Logger
+}
+
+// Assert that the expected interfaces are implemented
+var _ port.Configurable = &CalendarAPI{}
+var _ port.LogSetter = &CalendarAPI{}
+var _ port.CalendarIDSetter = &CalendarAPI{}
+var _ port.SeriesSetter = &CalendarAPI{}
+
+func (c *CalendarAPI) SetCalendarID(calendarID string) error {
+	if calendarID == "" {
+		return fmt.Errorf("%s adapter 'calendar' cannot be empty", c.Name())
+	}
+	c.calendarID = calendarID
+	return nil
+}
+
+// SetSeries lists series as recurring events with their modified instances instead of their instances
+func (c *CalendarAPI) SetSeries(series bool) {
+	c.series = series
+}
+
+func (c *CalendarAPI) SetLogger(logger *log.Logger) {
+	c.logger = logger
+}
+
+func (c *CalendarAPI) Name() string {
+	return "CalDAV"
+}
+
+// GetCalendarHash calculates a unique hash for this adapter based on the user and the calendar
+func (c *CalendarAPI) GetCalendarHash() string {
+	components := []string{c.username, c.endpoint.Host, c.calendarPath}
+	sum := sha1.Sum([]byte(strings.Join(components, "")))
+	return base64.URLEncoding.EncodeToString(sum[:])
+}
+
+// Initialize connects to the CalDAV server and resolves the configured calendar
+func (c *CalendarAPI) Initialize(ctx context.Context, openBrowser bool, config map[string]interface{}) error {
+	for _, key := range []string{endpointKey, usernameKey, passwordKey} {
+		if _, ok := config[key].(string); !ok {
+			return fmt.Errorf("missing config key: %s", key)
+		}
+	}
+	c.username = config[usernameKey].(string)
+	c.password = config[passwordKey].(string)
+
+	var err error
+	c.endpoint, err = url.Parse(config[endpointKey].(string))
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	calendarURL, err := url.Parse(c.calendarID)
+	if err == nil && calendarURL.IsAbs() {
+		// the calendar may be hosted on a different server than the endpoint, e.g. on iCloud
+		c.endpoint = &url.URL{Scheme: calendarURL.Scheme, Host: calendarURL.Host, User: calendarURL.User}
+	}
+
+	c.httpClient = webdav.HTTPClientWithBasicAuth(http.DefaultClient, c.username, c.password)
+	c.client, err = caldav.NewClient(c.httpClient, c.endpoint.String())
+	if err != nil {
+		return fmt.Errorf("unable to create caldav client: %w", err)
+	}
+
+	c.calendarPath, err = c.findCalendar(ctx)
+	if err != nil {
+		return err
+	}
+	c.logger.Debug("using calendar", "path", c.calendarPath)
+	return nil
+}
+
+// findCalendar resolves the configured calendar, which is either the URL or the path of the calendar collection
+// or its display name
+func (c *CalendarAPI) findCalendar(ctx context.Context) (string, error) {
+	if calendarURL, err := url.Parse(c.calendarID); err == nil && (calendarURL.IsAbs() || strings.HasPrefix(c.calendarID, "/")) {
+		return collectionPath(calendarURL.Path), nil
+	}
+
+	principal, err := c.client.FindCurrentUserPrincipal(ctx)
+	if err != nil {
+		return "", fmt.Errorf("unable to find user principal: %w", err)
+	}
+	homeSet, err := c.client.FindCalendarHomeSet(ctx, principal)
+	if err != nil {
+		return "", fmt.Errorf("unable to find calendar homeSet: %w", err)
+	}
+	calendars, err := c.client.FindCalendars(ctx, homeSet)
+	if err != nil {
+		return "", fmt.Errorf("cannot find calendars: %w", err)
+	}
+
+	var names, matches []string
+	for _, calendar := range calendars {
+		names = append(names, calendar.Name)
+		if calendar.Name == c.calendarID {
+			matches = append(matches, calendar.Path)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("calendar %q not found, available calendars: %s", c.calendarID, strings.Join(names, ", "))
+	case 1:
+		return collectionPath(matches[0]), nil
+	default:
+		return "", fmt.Errorf("multiple calendars are named %q, please configure the URL of the calendar", c.calendarID)
+	}
+}
+
+func collectionPath(p string) string {
+	if !strings.HasSuffix(p, "/") {
+		return p + "/"
+	}
+	return p
+}
+
+// EventsInTimeframe returns the events of the calendar within the timeframe. Series are expanded to their
+// instances unless series are synced.
+func (c *CalendarAPI) EventsInTimeframe(ctx context.Context, start time.Time, end time.Time) ([]models.Event, error) {
+	objects, err := c.client.QueryCalendar(ctx, c.calendarPath, &caldav.CalendarQuery{
+		CompRequest: caldav.CalendarCompRequest{
+			Name:     ical.CompCalendar,
+			AllProps: true,
+			AllComps: true,
+		},
+		CompFilter: caldav.CompFilter{
+			Name: ical.CompCalendar,
+			Comps: []caldav.CompFilter{
+				{
+					Name:  ical.CompEvent,
+					Start: start,
+					End:   end,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to query calendar %s: %w", c.calendarPath, err)
+	}
+
+	decoder := icalendar.Decoder{SourceID: c.GetCalendarHash(), Series: c.series, Start: start, End: end}
+	var events []models.Event
+	for _, object := range objects {
+		objectEvents, err := decoder.Decode(object.Data, object.Path, object.ETag)
+		if err != nil {
+			c.logger.Warn("skipping calendar object", "path", object.Path, "error", err)
+			continue
+		}
+		events = append(events, objectEvents...)
+	}
+
+	c.logger.Infof("loaded %d events between %s and %s.", len(events), start.Format(time.DateOnly), end.Format(time.DateOnly))
+
+	return events, nil
+}
+
+// CreateEvent creates a calendar object for the event. Modified instances are added to the calendar object
+// of their series.
+func (c *CalendarAPI) CreateEvent(ctx context.Context, e models.Event) error {
+	var err error
+	if e.Instance != nil {
+		// the ETag of the event is the version of the source event, not of the series in this calendar
+		err = c.modifyObject(ctx, e.Instance.SeriesID, "", func(calendar *ical.Calendar) error {
+			return icalendar.SetInstance(calendar, e, e.Instance.OriginalStart)
+		})
+	} else {
+		// the UID is derived from the SyncID, as the instances of a series have the same iCalUID in the source
+		uid := "calendarsync-" + e.Metadata.SyncID
+		err = c.put(ctx, path.Join(c.calendarPath, uid+".ics"), icalendar.NewCalendar(e, uid), "", true)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info("Event created", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+// UpdateEvent replaces the event in its calendar object. It fails with errModified if the calendar object was
+// modified since the event was listed.
+func (c *CalendarAPI) UpdateEvent(ctx context.Context, e models.Event) error {
+	objectPath, originalStart, isInstance := instanceOf(e)
+
+	err := c.modifyObject(ctx, objectPath, e.ETag, func(calendar *ical.Calendar) error {
+		if isInstance {
+			return icalendar.SetInstance(calendar, e, originalStart)
+		}
+		return icalendar.SetEvent(calendar, e)
+	})
+	if errors.Is(err, errNotFound) {
+		return errors.New("already deleted")
+	} else if err != nil {
+		return err
+	}
+
+	c.logger.Info("Event updated", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+// DeleteEvent deletes the calendar object of the event. Instances of a series are cancelled.
+func (c *CalendarAPI) DeleteEvent(ctx context.Context, e models.Event) error {
+	objectPath, originalStart, isInstance := instanceOf(e)
+
+	var err error
+	if isInstance {
+		err = c.modifyObject(ctx, objectPath, e.ETag, func(calendar *ical.Calendar) error {
+			return icalendar.CancelInstance(calendar, originalStart)
+		})
+	} else {
+		err = c.delete(ctx, objectPath, e.ETag)
+	}
+	if errors.Is(err, errNotFound) {
+		c.logger.Debug("Event is already deleted.", "method", "DeleteEvent", "title", e.ShortTitle(), "time", e.StartTime.String())
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	c.logger.Info("Event deleted", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+// instanceOf returns the path of the calendar object of the event. If the event is an instance of a series,
+// it returns its original start as well.
+func instanceOf(e models.Event) (string, time.Time, bool) {
+	if e.Instance != nil {
+		return e.Instance.SeriesID, e.Instance.OriginalStart, true
+	}
+	return icalendar.SplitInstanceID(e.ID)
+}
@@ -0,0 +1,392 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav"
+	"github.com/emersion/go-webdav/caldav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// memoryBackend is an in-memory CalDAV backend with the calendars of a single user
+type memoryBackend struct {
+	mu        sync.Mutex
+	calendars []caldav.Calendar
+	objects   map[string]caldav.CalendarObject
+	version   int
+}
+
+func newMemoryBackend() *memoryBackend {
+	return &memoryBackend{
+		calendars: []caldav.Calendar{
+			{Path: "/user/calendars/work/", Name: "Work"},
+			{Path: "/user/calendars/private/", Name: "Private"},
+		},
+		objects: make(map[string]caldav.CalendarObject),
+	}
+}
+
+func (b *memoryBackend) CurrentUserPrincipal(ctx context.Context) (string, error) {
+	return "/user/", nil
+}
+
+func (b *memoryBackend) CalendarHomeSetPath(ctx context.Context) (string, error) {
+	return "/user/calendars/", nil
+}
+
+func (b *memoryBackend) CreateCalendar(ctx context.Context, calendar *caldav.Calendar) error {
+	return webdav.NewHTTPError(http.StatusForbidden, fmt.Errorf("not supported"))
+}
+
+func (b *memoryBackend) ListCalendars(ctx context.Context) ([]caldav.Calendar, error) {
+	return b.calendars, nil
+}
+
+func (b *memoryBackend) GetCalendar(ctx context.Context, path string) (*caldav.Calendar, error) {
+	for _, calendar := range b.calendars {
+		if calendar.Path == path {
+			return &calendar, nil
+		}
+	}
+	return nil, webdav.NewHTTPError(http.StatusNotFound, fmt.Errorf("calendar %s not found", path))
+}
+
+func (b *memoryBackend) GetCalendarObject(ctx context.Context, path string, req *caldav.CalendarCompRequest) (*caldav.CalendarObject, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	object, ok := b.objects[path]
+	if !ok {
+		return nil, webdav.NewHTTPError(http.StatusNotFound, fmt.Errorf("object %s not found", path))
+	}
+	return &object, nil
+}
+
+func (b *memoryBackend) ListCalendarObjects(ctx context.Context, path string, req *caldav.CalendarCompRequest) ([]caldav.CalendarObject, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var objects []caldav.CalendarObject
+	for objectPath, object := range b.objects {
+		if strings.HasPrefix(objectPath, path) {
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+func (b *memoryBackend) QueryCalendarObjects(ctx context.Context, path string, query *caldav.CalendarQuery) ([]caldav.CalendarObject, error) {
+	objects, err := b.ListCalendarObjects(ctx, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return caldav.Filter(query, objects)
+}
+
+func (b *memoryBackend) PutCalendarObject(ctx context.Context, path string, calendar *ical.Calendar, opts *caldav.PutCalendarObjectOptions) (*caldav.CalendarObject, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	existing, exists := b.objects[path]
+	if opts.IfNoneMatch.IsWildcard() && exists {
+		return nil, webdav.NewHTTPError(http.StatusPreconditionFailed, fmt.Errorf("object %s exists", path))
+	}
+	if opts.IfMatch.IsSet() {
+		if etag, err := opts.IfMatch.ETag(); err != nil || !exists || etag != existing.ETag {
+			return nil, webdav.NewHTTPError(http.StatusPreconditionFailed, fmt.Errorf("object %s was modified", path))
+		}
+	}
+
+	// store the encoded calendar, as the server would
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(calendar); err != nil {
+		return nil, webdav.NewHTTPError(http.StatusBadRequest, err)
+	}
+	decoded, err := ical.NewDecoder(&buf).Decode()
+	if err != nil {
+		return nil, webdav.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	b.version++
+	object := caldav.CalendarObject{Path: path, ModTime: time.Now(), ETag: fmt.Sprintf("v%d", b.version), Data: decoded}
+	b.objects[path] = object
+	return &object, nil
+}
+
+func (b *memoryBackend) DeleteCalendarObject(ctx context.Context, path string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.objects[path]; !ok {
+		return webdav.NewHTTPError(http.StatusNotFound, fmt.Errorf("object %s not found", path))
+	}
+	delete(b.objects, path)
+	return nil
+}
+
+// putRaw stores a calendar object which was written by another client
+func (b *memoryBackend) putRaw(t *testing.T, path string, data string) {
+	calendar, err := ical.NewDecoder(strings.NewReader(strings.ReplaceAll(data, "\n", "\r\n"))).Decode()
+	require.NoError(t, err)
+	_, err = b.PutCalendarObject(context.Background(), path, calendar, &caldav.PutCalendarObjectOptions{})
+	require.NoError(t, err)
+}
+
+// ServeHTTP checks the If-Match header of DELETE requests, which is not passed to the backend
+func (b *memoryBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ifMatch := webdav.ConditionalMatch(r.Header.Get("If-Match")); r.Method == http.MethodDelete && ifMatch.IsSet() {
+		object, err := b.GetCalendarObject(r.Context(), r.URL.Path, nil)
+		if etag, _ := ifMatch.ETag(); err == nil && etag != object.ETag {
+			http.Error(w, "object was modified", http.StatusPreconditionFailed)
+			return
+		}
+	}
+	(&caldav.Handler{Backend: b}).ServeHTTP(w, r)
+}
+
+func newTestAdapter(t *testing.T, backend *memoryBackend, calendar string) *CalendarAPI {
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+
+	adapter := &CalendarAPI{}
+	adapter.SetLogger(log.Default())
+	require.NoError(t, adapter.SetCalendarID(calendar))
+	require.NoError(t, adapter.Initialize(context.Background(), false, map[string]interface{}{
+		endpointKey: server.URL,
+		usernameKey: "user",
+		passwordKey: "secret",
+	}))
+	return adapter
+}
+
+func TestFindCalendar(t *testing.T) {
+	backend := newMemoryBackend()
+
+	assert.Equal(t, "/user/calendars/private/", newTestAdapter(t, backend, "Private").calendarPath)
+	assert.Equal(t, "/user/calendars/work/", newTestAdapter(t, backend, "/user/calendars/work").calendarPath)
+
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	assert.Equal(t, "/user/calendars/work/", newTestAdapter(t, backend, server.URL+"/user/calendars/work/").calendarPath)
+
+	adapter := &CalendarAPI{}
+	adapter.SetLogger(log.Default())
+	require.NoError(t, adapter.SetCalendarID("Holidays"))
+	err := adapter.Initialize(context.Background(), false, map[string]interface{}{
+		endpointKey: server.URL,
+		usernameKey: "user",
+		passwordKey: "secret",
+	})
+	assert.ErrorContains(t, err, `calendar "Holidays" not found, available calendars: Work, Private`)
+}
+
+func TestCreateUpdateDeleteEvent(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryBackend()
+	adapter := newTestAdapter(t, backend, "Work")
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	start := time.Date(2024, 3, 12, 10, 0, 0, 0, berlin)
+	event := models.Event{
+		Title:       "Standup",
+		Description: "Daily standup, with everyone",
+		Location:    "Room 1; 2nd floor",
+		StartTime:   start,
+		EndTime:     start.Add(15 * time.Minute),
+		TimeZone:    "Europe/Berlin",
+		Attendees:   models.Attendees{{Email: "alice@example.com", DisplayName: "Alice"}},
+		Reminders: models.Reminders{{
+			Actions: models.ReminderActionDisplay,
+			Trigger: models.ReminderTrigger{PointInTime: start.Add(-10 * time.Minute)},
+		}},
+		Metadata: &models.Metadata{SyncID: "1234", OriginalEventUri: "https://example.com/events/1", SourceID: "source"},
+	}
+	require.NoError(t, adapter.CreateEvent(ctx, event))
+	require.Error(t, adapter.CreateEvent(ctx, event), "creating an existing event must fail")
+
+	events, err := adapter.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	listed := events[0]
+	assert.Equal(t, "/user/calendars/work/calendarsync-1234.ics", listed.ID)
+	assert.Equal(t, "calendarsync-1234", listed.ICalUID)
+	assert.NotEmpty(t, listed.ETag)
+	assert.Equal(t, event.Metadata, listed.Metadata)
+	assert.Equal(t, "Europe/Berlin", listed.TimeZone)
+	assert.Empty(t, models.DiffEvents(listed, event))
+
+	listed.Title = "Retro"
+	require.NoError(t, adapter.UpdateEvent(ctx, listed))
+	events, err = adapter.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "Retro", events[0].Title)
+	assert.NotEqual(t, listed.ETag, events[0].ETag)
+
+	// the event was modified since it was listed
+	assert.ErrorIs(t, adapter.DeleteEvent(ctx, listed), errModified)
+
+	require.NoError(t, adapter.DeleteEvent(ctx, events[0]))
+	require.NoError(t, adapter.DeleteEvent(ctx, events[0]), "deleting a deleted event must succeed")
+	events, err = adapter.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+// TestUpdateModifiedEvent asserts that an event which was modified since it was listed is not overwritten
+func TestUpdateModifiedEvent(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryBackend()
+	adapter := newTestAdapter(t, backend, "Work")
+
+	start := time.Date(2024, 3, 12, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, adapter.CreateEvent(ctx, models.Event{
+		Title:     "Standup",
+		StartTime: start,
+		EndTime:   start.Add(15 * time.Minute),
+		Metadata:  &models.Metadata{SyncID: "1234", SourceID: "source"},
+	}))
+	events, err := adapter.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	listed := events[0]
+
+	// another client modifies the event after it was listed
+	modified := listed
+	modified.Title = "Retro"
+	require.NoError(t, adapter.UpdateEvent(ctx, modified))
+
+	listed.Title = "Planning"
+	assert.ErrorIs(t, adapter.UpdateEvent(ctx, listed), errModified)
+
+	events, err = adapter.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "Retro", events[0].Title)
+}
+
+func TestSeries(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryBackend()
+	adapter := newTestAdapter(t, backend, "Work")
+	adapter.SetSeries(true)
+
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	series := models.Event{
+		Title:     "Weekly",
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		Recurrence: &models.Recurrence{
+			Rule:    "FREQ=WEEKLY;COUNT=4",
+			ExDates: []time.Time{start.AddDate(0, 0, 7)},
+		},
+		Metadata: &models.Metadata{SyncID: "series", SourceID: "source"},
+	}
+	require.NoError(t, adapter.CreateEvent(ctx, series))
+
+	instance := models.Event{
+		Title:     "Weekly (moved)",
+		StartTime: start.AddDate(0, 0, 15),
+		EndTime:   start.AddDate(0, 0, 15).Add(time.Hour),
+		Metadata:  &models.Metadata{SyncID: "instance", SourceID: "source"},
+		Instance: &models.SeriesInstance{
+			SeriesID:      "/user/calendars/work/calendarsync-series.ics",
+			SeriesSyncID:  "series",
+			OriginalStart: start.AddDate(0, 0, 14),
+		},
+	}
+	require.NoError(t, adapter.CreateEvent(ctx, instance))
+
+	from, to := start.AddDate(0, 0, -1), start.AddDate(0, 1, 0)
+	events, err := adapter.EventsInTimeframe(ctx, from, to)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "FREQ=WEEKLY;COUNT=4", events[0].Recurrence.Rule)
+	assert.Empty(t, models.DiffEvents(events[0], series))
+	assert.Equal(t, "Weekly (moved)", events[1].Title)
+	assert.Equal(t, "instance", events[1].Metadata.SyncID)
+	assert.Equal(t, &models.SeriesInstance{
+		SeriesID:      "/user/calendars/work/calendarsync-series.ics",
+		SeriesSyncID:  "series",
+		OriginalStart: start.AddDate(0, 0, 14),
+	}, events[1].Instance)
+
+	// without series, every instance is listed
+	adapter.SetSeries(false)
+	events, err = adapter.EventsInTimeframe(ctx, from, to)
+	require.NoError(t, err)
+	var titles []string
+	for _, event := range events {
+		titles = append(titles, event.StartTime.Format(time.DateOnly)+" "+event.Title)
+	}
+	assert.Equal(t, []string{"2024-03-04 Weekly", "2024-03-25 Weekly", "2024-03-19 Weekly (moved)"}, titles)
+
+	// deleting an instance cancels it
+	require.NoError(t, adapter.DeleteEvent(ctx, events[1]))
+	adapter.SetSeries(true)
+	events, err = adapter.EventsInTimeframe(ctx, from, to)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Len(t, events[0].Recurrence.ExDates, 2)
+}
+
+func TestEventsOfOtherClients(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemoryBackend()
+	backend.putRaw(t, "/user/calendars/work/meeting.ics", `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Example//Client//EN
+BEGIN:VTIMEZONE
+TZID:Europe/Berlin
+BEGIN:STANDARD
+DTSTART:16010101T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=10
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:16010101T020000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=3
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:meeting@example.com
+DTSTAMP:20240301T000000Z
+DTSTART;TZID=Europe/Berlin:20240312T100000
+DTEND;TZID=Europe/Berlin:20240312T110000
+SUMMARY:Meeting
+END:VEVENT
+BEGIN:VEVENT
+UID:holiday@example.com
+DTSTAMP:20240301T000000Z
+DTSTART;VALUE=DATE:20240313
+SUMMARY:Holiday
+END:VEVENT
+END:VCALENDAR
+`)
+	adapter := newTestAdapter(t, backend, "Work")
+
+	events, err := adapter.EventsInTimeframe(ctx, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	assert.Equal(t, "Meeting", events[0].Title)
+	assert.Equal(t, "Europe/Berlin", events[0].TimeZone)
+	assert.True(t, time.Date(2024, 3, 12, 9, 0, 0, 0, time.UTC).Equal(events[0].StartTime))
+	assert.Equal(t, models.NewEventMetadata("meeting@example.com", "", adapter.GetCalendarHash()), events[0].Metadata)
+
+	assert.True(t, events[1].AllDay)
+	assert.Equal(t, time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC), events[1].EndTime)
+}
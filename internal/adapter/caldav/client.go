@@ -0,0 +1,129 @@
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/emersion/go-ical"
+)
+
+var (
+	errNotFound = errors.New("calendar object not found")
+	// errModified is returned if a calendar object was modified since it was loaded
+	errModified = errors.New("calendar object was modified concurrently")
+)
+
+// modifyObject loads the calendar object, applies the modification and writes it back, if it still has the
+// given ETag, i.e. the version in which the event was listed. Otherwise, errModified is returned, so that changes
+// which were made since the sync listed the event are not overwritten. If the ETag is empty, e.g. when an instance
+// is added to a series, the object is only written back if it was not modified since it was loaded.
+func (c *CalendarAPI) modifyObject(ctx context.Context, objectPath string, etag string, modify func(calendar *ical.Calendar) error) error {
+	calendar, loadedETag, err := c.get(ctx, objectPath)
+	if err != nil {
+		return err
+	}
+	if etag == "" {
+		etag = loadedETag
+	}
+	if err := modify(calendar); err != nil {
+		return fmt.Errorf("failed to modify calendar object %s: %w", objectPath, err)
+	}
+	return c.put(ctx, objectPath, calendar, etag, false)
+}
+
+// get loads the calendar object and returns its ETag as given by the server
+func (c *CalendarAPI) get(ctx context.Context, objectPath string) (*ical.Calendar, string, error) {
+	resp, err := c.do(ctx, http.MethodGet, objectPath, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	calendar, err := ical.NewDecoder(resp.Body).Decode()
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to decode calendar object %s: %w", objectPath, err)
+	}
+	return calendar, resp.Header.Get("ETag"), nil
+}
+
+// put writes the calendar object. A created object must not exist yet, an existing object is only overwritten
+// if it still has the given ETag.
+func (c *CalendarAPI) put(ctx context.Context, objectPath string, calendar *ical.Calendar, etag string, create bool) error {
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(calendar); err != nil {
+		return fmt.Errorf("unable to encode calendar object %s: %w", objectPath, err)
+	}
+
+	header := http.Header{"Content-Type": {ical.MIMEType}}
+	switch {
+	case create:
+		header.Set("If-None-Match", "*")
+	case etag != "":
+		header.Set("If-Match", quoteETag(etag))
+	}
+
+	resp, err := c.do(ctx, http.MethodPut, objectPath, header, &buf)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// delete deletes the calendar object, if it still has the given ETag
+func (c *CalendarAPI) delete(ctx context.Context, objectPath string, etag string) error {
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-Match", quoteETag(etag))
+	}
+	resp, err := c.do(ctx, http.MethodDelete, objectPath, header, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// do sends the request to the path relative to the endpoint. Responses without a success status are returned
+// as error.
+func (c *CalendarAPI) do(ctx context.Context, method string, objectPath string, header http.Header, body io.Reader) (*http.Response, error) {
+	target := c.endpoint.ResolveReference(&url.URL{Path: objectPath})
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, objectPath, err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusGone:
+		return nil, errNotFound
+	case http.StatusPreconditionFailed:
+		return nil, fmt.Errorf("%s %s failed: %w", method, objectPath, errModified)
+	default:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%s %s failed: %s %s", method, objectPath, resp.Status, strings.TrimSpace(string(message)))
+	}
+}
+
+// quoteETag quotes an ETag which was unquoted while listing the calendar
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
@@ -0,0 +1,404 @@
+package ews
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/pkg/browser"
+	"golang.org/x/oauth2"
+
+	"github.com/inovex/CalendarSync/internal/adapter/port"
+	"github.com/inovex/CalendarSync/internal/auth"
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+const (
+	endpointKey = "endpoint"
+	usernameKey = "username"
+	passwordKey = "password"
+	authKey     = "auth"
+
+	authNTLM  = "ntlm"
+	authBasic = "basic"
+
+	// timeFormat is the format of the times in the requests, EWS expects them without fractional seconds
+	timeFormat = "2006-01-02T15:04:05Z"
+	// pageSize is the maximum number of items which are found or loaded by a single request
+	pageSize = 100
+)
+
+// CalendarAPI reads and writes the events of a calendar on an on-premises Exchange server via Exchange Web Services.
+// The metadata of CalendarSync is stored in extended properties of the calendar items. Series are always expanded
+// to their instances.
+type CalendarAPI struct {
+	endpoint   string
+	username   string
+	httpClient *http.Client
+
+	// calendarID is the configured calendar, either the default calendar or the id of a calendar folder
+	calendarID string
+
+	credentials auth.Credentials
+	storage     auth.Storage
+	bindPort    uint
+
+	logger *log.Logger
+}
+
+// Assert that the expected interfaces are implemented
+var _ port.Configurable = &CalendarAPI{}
+var _ port.LogSetter = &CalendarAPI{}
+var _ port.CalendarIDSetter = &CalendarAPI{}
+var _ port.OAuth2Adapter = &CalendarAPI{}
+
+func (c *CalendarAPI) SetCalendarID(calendarID string) error {
+	if calendarID == "" {
+		return fmt.Errorf("%s adapter 'calendar' cannot be empty", c.Name())
+	}
+	c.calendarID = calendarID
+	return nil
+}
+
+// SetupOauth2 keeps the oAuth2 credentials. They are optional, without a client id the adapter authenticates
+// with the username and the password.
+func (c *CalendarAPI) SetupOauth2(ctx context.Context, credentials auth.Credentials, storage auth.Storage, bindPort uint) error {
+	if credentials.Client.Id != "" && credentials.Tenant.Id == "" {
+		return fmt.Errorf("%s adapter oAuth2 'tenantId' cannot be empty", c.Name())
+	}
+	c.credentials = credentials
+	c.storage = storage
+	c.bindPort = bindPort
+	return nil
+}
+
+func (c *CalendarAPI) SetLogger(logger *log.Logger) {
+	c.logger = logger
+}
+
+func (c *CalendarAPI) Name() string {
+	return "EWS"
+}
+
+// GetCalendarHash calculates a unique hash for this adapter based on the user and the calendar
+func (c *CalendarAPI) GetCalendarHash() string {
+	sum := sha1.Sum([]byte(c.username + c.endpoint + c.calendarID))
+	return base64.URLEncoding.EncodeToString(sum[:])
+}
+
+// Initialize sets up the authentication against the EWS endpoint
+func (c *CalendarAPI) Initialize(ctx context.Context, openBrowser bool, config map[string]interface{}) error {
+	endpoint, ok := config[endpointKey].(string)
+	if !ok {
+		return fmt.Errorf("missing config key: %s", endpointKey)
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" {
+		return fmt.Errorf("invalid endpoint: %s", endpoint)
+	}
+	c.endpoint = endpoint
+
+	if c.credentials.Client.Id != "" {
+		// EWS only accepts tokens for the resource of the server, which is registered for Exchange Online and hybrid setups
+		scope := fmt.Sprintf("https://%s/EWS.AccessAsUser.All", endpointURL.Host)
+		c.httpClient, err = c.oAuthClient(ctx, openBrowser, scope)
+		return err
+	}
+
+	for _, key := range []string{usernameKey, passwordKey} {
+		if _, ok := config[key].(string); !ok {
+			return fmt.Errorf("missing config key: %s", key)
+		}
+	}
+	c.username = config[usernameKey].(string)
+	password := config[passwordKey].(string)
+
+	authentication := authNTLM
+	if value, ok := config[authKey]; ok {
+		authentication, _ = value.(string)
+	}
+	switch authentication {
+	case authNTLM:
+		c.httpClient = &http.Client{Transport: &ntlmTransport{username: c.username, password: password}}
+	case authBasic:
+		c.httpClient = &http.Client{Transport: &basicTransport{username: c.username, password: password}}
+	default:
+		return fmt.Errorf("config key %s must be one of %s, %s", authKey, authNTLM, authBasic)
+	}
+	return nil
+}
+
+// oAuthClient returns a client which authenticates with the stored token or with a token of the browser flow
+func (c *CalendarAPI) oAuthClient(ctx context.Context, openBrowser bool, scope string) (*http.Client, error) {
+	oAuthConfig := oauth2.Config{
+		ClientID:     c.credentials.Client.Id,
+		ClientSecret: c.credentials.Client.Secret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/authorize", c.credentials.Tenant.Id),
+			TokenURL:  fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", c.credentials.Tenant.Id),
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+		Scopes: []string{scope, "offline_access"}, // You need to request offline_access in order to retrieve a refresh token
+	}
+	storageKey := c.GetCalendarHash()
+
+	var token *oauth2.Token
+	storedAuth, err := c.storage.ReadCalendarAuth(storageKey)
+	if err != nil {
+		return nil, err
+	}
+	if storedAuth != nil {
+		expiry, err := time.Parse(time.RFC3339, storedAuth.OAuth2.Expiry)
+		if err != nil {
+			return nil, err
+		}
+		// the token source refreshes the access token if it's expired
+		token, err = oAuthConfig.TokenSource(ctx, &oauth2.Token{
+			AccessToken:  storedAuth.OAuth2.AccessToken,
+			RefreshToken: storedAuth.OAuth2.RefreshToken,
+			Expiry:       expiry,
+			TokenType:    storedAuth.OAuth2.TokenType,
+		}).Token()
+		if err != nil {
+			// most probably the refresh token is now also expired
+			c.logger.Info("saved credentials expired, we need to reauthenticate..", "error", err)
+			token = nil
+			if err := c.storage.RemoveCalendarAuth(storageKey); err != nil {
+				return nil, fmt.Errorf("failed to remove authentication for calendar %s: %w", c.calendarID, err)
+			}
+		} else {
+			c.logger.Debug("using stored credentials")
+		}
+	}
+
+	if token == nil {
+		handler, err := auth.NewOAuthHandler(oAuthConfig, c.bindPort)
+		if err != nil {
+			return nil, err
+		}
+		authURL := handler.Configuration().AuthCodeURL("state", oauth2.AccessTypeOffline)
+		if openBrowser {
+			c.logger.Infof("opening browser window for authentication of %s\n", c.Name())
+			if err := browser.OpenURL(authURL); err != nil {
+				c.logger.Infof("browser did not open, please authenticate adapter %s:\n\n %s\n\n\n", c.Name(), authURL)
+			}
+		} else {
+			c.logger.Infof("Please authenticate adapter %s:\n\n %s\n\n\n", c.Name(), authURL)
+		}
+		if err := handler.Listen(ctx); err != nil {
+			return nil, err
+		}
+		token = handler.Token()
+	}
+
+	// save the token for the next use
+	_, err = c.storage.WriteCalendarAuth(auth.CalendarAuth{
+		CalendarID: storageKey,
+		OAuth2: auth.OAuth2Object{
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+			Expiry:       token.Expiry.Format(time.RFC3339),
+			TokenType:    token.TokenType,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return oAuthConfig.Client(ctx, token), nil
+}
+
+// folder returns the configured calendar folder, "primary" and "calendar" refer to the default calendar
+func (c *CalendarAPI) folder() folderID {
+	if c.calendarID == "primary" || c.calendarID == "calendar" {
+		return folderID{Distinguished: &distinguishedFolderID{ID: "calendar"}}
+	}
+	return folderID{Folder: &itemID{ID: c.calendarID}}
+}
+
+// EventsInTimeframe returns the events of the calendar within the timeframe, series are expanded to their instances
+func (c *CalendarAPI) EventsInTimeframe(ctx context.Context, start time.Time, end time.Time) ([]models.Event, error) {
+	ids, err := c.findItems(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	items, err := c.getItems(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.Event, 0, len(items))
+	for _, item := range items {
+		events = append(events, itemToEvent(item, c.GetCalendarHash()))
+	}
+
+	c.logger.Infof("loaded %d events between %s and %s.", len(events), start.Format(time.DateOnly), end.Format(time.DateOnly))
+
+	return events, nil
+}
+
+// findItems returns the ids of the items within the timeframe. A calendar view cannot be paged, instead the next
+// view starts at the start of the last item of the previous one.
+func (c *CalendarAPI) findItems(ctx context.Context, start time.Time, end time.Time) ([]itemID, error) {
+	var ids []itemID
+	found := make(map[string]bool)
+	for {
+		var resp findItemResponse
+		err := c.call(ctx, findItemRequest{
+			Traversal: "Shallow",
+			Shape: itemShape{
+				BaseShape:  "IdOnly",
+				Properties: &additionalProperties{Fields: []fieldURI{{FieldURI: "calendar:Start"}}},
+			},
+			View: calendarView{
+				MaxEntries: pageSize,
+				Start:      start.UTC().Format(timeFormat),
+				End:        end.UTC().Format(timeFormat),
+			},
+			Parent: c.folder(),
+		}, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find items in calendar %s: %w", c.calendarID, err)
+		}
+		if len(resp.Messages) != 1 {
+			return nil, fmt.Errorf("unable to find items in calendar %s: unexpected response", c.calendarID)
+		}
+		if err := resp.Messages[0].err(); err != nil {
+			return nil, fmt.Errorf("unable to find items in calendar %s: %w", c.calendarID, err)
+		}
+
+		view := resp.Messages[0].RootFolder
+		for _, item := range view.Items {
+			if !found[item.ItemID.ID] {
+				found[item.ItemID.ID] = true
+				ids = append(ids, item.ItemID)
+			}
+		}
+		if view.IncludesLastItemInRange || len(view.Items) == 0 {
+			return ids, nil
+		}
+
+		next := view.Items[len(view.Items)-1].Start
+		if !next.After(start) {
+			return nil, fmt.Errorf("more than %d items start at %s", pageSize, next)
+		}
+		start = next
+	}
+}
+
+// getItems loads the items with all properties which are needed for the events
+func (c *CalendarAPI) getItems(ctx context.Context, ids []itemID) ([]calendarItem, error) {
+	properties := &additionalProperties{Fields: []fieldURI{{FieldURI: "calendar:StartTimeZone"}}}
+	for _, name := range metadataProperties {
+		properties.Extended = append(properties.Extended, metadataField(name))
+	}
+
+	var items []calendarItem
+	for len(ids) > 0 {
+		batch := ids[:min(pageSize, len(ids))]
+		ids = ids[len(batch):]
+
+		var resp getItemResponse
+		err := c.call(ctx, getItemRequest{
+			Shape: itemShape{BaseShape: "AllProperties", BodyType: "Text", Properties: properties},
+			IDs:   batch,
+		}, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load items: %w", err)
+		}
+		for _, message := range resp.Messages {
+			if err := message.err(); errors.Is(err, errItemNotFound) {
+				// the item was deleted in the meantime
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("unable to load items: %w", err)
+			}
+			items = append(items, message.Items...)
+		}
+	}
+	return items, nil
+}
+
+// CreateEvent creates a calendar item for the event without sending invitations to the attendees
+func (c *CalendarAPI) CreateEvent(ctx context.Context, e models.Event) error {
+	var resp createItemResponse
+	err := c.call(ctx, createItemRequest{
+		SendMeetingInvitations: "SendToNone",
+		Folder:                 c.folder(),
+		Items:                  []calendarItemData{eventToItem(e)},
+	}, &resp)
+	if err == nil {
+		err = singleResponse(resp.Messages)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to create event %s: %w", e.ShortTitle(), err)
+	}
+
+	c.logger.Info("Event created", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+// UpdateEvent replaces the fields of the calendar item which are written by the adapter. It fails with
+// ErrorIrresolvableConflict if the item was modified since it was loaded, as changes in the meantime must
+// not be overwritten.
+func (c *CalendarAPI) UpdateEvent(ctx context.Context, e models.Event) error {
+	var resp updateItemResponse
+	err := c.call(ctx, updateItemRequest{
+		ConflictResolution:                    "NeverOverwrite",
+		SendMeetingInvitationsOrCancellations: "SendToNone",
+		Changes: []itemChange{{
+			ID:      itemID{ID: e.ID, ChangeKey: e.ETag},
+			Updates: itemUpdates(eventToItem(e)),
+		}},
+	}, &resp)
+	if err == nil {
+		err = singleResponse(resp.Messages)
+	}
+	if errors.Is(err, errItemNotFound) {
+		return errors.New("already deleted")
+	} else if err != nil {
+		return fmt.Errorf("unable to update event %s: %w", e.ShortTitle(), err)
+	}
+
+	c.logger.Info("Event updated", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+// DeleteEvent moves the calendar item to the deleted items without sending cancellations to the attendees
+func (c *CalendarAPI) DeleteEvent(ctx context.Context, e models.Event) error {
+	var resp deleteItemResponse
+	err := c.call(ctx, deleteItemRequest{
+		DeleteType:               "MoveToDeletedItems",
+		SendMeetingCancellations: "SendToNone",
+		IDs:                      []itemID{{ID: e.ID}},
+	}, &resp)
+	if err == nil {
+		err = singleResponse(resp.Messages)
+	}
+	if errors.Is(err, errItemNotFound) {
+		c.logger.Debug("Event is already deleted.", "method", "DeleteEvent", "title", e.ShortTitle(), "time", e.StartTime.String())
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to delete event %s: %w", e.ShortTitle(), err)
+	}
+
+	c.logger.Info("Event deleted", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+// singleResponse returns the error of the response message of a request for a single item
+func singleResponse(messages []responseMessage) error {
+	if len(messages) != 1 {
+		return errors.New("unexpected response")
+	}
+	return messages[0].err()
+}
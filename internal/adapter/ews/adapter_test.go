@@ -0,0 +1,429 @@
+package ews
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// stubServer is an EWS endpoint which keeps the calendar items of a single calendar in memory
+type stubServer struct {
+	mu       sync.Mutex
+	items    map[string]*stubItem
+	nextID   int
+	requests []string
+}
+
+type stubItem struct {
+	calendarItem
+	changeKey int
+}
+
+// stubRequest contains the operations of the requests, decoded by their local names
+type stubRequest struct {
+	Body struct {
+		FindItem *struct {
+			View struct {
+				MaxEntries int       `xml:"MaxEntriesReturned,attr"`
+				Start      time.Time `xml:"StartDate,attr"`
+				End        time.Time `xml:"EndDate,attr"`
+			} `xml:"CalendarView"`
+		} `xml:"FindItem"`
+		GetItem *struct {
+			IDs []itemID `xml:"ItemIds>ItemId"`
+		} `xml:"GetItem"`
+		CreateItem *struct {
+			Items []calendarItem `xml:"Items>CalendarItem"`
+		} `xml:"CreateItem"`
+		UpdateItem *struct {
+			ConflictResolution string `xml:"ConflictResolution,attr"`
+			Changes            []struct {
+				ID     itemID       `xml:"ItemId"`
+				Set    []stubUpdate `xml:"Updates>SetItemField"`
+				Delete []stubUpdate `xml:"Updates>DeleteItemField"`
+			} `xml:"ItemChanges>ItemChange"`
+		} `xml:"UpdateItem"`
+		DeleteItem *struct {
+			IDs []itemID `xml:"ItemIds>ItemId"`
+		} `xml:"DeleteItem"`
+	} `xml:"Body"`
+}
+
+type stubUpdate struct {
+	Field    fieldURI         `xml:"FieldURI"`
+	Extended extendedFieldURI `xml:"ExtendedFieldURI"`
+	Item     calendarItem     `xml:"CalendarItem"`
+}
+
+func newStubServer(t *testing.T) *httptest.Server {
+	stub := &stubServer{items: make(map[string]*stubItem)}
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *stubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if username, password, ok := r.BasicAuth(); !ok || username != "alice" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, string(body))
+
+	var req stubRequest
+	if err := xml.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, `<s:Envelope xmlns:s="%s"><s:Body><s:Fault><faultcode>s:Client</faultcode><faultstring>%s</faultstring></s:Fault></s:Body></s:Envelope>`, soapNamespace, err)
+		return
+	}
+
+	var response string
+	switch op := req.Body; {
+	case op.FindItem != nil:
+		response = s.findItem(op.FindItem.View.Start, op.FindItem.View.End, op.FindItem.View.MaxEntries)
+	case op.GetItem != nil:
+		response = "<m:GetItemResponse><m:ResponseMessages>"
+		for _, id := range op.GetItem.IDs {
+			if item, ok := s.items[id.ID]; ok {
+				response += `<m:GetItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:Items>` + item.xml() + `</m:Items></m:GetItemResponseMessage>`
+			} else {
+				response += notFound("GetItemResponseMessage")
+			}
+		}
+		response += "</m:ResponseMessages></m:GetItemResponse>"
+	case op.CreateItem != nil:
+		response = "<m:CreateItemResponse><m:ResponseMessages>"
+		for _, item := range op.CreateItem.Items {
+			s.nextID++
+			id := "item-" + strconv.Itoa(s.nextID)
+			item.ItemID = itemID{ID: id}
+			s.items[id] = &stubItem{calendarItem: item, changeKey: 1}
+			response += success("CreateItemResponseMessage")
+		}
+		response += "</m:ResponseMessages></m:CreateItemResponse>"
+	case op.UpdateItem != nil:
+		response = "<m:UpdateItemResponse><m:ResponseMessages>"
+		for _, change := range op.UpdateItem.Changes {
+			item, ok := s.items[change.ID.ID]
+			switch {
+			case !ok:
+				response += notFound("UpdateItemResponseMessage")
+			// Exchange only rejects changes to a modified item with NeverOverwrite, AutoResolve overwrites it
+			case op.UpdateItem.ConflictResolution == "NeverOverwrite" && change.ID.ChangeKey != "" && change.ID.ChangeKey != strconv.Itoa(item.changeKey):
+				response += `<m:UpdateItemResponseMessage ResponseClass="Error"><m:MessageText>The item was modified.</m:MessageText><m:ResponseCode>ErrorIrresolvableConflict</m:ResponseCode></m:UpdateItemResponseMessage>`
+			default:
+				for _, update := range change.Set {
+					item.set(update, update.Item)
+				}
+				for _, update := range change.Delete {
+					item.set(update, calendarItem{})
+				}
+				item.changeKey++
+				response += success("UpdateItemResponseMessage")
+			}
+		}
+		response += "</m:ResponseMessages></m:UpdateItemResponse>"
+	case op.DeleteItem != nil:
+		response = "<m:DeleteItemResponse><m:ResponseMessages>"
+		for _, id := range op.DeleteItem.IDs {
+			if _, ok := s.items[id.ID]; ok {
+				delete(s.items, id.ID)
+				response += success("DeleteItemResponseMessage")
+			} else {
+				response += notFound("DeleteItemResponseMessage")
+			}
+		}
+		response += "</m:ResponseMessages></m:DeleteItemResponse>"
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><s:Envelope xmlns:s="%s" xmlns:m="%s" xmlns:t="%s"><s:Body>%s</s:Body></s:Envelope>`,
+		soapNamespace, messagesNamespace, typesNamespace, response)
+}
+
+// findItem returns the ids of the items within the calendar view ordered by their start
+func (s *stubServer) findItem(start, end time.Time, maxEntries int) string {
+	var items []*stubItem
+	for _, item := range s.items {
+		if item.Start.Before(end) && item.End.After(start) {
+			items = append(items, item)
+		}
+	}
+	slices.SortFunc(items, func(a, b *stubItem) int { return a.Start.Compare(b.Start) })
+	includesLast := len(items) <= maxEntries
+	items = items[:min(len(items), maxEntries)]
+
+	response := fmt.Sprintf(`<m:FindItemResponse><m:ResponseMessages><m:FindItemResponseMessage ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode><m:RootFolder TotalItemsInView="%d" IncludesLastItemInRange="%t"><t:Items>`, len(items), includesLast)
+	for _, item := range items {
+		response += fmt.Sprintf(`<t:CalendarItem><t:ItemId Id="%s" ChangeKey="%d"/><t:Start>%s</t:Start></t:CalendarItem>`, item.ItemID.ID, item.changeKey, item.Start.Format(timeFormat))
+	}
+	return response + "</t:Items></m:RootFolder></m:FindItemResponseMessage></m:ResponseMessages></m:FindItemResponse>"
+}
+
+// set sets the field of the update to the value of the field of the item
+func (i *stubItem) set(update stubUpdate, value calendarItem) {
+	if name := update.Extended.PropertyName; name != "" {
+		i.ExtendedProperties = slices.DeleteFunc(i.ExtendedProperties, func(p extendedProperty) bool { return p.FieldURI.PropertyName == name })
+		i.ExtendedProperties = append(i.ExtendedProperties, value.ExtendedProperties...)
+		return
+	}
+	switch update.Field.FieldURI {
+	case "item:Subject":
+		i.Subject = value.Subject
+	case "item:Body":
+		i.Body = value.Body
+	case "item:ReminderIsSet":
+		i.ReminderIsSet = value.ReminderIsSet
+	case "item:ReminderMinutesBeforeStart":
+		i.ReminderMinutesBeforeStart = value.ReminderMinutesBeforeStart
+	case "calendar:Start":
+		i.Start = value.Start
+	case "calendar:End":
+		i.End = value.End
+	case "calendar:IsAllDayEvent":
+		i.IsAllDayEvent = value.IsAllDayEvent
+	case "calendar:Location":
+		i.Location = value.Location
+	case "calendar:RequiredAttendees":
+		i.RequiredAttendees = value.RequiredAttendees
+	case "calendar:StartTimeZone":
+		i.StartTimeZone = value.StartTimeZone
+	}
+}
+
+func (i *stubItem) xml() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<t:CalendarItem><t:ItemId Id="%s" ChangeKey="%d"/><t:Subject>%s</t:Subject><t:Body BodyType="Text">%s</t:Body>`,
+		i.ItemID.ID, i.changeKey, escape(i.Subject), escape(i.Body))
+	fmt.Fprintf(&b, `<t:ReminderIsSet>%t</t:ReminderIsSet><t:ReminderMinutesBeforeStart>%d</t:ReminderMinutesBeforeStart>`,
+		i.ReminderIsSet, i.ReminderMinutesBeforeStart)
+	for _, p := range i.ExtendedProperties {
+		fmt.Fprintf(&b, `<t:ExtendedProperty><t:ExtendedFieldURI DistinguishedPropertySetId="PublicStrings" PropertyName="%s" PropertyType="String"/><t:Value>%s</t:Value></t:ExtendedProperty>`,
+			p.FieldURI.PropertyName, escape(p.Value))
+	}
+	fmt.Fprintf(&b, `<t:UID>uid-%s</t:UID><t:Start>%s</t:Start><t:End>%s</t:End><t:IsAllDayEvent>%t</t:IsAllDayEvent><t:Location>%s</t:Location><t:MyResponseType>Organizer</t:MyResponseType>`,
+		i.ItemID.ID, i.Start.Format(timeFormat), i.End.Format(timeFormat), i.IsAllDayEvent, escape(i.Location))
+	if len(i.RequiredAttendees) > 0 {
+		b.WriteString("<t:RequiredAttendees>")
+		for _, a := range i.RequiredAttendees {
+			fmt.Fprintf(&b, `<t:Attendee><t:Mailbox><t:Name>%s</t:Name><t:EmailAddress>%s</t:EmailAddress></t:Mailbox><t:ResponseType>Unknown</t:ResponseType></t:Attendee>`,
+				escape(a.Mailbox.Name), escape(a.Mailbox.EmailAddress))
+		}
+		b.WriteString("</t:RequiredAttendees>")
+	}
+	fmt.Fprintf(&b, `<t:StartTimeZone Id="%s" Name="%s"/></t:CalendarItem>`, i.StartTimeZone.ID, i.StartTimeZone.ID)
+	return b.String()
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func success(message string) string {
+	return fmt.Sprintf(`<m:%s ResponseClass="Success"><m:ResponseCode>NoError</m:ResponseCode></m:%s>`, message, message)
+}
+
+func notFound(message string) string {
+	return fmt.Sprintf(`<m:%s ResponseClass="Error"><m:MessageText>The specified object was not found in the store.</m:MessageText><m:ResponseCode>ErrorItemNotFound</m:ResponseCode></m:%s>`, message, message)
+}
+
+func newTestAPI(t *testing.T, endpoint string, password string) *CalendarAPI {
+	api := &CalendarAPI{}
+	api.SetLogger(log.Default())
+	require.NoError(t, api.SetCalendarID("primary"))
+	require.NoError(t, api.Initialize(context.Background(), false, map[string]interface{}{
+		endpointKey: endpoint,
+		usernameKey: "alice",
+		passwordKey: password,
+		authKey:     authBasic,
+	}))
+	return api
+}
+
+func TestEvents(t *testing.T) {
+	server := newStubServer(t)
+	api := newTestAPI(t, server.URL, "secret")
+	stub := server.Config.Handler.(*stubServer)
+
+	ctx := context.Background()
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, berlin)
+	event := models.Event{
+		Title:       "Planning",
+		Description: "Agenda <draft>",
+		Location:    "Room 1",
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		TimeZone:    "Europe/Berlin",
+		Attendees:   models.Attendees{{Email: "bob@example.com", DisplayName: "Bob"}},
+		Reminders: models.Reminders{{
+			Actions: models.ReminderActionDisplay,
+			Trigger: models.ReminderTrigger{PointInTime: start.Add(-15 * time.Minute)},
+		}},
+		Metadata: models.NewEventMetadata("event", "https://example.com/event", "source"),
+	}
+	require.NoError(t, api.CreateEvent(ctx, event))
+	// the attendees must not be invited
+	assert.Contains(t, stub.requests[len(stub.requests)-1], `SendMeetingInvitations="SendToNone"`)
+	assert.Contains(t, stub.requests[len(stub.requests)-1], `<t:StartTimeZone Id="W. Europe Standard Time">`)
+
+	events, err := api.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	listed := events[0]
+	assert.Equal(t, "Planning", listed.Title)
+	assert.Equal(t, "Agenda <draft>", listed.Description)
+	assert.Equal(t, "Room 1", listed.Location)
+	assert.True(t, listed.StartTime.Equal(event.StartTime))
+	assert.True(t, listed.EndTime.Equal(event.EndTime))
+	assert.Equal(t, "Europe/Berlin", listed.TimeZone)
+	assert.Equal(t, event.Attendees, models.Attendees(listed.Attendees))
+	require.Len(t, listed.Reminders, 1)
+	assert.True(t, listed.Reminders[0].Trigger.PointInTime.Equal(start.Add(-15*time.Minute)))
+	assert.Equal(t, event.Metadata, listed.Metadata)
+	assert.True(t, listed.Accepted)
+	assert.NotEmpty(t, listed.ETag)
+
+	updated := listed
+	updated.Title = "Planning (moved)"
+	updated.Location = ""
+	updated.Attendees = nil
+	require.NoError(t, api.UpdateEvent(ctx, updated))
+	assert.Contains(t, stub.requests[len(stub.requests)-1], `SendMeetingInvitationsOrCancellations="SendToNone"`)
+	assert.Contains(t, stub.requests[len(stub.requests)-1], `ConflictResolution="NeverOverwrite"`)
+
+	events, err = api.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "Planning (moved)", events[0].Title)
+	assert.Empty(t, events[0].Location)
+	assert.Empty(t, events[0].Attendees)
+	assert.Equal(t, event.Metadata, events[0].Metadata)
+
+	// the item was modified since it was listed
+	assert.ErrorContains(t, api.UpdateEvent(ctx, listed), "ErrorIrresolvableConflict")
+
+	require.NoError(t, api.DeleteEvent(ctx, events[0]))
+	assert.Contains(t, stub.requests[len(stub.requests)-1], `SendMeetingCancellations="SendToNone"`)
+	events, err = api.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	assert.NoError(t, api.DeleteEvent(ctx, listed))
+	assert.EqualError(t, api.UpdateEvent(ctx, listed), "already deleted")
+}
+
+func TestAllDayEvent(t *testing.T) {
+	server := newStubServer(t)
+	api := newTestAPI(t, server.URL, "secret")
+
+	ctx := context.Background()
+	start := time.Date(2024, 3, 29, 0, 0, 0, 0, time.UTC)
+	event := models.Event{
+		Title:     "Holiday",
+		StartTime: start,
+		EndTime:   start.AddDate(0, 0, 1),
+		AllDay:    true,
+		Metadata:  models.NewEventMetadata("holiday", "", "source"),
+	}
+	require.NoError(t, api.CreateEvent(ctx, event))
+
+	events, err := api.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 2))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.True(t, events[0].AllDay)
+	assert.Equal(t, start, events[0].StartTime)
+	assert.Equal(t, start.AddDate(0, 0, 1), events[0].EndTime)
+	assert.Empty(t, events[0].TimeZone)
+}
+
+func TestEventsPaging(t *testing.T) {
+	server := newStubServer(t)
+	api := newTestAPI(t, server.URL, "secret")
+	stub := server.Config.Handler.(*stubServer)
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 250 {
+		id := "item-" + strconv.Itoa(i)
+		itemStart := start.Add(time.Duration(i/2) * time.Hour)
+		stub.items[id] = &stubItem{calendarItem: calendarItem{
+			ItemID:  itemID{ID: id},
+			Subject: id,
+			Start:   itemStart,
+			End:     itemStart.Add(90 * time.Minute),
+		}, changeKey: 1}
+	}
+
+	events, err := api.EventsInTimeframe(context.Background(), start, start.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	assert.Len(t, events, 250)
+	for _, event := range events {
+		// the metadata is derived from the item, as the items were not created by CalendarSync
+		assert.Equal(t, models.NewEventMetadata(event.ID, "", api.GetCalendarHash()), event.Metadata)
+	}
+}
+
+func TestAuthenticationFailed(t *testing.T) {
+	server := newStubServer(t)
+	api := newTestAPI(t, server.URL, "wrong")
+
+	_, err := api.EventsInTimeframe(context.Background(), time.Now(), time.Now().Add(time.Hour))
+	assert.ErrorContains(t, err, "authentication failed")
+}
+
+func TestItemToEvent(t *testing.T) {
+	// all-day items start at midnight in their time zone
+	item := calendarItem{
+		ItemID:         itemID{ID: "item", ChangeKey: "key"},
+		Subject:        "Holiday",
+		Start:          time.Date(2024, 3, 28, 23, 0, 0, 0, time.UTC),
+		End:            time.Date(2024, 3, 29, 23, 0, 0, 0, time.UTC),
+		IsAllDayEvent:  true,
+		MyResponseType: "Decline",
+		StartTimeZone:  timeZoneID{ID: "W. Europe Standard Time"},
+	}
+	event := itemToEvent(item, "source")
+	assert.True(t, event.AllDay)
+	assert.Equal(t, time.Date(2024, 3, 29, 0, 0, 0, 0, time.UTC), event.StartTime)
+	assert.Equal(t, time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC), event.EndTime)
+	assert.Empty(t, event.TimeZone)
+	assert.False(t, event.Accepted)
+	assert.Equal(t, "key", event.ETag)
+	assert.Equal(t, models.NewEventMetadata("item", "", "source"), event.Metadata)
+}
+
+func TestInitializeConfig(t *testing.T) {
+	api := &CalendarAPI{}
+	require.NoError(t, api.SetCalendarID("primary"))
+	assert.ErrorContains(t, api.Initialize(context.Background(), false, map[string]interface{}{}), "missing config key: endpoint")
+	assert.ErrorContains(t, api.Initialize(context.Background(), false, map[string]interface{}{
+		endpointKey: "https://mail.example.com/EWS/Exchange.asmx",
+	}), "missing config key: username")
+	assert.ErrorContains(t, api.Initialize(context.Background(), false, map[string]interface{}{
+		endpointKey: "https://mail.example.com/EWS/Exchange.asmx",
+		usernameKey: "alice",
+		passwordKey: "secret",
+		authKey:     "kerberos",
+	}), "config key auth must be one of")
+}
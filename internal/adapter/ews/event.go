@@ -0,0 +1,276 @@
+package ews
+
+import (
+	"slices"
+	"time"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+const (
+	propertySet  = "PublicStrings"
+	propertyType = "String"
+
+	propEventID          = "X-CALENDARSYNC-EVENT-ID"
+	propOriginalEventUri = "X-CALENDARSYNC-ORIGINAL-EVENT-URI"
+	propSourceID         = "X-CALENDARSYNC-SOURCE-ID"
+)
+
+// metadataProperties are the extended properties which store the metadata of CalendarSync
+var metadataProperties = []string{propEventID, propOriginalEventUri, propSourceID}
+
+// calendarItem is a calendar item as it is returned by EWS
+type calendarItem struct {
+	ItemID                     itemID             `xml:"ItemId"`
+	Subject                    string             `xml:"Subject"`
+	Body                       string             `xml:"Body"`
+	ReminderIsSet              bool               `xml:"ReminderIsSet"`
+	ReminderMinutesBeforeStart int                `xml:"ReminderMinutesBeforeStart"`
+	ExtendedProperties         []extendedProperty `xml:"ExtendedProperty"`
+	UID                        string             `xml:"UID"`
+	Start                      time.Time          `xml:"Start"`
+	End                        time.Time          `xml:"End"`
+	IsAllDayEvent              bool               `xml:"IsAllDayEvent"`
+	Location                   string             `xml:"Location"`
+	MyResponseType             string             `xml:"MyResponseType"`
+	RequiredAttendees          []attendee         `xml:"RequiredAttendees>Attendee"`
+	OptionalAttendees          []attendee         `xml:"OptionalAttendees>Attendee"`
+	StartTimeZone              timeZoneID         `xml:"StartTimeZone"`
+}
+
+// timeZoneID is the windows name of a time zone
+type timeZoneID struct {
+	ID string `xml:"Id,attr"`
+}
+
+type extendedProperty struct {
+	FieldURI struct {
+		PropertyName string `xml:"PropertyName,attr"`
+	} `xml:"ExtendedFieldURI"`
+	Value string `xml:"Value"`
+}
+
+type attendee struct {
+	Mailbox struct {
+		Name         string `xml:"Name"`
+		EmailAddress string `xml:"EmailAddress"`
+	} `xml:"Mailbox"`
+}
+
+// calendarItemData contains the fields of a calendar item which are written by the adapter. All fields are
+// optional, such that single fields can be set by UpdateItem. EWS expects the fields in the order of its schema.
+type calendarItemData struct {
+	Subject                    *string                `xml:"t:Subject,omitempty"`
+	Body                       *bodyData              `xml:"t:Body,omitempty"`
+	ReminderIsSet              *bool                  `xml:"t:ReminderIsSet,omitempty"`
+	ReminderMinutesBeforeStart *int                   `xml:"t:ReminderMinutesBeforeStart,omitempty"`
+	ExtendedProperties         []extendedPropertyData `xml:"t:ExtendedProperty,omitempty"`
+	Start                      *string                `xml:"t:Start,omitempty"`
+	End                        *string                `xml:"t:End,omitempty"`
+	IsAllDayEvent              *bool                  `xml:"t:IsAllDayEvent,omitempty"`
+	Location                   *string                `xml:"t:Location,omitempty"`
+	RequiredAttendees          *attendeesData         `xml:"t:RequiredAttendees,omitempty"`
+	StartTimeZone              *timeZoneID            `xml:"t:StartTimeZone,omitempty"`
+	EndTimeZone                *timeZoneID            `xml:"t:EndTimeZone,omitempty"`
+}
+
+type bodyData struct {
+	BodyType string `xml:"BodyType,attr"`
+	Content  string `xml:",chardata"`
+}
+
+type extendedPropertyData struct {
+	FieldURI extendedFieldURI `xml:"t:ExtendedFieldURI"`
+	Value    string           `xml:"t:Value"`
+}
+
+type attendeesData struct {
+	Attendees []attendeeData `xml:"t:Attendee"`
+}
+
+type attendeeData struct {
+	Name         string `xml:"t:Mailbox>t:Name,omitempty"`
+	EmailAddress string `xml:"t:Mailbox>t:EmailAddress"`
+}
+
+func metadataField(name string) extendedFieldURI {
+	return extendedFieldURI{PropertySet: propertySet, PropertyName: name, PropertyType: propertyType}
+}
+
+// itemToEvent transforms a calendar item to our form of event representation
+func itemToEvent(item calendarItem, sourceID string) models.Event {
+	var attendees = make([]models.Attendee, 0)
+	for _, a := range slices.Concat(item.RequiredAttendees, item.OptionalAttendees) {
+		attendees = append(attendees, models.Attendee{
+			Email:       a.Mailbox.EmailAddress,
+			DisplayName: a.Mailbox.Name,
+		})
+	}
+
+	var reminders = make([]models.Reminder, 0)
+	if item.ReminderIsSet {
+		reminders = append(reminders, models.Reminder{
+			Actions: models.ReminderActionDisplay,
+			Trigger: models.ReminderTrigger{
+				PointInTime: item.Start.Add(-(time.Minute * time.Duration(item.ReminderMinutesBeforeStart))),
+			},
+		})
+	}
+
+	// the times are returned in UTC, the time zone of the item is kept separately
+	timeZone, _ := models.IANATimeZone(item.StartTimeZone.ID)
+	start, end := item.Start.UTC(), item.End.UTC()
+	if item.IsAllDayEvent {
+		// all-day items start and end at midnight in their time zone, we use midnight in UTC like the other adapters
+		start, end = midnightUTC(start, timeZone), midnightUTC(end, timeZone)
+		timeZone = ""
+	}
+
+	return models.Event{
+		ICalUID:     item.UID,
+		ID:          item.ItemID.ID,
+		Title:       item.Subject,
+		Description: item.Body,
+		Location:    item.Location,
+		StartTime:   start,
+		EndTime:     end,
+		TimeZone:    timeZone,
+		AllDay:      item.IsAllDayEvent,
+		Metadata:    ensureMetadata(item, sourceID),
+		Attendees:   attendees,
+		Reminders:   reminders,
+		Accepted:    item.MyResponseType != "Decline",
+		ETag:        item.ItemID.ChangeKey,
+	}
+}
+
+// midnightUTC returns the date of the time in the time zone as midnight in UTC
+func midnightUTC(t time.Time, timeZone string) time.Time {
+	if location, err := time.LoadLocation(timeZone); err == nil && timeZone != "" {
+		t = t.In(location)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// ensureMetadata retrieves the metadata from the extended properties or regenerates it
+func ensureMetadata(item calendarItem, sourceID string) *models.Metadata {
+	values := make(map[string]string)
+	for _, property := range item.ExtendedProperties {
+		values[property.FieldURI.PropertyName] = property.Value
+	}
+	if values[propEventID] != "" && values[propSourceID] != "" {
+		return &models.Metadata{
+			SyncID:           values[propEventID],
+			OriginalEventUri: values[propOriginalEventUri],
+			SourceID:         values[propSourceID],
+		}
+	}
+	return models.NewEventMetadata(item.ItemID.ID, "", sourceID)
+}
+
+// eventToItem transforms our internal models.Event to a calendar item with all fields which are written
+func eventToItem(e models.Event) calendarItemData {
+	item := calendarItemData{
+		Subject:       &e.Title,
+		Location:      &e.Location,
+		IsAllDayEvent: &e.AllDay,
+		Body:          &bodyData{BodyType: "Text", Content: e.Description},
+	}
+
+	// the times are written in UTC, the time zone only determines how the item is displayed
+	start, end := e.StartTime.UTC().Format(time.RFC3339), e.EndTime.UTC().Format(time.RFC3339)
+	item.Start, item.End = &start, &end
+	timeZone := &timeZoneID{ID: "UTC"}
+	if e.TimeZone != "" && !e.AllDay {
+		timeZone = &timeZoneID{ID: models.WindowsTimeZone(e.TimeZone)}
+	}
+	item.StartTimeZone, item.EndTimeZone = timeZone, timeZone
+
+	reminderIsSet := len(e.Reminders) != 0
+	item.ReminderIsSet = &reminderIsSet
+	if reminderIsSet {
+		// we currently use the first reminder in the list, this may result in data loss
+		minutes := int(e.StartTime.Sub(e.Reminders[0].Trigger.PointInTime).Minutes())
+		item.ReminderMinutesBeforeStart = &minutes
+	}
+
+	if len(e.Attendees) != 0 {
+		item.RequiredAttendees = &attendeesData{}
+		for _, a := range e.Attendees {
+			item.RequiredAttendees.Attendees = append(item.RequiredAttendees.Attendees, attendeeData{
+				Name:         a.DisplayName,
+				EmailAddress: a.Email,
+			})
+		}
+	}
+
+	if e.Metadata != nil {
+		values := []string{e.Metadata.SyncID, e.Metadata.OriginalEventUri, e.Metadata.SourceID}
+		for i, name := range metadataProperties {
+			if values[i] != "" {
+				item.ExtendedProperties = append(item.ExtendedProperties, extendedPropertyData{
+					FieldURI: metadataField(name),
+					Value:    values[i],
+				})
+			}
+		}
+	}
+	return item
+}
+
+// itemUpdates returns the changes to replace all fields which are written by the adapter. Empty fields are
+// deleted, as EWS rejects some of them.
+func itemUpdates(item calendarItemData) updates {
+	var u updates
+	set := func(uri string, field calendarItemData) {
+		u.Set = append(u.Set, setItemField{path: path{Field: &fieldURI{FieldURI: uri}}, Item: field})
+	}
+	del := func(p path) {
+		u.Delete = append(u.Delete, deleteItemField{path: p})
+	}
+
+	// the time zones are set before the times they apply to
+	set("calendar:StartTimeZone", calendarItemData{StartTimeZone: item.StartTimeZone})
+	set("calendar:EndTimeZone", calendarItemData{EndTimeZone: item.EndTimeZone})
+	set("calendar:Start", calendarItemData{Start: item.Start})
+	set("calendar:End", calendarItemData{End: item.End})
+	set("calendar:IsAllDayEvent", calendarItemData{IsAllDayEvent: item.IsAllDayEvent})
+	set("item:Subject", calendarItemData{Subject: item.Subject})
+	set("item:ReminderIsSet", calendarItemData{ReminderIsSet: item.ReminderIsSet})
+	if item.ReminderMinutesBeforeStart != nil {
+		set("item:ReminderMinutesBeforeStart", calendarItemData{ReminderMinutesBeforeStart: item.ReminderMinutesBeforeStart})
+	}
+
+	if item.Body.Content != "" {
+		set("item:Body", calendarItemData{Body: item.Body})
+	} else {
+		del(path{Field: &fieldURI{FieldURI: "item:Body"}})
+	}
+	if *item.Location != "" {
+		set("calendar:Location", calendarItemData{Location: item.Location})
+	} else {
+		del(path{Field: &fieldURI{FieldURI: "calendar:Location"}})
+	}
+	if item.RequiredAttendees != nil {
+		set("calendar:RequiredAttendees", calendarItemData{RequiredAttendees: item.RequiredAttendees})
+	} else {
+		del(path{Field: &fieldURI{FieldURI: "calendar:RequiredAttendees"}})
+	}
+
+	values := make(map[string]extendedPropertyData)
+	for _, property := range item.ExtendedProperties {
+		values[property.FieldURI.PropertyName] = property
+	}
+	for _, name := range metadataProperties {
+		field := metadataField(name)
+		if property, ok := values[name]; ok {
+			u.Set = append(u.Set, setItemField{
+				path: path{Extended: &field},
+				Item: calendarItemData{ExtendedProperties: []extendedPropertyData{property}},
+			})
+		} else {
+			del(path{Extended: &field})
+		}
+	}
+	return u
+}
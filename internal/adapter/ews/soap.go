@@ -0,0 +1,259 @@
+package ews
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	soapNamespace     = "http://schemas.xmlsoap.org/soap/envelope/"
+	typesNamespace    = "http://schemas.microsoft.com/exchange/services/2006/types"
+	messagesNamespace = "http://schemas.microsoft.com/exchange/services/2006/messages"
+	// serverVersion is the oldest version which supports the time zones and extended properties used by the adapter
+	serverVersion = "Exchange2010_SP1"
+)
+
+// errItemNotFound is returned if the item does not exist (anymore)
+var errItemNotFound = errors.New("item not found")
+
+// The requests are encoded with the prefixes of the namespaces, as EWS expects them. The responses are decoded
+// by their local names.
+
+type requestEnvelope struct {
+	XMLName  xml.Name            `xml:"soap:Envelope"`
+	Soap     string              `xml:"xmlns:soap,attr"`
+	Types    string              `xml:"xmlns:t,attr"`
+	Messages string              `xml:"xmlns:m,attr"`
+	Version  serverVersionHeader `xml:"soap:Header>t:RequestServerVersion"`
+	Body     requestBody         `xml:"soap:Body"`
+}
+
+// requestBody contains the request of an operation, which is encoded by the name of its XMLName field
+type requestBody struct {
+	Request any
+}
+
+type serverVersionHeader struct {
+	Version string `xml:"Version,attr"`
+}
+
+type responseEnvelope struct {
+	Body struct {
+		Fault   *soapFault `xml:"Fault"`
+		Content []byte     `xml:",innerxml"`
+	} `xml:"Body"`
+}
+
+type soapFault struct {
+	Code    string `xml:"faultcode"`
+	Message string `xml:"faultstring"`
+}
+
+// responseMessage is the common part of the response messages of all operations
+type responseMessage struct {
+	ResponseClass string `xml:"ResponseClass,attr"`
+	ResponseCode  string `xml:"ResponseCode"`
+	MessageText   string `xml:"MessageText"`
+}
+
+func (m responseMessage) err() error {
+	switch {
+	case m.ResponseClass == "Success":
+		return nil
+	case m.ResponseCode == "ErrorItemNotFound":
+		return errItemNotFound
+	default:
+		return fmt.Errorf("%s: %s", m.ResponseCode, m.MessageText)
+	}
+}
+
+// call sends the request to the EWS endpoint and decodes the response into the response struct
+func (c *CalendarAPI) call(ctx context.Context, request any, response any) error {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	err := xml.NewEncoder(&buf).Encode(requestEnvelope{
+		Soap:     soapNamespace,
+		Types:    typesNamespace,
+		Messages: messagesNamespace,
+		Version:  serverVersionHeader{Version: serverVersion},
+		Body:     requestBody{Request: request},
+	})
+	if err != nil {
+		return fmt.Errorf("unable to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("authentication failed: %s", resp.Status)
+	}
+
+	// faults are returned with the status 500
+	var envelope responseEnvelope
+	if err := xml.Unmarshal(body, &envelope); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		return fmt.Errorf("unable to decode response: %w", err)
+	}
+	if envelope.Body.Fault != nil {
+		return fmt.Errorf("%s: %s", envelope.Body.Fault.Code, envelope.Body.Fault.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if err := xml.Unmarshal(envelope.Body.Content, response); err != nil {
+		return fmt.Errorf("unable to decode response: %w", err)
+	}
+	return nil
+}
+
+// folderID is either a distinguished folder like the default calendar or the id of a folder
+type folderID struct {
+	Distinguished *distinguishedFolderID `xml:"t:DistinguishedFolderId,omitempty"`
+	Folder        *itemID                `xml:"t:FolderId,omitempty"`
+}
+
+type distinguishedFolderID struct {
+	ID string `xml:"Id,attr"`
+}
+
+type itemID struct {
+	ID        string `xml:"Id,attr"`
+	ChangeKey string `xml:"ChangeKey,attr,omitempty"`
+}
+
+type fieldURI struct {
+	FieldURI string `xml:"FieldURI,attr"`
+}
+
+type extendedFieldURI struct {
+	PropertySet  string `xml:"DistinguishedPropertySetId,attr"`
+	PropertyName string `xml:"PropertyName,attr"`
+	PropertyType string `xml:"PropertyType,attr"`
+}
+
+// path is the field of an item which is requested, set or deleted
+type path struct {
+	Field    *fieldURI         `xml:"t:FieldURI,omitempty"`
+	Extended *extendedFieldURI `xml:"t:ExtendedFieldURI,omitempty"`
+}
+
+type itemShape struct {
+	BaseShape  string                `xml:"t:BaseShape"`
+	BodyType   string                `xml:"t:BodyType,omitempty"`
+	Properties *additionalProperties `xml:"t:AdditionalProperties,omitempty"`
+}
+
+type additionalProperties struct {
+	Fields   []fieldURI         `xml:"t:FieldURI"`
+	Extended []extendedFieldURI `xml:"t:ExtendedFieldURI"`
+}
+
+type findItemRequest struct {
+	XMLName   xml.Name     `xml:"m:FindItem"`
+	Traversal string       `xml:"Traversal,attr"`
+	Shape     itemShape    `xml:"m:ItemShape"`
+	View      calendarView `xml:"m:CalendarView"`
+	Parent    folderID     `xml:"m:ParentFolderIds"`
+}
+
+type calendarView struct {
+	MaxEntries int    `xml:"MaxEntriesReturned,attr"`
+	Start      string `xml:"StartDate,attr"`
+	End        string `xml:"EndDate,attr"`
+}
+
+type findItemResponse struct {
+	Messages []struct {
+		responseMessage
+		RootFolder struct {
+			IncludesLastItemInRange bool           `xml:"IncludesLastItemInRange,attr"`
+			Items                   []calendarItem `xml:"Items>CalendarItem"`
+		} `xml:"RootFolder"`
+	} `xml:"ResponseMessages>FindItemResponseMessage"`
+}
+
+type getItemRequest struct {
+	XMLName xml.Name  `xml:"m:GetItem"`
+	Shape   itemShape `xml:"m:ItemShape"`
+	IDs     []itemID  `xml:"m:ItemIds>t:ItemId"`
+}
+
+type getItemResponse struct {
+	Messages []struct {
+		responseMessage
+		Items []calendarItem `xml:"Items>CalendarItem"`
+	} `xml:"ResponseMessages>GetItemResponseMessage"`
+}
+
+type createItemRequest struct {
+	XMLName                xml.Name           `xml:"m:CreateItem"`
+	SendMeetingInvitations string             `xml:"SendMeetingInvitations,attr"`
+	Folder                 folderID           `xml:"m:SavedItemFolderId"`
+	Items                  []calendarItemData `xml:"m:Items>t:CalendarItem"`
+}
+
+type createItemResponse struct {
+	Messages []responseMessage `xml:"ResponseMessages>CreateItemResponseMessage"`
+}
+
+type updateItemRequest struct {
+	XMLName                               xml.Name     `xml:"m:UpdateItem"`
+	ConflictResolution                    string       `xml:"ConflictResolution,attr"`
+	SendMeetingInvitationsOrCancellations string       `xml:"SendMeetingInvitationsOrCancellations,attr"`
+	Changes                               []itemChange `xml:"m:ItemChanges>t:ItemChange"`
+}
+
+type itemChange struct {
+	ID      itemID  `xml:"t:ItemId"`
+	Updates updates `xml:"t:Updates"`
+}
+
+type updates struct {
+	Set    []setItemField    `xml:"t:SetItemField"`
+	Delete []deleteItemField `xml:"t:DeleteItemField"`
+}
+
+type setItemField struct {
+	path
+	Item calendarItemData `xml:"t:CalendarItem"`
+}
+
+type deleteItemField struct {
+	path
+}
+
+type updateItemResponse struct {
+	Messages []responseMessage `xml:"ResponseMessages>UpdateItemResponseMessage"`
+}
+
+type deleteItemRequest struct {
+	XMLName                  xml.Name `xml:"m:DeleteItem"`
+	DeleteType               string   `xml:"DeleteType,attr"`
+	SendMeetingCancellations string   `xml:"SendMeetingCancellations,attr"`
+	IDs                      []itemID `xml:"m:ItemIds>t:ItemId"`
+}
+
+type deleteItemResponse struct {
+	Messages []responseMessage `xml:"ResponseMessages>DeleteItemResponseMessage"`
+}
@@ -0,0 +1,42 @@
+package ews
+
+import (
+	"net/http"
+
+	"github.com/Azure/go-ntlmssp"
+)
+
+// basicTransport authenticates the requests with basic authentication
+type basicTransport struct {
+	username string
+	password string
+	base     http.RoundTripper
+}
+
+func (t *basicTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(t.username, t.password)
+	return roundTripper(t.base).RoundTrip(req)
+}
+
+// ntlmTransport authenticates the requests with NTLMv2, which is the default of on-premises Exchange servers.
+// The handshake is done by go-ntlmssp, which takes the credentials from the basic authentication of the request
+// but never sends them to the server in clear text.
+type ntlmTransport struct {
+	username string
+	password string
+	base     http.RoundTripper
+}
+
+func (t *ntlmTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(t.username, t.password)
+	return ntlmssp.Negotiator{RoundTripper: roundTripper(t.base)}.RoundTrip(req)
+}
+
+func roundTripper(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		return http.DefaultTransport
+	}
+	return base
+}
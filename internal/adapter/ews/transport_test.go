@@ -0,0 +1,98 @@
+package ews
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func utf16le(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, c)
+	}
+	return b
+}
+
+// ntlmChallenge returns a challenge message with the server challenge and the domain in the target info
+func ntlmChallenge(serverChallenge []byte) []byte {
+	targetInfo := []byte{2, 0, 8, 0}
+	targetInfo = append(targetInfo, utf16le("CORP")...)
+	targetInfo = append(targetInfo, 0, 0, 0, 0)
+
+	// unicode, request target, NTLM, extended session security, target info, 128 and 56 bit
+	const flags = 0x00000001 | 0x00000004 | 0x00000200 | 0x00080000 | 0x00800000 | 0x20000000 | 0x80000000
+	msg := make([]byte, 48)
+	copy(msg, "NTLMSSP\x00")
+	binary.LittleEndian.PutUint32(msg[8:], 2)
+	binary.LittleEndian.PutUint32(msg[12:], 48)
+	binary.LittleEndian.PutUint32(msg[20:], flags)
+	copy(msg[24:], serverChallenge)
+	binary.LittleEndian.PutUint16(msg[40:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint16(msg[42:], uint16(len(targetInfo)))
+	binary.LittleEndian.PutUint32(msg[44:], 48)
+	return append(msg, targetInfo...)
+}
+
+// ntlmPayload returns the payload referenced by the security buffer at the offset of the message
+func ntlmPayload(msg []byte, offset int) []byte {
+	length := int(binary.LittleEndian.Uint16(msg[offset:]))
+	start := int(binary.LittleEndian.Uint32(msg[offset+4:]))
+	return msg[start : start+length]
+}
+
+func TestNTLMTransport(t *testing.T) {
+	var challengedAddr string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "<request/>", string(body))
+
+		encoded, ok := strings.CutPrefix(r.Header.Get("Authorization"), "NTLM ")
+		msg, err := base64.StdEncoding.DecodeString(encoded)
+		if !ok || err != nil || len(msg) < 12 {
+			// the credentials must never be sent in clear text
+			assert.Empty(t, r.Header.Get("Authorization"))
+			w.Header().Set("WWW-Authenticate", "NTLM")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch binary.LittleEndian.Uint32(msg[8:]) {
+		case 1:
+			challengedAddr = r.RemoteAddr
+			w.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(ntlmChallenge([]byte("01234567"))))
+			w.WriteHeader(http.StatusUnauthorized)
+		case 3:
+			// the authenticate message must be sent on the connection of the challenge
+			assert.Equal(t, challengedAddr, r.RemoteAddr)
+			// the NTLMv2 response is the proof followed by the client blob, which starts with its version
+			ntResponse := ntlmPayload(msg, 20)
+			if !assert.Greater(t, len(ntResponse), 24) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			assert.Equal(t, []byte{1, 1}, ntResponse[16:18], "expected an NTLMv2 response")
+			assert.Equal(t, utf16le("CORP"), ntlmPayload(msg, 28))
+			assert.Equal(t, utf16le("alice"), ntlmPayload(msg, 36))
+			_, _ = w.Write([]byte("authenticated"))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &ntlmTransport{username: `CORP\alice`, password: "secret"}}
+	resp, err := client.Post(server.URL, "text/xml", strings.NewReader("<request/>"))
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "authenticated", string(body))
+}
@@ -0,0 +1,215 @@
+package feed
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emersion/go-ical"
+
+	"github.com/inovex/CalendarSync/internal/adapter/icalendar"
+	"github.com/inovex/CalendarSync/internal/adapter/port"
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+const tokenKey = "token"
+
+// validName restricts the feed names to characters which can be used in a URL path without escaping
+var validName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Calendar is a sink which keeps the events in memory, such that they can be published as an iCalendar feed by
+// the serve command. The events are lost when CalendarSync stops.
+type Calendar struct {
+	// name is the configured calendar, it is the path of the feed
+	name string
+	// token protects the feed, it's part of the path of the feed
+	token  string
+	series bool
+
+	mu      sync.Mutex
+	objects []*ical.Calendar
+	// rendered and etag are the encoded feed, they are reset on every change
+	rendered []byte
+	etag     string
+
+	logger *log.Logger
+}
+
+// Assert that the expected interfaces are implemented
+var _ port.Configurable = &Calendar{}
+var _ port.LogSetter = &Calendar{}
+var _ port.CalendarIDSetter = &Calendar{}
+var _ port.SeriesSetter = &Calendar{}
+
+func (c *Calendar) SetCalendarID(calendarID string) error {
+	if !validName.MatchString(calendarID) {
+		return fmt.Errorf("%s adapter 'calendar' must only contain letters, digits, '.', '_' and '-'", c.Name())
+	}
+	c.name = calendarID
+	return nil
+}
+
+// SetSeries publishes series as recurring events with their modified instances instead of their instances
+func (c *Calendar) SetSeries(series bool) {
+	c.series = series
+}
+
+func (c *Calendar) SetLogger(logger *log.Logger) {
+	c.logger = logger
+}
+
+func (c *Calendar) Name() string {
+	return "Feed"
+}
+
+// FeedName returns the name of the feed, which is used in its path
+func (c *Calendar) FeedName() string {
+	return c.name
+}
+
+// Token returns the secret token which protects the feed, it's empty if the feed is public
+func (c *Calendar) Token() string {
+	return c.token
+}
+
+// GetCalendarHash calculates a unique hash for this adapter based on the name of the feed
+func (c *Calendar) GetCalendarHash() string {
+	sum := sha1.Sum([]byte("feed" + c.name))
+	return base64.URLEncoding.EncodeToString(sum[:])
+}
+
+// Initialize reads the optional token of the feed
+func (c *Calendar) Initialize(ctx context.Context, openBrowser bool, config map[string]interface{}) error {
+	if value, ok := config[tokenKey]; ok {
+		token, ok := value.(string)
+		if !ok || !validName.MatchString(token) {
+			return fmt.Errorf("config key %s must only contain letters, digits, '.', '_' and '-'", tokenKey)
+		}
+		c.token = token
+	}
+	return nil
+}
+
+// EventsInTimeframe returns the published events within the timeframe
+func (c *Calendar) EventsInTimeframe(ctx context.Context, start time.Time, end time.Time) ([]models.Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	decoder := icalendar.Decoder{SourceID: c.GetCalendarHash(), Series: c.series, Start: start, End: end}
+	var events []models.Event
+	for _, object := range c.objects {
+		objectEvents, err := decoder.Decode(object, "", "")
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, objectEvents...)
+	}
+	return events, nil
+}
+
+func (c *Calendar) CreateEvent(ctx context.Context, e models.Event) error {
+	err := c.modify(func(objects []*ical.Calendar) ([]*ical.Calendar, error) {
+		return icalendar.AddEvent(objects, e)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.logger.Debug("Event created", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+func (c *Calendar) UpdateEvent(ctx context.Context, e models.Event) error {
+	err := c.modify(func(objects []*ical.Calendar) ([]*ical.Calendar, error) {
+		return objects, icalendar.UpdateEvent(objects, e)
+	})
+	if errors.Is(err, icalendar.ErrNotFound) {
+		return errors.New("already deleted")
+	} else if err != nil {
+		return err
+	}
+
+	c.logger.Debug("Event updated", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+func (c *Calendar) DeleteEvent(ctx context.Context, e models.Event) error {
+	err := c.modify(func(objects []*ical.Calendar) ([]*ical.Calendar, error) {
+		return icalendar.DeleteEvent(objects, e)
+	})
+	if errors.Is(err, icalendar.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	c.logger.Debug("Event deleted", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+func (c *Calendar) modify(modify func(objects []*ical.Calendar) ([]*ical.Calendar, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	objects, err := modify(c.objects)
+	if err != nil {
+		return err
+	}
+	c.objects = objects
+	c.rendered = nil
+	c.etag = ""
+	return nil
+}
+
+// Render returns the encoded feed and its ETag. The feed is only encoded again if the events changed, such that
+// the ETag stays the same as long as the events do not change.
+func (c *Calendar) Render() ([]byte, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rendered == nil {
+		rendered, err := icalendar.Encode(withoutMetadata(c.objects))
+		if err != nil {
+			return nil, "", err
+		}
+		sum := sha256.Sum256(rendered)
+		c.rendered = rendered
+		c.etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	}
+	return c.rendered, c.etag, nil
+}
+
+// withoutMetadata returns copies of the calendar objects without the metadata of CalendarSync, which is only
+// needed to sync the feed and may reveal the original events to the subscribers of the feed
+func withoutMetadata(objects []*ical.Calendar) []*ical.Calendar {
+	var result []*ical.Calendar
+	for _, object := range objects {
+		published := ical.NewCalendar()
+		published.Props = object.Props
+		for _, child := range object.Children {
+			if child.Name == ical.CompEvent {
+				event := *child
+				event.Props = maps.Clone(child.Props)
+				for _, name := range []string{icalendar.PropEventID, icalendar.PropOriginalEventUri, icalendar.PropSourceID} {
+					event.Props.Del(name)
+				}
+				child = &event
+			}
+			published.Children = append(published.Children, child)
+		}
+		result = append(result, published)
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+func TestRender(t *testing.T) {
+	calendar := &Calendar{}
+	calendar.SetLogger(log.Default())
+	require.NoError(t, calendar.SetCalendarID("team-busy"))
+	require.NoError(t, calendar.Initialize(context.Background(), false, map[string]interface{}{tokenKey: "secret"}))
+	assert.Equal(t, "team-busy", calendar.FeedName())
+	assert.Equal(t, "secret", calendar.Token())
+
+	empty, emptyETag, err := calendar.Render()
+	require.NoError(t, err)
+	assert.Contains(t, string(empty), "BEGIN:VCALENDAR")
+
+	ctx := context.Background()
+	start := time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)
+	event := models.Event{
+		Title:     "Busy",
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		Metadata:  models.NewEventMetadata("event", "", "source"),
+	}
+	require.NoError(t, calendar.CreateEvent(ctx, event))
+
+	rendered, etag, err := calendar.Render()
+	require.NoError(t, err)
+	assert.NotEqual(t, emptyETag, etag)
+	assert.Contains(t, string(rendered), "SUMMARY:Busy")
+	assert.NotContains(t, string(rendered), "X-CALENDARSYNC")
+
+	// the feed is not encoded again, such that the ETag stays the same
+	again, againETag, err := calendar.Render()
+	require.NoError(t, err)
+	assert.Equal(t, etag, againETag)
+	assert.Equal(t, rendered, again)
+
+	// the metadata is only removed from the published feed
+	events, err := calendar.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, event.Metadata, events[0].Metadata)
+
+	events[0].Title = "Out of office"
+	require.NoError(t, calendar.UpdateEvent(ctx, events[0]))
+	rendered, updatedETag, err := calendar.Render()
+	require.NoError(t, err)
+	assert.NotEqual(t, etag, updatedETag)
+	assert.Contains(t, string(rendered), "SUMMARY:Out of office")
+
+	require.NoError(t, calendar.DeleteEvent(ctx, events[0]))
+	events, err = calendar.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestInvalidName(t *testing.T) {
+	calendar := &Calendar{}
+	assert.Error(t, calendar.SetCalendarID("team/busy"))
+	assert.Error(t, calendar.Initialize(context.Background(), false, map[string]interface{}{tokenKey: "a b"}))
+}
@@ -5,6 +5,7 @@ import (
 	//	"encoding/json"
 	"context"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -17,11 +18,13 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/inovex/CalendarSync/internal/auth"
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
 	"google.golang.org/api/calendar/v3"
 )
 
 type GoogleCalendarClient interface {
 	ListEvents(ctx context.Context, starttime time.Time, enddtime time.Time) ([]models.Event, error)
+	ListEventChanges(ctx context.Context, starttime time.Time, endtime time.Time, syncToken string) (models.Changes, error)
 	CreateEvent(ctx context.Context, event models.Event) error
 	UpdateEvent(ctx context.Context, event models.Event) error
 	DeleteEvent(ctx context.Context, event models.Event) error
@@ -39,10 +42,16 @@ type CalendarAPI struct {
 	oAuthUrl      string
 	oAuthToken    *oauth2.Token
 	oAuthHandler  *auth.OAuthHandler
+	oAuthConfig   *oauth2.Config
+	// deviceFlow is set if the user is authorized with the device authorization flow instead of the oAuthHandler
+	deviceFlow *auth.DeviceFlow
+	// serviceAccount is set if the adapter authenticates with the key of a service account instead of a user
+	serviceAccount *jwt.Config
 
 	logger *log.Logger
 
 	storage auth.Storage
+	series  bool
 }
 
 // Assert that the expected interfaces are implemented
@@ -50,6 +59,7 @@ var _ port.Configurable = &CalendarAPI{}
 var _ port.LogSetter = &CalendarAPI{}
 var _ port.CalendarIDSetter = &CalendarAPI{}
 var _ port.OAuth2Adapter = &CalendarAPI{}
+var _ port.SeriesSetter = &CalendarAPI{}
 
 func (c *CalendarAPI) SetCalendarID(calendarID string) error {
 	if calendarID == "" {
@@ -60,6 +70,10 @@ func (c *CalendarAPI) SetCalendarID(calendarID string) error {
 }
 
 func (c *CalendarAPI) SetupOauth2(ctx context.Context, credentials auth.Credentials, storage auth.Storage, bindPort uint) error {
+	if credentials.ServiceAccount.KeyFile != "" {
+		return c.setupServiceAccount(credentials.ServiceAccount)
+	}
+
 	// Google Adapter does not need the tenantId
 	switch {
 	case credentials.Client.Id == "":
@@ -68,17 +82,24 @@ func (c *CalendarAPI) SetupOauth2(ctx context.Context, credentials auth.Credenti
 		return fmt.Errorf("oAuth2 adapter (%s) 'clientSecret' cannot be empty", c.Name())
 	}
 
-	oauthListener, err := auth.NewOAuthHandler(oauth2.Config{
+	oAuthConfig := oauth2.Config{
 		ClientID:     credentials.Client.Id,
 		ClientSecret: credentials.Client.Secret,
 		Endpoint:     google.Endpoint,
 		Scopes:       []string{calendar.CalendarReadonlyScope, calendar.CalendarEventsScope},
-	}, bindPort)
-	if err != nil {
-		return err
 	}
 
-	c.oAuthHandler = oauthListener
+	if credentials.DeviceFlow {
+		c.deviceFlow = auth.NewDeviceFlow(oAuthConfig)
+	} else {
+		oauthListener, err := auth.NewOAuthHandler(oAuthConfig, bindPort)
+		if err != nil {
+			return err
+		}
+		c.oAuthHandler = oauthListener
+	}
+
+	c.oAuthConfig = &oAuthConfig
 	c.storage = storage
 
 	storedAuth, err := c.storage.ReadCalendarAuth(c.calendarID)
@@ -106,29 +127,37 @@ func (c *CalendarAPI) SetupOauth2(ctx context.Context, credentials auth.Credenti
 	return nil
 }
 
+// setupServiceAccount loads the key of the service account. Its tokens are requested on demand, so neither the
+// redirect listener nor the auth storage are used.
+func (c *CalendarAPI) setupServiceAccount(serviceAccount auth.ServiceAccount) error {
+	key, err := os.ReadFile(serviceAccount.KeyFile)
+	if err != nil {
+		return fmt.Errorf("unable to read service account key file: %w", err)
+	}
+	c.serviceAccount, err = google.JWTConfigFromJSON(key, calendar.CalendarReadonlyScope, calendar.CalendarEventsScope)
+	if err != nil {
+		return fmt.Errorf("invalid service account key file %s: %w", serviceAccount.KeyFile, err)
+	}
+	// with domain-wide delegation, the service account acts on behalf of the subject
+	c.serviceAccount.Subject = serviceAccount.Subject
+	c.authenticated = true
+	c.logger.Debug("using service account", "email", c.serviceAccount.Email, "subject", serviceAccount.Subject)
+	return nil
+}
+
 // Initialize implements the Configurable interface and allows the adapter to be dynamically configured.
 // The given config is presumably unknown and is validated and loaded in order to construct a valid
 // CalendarAPI struct.
 // If anything fails, an error is returned and the CalendarAPI should be considered non-functional.
 func (c *CalendarAPI) Initialize(ctx context.Context, openBrowser bool, config map[string]interface{}) error {
 	if !c.authenticated {
-		c.oAuthUrl = c.oAuthHandler.Configuration().AuthCodeURL("state", oauth2.AccessTypeOffline)
-		if openBrowser {
-			c.logger.Infof("opening browser window for authentication of %s\n", c.Name())
-			err := browser.OpenURL(c.oAuthUrl)
-			if err != nil {
-				c.logger.Infof("browser did not open, please authenticate adapter %s:\n\n %s\n\n\n", c.Name(), c.oAuthUrl)
-			}
-		} else {
-			c.logger.Infof("Please authenticate adapter %s:\n\n %s\n\n\n", c.Name(), c.oAuthUrl)
-		}
-
-		if err := c.oAuthHandler.Listen(ctx); err != nil {
+		var err error
+		c.oAuthToken, err = c.authorize(ctx, openBrowser)
+		if err != nil {
 			return err
 		}
 
-		c.oAuthToken = c.oAuthHandler.Token()
-		_, err := c.storage.WriteCalendarAuth(auth.CalendarAuth{
+		_, err = c.storage.WriteCalendarAuth(auth.CalendarAuth{
 			CalendarID: c.calendarID,
 			OAuth2: auth.OAuth2Object{
 				AccessToken:  c.oAuthToken.AccessToken,
@@ -146,7 +175,13 @@ func (c *CalendarAPI) Initialize(ctx context.Context, openBrowser bool, config m
 	}
 
 	c.pageMaxResults = defaultPageMaxResults
-	c.gcalClient = &GCalClient{oauthClient: c.oAuthHandler.Configuration().Client(ctx, c.oAuthToken)}
+	if c.serviceAccount != nil {
+		c.gcalClient = &GCalClient{oauthClient: c.serviceAccount.Client(ctx), series: c.series}
+		// the tokens of service accounts are not stored, a reauthentication would not help
+		return c.gcalClient.InitGoogleCalendarClient(c.calendarID, c.logger)
+	}
+
+	c.gcalClient = &GCalClient{oauthClient: c.oAuthConfig.Client(ctx, c.oAuthToken), series: c.series}
 	err := c.gcalClient.InitGoogleCalendarClient(c.calendarID, c.logger)
 	if err != nil {
 		return err
@@ -177,6 +212,35 @@ func (c *CalendarAPI) Initialize(ctx context.Context, openBrowser bool, config m
 	return nil
 }
 
+// authorize lets the user authorize the adapter, either with the device authorization flow or in the browser
+func (c *CalendarAPI) authorize(ctx context.Context, openBrowser bool) (*oauth2.Token, error) {
+	if c.deviceFlow != nil {
+		err := c.deviceFlow.Authorize(ctx, func(userCode string, verificationURL string) {
+			c.logger.Infof("Please authenticate adapter %s with the code %s at:\n\n %s\n\n\n", c.Name(), userCode, verificationURL)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return c.deviceFlow.Token(), nil
+	}
+
+	c.oAuthUrl = c.oAuthHandler.Configuration().AuthCodeURL("state", oauth2.AccessTypeOffline)
+	if openBrowser {
+		c.logger.Infof("opening browser window for authentication of %s\n", c.Name())
+		err := browser.OpenURL(c.oAuthUrl)
+		if err != nil {
+			c.logger.Infof("browser did not open, please authenticate adapter %s:\n\n %s\n\n\n", c.Name(), c.oAuthUrl)
+		}
+	} else {
+		c.logger.Infof("Please authenticate adapter %s:\n\n %s\n\n\n", c.Name(), c.oAuthUrl)
+	}
+
+	if err := c.oAuthHandler.Listen(ctx); err != nil {
+		return nil, err
+	}
+	return c.oAuthHandler.Token(), nil
+}
+
 // EventsInTimeframe returns all events in a Google calendar within the given start and end times.
 func (c *CalendarAPI) EventsInTimeframe(ctx context.Context, start time.Time, end time.Time) ([]models.Event, error) {
 	events, err := c.gcalClient.ListEvents(ctx, start, end)
@@ -189,6 +253,22 @@ func (c *CalendarAPI) EventsInTimeframe(ctx context.Context, start time.Time, en
 	return events, nil
 }
 
+// ChangedEventsInTimeframe returns the changes in a Google calendar since the listing which returned the given sync token.
+func (c *CalendarAPI) ChangedEventsInTimeframe(ctx context.Context, start time.Time, end time.Time, token string) (models.Changes, error) {
+	changes, err := c.gcalClient.ListEventChanges(ctx, start, end, token)
+	if err != nil {
+		return models.Changes{}, err
+	}
+
+	if changes.Full {
+		c.logger.Infof("loaded %d events between %s and %s.", len(changes.Events), start.Format(time.DateOnly), end.Format(time.DateOnly))
+	} else {
+		c.logger.Infof("loaded %d changed and %d deleted events since the last sync.", len(changes.Events), len(changes.Deleted))
+	}
+
+	return changes, nil
+}
+
 // CreateEvent inserts a new event in the configured Google Calendar based on a given sync.Event.
 func (c *CalendarAPI) CreateEvent(ctx context.Context, e models.Event) error {
 	err := c.gcalClient.CreateEvent(ctx, e)
@@ -240,6 +320,11 @@ func (c *CalendarAPI) GetCalendarHash() string {
 	return c.gcalClient.GetCalendarHash()
 }
 
+// SetSeries lists series as recurring events with their modified instances instead of their instances
+func (c *CalendarAPI) SetSeries(series bool) {
+	c.series = series
+}
+
 func (c *CalendarAPI) SetLogger(logger *log.Logger) {
 	c.logger = logger
 }
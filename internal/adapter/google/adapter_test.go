@@ -0,0 +1,73 @@
+package google
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inovex/CalendarSync/internal/auth"
+)
+
+func writeServiceAccountKey(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   "calendarsync@example.iam.gserviceaccount.com",
+		"private_key_id": "key-id",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":      "https://oauth2.googleapis.com/token",
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestSetupOauth2ServiceAccount(t *testing.T) {
+	api := &CalendarAPI{}
+	api.SetLogger(log.Default())
+	require.NoError(t, api.SetCalendarID("primary"))
+
+	// the auth storage must not be used, the missing client id must not be reported
+	err := api.SetupOauth2(context.Background(), auth.Credentials{
+		ServiceAccount: auth.ServiceAccount{KeyFile: writeServiceAccountKey(t), Subject: "alice@example.com"},
+	}, nil, 0)
+	require.NoError(t, err)
+
+	assert.True(t, api.authenticated)
+	assert.Nil(t, api.oAuthHandler)
+	require.NotNil(t, api.serviceAccount)
+	assert.Equal(t, "calendarsync@example.iam.gserviceaccount.com", api.serviceAccount.Email)
+	assert.Equal(t, "alice@example.com", api.serviceAccount.Subject)
+}
+
+func TestSetupOauth2InvalidServiceAccount(t *testing.T) {
+	api := &CalendarAPI{}
+	api.SetLogger(log.Default())
+
+	err := api.SetupOauth2(context.Background(), auth.Credentials{
+		ServiceAccount: auth.ServiceAccount{KeyFile: filepath.Join(t.TempDir(), "missing.json")},
+	}, nil, 0)
+	assert.ErrorContains(t, err, "unable to read service account key file")
+
+	path := filepath.Join(t.TempDir(), "key.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"type": "authorized_user"}`), 0o600))
+	err = api.SetupOauth2(context.Background(), auth.Credentials{
+		ServiceAccount: auth.ServiceAccount{KeyFile: path},
+	}, nil, 0)
+	assert.ErrorContains(t, err, "invalid service account key file")
+}
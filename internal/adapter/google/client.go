@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/inovex/CalendarSync/internal/models"
@@ -23,6 +24,9 @@ const (
 	maxCallsPerSecond     = 10
 )
 
+// listedEventTypes are the event types which are synced
+var listedEventTypes = []string{"default", "focusTime", "outOfOffice"}
+
 // GCalClient implements the GoogleCalendarClient interface
 type GCalClient struct {
 	Client      *calendar.Service
@@ -30,6 +34,12 @@ type GCalClient struct {
 	CalendarId  string
 	oauthClient *http.Client
 	logger      *log.Logger
+	// series lists series as recurring events instead of their instances
+	series bool
+	// listedStart and listedEnd are the timeframe in which the series were listed last, the EXDATEs of a series
+	// outside of it are unknown to the source
+	listedStart time.Time
+	listedEnd   time.Time
 }
 
 func (g *GCalClient) InitGoogleCalendarClient(CalendarId string, logger *log.Logger) error {
@@ -49,12 +59,16 @@ func (g *GCalClient) InitRateLimiter() {
 }
 
 func (g *GCalClient) ListEvents(ctx context.Context, starttime time.Time, endtime time.Time) ([]models.Event, error) {
+	if g.series {
+		return g.listSeries(ctx, starttime, endtime)
+	}
+
 	g.RateLimiter.Take()
 	listCall := g.Client.Events.List(g.CalendarId).
 		ShowDeleted(false).
 		SingleEvents(true).
 		// see: https://developers.google.com/calendar/api/v3/reference/events/list
-		EventTypes("default", "focusTime", "outOfOffice").
+		EventTypes(listedEventTypes...).
 		TimeMin(starttime.Format(time.RFC3339)).
 		TimeMax(endtime.Format(time.RFC3339)).
 		MaxResults(defaultPageMaxResults).
@@ -86,7 +100,81 @@ func (g *GCalClient) ListEvents(ctx context.Context, starttime time.Time, endtim
 	return loadedEvents, nil
 }
 
+// ListEventChanges lists the changes of the calendar since the listing which returned the given syncToken.
+// A listing without a token is bounded to the timeframe. The token of a listing can't be combined with a timeframe,
+// it lists the changes of all events in the calendar, and the events outside the timeframe are dropped.
+// See https://developers.google.com/calendar/api/guides/sync
+func (g *GCalClient) ListEventChanges(ctx context.Context, starttime time.Time, endtime time.Time, syncToken string) (models.Changes, error) {
+	listCall := g.Client.Events.List(g.CalendarId).
+		SingleEvents(true).
+		MaxResults(defaultPageMaxResults).
+		TimeZone("UTC").
+		Context(ctx)
+	if syncToken != "" {
+		listCall = listCall.SyncToken(syncToken)
+	} else {
+		listCall = listCall.
+			TimeMin(starttime.Format(time.RFC3339)).
+			TimeMax(endtime.Format(time.RFC3339))
+	}
+
+	var items []*calendar.Event
+	var nextSyncToken string
+	for pageToken := ""; ; {
+		if pageToken != "" {
+			listCall = listCall.PageToken(pageToken)
+		}
+		g.RateLimiter.Take()
+		eventList, err := listCall.Do()
+		if isGone(err) {
+			return models.Changes{}, models.ErrSyncTokenInvalid
+		} else if err != nil {
+			return models.Changes{}, fmt.Errorf("failed to list changed events from calendar %s: %w", g.CalendarId, err)
+		}
+		items = append(items, eventList.Items...)
+
+		if eventList.NextPageToken == "" {
+			nextSyncToken = eventList.NextSyncToken
+			break
+		}
+		pageToken = eventList.NextPageToken
+	}
+
+	changes := models.Changes{Token: nextSyncToken, Full: syncToken == ""}
+	for _, item := range items {
+		switch {
+		case item.Status == "cancelled":
+			// cancelled events only contain their ID
+			changes.Deleted = append(changes.Deleted, models.Event{ID: item.Id, Metadata: ensureMetadata(item, g.GetCalendarHash())})
+		case item.EventType != "" && !slices.Contains(listedEventTypes, item.EventType):
+			// the full listing does not filter the event types, only sync those which are listed by ListEvents
+		case !eventDateTimeToTime(item.End).After(starttime) || !eventDateTimeToTime(item.Start).Before(endtime):
+			// the event was moved out of the timeframe
+			changes.Deleted = append(changes.Deleted, models.Event{ID: item.Id, Metadata: ensureMetadata(item, g.GetCalendarHash())})
+		default:
+			changes.Events = append(changes.Events, calendarEventToEvent(item, g.GetCalendarHash()))
+		}
+	}
+
+	if changes.Full {
+		// a full listing contains no deleted events, but all events outside the timeframe
+		changes.Deleted = nil
+	}
+	return changes, nil
+}
+
+func isGone(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusGone
+}
+
 func (g *GCalClient) CreateEvent(ctx context.Context, event models.Event) error {
+	if event.Instance != nil {
+		// modified instances already exist as instances of the series
+		event.ID = instanceID(event.Instance, event.AllDay)
+		return g.UpdateEvent(ctx, event)
+	}
+
 	extProperties := &calendar.EventExtendedProperties{
 		Private: eventMetadataToEventProperties(event.Metadata),
 	}
@@ -112,18 +200,25 @@ func (g *GCalClient) CreateEvent(ctx context.Context, event models.Event) error
 		}
 	}
 
+	calendarEvent := &calendar.Event{
+		Summary:            event.Title,
+		Description:        event.Description,
+		Location:           event.Location,
+		Start:              timeToEventDateTime(event.AllDay, event.StartTime, event.TimeZone),
+		End:                timeToEventDateTime(event.AllDay, event.EndTime, event.TimeZone),
+		ExtendedProperties: extProperties,
+		Attendees:          calendarAttendees,
+		Reminders:          &calendarReminders,
+	}
+	if event.Recurrence != nil {
+		if err := setRecurrence(calendarEvent, event, nil, g.listedStart, g.listedEnd); err != nil {
+			return err
+		}
+	}
+
 	call, err := retry(ctx, func() (*calendar.Event, error) {
 		g.RateLimiter.Take()
-		return g.Client.Events.Insert(g.CalendarId, &calendar.Event{
-			Summary:            event.Title,
-			Description:        event.Description,
-			Location:           event.Location,
-			Start:              timeToEventDateTime(event.AllDay, event.StartTime),
-			End:                timeToEventDateTime(event.AllDay, event.EndTime),
-			ExtendedProperties: extProperties,
-			Attendees:          calendarAttendees,
-			Reminders:          &calendarReminders,
-		}).Context(ctx).SendUpdates("none").Do()
+		return g.Client.Events.Insert(g.CalendarId, calendarEvent).Context(ctx).SendUpdates("none").Do()
 	})
 	if err != nil {
 		return err
@@ -165,18 +260,34 @@ func (g *GCalClient) UpdateEvent(ctx context.Context, event models.Event) error
 		}
 	}
 
+	calendarEvent := &calendar.Event{
+		Summary:            event.Title,
+		Description:        event.Description,
+		Location:           event.Location,
+		Start:              timeToEventDateTime(event.AllDay, event.StartTime, event.TimeZone),
+		End:                timeToEventDateTime(event.AllDay, event.EndTime, event.TimeZone),
+		ExtendedProperties: extProperties,
+		Attendees:          calendarAttendees,
+		Reminders:          calendarReminders,
+	}
+	if event.Instance != nil {
+		calendarEvent.RecurringEventId = event.Instance.SeriesID
+		calendarEvent.OriginalStartTime = timeToEventDateTime(event.AllDay, event.Instance.OriginalStart, event.TimeZone)
+	}
+	if event.Recurrence != nil {
+		g.RateLimiter.Take()
+		existing, err := g.Client.Events.Get(g.CalendarId, event.ID).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to load series: %w", err)
+		}
+		if err := setRecurrence(calendarEvent, event, existing.Recurrence, g.listedStart, g.listedEnd); err != nil {
+			return err
+		}
+	}
+
 	_, err := retry(ctx, func() (*calendar.Event, error) {
 		g.RateLimiter.Take()
-		return g.Client.Events.Update(g.CalendarId, event.ID, &calendar.Event{
-			Summary:            event.Title,
-			Description:        event.Description,
-			Location:           event.Location,
-			Start:              timeToEventDateTime(event.AllDay, event.StartTime),
-			End:                timeToEventDateTime(event.AllDay, event.EndTime),
-			ExtendedProperties: extProperties,
-			Attendees:          calendarAttendees,
-			Reminders:          calendarReminders,
-		}).Context(ctx).SendUpdates("none").Do()
+		return g.Client.Events.Update(g.CalendarId, event.ID, calendarEvent).Context(ctx).SendUpdates("none").Do()
 	})
 	if isNotFound(err) {
 		return errors.New("already deleted")
@@ -193,7 +304,7 @@ func (g *GCalClient) DeleteEvent(ctx context.Context, event models.Event) error
 		err := g.Client.Events.Delete(g.CalendarId, event.ID).Context(ctx).SendUpdates("none").Do()
 		return nil, err
 	})
-	if isNotFound(err) {
+	if isNotFound(err) || isGone(err) {
 		g.logger.Debug("Event is already deleted.", "method", "DeleteEvent", "title", event.ShortTitle(), "time", event.StartTime.String())
 		return nil
 	} else if err != nil {
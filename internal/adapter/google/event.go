@@ -47,11 +47,13 @@ func calendarEventToEvent(e *calendar.Event, adapterSourceID string) models.Even
 		AllDay:      isAllDayEvent(*e),
 		StartTime:   eventDateTimeToTime(e.Start),
 		EndTime:     eventDateTimeToTime(e.End),
+		TimeZone:    eventTimeZone(e),
 		Metadata:    metadata,
 		Attendees:   attendees,
 		Reminders:   reminders,
 		MeetingLink: e.HangoutLink,
 		Accepted:    hasEventAccepted,
+		ETag:        e.Etag,
 	}
 }
 
@@ -74,6 +76,18 @@ func ensureMetadata(event *calendar.Event, adapterSourceID string) *models.Metad
 	return metadata
 }
 
+// eventTimeZone returns the IANA time zone of the event, which is empty for all-day events and
+// events which use the time zone of the calendar
+func eventTimeZone(e *calendar.Event) string {
+	if e.Start == nil || e.Start.Date != "" {
+		return ""
+	}
+	if _, err := time.LoadLocation(e.Start.TimeZone); e.Start.TimeZone == "" || err != nil {
+		return ""
+	}
+	return e.Start.TimeZone
+}
+
 // isAllDayEvent returns true if the event is an 'all-day' event.
 func isAllDayEvent(event calendar.Event) bool {
 	return event.Start.Date != ""
@@ -81,8 +95,8 @@ func isAllDayEvent(event calendar.Event) bool {
 
 // timeToEventDateTime converts an internal event time representation
 // to EventDateTime which is required by the Google Calendar API.
-// todo: handle timezone
-func timeToEventDateTime(allDay bool, t time.Time) *calendar.EventDateTime {
+// Times of events with a known time zone are written in that time zone.
+func timeToEventDateTime(allDay bool, t time.Time, timeZone string) *calendar.EventDateTime {
 	if allDay {
 		return &calendar.EventDateTime{
 			Date:     t.Format("2006-01-02"),
@@ -91,6 +105,14 @@ func timeToEventDateTime(allDay bool, t time.Time) *calendar.EventDateTime {
 		}
 	}
 
+	if location, err := time.LoadLocation(timeZone); timeZone != "" && err == nil {
+		return &calendar.EventDateTime{
+			Date:     "",
+			DateTime: t.In(location).Format(time.RFC3339),
+			TimeZone: timeZone,
+		}
+	}
+
 	return &calendar.EventDateTime{
 		Date:     "",
 		DateTime: t.Format(time.RFC3339),
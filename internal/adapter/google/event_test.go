@@ -2,6 +2,7 @@ package google
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/inovex/CalendarSync/internal/models"
@@ -112,3 +113,18 @@ func Test_ensureMetadata(t *testing.T) {
 		})
 	}
 }
+
+func Test_timeToEventDateTime(t *testing.T) {
+	start := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, &calendar.EventDateTime{DateTime: "2024-03-04T09:00:00+01:00", TimeZone: "Europe/Berlin"}, timeToEventDateTime(false, start, "Europe/Berlin"))
+	assert.Equal(t, &calendar.EventDateTime{DateTime: "2024-03-04T08:00:00Z"}, timeToEventDateTime(false, start, ""))
+	assert.Equal(t, &calendar.EventDateTime{DateTime: "2024-03-04T08:00:00Z"}, timeToEventDateTime(false, start, "Nowhere/Special"))
+	assert.Equal(t, &calendar.EventDateTime{Date: "2024-03-04"}, timeToEventDateTime(true, start, "Europe/Berlin"))
+}
+
+func Test_eventTimeZone(t *testing.T) {
+	assert.Equal(t, "Europe/Berlin", eventTimeZone(&calendar.Event{Start: &calendar.EventDateTime{DateTime: "2024-03-04T08:00:00Z", TimeZone: "Europe/Berlin"}}))
+	assert.Equal(t, "", eventTimeZone(&calendar.Event{Start: &calendar.EventDateTime{Date: "2024-03-04", TimeZone: "Europe/Berlin"}}))
+	assert.Equal(t, "", eventTimeZone(&calendar.Event{Start: &calendar.EventDateTime{DateTime: "2024-03-04T08:00:00Z"}}))
+}
@@ -0,0 +1,235 @@
+package google
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// listSeries lists the events in the timeframe without expanding series: a series is listed as its master event
+// with the recurrence, modified instances are listed as separate events. The cancelled instances within the
+// timeframe are added to the ExDates of the master event.
+func (g *GCalClient) listSeries(ctx context.Context, starttime time.Time, endtime time.Time) ([]models.Event, error) {
+	g.listedStart, g.listedEnd = starttime, endtime
+	listCall := g.Client.Events.List(g.CalendarId).
+		ShowDeleted(false).
+		SingleEvents(false).
+		EventTypes(listedEventTypes...).
+		TimeMin(starttime.Format(time.RFC3339)).
+		TimeMax(endtime.Format(time.RFC3339)).
+		MaxResults(defaultPageMaxResults).
+		TimeZone("UTC").
+		Context(ctx)
+
+	var items []*calendar.Event
+	for pageToken := ""; ; {
+		if pageToken != "" {
+			listCall = listCall.PageToken(pageToken)
+		}
+		g.RateLimiter.Take()
+		eventList, err := listCall.Do()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list series from calendar %s: %w", g.CalendarId, err)
+		}
+		items = append(items, eventList.Items...)
+
+		if eventList.NextPageToken == "" {
+			break
+		}
+		pageToken = eventList.NextPageToken
+	}
+
+	var events []models.Event
+	masters := make(map[string]int)
+	for _, item := range items {
+		if item.Status == "cancelled" || item.RecurringEventId != "" || len(item.Recurrence) == 0 {
+			continue
+		}
+		event := calendarEventToEvent(item, g.GetCalendarHash())
+		recurrence, err := parseRecurrence(item.Recurrence, item.Start.TimeZone)
+		if err != nil {
+			g.logger.Warn("skipping series with unsupported recurrence", "title", event.ShortTitle(), "error", err)
+			continue
+		}
+		recurrence.ExDates = models.UniqueDates(inTimeframe(recurrence.ExDates, starttime, endtime))
+		event.Recurrence = recurrence
+		masters[item.Id] = len(events)
+		events = append(events, event)
+	}
+
+	for _, item := range items {
+		switch {
+		case item.RecurringEventId == "" && len(item.Recurrence) == 0:
+			events = append(events, calendarEventToEvent(item, g.GetCalendarHash()))
+
+		case item.RecurringEventId == "":
+			// series master, already listed
+
+		case item.Status == "cancelled":
+			i, ok := masters[item.RecurringEventId]
+			originalStart := eventDateTimeToTime(item.OriginalStartTime)
+			if ok && !originalStart.Before(starttime) && originalStart.Before(endtime) {
+				events[i].Recurrence.ExDates = models.UniqueDates(append(events[i].Recurrence.ExDates, originalStart))
+			}
+
+		default:
+			event := calendarEventToEvent(item, g.GetCalendarHash())
+			event.Instance = &models.SeriesInstance{
+				SeriesID:      item.RecurringEventId,
+				SeriesSyncID:  models.NewEventID(item.RecurringEventId),
+				OriginalStart: eventDateTimeToTime(item.OriginalStartTime),
+			}
+			if i, ok := masters[item.RecurringEventId]; ok {
+				event.Instance.SeriesSyncID = events[i].Metadata.SyncID
+			}
+			if event.Metadata.SyncID == event.Instance.SeriesSyncID {
+				// the instance was modified in this calendar and inherited the metadata of the series
+				g.logger.Debug("skipping modified instance without own metadata", "title", event.ShortTitle(), "time", event.StartTime.String())
+				continue
+			}
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// parseRecurrence parses the RRULE and EXDATE lines of a series, RDATE lines are not supported
+func parseRecurrence(lines []string, timeZone string) (*models.Recurrence, error) {
+	recurrence := &models.Recurrence{TimeZone: timeZone}
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid recurrence line %q", line)
+		}
+		name, params, _ := strings.Cut(name, ";")
+
+		switch name {
+		case "RRULE":
+			if recurrence.Rule != "" {
+				return nil, fmt.Errorf("multiple recurrence rules are not supported")
+			}
+			recurrence.Rule = value
+		case "EXDATE":
+			exDates, err := parseDates(params, value)
+			if err != nil {
+				return nil, err
+			}
+			recurrence.ExDates = append(recurrence.ExDates, exDates...)
+		default:
+			return nil, fmt.Errorf("recurrence property %s is not supported", name)
+		}
+	}
+	if recurrence.Rule == "" {
+		return nil, fmt.Errorf("recurrence without a rule is not supported")
+	}
+	return recurrence, nil
+}
+
+// parseDates parses the comma separated dates of an EXDATE line, which are either UTC date-times,
+// date-times in the time zone given by the TZID parameter or dates
+func parseDates(params string, value string) ([]time.Time, error) {
+	location := time.UTC
+	layout := models.RFC5545DateTime
+	for _, param := range strings.Split(params, ";") {
+		key, paramValue, _ := strings.Cut(param, "=")
+		switch key {
+		case "TZID":
+			var err error
+			if location, err = time.LoadLocation(paramValue); err != nil {
+				return nil, fmt.Errorf("unknown time zone %s: %w", paramValue, err)
+			}
+			layout = strings.TrimSuffix(models.RFC5545DateTime, "Z")
+		case "VALUE":
+			if paramValue == "DATE" {
+				layout = models.RFC5545Date
+			}
+		}
+	}
+
+	var dates []time.Time
+	for _, date := range strings.Split(value, ",") {
+		t, err := time.ParseInLocation(layout, date, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q: %w", date, err)
+		}
+		dates = append(dates, t.UTC())
+	}
+	return dates, nil
+}
+
+func inTimeframe(dates []time.Time, start time.Time, end time.Time) []time.Time {
+	var result []time.Time
+	for _, date := range dates {
+		if !date.Before(start) && date.Before(end) {
+			result = append(result, date)
+		}
+	}
+	return result
+}
+
+// setRecurrence sets the recurrence of the master event of a series. Google requires the time zone of a series,
+// in which the rule is expanded. The source only lists the cancelled instances within the synced timeframe from
+// start to end, so the EXDATEs of the existing master event outside of it are kept and stay cancelled.
+func setRecurrence(calendarEvent *calendar.Event, event models.Event, existing []string, start time.Time, end time.Time) error {
+	location, err := event.Recurrence.Location()
+	if err != nil {
+		return err
+	}
+
+	exDates := event.Recurrence.ExDates
+	for _, line := range existing {
+		name, value, _ := strings.Cut(line, ":")
+		name, params, _ := strings.Cut(name, ";")
+		if name != "EXDATE" {
+			continue
+		}
+		dates, err := parseDates(params, value)
+		if err != nil {
+			return err
+		}
+		for _, date := range dates {
+			if date.Before(start) || !date.Before(end) {
+				exDates = append(exDates, date)
+			}
+		}
+	}
+
+	calendarEvent.Recurrence = []string{"RRULE:" + event.Recurrence.Rule}
+	if len(exDates) > 0 {
+		var formatted []string
+		for _, exDate := range models.UniqueDates(exDates) {
+			formatted = append(formatted, formatDate(event.AllDay, exDate))
+		}
+		if event.AllDay {
+			calendarEvent.Recurrence = append(calendarEvent.Recurrence, "EXDATE;VALUE=DATE:"+strings.Join(formatted, ","))
+		} else {
+			calendarEvent.Recurrence = append(calendarEvent.Recurrence, "EXDATE:"+strings.Join(formatted, ","))
+		}
+	}
+
+	if !event.AllDay {
+		calendarEvent.Start.DateTime = event.StartTime.In(location).Format(time.RFC3339)
+		calendarEvent.Start.TimeZone = location.String()
+		calendarEvent.End.DateTime = event.EndTime.In(location).Format(time.RFC3339)
+		calendarEvent.End.TimeZone = location.String()
+	}
+	return nil
+}
+
+// instanceID returns the ID of the instance of a series, which is derived from its original start time
+func instanceID(instance *models.SeriesInstance, allDay bool) string {
+	return instance.SeriesID + "_" + formatDate(allDay, instance.OriginalStart)
+}
+
+func formatDate(allDay bool, t time.Time) string {
+	if allDay {
+		return t.Format(models.RFC5545Date)
+	}
+	return t.UTC().Format(models.RFC5545DateTime)
+}
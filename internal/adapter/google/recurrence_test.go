@@ -0,0 +1,141 @@
+package google
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/calendar/v3"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+func Test_parseRecurrence(t *testing.T) {
+	tt := []struct {
+		name        string
+		lines       []string
+		expected    *models.Recurrence
+		expectedErr bool
+	}{
+		{
+			name:     "rule",
+			lines:    []string{"RRULE:FREQ=WEEKLY;BYDAY=MO"},
+			expected: &models.Recurrence{Rule: "FREQ=WEEKLY;BYDAY=MO", TimeZone: "Europe/Berlin"},
+		},
+		{
+			name: "rule with cancelled instances",
+			lines: []string{
+				"EXDATE;TZID=Europe/Berlin:20240311T090000,20240318T090000",
+				"RRULE:FREQ=WEEKLY",
+				"EXDATE:20240401T070000Z",
+			},
+			expected: &models.Recurrence{
+				Rule: "FREQ=WEEKLY",
+				ExDates: []time.Time{
+					time.Date(2024, 3, 11, 8, 0, 0, 0, time.UTC),
+					time.Date(2024, 3, 18, 8, 0, 0, 0, time.UTC),
+					time.Date(2024, 4, 1, 7, 0, 0, 0, time.UTC),
+				},
+				TimeZone: "Europe/Berlin",
+			},
+		},
+		{
+			name:     "cancelled all-day instances",
+			lines:    []string{"RRULE:FREQ=DAILY", "EXDATE;VALUE=DATE:20240305"},
+			expected: &models.Recurrence{Rule: "FREQ=DAILY", ExDates: []time.Time{time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)}, TimeZone: "Europe/Berlin"},
+		},
+		{
+			name:        "additional instances are not supported",
+			lines:       []string{"RRULE:FREQ=DAILY", "RDATE:20240305T090000Z"},
+			expectedErr: true,
+		},
+		{
+			name:        "multiple rules are not supported",
+			lines:       []string{"RRULE:FREQ=DAILY", "RRULE:FREQ=WEEKLY"},
+			expectedErr: true,
+		},
+		{
+			name:        "rule is required",
+			lines:       []string{"EXDATE:20240305T090000Z"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			recurrence, err := parseRecurrence(tc.lines, "Europe/Berlin")
+			if tc.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, recurrence)
+		})
+	}
+}
+
+func Test_setRecurrence(t *testing.T) {
+	event := models.Event{
+		StartTime: time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+		Recurrence: &models.Recurrence{
+			Rule: "FREQ=WEEKLY",
+			ExDates: []time.Time{
+				time.Date(2024, 3, 18, 8, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 11, 8, 0, 0, 0, time.UTC),
+				time.Date(2024, 3, 11, 8, 0, 0, 0, time.UTC),
+			},
+			TimeZone: "Europe/Berlin",
+		},
+	}
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	calendarEvent := &calendar.Event{Start: &calendar.EventDateTime{}, End: &calendar.EventDateTime{}}
+
+	// the instance on 2024-03-25 was restored in the source, the one on 2024-01-01 is outside the timeframe
+	err := setRecurrence(calendarEvent, event, []string{
+		"RRULE:FREQ=DAILY",
+		"EXDATE:20240101T080000Z,20240311T080000Z",
+		"EXDATE;TZID=Europe/Berlin:20240325T090000",
+	}, start, end)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"RRULE:FREQ=WEEKLY", "EXDATE:20240101T080000Z,20240311T080000Z,20240318T080000Z"}, calendarEvent.Recurrence)
+	assert.Equal(t, &calendar.EventDateTime{DateTime: "2024-03-04T09:00:00+01:00", TimeZone: "Europe/Berlin"}, calendarEvent.Start)
+	assert.Equal(t, &calendar.EventDateTime{DateTime: "2024-03-04T10:00:00+01:00", TimeZone: "Europe/Berlin"}, calendarEvent.End)
+}
+
+func Test_setRecurrenceTwice(t *testing.T) {
+	event := models.Event{
+		StartTime: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+		AllDay:    true,
+		Recurrence: &models.Recurrence{
+			Rule:    "FREQ=DAILY",
+			ExDates: []time.Time{time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	first := &calendar.Event{Start: &calendar.EventDateTime{}, End: &calendar.EventDateTime{}}
+	assert.NoError(t, setRecurrence(first, event, []string{"RRULE:FREQ=DAILY", "EXDATE;VALUE=DATE:20240201"}, start, end))
+	second := &calendar.Event{Start: &calendar.EventDateTime{}, End: &calendar.EventDateTime{}}
+	assert.NoError(t, setRecurrence(second, event, first.Recurrence, start, end))
+
+	assert.Equal(t, []string{"RRULE:FREQ=DAILY", "EXDATE;VALUE=DATE:20240201,20240306"}, first.Recurrence)
+	assert.Equal(t, first.Recurrence, second.Recurrence)
+
+	// the series is not changed by the update, so the next diff doesn't find a change
+	recurrence, err := parseRecurrence(second.Recurrence, "")
+	assert.NoError(t, err)
+	recurrence.ExDates = inTimeframe(recurrence.ExDates, start, end)
+	assert.True(t, models.SameRecurrence(event, models.Event{StartTime: event.StartTime, Recurrence: recurrence}))
+}
+
+func Test_instanceID(t *testing.T) {
+	instance := &models.SeriesInstance{SeriesID: "series", OriginalStart: time.Date(2024, 3, 11, 8, 0, 0, 0, time.UTC)}
+
+	assert.Equal(t, "series_20240311T080000Z", instanceID(instance, false))
+	assert.Equal(t, "series_20240311", instanceID(instance, true))
+}
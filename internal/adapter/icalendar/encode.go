@@ -0,0 +1,302 @@
+package icalendar
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// NewCalendar returns a calendar object which contains the event, i.e. the master event of a series, with the
+// given UID. The metadata of the event is stored in X- properties.
+func NewCalendar(event models.Event, uid string) *ical.Calendar {
+	calendar := ical.NewCalendar()
+	calendar.Props.SetText(ical.PropVersion, "2.0")
+	calendar.Props.SetText(ical.PropProductID, ProductID)
+	calendar.Children = append(calendar.Children, eventComponent(event, uid, eventFormat(event)))
+	addTimeZones(calendar)
+	return calendar
+}
+
+// Join combines the calendar objects, e.g. the ones returned by Split, into a single calendar. Time zones which
+// are defined by multiple calendar objects are only added once.
+func Join(objects []*ical.Calendar) *ical.Calendar {
+	calendar := ical.NewCalendar()
+	calendar.Props.SetText(ical.PropVersion, "2.0")
+	calendar.Props.SetText(ical.PropProductID, ProductID)
+
+	var timeZones, events []*ical.Component
+	defined := make(map[string]bool)
+	for _, object := range objects {
+		for _, child := range object.Children {
+			if child.Name != ical.CompTimezone {
+				events = append(events, child)
+				continue
+			}
+			if tzid := child.Props.Get(ical.PropTimezoneID); tzid != nil && !defined[tzid.Value] {
+				defined[tzid.Value] = true
+				timeZones = append(timeZones, child)
+			}
+		}
+	}
+	calendar.Children = append(timeZones, events...)
+	return calendar
+}
+
+// SetEvent replaces the event, i.e. the master event of a series, in the calendar object. If the event is still
+// a series, the modified instances and the cancelled instances of the existing series are kept.
+func SetEvent(calendar *ical.Calendar, event models.Event) error {
+	i, master := findMaster(calendar)
+	if master == nil {
+		return fmt.Errorf("calendar object contains no event")
+	}
+
+	format := eventFormat(event)
+	component := eventComponent(event, master.Props.Get(ical.PropUID).Value, format)
+	if event.Recurrence != nil {
+		exDates, err := masterExDates(master, TimeZones(calendar))
+		if err != nil {
+			return err
+		}
+		for _, exDate := range exDates {
+			if !slices.ContainsFunc(event.Recurrence.ExDates, func(t time.Time) bool { return sameStart(event.AllDay, t, exDate) }) {
+				component.Props.Add(format.prop(ical.PropExceptionDates, exDate))
+			}
+		}
+	}
+	calendar.Children[i] = component
+
+	if event.Recurrence == nil {
+		calendar.Children = slices.DeleteFunc(calendar.Children, func(child *ical.Component) bool {
+			return child.Name == ical.CompEvent && child.Props.Get(ical.PropRecurrenceID) != nil
+		})
+	}
+	addTimeZones(calendar)
+	return nil
+}
+
+// SetInstance adds or replaces the modified instance of the series in the calendar object, the instance is
+// identified by its original start
+func SetInstance(calendar *ical.Calendar, event models.Event, originalStart time.Time) error {
+	zones := TimeZones(calendar)
+	_, master := findMaster(calendar)
+	if master == nil || master.Props.Get(ical.PropRecurrenceRule) == nil {
+		return fmt.Errorf("calendar object contains no series")
+	}
+	format, err := masterFormat(master, zones)
+	if err != nil {
+		return err
+	}
+
+	event.Recurrence = nil
+	component := eventComponent(event, master.Props.Get(ical.PropUID).Value, eventFormat(event))
+	component.Props.Set(format.prop(ical.PropRecurrenceID, originalStart))
+
+	if i := findInstance(calendar, format, originalStart, zones); i >= 0 {
+		calendar.Children[i] = component
+	} else {
+		calendar.Children = append(calendar.Children, component)
+	}
+	addTimeZones(calendar)
+	return nil
+}
+
+// CancelInstance removes the modified instance from the series in the calendar object and adds its original
+// start to the cancelled instances of the series
+func CancelInstance(calendar *ical.Calendar, originalStart time.Time) error {
+	zones := TimeZones(calendar)
+	_, master := findMaster(calendar)
+	if master == nil || master.Props.Get(ical.PropRecurrenceRule) == nil {
+		return fmt.Errorf("calendar object contains no series")
+	}
+	format, err := masterFormat(master, zones)
+	if err != nil {
+		return err
+	}
+
+	if i := findInstance(calendar, format, originalStart, zones); i >= 0 {
+		calendar.Children = slices.Delete(calendar.Children, i, i+1)
+	}
+	master.Props.Add(format.prop(ical.PropExceptionDates, originalStart))
+	return nil
+}
+
+// findMaster returns the index and the component of the event without a RECURRENCE-ID
+func findMaster(calendar *ical.Calendar) (int, *ical.Component) {
+	for i, child := range calendar.Children {
+		if child.Name == ical.CompEvent && child.Props.Get(ical.PropRecurrenceID) == nil {
+			return i, child
+		}
+	}
+	return -1, nil
+}
+
+// findInstance returns the index of the modified instance with the original start or -1 if it does not exist
+func findInstance(calendar *ical.Calendar, format timeFormat, originalStart time.Time, zones map[string]TimeZone) int {
+	for i, child := range calendar.Children {
+		if child.Name != ical.CompEvent || child.Props.Get(ical.PropRecurrenceID) == nil {
+			continue
+		}
+		t, _, err := ParseDateTime(child.Props.Get(ical.PropRecurrenceID), zones)
+		if err == nil && sameStart(format.allDay, t, originalStart) {
+			return i
+		}
+	}
+	return -1
+}
+
+func masterExDates(master *ical.Component, zones map[string]TimeZone) ([]time.Time, error) {
+	var exDates []time.Time
+	for _, prop := range master.Props.Values(ical.PropExceptionDates) {
+		dates, err := parseDates(prop, zones)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode exdate: %w", err)
+		}
+		exDates = append(exDates, dates...)
+	}
+	return exDates, nil
+}
+
+// timeFormat describes how times are written: as dates, in UTC, as floating times in the local time zone
+// or in a time zone
+type timeFormat struct {
+	allDay   bool
+	location *time.Location
+}
+
+// eventFormat writes the times in the time zone of the event, series are written in the time zone of their
+// recurrence. Times of events without a time zone are written in UTC.
+func eventFormat(event models.Event) timeFormat {
+	location := event.TimeLocation()
+	if event.Recurrence != nil && event.Recurrence.TimeZone != "" {
+		if recurrenceLocation, err := event.Recurrence.Location(); err == nil {
+			location = recurrenceLocation
+		}
+	}
+	return timeFormat{allDay: event.AllDay, location: location}
+}
+
+// masterFormat returns the format of the start of the master event, which is used for RECURRENCE-ID and EXDATE
+func masterFormat(master *ical.Component, zones map[string]TimeZone) (timeFormat, error) {
+	prop := master.Props.Get(ical.PropDateTimeStart)
+	start, _, err := ParseDateTime(prop, zones)
+	if err != nil {
+		return timeFormat{}, fmt.Errorf("unable to decode dtstart: %w", err)
+	}
+	return timeFormat{allDay: isDate(prop), location: start.Location()}, nil
+}
+
+func (f timeFormat) prop(name string, t time.Time) *ical.Prop {
+	prop := ical.NewProp(name)
+	switch {
+	case f.allDay:
+		prop.SetDate(t)
+	case f.location == time.UTC:
+		prop.SetDateTime(t.UTC())
+	case f.location == time.Local:
+		prop.Value = t.In(time.Local).Format(floatingDateTime)
+	default:
+		prop.SetDateTime(t.In(f.location))
+	}
+	return prop
+}
+
+// eventComponent returns the VEVENT of the event with its recurrence and metadata
+func eventComponent(event models.Event, uid string, format timeFormat) *ical.Component {
+	component := ical.NewComponent(ical.CompEvent)
+	component.Props.SetText(ical.PropUID, uid)
+	component.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+	component.Props.Set(format.prop(ical.PropDateTimeStart, event.StartTime))
+	component.Props.Set(format.prop(ical.PropDateTimeEnd, event.EndTime))
+
+	for name, value := range map[string]string{
+		ical.PropSummary:     event.Title,
+		ical.PropDescription: event.Description,
+		ical.PropLocation:    event.Location,
+	} {
+		if value != "" {
+			component.Props.SetText(name, value)
+		}
+	}
+
+	for _, attendee := range event.Attendees {
+		prop := ical.NewProp(ical.PropAttendee)
+		prop.Value = "mailto:" + attendee.Email
+		if attendee.DisplayName != "" {
+			prop.Params.Set(ical.ParamCommonName, attendee.DisplayName)
+		}
+		component.Props.Add(prop)
+	}
+
+	for _, reminder := range event.Reminders {
+		if reminder.Actions != models.ReminderActionDisplay {
+			continue
+		}
+		alarm := ical.NewComponent(ical.CompAlarm)
+		alarm.Props.SetText(ical.PropAction, "DISPLAY")
+		alarm.Props.SetText(ical.PropDescription, "Reminder")
+		trigger := ical.NewProp(ical.PropTrigger)
+		trigger.SetDuration(reminder.Trigger.PointInTime.Sub(event.StartTime))
+		alarm.Props.Set(trigger)
+		component.Children = append(component.Children, alarm)
+	}
+
+	if event.Metadata != nil {
+		component.Props.SetText(PropEventID, event.Metadata.SyncID)
+		component.Props.SetText(PropOriginalEventUri, event.Metadata.OriginalEventUri)
+		component.Props.SetText(PropSourceID, event.Metadata.SourceID)
+	}
+
+	if event.Recurrence != nil {
+		component.Props.Set(&ical.Prop{Name: ical.PropRecurrenceRule, Params: ical.Params{}, Value: event.Recurrence.Rule})
+		for _, exDate := range event.Recurrence.ExDates {
+			component.Props.Add(format.prop(ical.PropExceptionDates, exDate))
+		}
+	}
+	return component
+}
+
+// addTimeZones adds the missing VTIMEZONE components for the IANA time zones which are used by the events
+func addTimeZones(calendar *ical.Calendar) {
+	defined := make(map[string]bool)
+	for _, child := range calendar.Children {
+		if child.Name == ical.CompTimezone && child.Props.Get(ical.PropTimezoneID) != nil {
+			defined[child.Props.Get(ical.PropTimezoneID).Value] = true
+		}
+	}
+
+	missing := make(map[string]*ical.Component)
+	for _, child := range calendar.Children {
+		if child.Name != ical.CompEvent {
+			continue
+		}
+		for _, props := range child.Props {
+			for _, prop := range props {
+				tzid := prop.Params.Get(ical.ParamTimezoneID)
+				if tzid == "" || defined[tzid] || missing[tzid] != nil {
+					continue
+				}
+				location, err := time.LoadLocation(tzid)
+				if err != nil {
+					continue
+				}
+				around, err := time.ParseInLocation(floatingDateTime, strings.Split(prop.Value, ",")[0], location)
+				if err != nil {
+					continue
+				}
+				missing[tzid] = timeZoneComponent(location, around)
+			}
+		}
+	}
+
+	var timeZones []*ical.Component
+	for _, tzid := range slices.Sorted(maps.Keys(missing)) {
+		timeZones = append(timeZones, missing[tzid])
+	}
+	calendar.Children = append(timeZones, calendar.Children...)
+}
@@ -0,0 +1,492 @@
+package icalendar
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// The properties which store the metadata of CalendarSync in the events it writes
+const (
+	PropEventID          = "X-CALENDARSYNC-EVENT-ID"
+	PropOriginalEventUri = "X-CALENDARSYNC-ORIGINAL-EVENT-URI"
+	PropSourceID         = "X-CALENDARSYNC-SOURCE-ID"
+)
+
+// ProductID identifies CalendarSync as the product which created a calendar
+const ProductID = "-//inovex//CalendarSync//EN"
+
+// Decoder converts the events of iCalendar objects to models.Event
+type Decoder struct {
+	// SourceID is the hash of the calendar, it's used for the metadata of events without CalendarSync metadata
+	SourceID string
+	// Series lists a series as its master event with the recurrence and its modified instances.
+	// Otherwise, every instance within the timeframe is listed as a separate event.
+	Series bool
+	// Start and End are the timeframe of the listed events
+	Start time.Time
+	End   time.Time
+}
+
+// Decode converts the events of the calendar within the timeframe. The events are grouped by their UID, a series
+// consists of its master event with the recurrence and its modified instances, which have a RECURRENCE-ID.
+// The ID of the events is the given id, or the UID if it's empty. Instances are identified by the ID of their
+// series and their original start, see InstanceID.
+func (d Decoder) Decode(calendar *ical.Calendar, id string, etag string) ([]models.Event, error) {
+	zones := TimeZones(calendar)
+
+	var uids []string
+	components := make(map[string][]*ical.Component)
+	for _, child := range calendar.Children {
+		if child.Name != ical.CompEvent {
+			continue
+		}
+		uid := child.Props.Get(ical.PropUID)
+		if uid == nil {
+			return nil, fmt.Errorf("event without UID")
+		}
+		if _, ok := components[uid.Value]; !ok {
+			uids = append(uids, uid.Value)
+		}
+		components[uid.Value] = append(components[uid.Value], child)
+	}
+
+	var events []models.Event
+	for _, uid := range uids {
+		objectID := id
+		if objectID == "" {
+			objectID = uid
+		}
+		decoded, err := d.decodeObject(uid, components[uid], objectID, etag, zones)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode event %s: %w", uid, err)
+		}
+		events = append(events, decoded...)
+	}
+	return events, nil
+}
+
+// Split splits a calendar with many events, e.g. an ICS feed, into calendar objects which contain the events
+// with the same UID, i.e. a single event or a series with its modified instances, and the time zones of the
+// calendar. This allows to decode the events separately.
+func Split(calendar *ical.Calendar) []*ical.Calendar {
+	var timeZones []*ical.Component
+	var objects []*ical.Calendar
+	byUID := make(map[string]*ical.Calendar)
+	for _, child := range calendar.Children {
+		switch child.Name {
+		case ical.CompTimezone:
+			timeZones = append(timeZones, child)
+		case ical.CompEvent:
+			var uid string
+			if prop := child.Props.Get(ical.PropUID); prop != nil {
+				uid = prop.Value
+			}
+			object, ok := byUID[uid]
+			if !ok || uid == "" {
+				object = ical.NewCalendar()
+				object.Props = calendar.Props
+				objects = append(objects, object)
+				byUID[uid] = object
+			}
+			object.Children = append(object.Children, child)
+		}
+	}
+
+	for _, object := range objects {
+		object.Children = append(slices.Clone(timeZones), object.Children...)
+	}
+	return objects
+}
+
+// instance is a modified or cancelled instance of a series
+type instance struct {
+	event         models.Event
+	originalStart time.Time
+	cancelled     bool
+}
+
+// decodeObject converts the events with the same UID
+func (d Decoder) decodeObject(uid string, components []*ical.Component, id string, etag string, zones map[string]TimeZone) ([]models.Event, error) {
+	var master *ical.Component
+	var overrides []*ical.Component
+	for _, component := range components {
+		if component.Props.Get(ical.PropRecurrenceID) != nil {
+			overrides = append(overrides, component)
+		} else {
+			master = component
+		}
+	}
+
+	var events []models.Event
+	var series models.Event
+	seriesMetadata := models.NewEventMetadata(uid, "", d.SourceID)
+	if master != nil {
+		var err error
+		series, err = decodeEvent(master, zones)
+		if err != nil {
+			return nil, err
+		}
+		series.ID = id
+		series.ETag = etag
+		if metadata := decodeMetadata(master); metadata != nil {
+			series.Metadata = metadata
+		} else {
+			series.Metadata = seriesMetadata
+		}
+		seriesMetadata = series.Metadata
+	}
+
+	var instances []instance
+	for _, override := range overrides {
+		event, err := decodeEvent(override, zones)
+		if err != nil {
+			return nil, err
+		}
+		originalStart, _, err := ParseDateTime(override.Props.Get(ical.PropRecurrenceID), zones)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode recurrence-id: %w", err)
+		}
+		event.ID = InstanceID(id, originalStart, event.AllDay)
+		event.ETag = etag
+		if metadata := decodeMetadata(override); metadata != nil {
+			event.Metadata = metadata
+		} else {
+			event.Metadata = instanceMetadata(seriesMetadata, originalStart, event.AllDay)
+		}
+		status := override.Props.Get(ical.PropStatus)
+		instances = append(instances, instance{
+			event:         event,
+			originalStart: originalStart,
+			cancelled:     status != nil && strings.EqualFold(status.Value, string(ical.EventCancelled)),
+		})
+	}
+
+	var recurrence *models.Recurrence
+	if master != nil {
+		var err error
+		if recurrence, err = decodeRecurrence(master, series.TimeZone, zones); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case recurrence == nil:
+		// a single event or instances of a series which is not part of the calendar, e.g. invitations to an instance
+		if master != nil && d.inTimeframe(series) {
+			events = append(events, series)
+		}
+		for _, instance := range instances {
+			if !instance.cancelled && d.inTimeframe(instance.event) {
+				events = append(events, instance.event)
+			}
+		}
+		return events, nil
+
+	case d.Series:
+		return d.series(series, recurrence, instances)
+
+	default:
+		return d.expand(series, recurrence, instances)
+	}
+}
+
+// series lists the master event of the series with its modified instances. The cancelled instances within the
+// timeframe are listed as ExDates.
+func (d Decoder) series(series models.Event, recurrence *models.Recurrence, instances []instance) ([]models.Event, error) {
+	starts, err := recurrence.Instances(series.StartTime, d.Start.Add(-series.EndTime.Sub(series.StartTime)), d.End)
+	if err != nil {
+		return nil, err
+	}
+
+	var exDates []time.Time
+	for _, exDate := range recurrence.ExDates {
+		if d.inTimeframe(models.Event{StartTime: exDate, EndTime: exDate}) {
+			exDates = append(exDates, exDate)
+		}
+	}
+
+	var events []models.Event
+	for _, instance := range instances {
+		switch {
+		case instance.cancelled:
+			if d.inTimeframe(models.Event{StartTime: instance.originalStart, EndTime: instance.originalStart}) {
+				exDates = append(exDates, instance.originalStart)
+			}
+		case !d.inTimeframe(instance.event):
+		case instance.event.Metadata.SyncID == series.Metadata.SyncID:
+			// the instance was modified in this calendar and inherited the metadata of the series
+		default:
+			instance.event.Instance = &models.SeriesInstance{
+				SeriesID:      series.ID,
+				SeriesSyncID:  series.Metadata.SyncID,
+				OriginalStart: instance.originalStart,
+			}
+			events = append(events, instance.event)
+		}
+	}
+
+	if len(starts) == 0 && len(events) == 0 {
+		// no instance of the series is within the timeframe
+		return nil, nil
+	}
+	recurrence.ExDates = exDates
+	series.Recurrence = recurrence
+	return append([]models.Event{series}, events...), nil
+}
+
+// expand lists every instance of the series within the timeframe as a separate event
+func (d Decoder) expand(series models.Event, recurrence *models.Recurrence, instances []instance) ([]models.Event, error) {
+	duration := series.EndTime.Sub(series.StartTime)
+	starts, err := recurrence.Instances(series.StartTime, d.Start.Add(-duration), d.End)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []models.Event
+	for _, start := range starts {
+		isException := func(t time.Time) bool {
+			return sameStart(series.AllDay, t, start)
+		}
+		if slices.ContainsFunc(recurrence.ExDates, isException) || slices.ContainsFunc(instances, func(i instance) bool {
+			return isException(i.originalStart)
+		}) {
+			continue
+		}
+
+		event := series
+		event.ID = InstanceID(series.ID, start, series.AllDay)
+		event.StartTime = start
+		event.EndTime = start.Add(duration)
+		event.Metadata = instanceMetadata(series.Metadata, start, series.AllDay)
+		event.Reminders = nil
+		for _, reminder := range series.Reminders {
+			reminder.Trigger.PointInTime = reminder.Trigger.PointInTime.Add(start.Sub(series.StartTime))
+			event.Reminders = append(event.Reminders, reminder)
+		}
+		if d.inTimeframe(event) {
+			events = append(events, event)
+		}
+	}
+
+	for _, instance := range instances {
+		if !instance.cancelled && d.inTimeframe(instance.event) {
+			events = append(events, instance.event)
+		}
+	}
+	return events, nil
+}
+
+// inTimeframe returns true if the event overlaps with the timeframe
+func (d Decoder) inTimeframe(event models.Event) bool {
+	return event.StartTime.Before(d.End) && (event.EndTime.After(d.Start) || !event.StartTime.Before(d.Start))
+}
+
+// decodeEvent converts the properties of a VEVENT, the ID and metadata are not set
+func decodeEvent(component *ical.Component, zones map[string]TimeZone) (models.Event, error) {
+	startProp := component.Props.Get(ical.PropDateTimeStart)
+	start, timeZone, err := ParseDateTime(startProp, zones)
+	if err != nil {
+		return models.Event{}, fmt.Errorf("unable to decode dtstart: %w", err)
+	}
+	allDay := isDate(startProp)
+
+	var end time.Time
+	switch {
+	case component.Props.Get(ical.PropDateTimeEnd) != nil:
+		if end, _, err = ParseDateTime(component.Props.Get(ical.PropDateTimeEnd), zones); err != nil {
+			return models.Event{}, fmt.Errorf("unable to decode dtend: %w", err)
+		}
+	case component.Props.Get(ical.PropDuration) != nil:
+		duration, err := component.Props.Get(ical.PropDuration).Duration()
+		if err != nil {
+			return models.Event{}, fmt.Errorf("unable to decode duration: %w", err)
+		}
+		end = start.Add(duration)
+	case allDay:
+		end = start.AddDate(0, 0, 1)
+	default:
+		end = start
+	}
+
+	var attendees models.Attendees
+	for _, prop := range component.Props.Values(ical.PropAttendee) {
+		email := prop.Value
+		if len(email) > len("mailto:") && strings.EqualFold(email[:len("mailto:")], "mailto:") {
+			email = email[len("mailto:"):]
+		}
+		attendees = append(attendees, models.Attendee{
+			Email:       email,
+			DisplayName: prop.Params.Get(ical.ParamCommonName),
+		})
+	}
+
+	return models.Event{
+		ICalUID:     component.Props.Get(ical.PropUID).Value,
+		Title:       text(component, ical.PropSummary),
+		Description: text(component, ical.PropDescription),
+		Location:    text(component, ical.PropLocation),
+		StartTime:   start,
+		EndTime:     end,
+		TimeZone:    timeZone,
+		AllDay:      allDay,
+		Attendees:   attendees,
+		Reminders:   decodeReminders(component, start, end),
+		// the participation status of the owner of the calendar is unknown
+		Accepted: true,
+	}, nil
+}
+
+// decodeReminders converts the VALARMs with the action DISPLAY
+func decodeReminders(component *ical.Component, start time.Time, end time.Time) models.Reminders {
+	var reminders models.Reminders
+	for _, alarm := range component.Children {
+		if alarm.Name != ical.CompAlarm {
+			continue
+		}
+		action := alarm.Props.Get(ical.PropAction)
+		trigger := alarm.Props.Get(ical.PropTrigger)
+		if action == nil || !strings.EqualFold(action.Value, "DISPLAY") || trigger == nil {
+			continue
+		}
+
+		var pointInTime time.Time
+		if trigger.ValueType() == ical.ValueDateTime {
+			t, err := trigger.DateTime(time.UTC)
+			if err != nil {
+				continue
+			}
+			pointInTime = t
+		} else {
+			duration, err := trigger.Duration()
+			if err != nil {
+				continue
+			}
+			related := start
+			if strings.EqualFold(trigger.Params.Get(ical.ParamRelated), "END") {
+				related = end
+			}
+			pointInTime = related.Add(duration)
+		}
+
+		reminders = append(reminders, models.Reminder{
+			Actions: models.ReminderActionDisplay,
+			Trigger: models.ReminderTrigger{PointInTime: pointInTime},
+		})
+	}
+	return reminders
+}
+
+// decodeRecurrence parses the RRULE and EXDATE properties of the master event of a series, it returns nil if the
+// event is not a series. RDATE properties are not supported.
+func decodeRecurrence(component *ical.Component, timeZone string, zones map[string]TimeZone) (*models.Recurrence, error) {
+	rules := component.Props.Values(ical.PropRecurrenceRule)
+	switch {
+	case len(rules) == 0:
+		return nil, nil
+	case len(rules) > 1:
+		return nil, fmt.Errorf("multiple recurrence rules are not supported")
+	case len(component.Props.Values(ical.PropRecurrenceDates)) > 0:
+		return nil, fmt.Errorf("recurrence dates are not supported")
+	}
+
+	recurrence := &models.Recurrence{Rule: rules[0].Value, TimeZone: timeZone}
+	for _, prop := range component.Props.Values(ical.PropExceptionDates) {
+		exDates, err := parseDates(prop, zones)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode exdate: %w", err)
+		}
+		recurrence.ExDates = append(recurrence.ExDates, exDates...)
+	}
+	return recurrence, nil
+}
+
+// parseDates parses the comma separated dates or date-times of a property like EXDATE
+func parseDates(prop ical.Prop, zones map[string]TimeZone) ([]time.Time, error) {
+	var dates []time.Time
+	for _, value := range strings.Split(prop.Value, ",") {
+		single := prop
+		single.Value = value
+		t, _, err := ParseDateTime(&single, zones)
+		if err != nil {
+			return nil, err
+		}
+		dates = append(dates, t)
+	}
+	return dates, nil
+}
+
+// decodeMetadata returns the metadata which is stored in the X- properties of the event, or nil if there is none
+func decodeMetadata(component *ical.Component) *models.Metadata {
+	syncID := component.Props.Get(PropEventID)
+	sourceID := component.Props.Get(PropSourceID)
+	if syncID == nil || sourceID == nil {
+		return nil
+	}
+	return &models.Metadata{
+		SyncID:           text(component, PropEventID),
+		OriginalEventUri: text(component, PropOriginalEventUri),
+		SourceID:         text(component, PropSourceID),
+	}
+}
+
+// instanceMetadata derives the metadata of an instance from the metadata of its series, such that the SyncID
+// stays the same when the instance is modified
+func instanceMetadata(series *models.Metadata, originalStart time.Time, allDay bool) *models.Metadata {
+	return &models.Metadata{
+		SyncID:           models.NewEventID(series.SyncID + "_" + formatDate(allDay, originalStart)),
+		OriginalEventUri: series.OriginalEventUri,
+		SourceID:         series.SourceID,
+	}
+}
+
+func text(component *ical.Component, name string) string {
+	value, err := component.Props.Text(name)
+	if err != nil {
+		return component.Props.Get(name).Value
+	}
+	return value
+}
+
+// InstanceID returns the ID of an instance of the series with the given ID, which is derived from its original start
+func InstanceID(seriesID string, originalStart time.Time, allDay bool) string {
+	return seriesID + "#" + formatDate(allDay, originalStart)
+}
+
+// SplitInstanceID returns the ID of the series and the original start of the instance with the given ID.
+// If the ID is not the ID of an instance, ok is false.
+func SplitInstanceID(id string) (seriesID string, originalStart time.Time, ok bool) {
+	i := strings.LastIndex(id, "#")
+	if i < 0 {
+		return id, time.Time{}, false
+	}
+	layout := models.RFC5545DateTime
+	if len(id[i+1:]) == len(models.RFC5545Date) {
+		layout = models.RFC5545Date
+	}
+	originalStart, err := time.Parse(layout, id[i+1:])
+	if err != nil {
+		return id, time.Time{}, false
+	}
+	return id[:i], originalStart, true
+}
+
+func formatDate(allDay bool, t time.Time) string {
+	if allDay {
+		return t.Format(models.RFC5545Date)
+	}
+	return t.UTC().Format(models.RFC5545DateTime)
+}
+
+// sameStart returns true if both times are the same start of an instance, only the dates of all-day events are compared
+func sameStart(allDay bool, a time.Time, b time.Time) bool {
+	if allDay {
+		return a.Format(models.RFC5545Date) == b.Format(models.RFC5545Date)
+	}
+	return a.Equal(b)
+}
@@ -0,0 +1,254 @@
+package icalendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+func decodeCalendar(t *testing.T, data string) *ical.Calendar {
+	if !strings.Contains(data, "\r\n") {
+		data = strings.ReplaceAll(data, "\n", "\r\n")
+	}
+	calendar, err := ical.NewDecoder(strings.NewReader(data)).Decode()
+	require.NoError(t, err)
+	return calendar
+}
+
+const seriesCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//Example//Client//EN
+BEGIN:VTIMEZONE
+TZID:W. Europe Standard Time
+BEGIN:STANDARD
+DTSTART:16010101T030000
+TZOFFSETFROM:+0200
+TZOFFSETTO:+0100
+RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=10
+END:STANDARD
+BEGIN:DAYLIGHT
+DTSTART:16010101T020000
+TZOFFSETFROM:+0100
+TZOFFSETTO:+0200
+RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=3
+END:DAYLIGHT
+END:VTIMEZONE
+BEGIN:VEVENT
+UID:weekly@example.com
+DTSTAMP:20240301T000000Z
+DTSTART;TZID=W. Europe Standard Time:20240320T100000
+DURATION:PT30M
+RRULE:FREQ=WEEKLY;COUNT=4
+EXDATE;TZID=W. Europe Standard Time:20240403T100000
+SUMMARY:Weekly
+BEGIN:VALARM
+ACTION:DISPLAY
+DESCRIPTION:Reminder
+TRIGGER:-PT15M
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+UID:weekly@example.com
+DTSTAMP:20240301T000000Z
+RECURRENCE-ID;TZID=W. Europe Standard Time:20240327T100000
+DTSTART;TZID=W. Europe Standard Time:20240327T140000
+DTEND;TZID=W. Europe Standard Time:20240327T143000
+SUMMARY:Weekly (moved)
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestDecodeExpandsSeries(t *testing.T) {
+	decoder := Decoder{
+		SourceID: "source",
+		Start:    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+	events, err := decoder.Decode(decodeCalendar(t, seriesCalendar), "", "etag")
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	seriesMetadata := models.NewEventMetadata("weekly@example.com", "", "source")
+
+	// the instances are expanded in the time zone of the series, which changes to summer time on 2024-03-31
+	assert.Equal(t, "weekly@example.com#20240320T090000Z", events[0].ID)
+	assert.True(t, time.Date(2024, 3, 20, 9, 0, 0, 0, time.UTC).Equal(events[0].StartTime))
+	assert.True(t, time.Date(2024, 3, 20, 9, 30, 0, 0, time.UTC).Equal(events[0].EndTime))
+	assert.Equal(t, "Europe/Berlin", events[0].TimeZone)
+	assert.Equal(t, instanceMetadata(seriesMetadata, events[0].StartTime, false), events[0].Metadata)
+	require.Len(t, events[0].Reminders, 1)
+	assert.True(t, time.Date(2024, 3, 20, 8, 45, 0, 0, time.UTC).Equal(events[0].Reminders[0].Trigger.PointInTime))
+
+	assert.True(t, time.Date(2024, 4, 10, 8, 0, 0, 0, time.UTC).Equal(events[1].StartTime))
+	assert.Equal(t, "etag", events[1].ETag)
+	require.Len(t, events[1].Reminders, 1)
+	assert.True(t, time.Date(2024, 4, 10, 7, 45, 0, 0, time.UTC).Equal(events[1].Reminders[0].Trigger.PointInTime))
+
+	// the modified instance keeps the SyncID of the instance
+	assert.Equal(t, "Weekly (moved)", events[2].Title)
+	assert.Equal(t, "weekly@example.com#20240327T090000Z", events[2].ID)
+	assert.True(t, time.Date(2024, 3, 27, 13, 0, 0, 0, time.UTC).Equal(events[2].StartTime))
+	originalStart := time.Date(2024, 3, 27, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, instanceMetadata(seriesMetadata, originalStart, false), events[2].Metadata)
+	assert.Nil(t, events[2].Instance)
+}
+
+func TestDecodeSeries(t *testing.T) {
+	decoder := Decoder{
+		SourceID: "source",
+		Series:   true,
+		Start:    time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		End:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+	events, err := decoder.Decode(decodeCalendar(t, seriesCalendar), "/calendar/weekly.ics", "etag")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	assert.Equal(t, "/calendar/weekly.ics", events[0].ID)
+	assert.Equal(t, &models.Recurrence{
+		Rule:     "FREQ=WEEKLY;COUNT=4",
+		ExDates:  []time.Time{time.Date(2024, 4, 3, 10, 0, 0, 0, events[0].StartTime.Location())},
+		TimeZone: "Europe/Berlin",
+	}, events[0].Recurrence)
+
+	assert.Equal(t, "/calendar/weekly.ics#20240327T090000Z", events[1].ID)
+	require.NotNil(t, events[1].Instance)
+	assert.Equal(t, "/calendar/weekly.ics", events[1].Instance.SeriesID)
+	assert.Equal(t, events[0].Metadata.SyncID, events[1].Instance.SeriesSyncID)
+	assert.True(t, time.Date(2024, 3, 27, 9, 0, 0, 0, time.UTC).Equal(events[1].Instance.OriginalStart))
+
+	// series which end before the timeframe are not listed
+	decoder.Start = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	decoder.End = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	events, err = decoder.Decode(decodeCalendar(t, seriesCalendar), "/calendar/weekly.ics", "etag")
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestEncodeSeries(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	start := time.Date(2024, 3, 20, 10, 0, 0, 0, berlin)
+	series := models.Event{
+		Title:     "Weekly",
+		StartTime: start,
+		EndTime:   start.Add(30 * time.Minute),
+		Recurrence: &models.Recurrence{
+			Rule:     "FREQ=WEEKLY;COUNT=4",
+			TimeZone: "Europe/Berlin",
+		},
+		Metadata: &models.Metadata{SyncID: "series", OriginalEventUri: "https://example.com/a,b", SourceID: "source"},
+	}
+	calendar := NewCalendar(series, "uid")
+
+	originalStart := start.AddDate(0, 0, 7)
+	instance := models.Event{
+		Title:     "Weekly (moved)",
+		StartTime: originalStart.Add(4 * time.Hour),
+		EndTime:   originalStart.Add(4*time.Hour + 30*time.Minute),
+		Metadata:  &models.Metadata{SyncID: "instance", SourceID: "source"},
+	}
+	require.NoError(t, SetInstance(calendar, instance, originalStart))
+	require.NoError(t, CancelInstance(calendar, start.AddDate(0, 0, 14)))
+
+	// the existing cancelled instances are kept
+	series.Title = "Weekly sync"
+	require.NoError(t, SetEvent(calendar, series))
+
+	var buf strings.Builder
+	require.NoError(t, ical.NewEncoder(&buf).Encode(calendar))
+	encoded := buf.String()
+	assert.Contains(t, encoded, "BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\n")
+	assert.Contains(t, encoded, "RECURRENCE-ID;TZID=Europe/Berlin:20240327T100000\r\n")
+	assert.Contains(t, encoded, "EXDATE;TZID=Europe/Berlin:20240403T100000\r\n")
+
+	decoder := Decoder{
+		Series: true,
+		Start:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+	events, err := decoder.Decode(decodeCalendar(t, encoded), "", "")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "Weekly sync", events[0].Title)
+	assert.Equal(t, series.Metadata, events[0].Metadata)
+	require.Len(t, events[0].Recurrence.ExDates, 1)
+	assert.True(t, start.AddDate(0, 0, 14).Equal(events[0].Recurrence.ExDates[0]))
+	series.Recurrence.ExDates = events[0].Recurrence.ExDates
+	assert.Empty(t, models.DiffEvents(events[0], series))
+	assert.Equal(t, "instance", events[1].Metadata.SyncID)
+	assert.True(t, originalStart.Equal(events[1].Instance.OriginalStart))
+
+	// without the recurrence, the modified instances are removed
+	series.Recurrence = nil
+	require.NoError(t, SetEvent(calendar, series))
+	events, err = decoder.Decode(calendar, "", "")
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestSplitInstanceID(t *testing.T) {
+	seriesID, originalStart, ok := SplitInstanceID(InstanceID("/calendar/a.ics", time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), false))
+	assert.True(t, ok)
+	assert.Equal(t, "/calendar/a.ics", seriesID)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), originalStart)
+
+	_, originalStart, ok = SplitInstanceID(InstanceID("uid", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), true))
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), originalStart)
+
+	seriesID, _, ok = SplitInstanceID("/calendar/a.ics")
+	assert.False(t, ok)
+	assert.Equal(t, "/calendar/a.ics", seriesID)
+}
+
+func TestTimeZoneComponent(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	calendar := ical.NewCalendar()
+	calendar.Props.SetText(ical.PropVersion, "2.0")
+	calendar.Props.SetText(ical.PropProductID, ProductID)
+	calendar.Children = append(calendar.Children, timeZoneComponent(newYork, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+	var buf strings.Builder
+	require.NoError(t, ical.NewEncoder(&buf).Encode(calendar))
+
+	assert.Equal(t, strings.ReplaceAll(`BEGIN:VCALENDAR
+PRODID:-//inovex//CalendarSync//EN
+VERSION:2.0
+BEGIN:VTIMEZONE
+TZID:America/New_York
+X-LIC-LOCATION:America/New_York
+BEGIN:DAYLIGHT
+DTSTART:20240310T020000
+RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU
+TZNAME:EDT
+TZOFFSETFROM:-0500
+TZOFFSETTO:-0400
+END:DAYLIGHT
+BEGIN:STANDARD
+DTSTART:20241103T020000
+RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU
+TZNAME:EST
+TZOFFSETFROM:-0400
+TZOFFSETTO:-0500
+END:STANDARD
+END:VTIMEZONE
+END:VCALENDAR
+`, "\n", "\r\n"), buf.String())
+
+	zones := TimeZones(decodeCalendar(t, buf.String()))
+	assert.Equal(t, "America/New_York", zones["America/New_York"].Name)
+
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+	component := timeZoneComponent(kolkata, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	require.Len(t, component.Children, 1)
+	assert.Equal(t, "+0530", component.Children[0].Props.Get(ical.PropTimezoneOffsetTo).Value)
+}
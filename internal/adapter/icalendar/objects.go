@@ -0,0 +1,103 @@
+package icalendar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/emersion/go-ical"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// ErrNotFound is returned if the calendar object of an event does not exist
+var ErrNotFound = errors.New("event not found")
+
+// emptyCalendar is encoded once all events are deleted, as go-ical refuses to encode a calendar without components
+const emptyCalendar = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:" + ProductID + "\r\nEND:VCALENDAR\r\n"
+
+// The following functions manage the events of a calendar which is written as a whole, e.g. a file. The events
+// are kept as calendar objects, see Split. Events are identified by their UID, which is derived from the SyncID.
+// Modified instances are identified by the UID of their series and their original start, see InstanceID.
+
+// AddEvent adds the event to the calendar objects. Modified instances are added to the calendar object of their
+// series.
+func AddEvent(objects []*ical.Calendar, e models.Event) ([]*ical.Calendar, error) {
+	if e.Instance != nil {
+		i := findObject(objects, e.Instance.SeriesID)
+		if i < 0 {
+			return nil, fmt.Errorf("series %s of the instance: %w", e.Instance.SeriesID, ErrNotFound)
+		}
+		return objects, SetInstance(objects[i], e, e.Instance.OriginalStart)
+	}
+
+	// the UID is derived from the SyncID, as the instances of a series have the same iCalUID in the source
+	uid := "calendarsync-" + e.Metadata.SyncID
+	if findObject(objects, uid) >= 0 {
+		return nil, fmt.Errorf("event %s already exists", uid)
+	}
+	return append(objects, NewCalendar(e, uid)), nil
+}
+
+// UpdateEvent replaces the event in its calendar object
+func UpdateEvent(objects []*ical.Calendar, e models.Event) error {
+	uid, originalStart, isInstance := instanceOf(e)
+	i := findObject(objects, uid)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if isInstance {
+		return SetInstance(objects[i], e, originalStart)
+	}
+	return SetEvent(objects[i], e)
+}
+
+// DeleteEvent removes the calendar object of the event. Instances of a series are cancelled.
+func DeleteEvent(objects []*ical.Calendar, e models.Event) ([]*ical.Calendar, error) {
+	uid, originalStart, isInstance := instanceOf(e)
+	i := findObject(objects, uid)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	if isInstance {
+		return objects, CancelInstance(objects[i], originalStart)
+	}
+	return slices.Delete(objects, i, i+1), nil
+}
+
+// Encode joins the calendar objects and encodes them as a single calendar
+func Encode(objects []*ical.Calendar) ([]byte, error) {
+	calendar := Join(objects)
+	if len(calendar.Children) == 0 {
+		return []byte(emptyCalendar), nil
+	}
+	var buf bytes.Buffer
+	if err := ical.NewEncoder(&buf).Encode(calendar); err != nil {
+		return nil, fmt.Errorf("unable to encode calendar: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// findObject returns the index of the calendar object with the UID or -1 if it does not exist
+func findObject(objects []*ical.Calendar, uid string) int {
+	return slices.IndexFunc(objects, func(object *ical.Calendar) bool {
+		for _, child := range object.Children {
+			if child.Name == ical.CompEvent {
+				prop := child.Props.Get(ical.PropUID)
+				return prop != nil && prop.Value == uid
+			}
+		}
+		return false
+	})
+}
+
+// instanceOf returns the UID of the event. If the event is an instance of a series, it returns its original
+// start as well.
+func instanceOf(e models.Event) (string, time.Time, bool) {
+	if e.Instance != nil {
+		return e.Instance.SeriesID, e.Instance.OriginalStart, true
+	}
+	return SplitInstanceID(e.ID)
+}
@@ -0,0 +1,227 @@
+package icalendar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-ical"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// TimeZone is a time zone of a calendar, the name is the IANA name and empty if the time zone could only be
+// resolved to a fixed offset
+type TimeZone struct {
+	Name     string
+	Location *time.Location
+}
+
+// TimeZones resolves the time zones which are defined by the VTIMEZONE components of the calendar by their TZID
+func TimeZones(calendar *ical.Calendar) map[string]TimeZone {
+	zones := make(map[string]TimeZone)
+	if calendar == nil {
+		return zones
+	}
+	for _, component := range calendar.Children {
+		if component.Name != ical.CompTimezone {
+			continue
+		}
+		tzid := component.Props.Get(ical.PropTimezoneID)
+		if tzid == nil {
+			continue
+		}
+		if zone, ok := resolveTimeZone(tzid.Value, component); ok {
+			zones[tzid.Value] = zone
+		}
+	}
+	return zones
+}
+
+// resolveTimeZone resolves the TZID of a VTIMEZONE component. Calendars use IANA names, windows names or prefixed
+// IANA names (e.g. /mozilla.org/20050126_1/Europe/Berlin) and some add the IANA name as X-LIC-LOCATION.
+// Other time zones are resolved to the fixed offset of their standard time.
+func resolveTimeZone(tzid string, component *ical.Component) (TimeZone, bool) {
+	candidates := []string{tzid}
+	if location := component.Props.Get("X-LIC-LOCATION"); location != nil {
+		candidates = append(candidates, location.Value)
+	}
+	parts := strings.Split(strings.Trim(tzid, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		candidates = append(candidates, strings.Join(parts[i:], "/"))
+	}
+
+	for _, candidate := range candidates {
+		if name, err := models.IANATimeZone(candidate); err == nil {
+			location, err := time.LoadLocation(name)
+			if err == nil {
+				return TimeZone{Name: name, Location: location}, true
+			}
+		}
+	}
+
+	for _, child := range component.Children {
+		if child.Name != ical.CompTimezoneStandard {
+			continue
+		}
+		if offset := child.Props.Get(ical.PropTimezoneOffsetTo); offset != nil {
+			seconds, err := parseOffset(offset.Value)
+			if err == nil {
+				return TimeZone{Location: time.FixedZone(tzid, seconds)}, true
+			}
+		}
+	}
+	return TimeZone{}, false
+}
+
+// parseOffset parses a UTC offset like +0100 or -053000 to seconds
+func parseOffset(offset string) (int, error) {
+	if len(offset) != 5 && len(offset) != 7 {
+		return 0, fmt.Errorf("invalid offset %q", offset)
+	}
+	var seconds int
+	for i, unit := range []int{3600, 60, 1} {
+		if 1+2*i >= len(offset) {
+			break
+		}
+		value, err := strconv.Atoi(offset[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid offset %q: %w", offset, err)
+		}
+		seconds += value * unit
+	}
+	switch offset[0] {
+	case '+':
+		return seconds, nil
+	case '-':
+		return -seconds, nil
+	default:
+		return 0, fmt.Errorf("invalid offset %q", offset)
+	}
+}
+
+// formatOffset formats a UTC offset in seconds like +0100, seconds are only added if necessary
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	offset := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		offset += fmt.Sprintf("%02d", seconds%60)
+	}
+	return offset
+}
+
+// ParseDateTime parses a date-time property like DTSTART and returns the IANA name of its time zone, if it's known.
+// Dates are parsed as midnight in UTC and floating times in the local time zone.
+func ParseDateTime(prop *ical.Prop, zones map[string]TimeZone) (time.Time, string, error) {
+	if prop == nil {
+		return time.Time{}, "", fmt.Errorf("missing property")
+	}
+	if isDate(prop) {
+		t, err := time.ParseInLocation(models.RFC5545Date, prop.Value, time.UTC)
+		return t, "", err
+	}
+	tzid := prop.Params.Get(ical.ParamTimezoneID)
+	if tzid == "" {
+		t, err := prop.DateTime(time.Local)
+		return t, "", err
+	}
+
+	zone, ok := zones[tzid]
+	if !ok {
+		name, err := models.IANATimeZone(tzid)
+		if err != nil {
+			return time.Time{}, "", err
+		}
+		location, err := time.LoadLocation(name)
+		if err != nil {
+			return time.Time{}, "", err
+		}
+		zone = TimeZone{Name: name, Location: location}
+	}
+
+	// parse the value without the TZID, which is only resolved by go-ical if it's an IANA name
+	withoutTZID := ical.Prop{Name: prop.Name, Params: ical.Params{}, Value: prop.Value}
+	for name, values := range prop.Params {
+		if name != ical.ParamTimezoneID {
+			withoutTZID.Params[name] = values
+		}
+	}
+	t, err := withoutTZID.DateTime(zone.Location)
+	return t, zone.Name, err
+}
+
+// isDate returns true if the property is a date without a time, some calendars omit the VALUE=DATE parameter
+func isDate(prop *ical.Prop) bool {
+	return prop.ValueType() == ical.ValueDate || (prop.Params.Get(ical.ParamValue) == "" && len(prop.Value) == len(models.RFC5545Date))
+}
+
+// timeZoneComponent returns a VTIMEZONE component for the location with the transitions of the year of the given
+// time, which are repeated yearly. The TZID is the IANA name of the location, which is resolved directly by most
+// calendars.
+func timeZoneComponent(location *time.Location, around time.Time) *ical.Component {
+	component := ical.NewComponent(ical.CompTimezone)
+	component.Props.SetText(ical.PropTimezoneID, location.String())
+	component.Props.Set(&ical.Prop{Name: "X-LIC-LOCATION", Params: ical.Params{}, Value: location.String()})
+
+	t := time.Date(around.In(location).Year(), time.January, 1, 0, 0, 0, 0, location)
+	end := t.AddDate(1, 0, 0)
+	for {
+		_, next := t.ZoneBounds()
+		if next.IsZero() || !next.Before(end) {
+			break
+		}
+		component.Children = append(component.Children, observance(t, next))
+		t = next
+	}
+
+	if len(component.Children) == 0 {
+		// the location has no transitions in this year
+		name, offset := t.Zone()
+		standard := ical.NewComponent(ical.CompTimezoneStandard)
+		standard.Props.SetText(ical.PropTimezoneName, name)
+		standard.Props.Set(&ical.Prop{Name: ical.PropTimezoneOffsetFrom, Params: ical.Params{}, Value: formatOffset(offset)})
+		standard.Props.Set(&ical.Prop{Name: ical.PropTimezoneOffsetTo, Params: ical.Params{}, Value: formatOffset(offset)})
+		standard.Props.Set(&ical.Prop{Name: ical.PropDateTimeStart, Params: ical.Params{}, Value: "19700101T000000"})
+		component.Children = append(component.Children, standard)
+	}
+	return component
+}
+
+// observance returns the STANDARD or DAYLIGHT component which starts at the transition, before is a time in the
+// previous observance. The transition is repeated yearly on the same weekday of the month, e.g. the last sunday.
+func observance(before time.Time, transition time.Time) *ical.Component {
+	_, fromOffset := before.Zone()
+	name, toOffset := transition.Zone()
+
+	component := ical.NewComponent(ical.CompTimezoneStandard)
+	if transition.IsDST() {
+		component.Name = ical.CompTimezoneDaylight
+	}
+
+	// the onset is the local time before the transition
+	onset := transition.In(time.FixedZone("", fromOffset))
+	nth := strconv.Itoa((onset.Day()-1)/7 + 1)
+	if onset.AddDate(0, 0, 7).Month() != onset.Month() {
+		nth = "-1"
+	}
+	weekday := strings.ToUpper(onset.Weekday().String()[:2])
+
+	component.Props.SetText(ical.PropTimezoneName, name)
+	component.Props.Set(&ical.Prop{Name: ical.PropTimezoneOffsetFrom, Params: ical.Params{}, Value: formatOffset(fromOffset)})
+	component.Props.Set(&ical.Prop{Name: ical.PropTimezoneOffsetTo, Params: ical.Params{}, Value: formatOffset(toOffset)})
+	component.Props.Set(&ical.Prop{Name: ical.PropDateTimeStart, Params: ical.Params{}, Value: onset.Format(floatingDateTime)})
+	component.Props.Set(&ical.Prop{
+		Name:   ical.PropRecurrenceRule,
+		Params: ical.Params{},
+		Value:  fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%s%s", onset.Month(), nth, weekday),
+	})
+	return component
+}
+
+// floatingDateTime is the format of RFC5545 date-times without a time zone
+const floatingDateTime = "20060102T150405"
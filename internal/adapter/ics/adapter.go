@@ -0,0 +1,210 @@
+package ics
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/emersion/go-ical"
+
+	"github.com/inovex/CalendarSync/internal/adapter/icalendar"
+	"github.com/inovex/CalendarSync/internal/adapter/port"
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+const (
+	usernameKey = "username"
+	passwordKey = "password"
+)
+
+// CalendarAPI reads the events of an ICS feed, which is either loaded from a HTTP(S) or webcal URL or from a
+// local file. The feed is only loaded again if it was modified since the previous run. As a sink, it writes the
+// events to a local file and stores the metadata of CalendarSync in X- properties.
+type CalendarAPI struct {
+	// calendarID is the configured URL or path of the feed
+	calendarID string
+	// feedURL is set if the feed is loaded via HTTP, otherwise the feed is read from filePath
+	feedURL  *url.URL
+	filePath string
+
+	username   string
+	password   string
+	httpClient *http.Client
+
+	series bool
+	// mu guards cached and pending
+	mu sync.Mutex
+	// cached is the last loaded feed, which is reused if the feed was not modified
+	cached *feed
+	// pending are the calendar objects of the file with the changes which were not written yet, see Flush
+	pending []*ical.Calendar
+
+	logger *log.Logger
+}
+
+// Assert that the expected interfaces are implemented
+var _ port.Configurable = &CalendarAPI{}
+var _ port.LogSetter = &CalendarAPI{}
+var _ port.CalendarIDSetter = &CalendarAPI{}
+var _ port.SeriesSetter = &CalendarAPI{}
+
+func (c *CalendarAPI) SetCalendarID(calendarID string) error {
+	if calendarID == "" {
+		return fmt.Errorf("%s adapter 'calendar' cannot be empty", c.Name())
+	}
+	c.calendarID = calendarID
+	return nil
+}
+
+// SetSeries lists series as recurring events with their modified instances instead of their instances
+func (c *CalendarAPI) SetSeries(series bool) {
+	c.series = series
+}
+
+func (c *CalendarAPI) SetLogger(logger *log.Logger) {
+	c.logger = logger
+}
+
+func (c *CalendarAPI) Name() string {
+	return "ICS"
+}
+
+// GetCalendarHash calculates a unique hash for this adapter based on the location of the feed. The credentials
+// are not part of the hash.
+func (c *CalendarAPI) GetCalendarHash() string {
+	location := c.filePath
+	if c.feedURL != nil {
+		withoutUser := *c.feedURL
+		withoutUser.User = nil
+		location = withoutUser.String()
+	}
+	sum := sha1.Sum([]byte(location))
+	return base64.URLEncoding.EncodeToString(sum[:])
+}
+
+// Initialize resolves the location of the feed. The username and password for feeds which require basic
+// authentication are optional.
+func (c *CalendarAPI) Initialize(ctx context.Context, openBrowser bool, config map[string]interface{}) error {
+	for _, key := range []string{usernameKey, passwordKey} {
+		if value, ok := config[key]; ok {
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("config key %s must be a string", key)
+			}
+		}
+	}
+	c.username, _ = config[usernameKey].(string)
+	c.password, _ = config[passwordKey].(string)
+	c.httpClient = http.DefaultClient
+
+	feedURL, err := url.Parse(c.calendarID)
+	if err != nil || feedURL.Scheme == "" || len(feedURL.Scheme) == 1 {
+		// a path, single letter schemes are Windows drive letters
+		c.filePath, err = expandHome(c.calendarID)
+		return err
+	}
+
+	switch feedURL.Scheme {
+	case "http", "https":
+	case "webcal", "webcals":
+		feedURL.Scheme = "https"
+	case "file":
+		c.filePath = feedURL.Path
+		return nil
+	default:
+		return fmt.Errorf("unsupported scheme %s of the feed URL", feedURL.Scheme)
+	}
+	c.feedURL = feedURL
+	return nil
+}
+
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, path[2:]), nil
+}
+
+// EventsInTimeframe returns the events of the feed within the timeframe. Series are expanded to their
+// instances unless series are synced.
+func (c *CalendarAPI) EventsInTimeframe(ctx context.Context, start time.Time, end time.Time) ([]models.Event, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var previous validators
+	if c.cached != nil {
+		previous = c.cached.validators
+	}
+
+	loaded, err := c.load(ctx, previous)
+	if errors.Is(err, errNotModified) {
+		c.logger.Debug("feed was not modified, using the previously loaded feed")
+		loaded = c.cached
+	} else if err != nil {
+		return nil, err
+	}
+	c.cached = loaded
+
+	return c.decode(loaded.calendar, start, end), nil
+}
+
+// ChangedEventsInTimeframe returns no changes if the feed was not modified since the listing which returned the
+// token. Otherwise, the feed is loaded and all events within the timeframe are returned as a full listing.
+func (c *CalendarAPI) ChangedEventsInTimeframe(ctx context.Context, start time.Time, end time.Time, token string) (models.Changes, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var previous validators
+	if token != "" {
+		if err := json.Unmarshal([]byte(token), &previous); err != nil {
+			return models.Changes{}, models.ErrSyncTokenInvalid
+		}
+	}
+
+	loaded, err := c.load(ctx, previous)
+	if errors.Is(err, errNotModified) {
+		c.logger.Info("feed was not modified since the last sync")
+		return models.Changes{Token: token}, nil
+	} else if err != nil {
+		return models.Changes{}, err
+	}
+	c.cached = loaded
+
+	return models.Changes{
+		Events: c.decode(loaded.calendar, start, end),
+		Token:  loaded.validators.token(),
+		Full:   true,
+	}, nil
+}
+
+// decode converts the events of the feed within the timeframe. Events which cannot be decoded are skipped.
+func (c *CalendarAPI) decode(calendar *ical.Calendar, start time.Time, end time.Time) []models.Event {
+	decoder := icalendar.Decoder{SourceID: c.GetCalendarHash(), Series: c.series, Start: start, End: end}
+	var events []models.Event
+	for _, object := range icalendar.Split(calendar) {
+		objectEvents, err := decoder.Decode(object, "", "")
+		if err != nil {
+			c.logger.Warn("skipping event", "error", err)
+			continue
+		}
+		events = append(events, objectEvents...)
+	}
+
+	c.logger.Infof("loaded %d events between %s and %s.", len(events), start.Format(time.DateOnly), end.Format(time.DateOnly))
+
+	return events
+}
@@ -0,0 +1,121 @@
+package ics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/emersion/go-ical"
+
+	"github.com/inovex/CalendarSync/internal/adapter/icalendar"
+)
+
+// errNotModified is returned if the feed was not modified since it was loaded with the given validators
+var errNotModified = errors.New("feed was not modified")
+
+// validators identify the version of a loaded feed, they are sent with the next request to only load the feed
+// if it was modified
+type validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// token returns the validators as sync token, it's empty if the feed does not support conditional requests
+func (v validators) token() string {
+	if v == (validators{}) {
+		return ""
+	}
+	token, _ := json.Marshal(v)
+	return string(token)
+}
+
+type feed struct {
+	validators validators
+	calendar   *ical.Calendar
+}
+
+// load loads the feed, unless it was not modified since it was loaded with the given validators
+func (c *CalendarAPI) load(ctx context.Context, previous validators) (*feed, error) {
+	if c.feedURL != nil {
+		return c.fetch(ctx, previous)
+	}
+	return c.read(previous)
+}
+
+// fetch sends a conditional GET request for the feed
+func (c *CalendarAPI) fetch(ctx context.Context, previous validators) (*feed, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.feedURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ical.MIMEType)
+	if previous.ETag != "" {
+		req.Header.Set("If-None-Match", previous.ETag)
+	}
+	if previous.LastModified != "" {
+		req.Header.Set("If-Modified-Since", previous.LastModified)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil, errNotModified
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("failed to load feed: %s %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	calendar, err := ical.NewDecoder(resp.Body).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode feed: %w", err)
+	}
+	return &feed{
+		validators: validators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		},
+		calendar: calendar,
+	}, nil
+}
+
+// read reads the feed from the file, if its modification time or size changed. A missing file is an empty
+// calendar, as a sink file is only created with the first event.
+func (c *CalendarAPI) read(previous validators) (*feed, error) {
+	info, err := os.Stat(c.filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.logger.Warn("file does not exist, assuming an empty calendar", "path", c.filePath)
+		return &feed{calendar: icalendar.Join(nil)}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load feed: %w", err)
+	}
+	current := validators{ETag: fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())}
+	if current == previous {
+		return nil, errNotModified
+	}
+
+	file, err := os.Open(c.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load feed: %w", err)
+	}
+	defer file.Close()
+
+	calendar, err := ical.NewDecoder(file).Decode()
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode feed %s: %w", c.filePath, err)
+	}
+	return &feed{validators: current, calendar: calendar}, nil
+}
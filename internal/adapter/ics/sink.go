@@ -0,0 +1,148 @@
+package ics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/emersion/go-ical"
+
+	"github.com/inovex/CalendarSync/internal/adapter/icalendar"
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// CreateEvent adds the event to the file. Modified instances are added to their series.
+func (c *CalendarAPI) CreateEvent(ctx context.Context, e models.Event) error {
+	err := c.modify(func(objects []*ical.Calendar) ([]*ical.Calendar, error) {
+		return icalendar.AddEvent(objects, e)
+	})
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info("Event created", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+// UpdateEvent replaces the event in the file
+func (c *CalendarAPI) UpdateEvent(ctx context.Context, e models.Event) error {
+	err := c.modify(func(objects []*ical.Calendar) ([]*ical.Calendar, error) {
+		return objects, icalendar.UpdateEvent(objects, e)
+	})
+	if errors.Is(err, icalendar.ErrNotFound) {
+		return errors.New("already deleted")
+	} else if err != nil {
+		return err
+	}
+
+	c.logger.Info("Event updated", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+// DeleteEvent removes the event from the file. Instances of a series are cancelled.
+func (c *CalendarAPI) DeleteEvent(ctx context.Context, e models.Event) error {
+	err := c.modify(func(objects []*ical.Calendar) ([]*ical.Calendar, error) {
+		return icalendar.DeleteEvent(objects, e)
+	})
+	if errors.Is(err, icalendar.ErrNotFound) {
+		c.logger.Debug("Event is already deleted.", "method", "DeleteEvent", "title", e.ShortTitle(), "time", e.StartTime.String())
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	c.logger.Info("Event deleted", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+// modify applies the modification to the calendar objects of the file. The file is read with the first change,
+// the changes are kept in memory until they are written with Flush.
+func (c *CalendarAPI) modify(modify func(objects []*ical.Calendar) ([]*ical.Calendar, error)) error {
+	if c.feedURL != nil {
+		return fmt.Errorf("the %s adapter can only write to local files", c.Name())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending == nil {
+		loaded, err := c.read(validators{})
+		if err != nil {
+			return err
+		}
+		c.pending = icalendar.Split(loaded.calendar)
+	}
+	objects, err := modify(c.pending)
+	if err != nil {
+		return err
+	}
+	if objects == nil {
+		// all events were deleted, the empty calendar is written with Flush
+		objects = []*ical.Calendar{}
+	}
+	c.pending = objects
+	return nil
+}
+
+// Flush writes the changes since the last Flush to the file, such that the file is written once per sync
+// instead of once per change
+func (c *CalendarAPI) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending == nil {
+		return nil
+	}
+
+	content, err := icalendar.Encode(c.pending)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(c.filePath, content); err != nil {
+		return err
+	}
+	c.pending = nil
+	// the file is read again with the next listing
+	c.cached = nil
+	return nil
+}
+
+// writeFile replaces the file atomically, such that readers, e.g. a web server which publishes the file, never
+// see a partially written file. The permissions of an existing file are kept.
+func writeFile(path string, content []byte) error {
+	perm := fs.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("cannot write file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot write file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cannot write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cannot write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("cannot write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("cannot write file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package ics
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+func TestCreateUpdateDeleteEvent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busy.ics")
+	adapter := newTestAdapter(t, path, nil)
+	ctx := context.Background()
+
+	events, err := adapter.EventsInTimeframe(ctx, timeframeStart, timeframeEnd)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	event := models.Event{
+		Title:       "Busy",
+		Description: "synced",
+		StartTime:   time.Date(2024, 3, 5, 10, 0, 0, 0, berlin),
+		EndTime:     time.Date(2024, 3, 5, 11, 0, 0, 0, berlin),
+		TimeZone:    "Europe/Berlin",
+		Metadata:    models.NewEventMetadata("source-event", "https://example.com/event", "source"),
+	}
+	require.NoError(t, adapter.CreateEvent(ctx, event))
+	assert.ErrorContains(t, adapter.CreateEvent(ctx, event), "already exists")
+
+	holiday := models.Event{
+		Title:     "Holiday",
+		StartTime: time.Date(2024, 3, 29, 0, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC),
+		AllDay:    true,
+		Metadata:  models.NewEventMetadata("source-holiday", "", "source"),
+	}
+	require.NoError(t, adapter.CreateEvent(ctx, holiday))
+
+	// the changes are only written with Flush
+	_, err = os.Stat(path)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	require.NoError(t, adapter.Flush(ctx))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	events, err = adapter.EventsInTimeframe(ctx, timeframeStart, timeframeEnd)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, event.Metadata, events[0].Metadata)
+	assert.Equal(t, "Europe/Berlin", events[0].TimeZone)
+	assert.Empty(t, models.DiffEvents(events[0], event))
+	assert.Empty(t, models.DiffEvents(events[1], holiday))
+
+	// the permissions of the published file are kept
+	require.NoError(t, os.Chmod(path, 0640))
+
+	updated := events[0]
+	updated.Title = "Out of office"
+	require.NoError(t, adapter.UpdateEvent(ctx, updated))
+	require.NoError(t, adapter.Flush(ctx))
+	events, err = adapter.EventsInTimeframe(ctx, timeframeStart, timeframeEnd)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Out of office", "Holiday"}, titles(events))
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	require.NoError(t, adapter.DeleteEvent(ctx, events[0]))
+	require.NoError(t, adapter.DeleteEvent(ctx, events[0]), "deleting a deleted event is a no-op")
+	assert.ErrorContains(t, adapter.UpdateEvent(ctx, events[0]), "already deleted")
+	require.NoError(t, adapter.DeleteEvent(ctx, events[1]))
+	require.NoError(t, adapter.Flush(ctx))
+
+	events, err = adapter.EventsInTimeframe(ctx, timeframeStart, timeframeEnd)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestSinkSeries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "busy.ics")
+	adapter := newTestAdapter(t, path, nil)
+	adapter.SetSeries(true)
+	ctx := context.Background()
+
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	series := models.Event{
+		Title:      "Weekly",
+		StartTime:  start,
+		EndTime:    start.Add(time.Hour),
+		Recurrence: &models.Recurrence{Rule: "FREQ=WEEKLY;COUNT=3"},
+		Metadata:   models.NewEventMetadata("series", "", "source"),
+	}
+	require.NoError(t, adapter.CreateEvent(ctx, series))
+	require.NoError(t, adapter.Flush(ctx))
+
+	events, err := adapter.EventsInTimeframe(ctx, timeframeStart, timeframeEnd)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	seriesID := events[0].ID
+
+	originalStart := start.AddDate(0, 0, 7)
+	instance := models.Event{
+		Title:     "Weekly (moved)",
+		StartTime: originalStart.Add(2 * time.Hour),
+		EndTime:   originalStart.Add(3 * time.Hour),
+		Metadata:  models.NewEventMetadata("instance", "", "source"),
+		Instance:  &models.SeriesInstance{SeriesID: seriesID, SeriesSyncID: series.Metadata.SyncID, OriginalStart: originalStart},
+	}
+	require.NoError(t, adapter.CreateEvent(ctx, instance))
+	require.NoError(t, adapter.Flush(ctx))
+
+	events, err = adapter.EventsInTimeframe(ctx, timeframeStart, timeframeEnd)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, instance.Metadata, events[1].Metadata)
+	require.NotNil(t, events[1].Instance)
+	assert.Equal(t, seriesID, events[1].Instance.SeriesID)
+
+	require.NoError(t, adapter.DeleteEvent(ctx, events[1]))
+	require.NoError(t, adapter.Flush(ctx))
+	events, err = adapter.EventsInTimeframe(ctx, timeframeStart, timeframeEnd)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Len(t, events[0].Recurrence.ExDates, 1)
+}
+
+func TestSinkRequiresFile(t *testing.T) {
+	adapter := newTestAdapter(t, "https://example.com/feed.ics", nil)
+	err := adapter.CreateEvent(context.Background(), models.Event{Metadata: models.NewEventMetadata("event", "", "source")})
+	assert.ErrorContains(t, err, "can only write to local files")
+}
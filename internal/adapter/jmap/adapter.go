@@ -0,0 +1,435 @@
+package jmap
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+
+	"github.com/inovex/CalendarSync/internal/adapter/port"
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+const (
+	endpointKey = "endpoint"
+	usernameKey = "username"
+	passwordKey = "password"
+	tokenKey    = "token"
+
+	// pageSize is the maximum number of events which are queried by a single request
+	pageSize = 256
+)
+
+// CalendarAPI reads and writes the events of a calendar on a JMAP server with support for calendars. The metadata
+// of CalendarSync is stored in a custom JSCalendar property of the events. Series are always expanded to their
+// instances.
+type CalendarAPI struct {
+	endpoint   string
+	username   string
+	password   string
+	token      string
+	httpClient *http.Client
+
+	apiURL    string
+	accountID string
+	// userEmail is the username of the session, it identifies the participant of the user
+	userEmail string
+
+	// calendarID is the configured calendar, either the id or the name of the calendar
+	calendarID string
+	// jmapCalendarID is the id of the configured calendar on the server
+	jmapCalendarID string
+
+	logger *log.Logger
+}
+
+// Assert that the expected interfaces are implemented
+var _ port.Configurable = &CalendarAPI{}
+var _ port.LogSetter = &CalendarAPI{}
+var _ port.CalendarIDSetter = &CalendarAPI{}
+
+func (c *CalendarAPI) SetCalendarID(calendarID string) error {
+	if calendarID == "" {
+		return fmt.Errorf("%s adapter 'calendar' cannot be empty", c.Name())
+	}
+	c.calendarID = calendarID
+	return nil
+}
+
+func (c *CalendarAPI) SetLogger(logger *log.Logger) {
+	c.logger = logger
+}
+
+func (c *CalendarAPI) Name() string {
+	return "JMAP"
+}
+
+// GetCalendarHash calculates a unique hash for this adapter based on the account and the calendar
+func (c *CalendarAPI) GetCalendarHash() string {
+	components := []string{c.apiURL, c.accountID, c.jmapCalendarID}
+	sum := sha1.Sum([]byte(strings.Join(components, "")))
+	return base64.URLEncoding.EncodeToString(sum[:])
+}
+
+// Initialize discovers the session of the user and resolves the configured calendar
+func (c *CalendarAPI) Initialize(ctx context.Context, openBrowser bool, config map[string]interface{}) error {
+	endpoint, ok := config[endpointKey].(string)
+	if !ok {
+		return fmt.Errorf("missing config key: %s", endpointKey)
+	}
+	c.endpoint = endpoint
+
+	if token, ok := config[tokenKey].(string); ok {
+		c.token = token
+	} else {
+		for _, key := range []string{usernameKey, passwordKey} {
+			if _, ok := config[key].(string); !ok {
+				return fmt.Errorf("missing config key: %s or %s", key, tokenKey)
+			}
+		}
+		c.username = config[usernameKey].(string)
+		c.password = config[passwordKey].(string)
+	}
+	c.httpClient = http.DefaultClient
+
+	s, err := c.discover(ctx)
+	if err != nil {
+		return err
+	}
+	c.apiURL = s.APIURL
+	c.userEmail = s.Username
+	c.accountID = s.PrimaryAccounts[calendarsCapability]
+	if c.accountID == "" {
+		return fmt.Errorf("the session has no calendar account")
+	}
+
+	c.jmapCalendarID, err = c.findCalendar(ctx)
+	if err != nil {
+		return err
+	}
+	c.logger.Debug("using calendar", "id", c.jmapCalendarID)
+	return nil
+}
+
+// findCalendar resolves the configured calendar, which is either the id or the name of the calendar
+func (c *CalendarAPI) findCalendar(ctx context.Context) (string, error) {
+	var result struct {
+		List []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"list"`
+	}
+	err := c.call(ctx, []invocation{{
+		Name:      "Calendar/get",
+		Arguments: map[string]any{"accountId": c.accountID, "ids": nil, "properties": []string{"id", "name"}},
+		CallID:    "calendars",
+	}}, &result)
+	if err != nil {
+		return "", fmt.Errorf("cannot find calendars: %w", err)
+	}
+
+	var names, matches []string
+	for _, calendar := range result.List {
+		if calendar.ID == c.calendarID {
+			return calendar.ID, nil
+		}
+		names = append(names, calendar.Name)
+		if calendar.Name == c.calendarID {
+			matches = append(matches, calendar.ID)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("calendar %q not found, available calendars: %s", c.calendarID, strings.Join(names, ", "))
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("multiple calendars are named %q, please configure the id of the calendar", c.calendarID)
+	}
+}
+
+// EventsInTimeframe returns the events of the calendar within the timeframe, series are expanded to their instances
+func (c *CalendarAPI) EventsInTimeframe(ctx context.Context, start time.Time, end time.Time) ([]models.Event, error) {
+	events, _, err := c.listEvents(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Infof("loaded %d events between %s and %s.", len(events), start.Format(time.DateOnly), end.Format(time.DateOnly))
+
+	return events, nil
+}
+
+// ChangedEventsInTimeframe returns the events which changed since the state of the token. Deleted events and changed
+// series cause a full listing, as the affected instances of series are unknown.
+func (c *CalendarAPI) ChangedEventsInTimeframe(ctx context.Context, start time.Time, end time.Time, token string) (models.Changes, error) {
+	if token == "" {
+		events, state, err := c.listEvents(ctx, start, end)
+		if err != nil {
+			return models.Changes{}, err
+		}
+		c.logger.Infof("loaded %d events between %s and %s.", len(events), start.Format(time.DateOnly), end.Format(time.DateOnly))
+		return models.Changes{Events: events, Token: state, Full: true}, nil
+	}
+
+	changed, destroyed, state, err := c.changes(ctx, token)
+	if err != nil {
+		return models.Changes{}, err
+	}
+	jsEvents, err := c.getEvents(ctx, changed)
+	if err != nil {
+		return models.Changes{}, err
+	}
+
+	full := len(destroyed) > 0
+	changes := models.Changes{Token: state}
+	for _, jsEvent := range jsEvents {
+		if !jsEvent.CalendarIDs[c.jmapCalendarID] {
+			// the event belongs to another calendar, it may have been moved out of this calendar
+			changes.Deleted = append(changes.Deleted, models.Event{ID: jsEvent.ID, Metadata: jsEvent.ensureMetadata(c.GetCalendarHash())})
+			continue
+		}
+		if jsEvent.isRecurring() {
+			full = true
+			break
+		}
+		event, err := jsEvent.toEvent(c.GetCalendarHash(), c.userEmail)
+		if err != nil {
+			c.logger.Warn("skipping event", "id", jsEvent.ID, "error", err)
+			continue
+		}
+		if event.StartTime.Before(end) && event.EndTime.After(start) {
+			changes.Events = append(changes.Events, event)
+		} else {
+			changes.Deleted = append(changes.Deleted, models.Event{ID: event.ID, Metadata: event.Metadata})
+		}
+	}
+
+	if full {
+		c.logger.Debug("events were deleted or series changed, listing all events")
+		events, _, err := c.listEvents(ctx, start, end)
+		if err != nil {
+			return models.Changes{}, err
+		}
+		c.logger.Infof("loaded %d events between %s and %s.", len(events), start.Format(time.DateOnly), end.Format(time.DateOnly))
+		return models.Changes{Events: events, Token: state, Full: true}, nil
+	}
+
+	c.logger.Infof("loaded %d changed and %d deleted events since the last sync.", len(changes.Events), len(changes.Deleted))
+
+	return changes, nil
+}
+
+// listEvents queries the events within the timeframe page by page. It returns the state of the events before they
+// were listed, such that changes during the listing are listed again by the next incremental sync.
+func (c *CalendarAPI) listEvents(ctx context.Context, start time.Time, end time.Time) ([]models.Event, string, error) {
+	var state string
+	var events []models.Event
+	for position := 0; ; {
+		var stateResult struct {
+			State string `json:"state"`
+		}
+		var queryResult struct {
+			IDs   []string `json:"ids"`
+			Total int      `json:"total"`
+		}
+		var getResult struct {
+			List []calendarEvent `json:"list"`
+		}
+		calls := []invocation{
+			{
+				Name: "CalendarEvent/query",
+				Arguments: map[string]any{
+					"accountId": c.accountID,
+					"filter": map[string]any{
+						"inCalendars": []string{c.jmapCalendarID},
+						"after":       start.UTC().Format(utcDateTime),
+						"before":      end.UTC().Format(utcDateTime),
+					},
+					"sort":              []map[string]any{{"property": "start"}},
+					"expandRecurrences": true,
+					"timeZone":          "Etc/UTC",
+					"position":          position,
+					"limit":             pageSize,
+					"calculateTotal":    true,
+				},
+				CallID: "query",
+			},
+			{
+				Name: "CalendarEvent/get",
+				Arguments: map[string]any{
+					"accountId": c.accountID,
+					"#ids":      resultReference{ResultOf: "query", Name: "CalendarEvent/query", Path: "/ids"},
+				},
+				CallID: "get",
+			},
+		}
+		results := []any{&queryResult, &getResult}
+		if position == 0 {
+			// the state is requested before the events are queried
+			calls = append([]invocation{{
+				Name:      "CalendarEvent/get",
+				Arguments: map[string]any{"accountId": c.accountID, "ids": []string{}},
+				CallID:    "state",
+			}}, calls...)
+			results = append([]any{&stateResult}, results...)
+		}
+		if err := c.call(ctx, calls, results...); err != nil {
+			return nil, "", fmt.Errorf("unable to query events of calendar %s: %w", c.calendarID, err)
+		}
+		if position == 0 {
+			state = stateResult.State
+		}
+
+		for _, jsEvent := range getResult.List {
+			event, err := jsEvent.toEvent(c.GetCalendarHash(), c.userEmail)
+			if err != nil {
+				c.logger.Warn("skipping event", "id", jsEvent.ID, "error", err)
+				continue
+			}
+			events = append(events, event)
+		}
+
+		position += len(queryResult.IDs)
+		if len(queryResult.IDs) == 0 || position >= queryResult.Total {
+			return events, state, nil
+		}
+	}
+}
+
+// changes returns the ids of the created or updated and of the destroyed events since the state
+func (c *CalendarAPI) changes(ctx context.Context, sinceState string) ([]string, []string, string, error) {
+	var changed, destroyed []string
+	for {
+		var result struct {
+			NewState       string   `json:"newState"`
+			HasMoreChanges bool     `json:"hasMoreChanges"`
+			Created        []string `json:"created"`
+			Updated        []string `json:"updated"`
+			Destroyed      []string `json:"destroyed"`
+		}
+		err := c.call(ctx, []invocation{{
+			Name:      "CalendarEvent/changes",
+			Arguments: map[string]any{"accountId": c.accountID, "sinceState": sinceState},
+			CallID:    "changes",
+		}}, &result)
+		if isMethodError(err, "cannotCalculateChanges") {
+			return nil, nil, "", models.ErrSyncTokenInvalid
+		} else if err != nil {
+			return nil, nil, "", fmt.Errorf("unable to load changes of calendar %s: %w", c.calendarID, err)
+		}
+
+		changed = append(changed, result.Created...)
+		changed = append(changed, result.Updated...)
+		destroyed = append(destroyed, result.Destroyed...)
+		sinceState = result.NewState
+		if !result.HasMoreChanges {
+			return changed, destroyed, sinceState, nil
+		}
+	}
+}
+
+// getEvents loads the events with the given ids, events which were deleted in the meantime are skipped
+func (c *CalendarAPI) getEvents(ctx context.Context, ids []string) ([]calendarEvent, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var result struct {
+		List []calendarEvent `json:"list"`
+	}
+	err := c.call(ctx, []invocation{{
+		Name:      "CalendarEvent/get",
+		Arguments: map[string]any{"accountId": c.accountID, "ids": ids},
+		CallID:    "get",
+	}}, &result)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load events: %w", err)
+	}
+	return result.List, nil
+}
+
+// setResult is the response of CalendarEvent/set, the errors are indexed by the creation id or the id of the event
+type setResult struct {
+	NotCreated   map[string]methodError `json:"notCreated"`
+	NotUpdated   map[string]methodError `json:"notUpdated"`
+	NotDestroyed map[string]methodError `json:"notDestroyed"`
+}
+
+// set sends a CalendarEvent/set call, scheduling messages are never sent to the attendees of the synced events
+func (c *CalendarAPI) set(ctx context.Context, arguments map[string]any) (setResult, error) {
+	arguments["accountId"] = c.accountID
+	arguments["sendSchedulingMessages"] = false
+	var result setResult
+	err := c.call(ctx, []invocation{{Name: "CalendarEvent/set", Arguments: arguments, CallID: "set"}}, &result)
+	return result, err
+}
+
+// CreateEvent creates an event in the calendar. The UID is derived from the SyncID, as the instances of a series have
+// the same UID in the source.
+func (c *CalendarAPI) CreateEvent(ctx context.Context, e models.Event) error {
+	properties := eventProperties(e)
+	properties["@type"] = "Event"
+	properties["calendarIds"] = map[string]bool{c.jmapCalendarID: true}
+	if e.Metadata != nil {
+		properties["uid"] = "calendarsync-" + e.Metadata.SyncID
+	}
+
+	result, err := c.set(ctx, map[string]any{"create": map[string]any{"event": properties}})
+	if err == nil {
+		if setErr, ok := result.NotCreated["event"]; ok {
+			err = &setErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("unable to create event %s: %w", e.ShortTitle(), err)
+	}
+
+	c.logger.Info("Event created", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+// UpdateEvent replaces the properties of the event which are written by the adapter
+func (c *CalendarAPI) UpdateEvent(ctx context.Context, e models.Event) error {
+	result, err := c.set(ctx, map[string]any{"update": map[string]any{e.ID: eventProperties(e)}})
+	if err == nil {
+		if setErr, ok := result.NotUpdated[e.ID]; ok {
+			err = &setErr
+		}
+	}
+	if isMethodError(err, "notFound") {
+		return errors.New("already deleted")
+	} else if err != nil {
+		return fmt.Errorf("unable to update event %s: %w", e.ShortTitle(), err)
+	}
+
+	c.logger.Info("Event updated", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
+
+// DeleteEvent deletes the event from the calendar
+func (c *CalendarAPI) DeleteEvent(ctx context.Context, e models.Event) error {
+	result, err := c.set(ctx, map[string]any{"destroy": []string{e.ID}})
+	if err == nil {
+		if setErr, ok := result.NotDestroyed[e.ID]; ok {
+			err = &setErr
+		}
+	}
+	if isMethodError(err, "notFound") {
+		c.logger.Debug("Event is already deleted.", "method", "DeleteEvent", "title", e.ShortTitle(), "time", e.StartTime.String())
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to delete event %s: %w", e.ShortTitle(), err)
+	}
+
+	c.logger.Info("Event deleted", "title", e.ShortTitle(), "time", e.StartTime.String())
+
+	return nil
+}
@@ -0,0 +1,550 @@
+package jmap
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// fakeServer is a JMAP server with the calendars of a single account, which keeps the events in memory
+type fakeServer struct {
+	mu        sync.Mutex
+	calendars []map[string]any
+	events    map[string]map[string]any
+	nextID    int
+	// state is incremented with every change, the changes since firstState are recorded
+	state      int
+	firstState int
+	changes    []change
+	// scheduling records the sendSchedulingMessages argument of every CalendarEvent/set call
+	scheduling []any
+}
+
+type change struct {
+	state     int
+	id        string
+	created   bool
+	destroyed bool
+}
+
+// maxChanges is the number of changes returned by a single CalendarEvent/changes call
+const maxChanges = 2
+
+func newFakeServer(t *testing.T) (*fakeServer, *httptest.Server) {
+	fake := &fakeServer{
+		calendars: []map[string]any{{"id": "cal-1", "name": "Work"}, {"id": "cal-2", "name": "Private"}},
+		events:    make(map[string]map[string]any),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/jmap", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{
+			"capabilities":    map[string]any{coreCapability: map[string]any{}, calendarsCapability: map[string]any{}},
+			"accounts":        map[string]any{"account": map[string]any{"name": "alice@example.com"}},
+			"primaryAccounts": map[string]any{calendarsCapability: "account"},
+			"username":        "alice@example.com",
+			"apiUrl":          "/jmap/api/",
+		})
+	})
+	mux.HandleFunc("POST /jmap/api/", fake.api)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return fake, server
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func (s *fakeServer) api(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		MethodCalls [][]json.RawMessage `json:"methodCalls"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make(map[string]map[string]any)
+	var responses []any
+	for _, call := range req.MethodCalls {
+		var name, callID string
+		var args map[string]any
+		_ = json.Unmarshal(call[0], &name)
+		_ = json.Unmarshal(call[1], &args)
+		_ = json.Unmarshal(call[2], &callID)
+
+		// resolve the references to the ids of previous results
+		for key, value := range args {
+			if reference, ok := strings.CutPrefix(key, "#"); ok {
+				args[reference] = results[value.(map[string]any)["resultOf"].(string)]["ids"]
+				delete(args, key)
+			}
+		}
+
+		var result map[string]any
+		switch name {
+		case "Calendar/get":
+			result = map[string]any{"list": s.calendars}
+		case "CalendarEvent/get":
+			result = s.get(args)
+		case "CalendarEvent/query":
+			result = s.query(args)
+		case "CalendarEvent/changes":
+			result = s.changesSince(args)
+		case "CalendarEvent/set":
+			result = s.set(args)
+		default:
+			result = map[string]any{"type": "unknownMethod"}
+		}
+		if _, isError := result["type"]; isError {
+			name = "error"
+		}
+		results[callID] = result
+		responses = append(responses, []any{name, result, callID})
+	}
+	writeJSON(w, map[string]any{"methodResponses": responses, "sessionState": "session"})
+}
+
+func (s *fakeServer) get(args map[string]any) map[string]any {
+	list := []any{}
+	var notFound []any
+	if ids, ok := args["ids"].([]any); ok {
+		for _, id := range ids {
+			if event, ok := s.events[id.(string)]; ok {
+				list = append(list, event)
+			} else {
+				notFound = append(notFound, id)
+			}
+		}
+	} else {
+		for _, event := range s.events {
+			list = append(list, event)
+		}
+	}
+	return map[string]any{"state": strconv.Itoa(s.state), "list": list, "notFound": notFound}
+}
+
+func (s *fakeServer) query(args map[string]any) map[string]any {
+	filter := args["filter"].(map[string]any)
+	calendarID := filter["inCalendars"].([]any)[0].(string)
+	after, _ := time.Parse(time.RFC3339, filter["after"].(string))
+	before, _ := time.Parse(time.RFC3339, filter["before"].(string))
+
+	type match struct {
+		id    string
+		start time.Time
+	}
+	var matches []match
+	for id, properties := range s.events {
+		event := decodeEvent(properties)
+		start, end, err := event.times()
+		if err == nil && event.CalendarIDs[calendarID] && start.Before(before) && end.After(after) {
+			matches = append(matches, match{id: id, start: start})
+		}
+	}
+	slices.SortFunc(matches, func(a, b match) int { return a.start.Compare(b.start) })
+
+	position, limit := int(args["position"].(float64)), int(args["limit"].(float64))
+	ids := []any{}
+	for _, m := range matches[min(position, len(matches)):min(position+limit, len(matches))] {
+		ids = append(ids, m.id)
+	}
+	return map[string]any{"ids": ids, "position": position, "total": len(matches)}
+}
+
+func (s *fakeServer) changesSince(args map[string]any) map[string]any {
+	since, err := strconv.Atoi(args["sinceState"].(string))
+	if err != nil || since < s.firstState {
+		return map[string]any{"type": "cannotCalculateChanges"}
+	}
+
+	created, updated, destroyed := []string{}, []string{}, []string{}
+	newState := s.state
+	var count int
+	for _, c := range s.changes {
+		if c.state <= since {
+			continue
+		}
+		if count == maxChanges {
+			break
+		}
+		switch {
+		case c.destroyed:
+			destroyed = append(destroyed, c.id)
+		case c.created:
+			created = append(created, c.id)
+		default:
+			updated = append(updated, c.id)
+		}
+		count++
+		newState = c.state
+	}
+	return map[string]any{
+		"oldState":       strconv.Itoa(since),
+		"newState":       strconv.Itoa(newState),
+		"hasMoreChanges": newState != s.state,
+		"created":        created,
+		"updated":        updated,
+		"destroyed":      destroyed,
+	}
+}
+
+func (s *fakeServer) set(args map[string]any) map[string]any {
+	s.scheduling = append(s.scheduling, args["sendSchedulingMessages"])
+	result := map[string]any{}
+
+	if create, ok := args["create"].(map[string]any); ok {
+		created, notCreated := map[string]any{}, map[string]any{}
+		for creationID, value := range create {
+			properties := value.(map[string]any)
+			if _, ok := properties["calendarIds"]; !ok {
+				notCreated[creationID] = map[string]any{"type": "invalidProperties"}
+				continue
+			}
+			s.nextID++
+			id := "event-" + strconv.Itoa(s.nextID)
+			properties["id"] = id
+			s.store(id, properties, true)
+			created[creationID] = map[string]any{"id": id}
+		}
+		result["created"], result["notCreated"] = created, notCreated
+	}
+	if update, ok := args["update"].(map[string]any); ok {
+		updated, notUpdated := map[string]any{}, map[string]any{}
+		for id, patch := range update {
+			event, ok := s.events[id]
+			if !ok {
+				notUpdated[id] = map[string]any{"type": "notFound"}
+				continue
+			}
+			for key, value := range patch.(map[string]any) {
+				if value == nil {
+					delete(event, key)
+				} else {
+					event[key] = value
+				}
+			}
+			s.store(id, event, false)
+			updated[id] = nil
+		}
+		result["updated"], result["notUpdated"] = updated, notUpdated
+	}
+	if destroy, ok := args["destroy"].([]any); ok {
+		destroyed, notDestroyed := []any{}, map[string]any{}
+		for _, id := range destroy {
+			if _, ok := s.events[id.(string)]; !ok {
+				notDestroyed[id.(string)] = map[string]any{"type": "notFound"}
+				continue
+			}
+			s.store(id.(string), nil, false)
+			destroyed = append(destroyed, id)
+		}
+		result["destroyed"], result["notDestroyed"] = destroyed, notDestroyed
+	}
+	return result
+}
+
+// store saves the event and records the change, a nil event is destroyed
+func (s *fakeServer) store(id string, properties map[string]any, created bool) {
+	s.state++
+	if properties == nil {
+		delete(s.events, id)
+	} else {
+		s.events[id] = properties
+	}
+	s.changes = append(s.changes, change{state: s.state, id: id, created: created, destroyed: properties == nil})
+}
+
+func decodeEvent(properties map[string]any) calendarEvent {
+	data, _ := json.Marshal(properties)
+	var event calendarEvent
+	_ = json.Unmarshal(data, &event)
+	return event
+}
+
+func newTestAPI(t *testing.T, endpoint string, calendar string) *CalendarAPI {
+	api := &CalendarAPI{}
+	api.SetLogger(log.Default())
+	require.NoError(t, api.SetCalendarID(calendar))
+	require.NoError(t, api.Initialize(context.Background(), false, map[string]interface{}{
+		endpointKey: endpoint,
+		tokenKey:    "secret-token",
+	}))
+	return api
+}
+
+func TestEvents(t *testing.T) {
+	fake, server := newFakeServer(t)
+	api := newTestAPI(t, server.URL, "Work")
+	assert.Equal(t, "cal-1", api.jmapCalendarID)
+
+	ctx := context.Background()
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	start := time.Date(2024, 3, 5, 9, 0, 0, 0, berlin)
+	event := models.Event{
+		Title:       "Planning",
+		Description: "Agenda",
+		Location:    "Room 1",
+		MeetingLink: "https://meet.example.com/planning",
+		StartTime:   start,
+		EndTime:     start.Add(90 * time.Minute),
+		TimeZone:    "Europe/Berlin",
+		Attendees:   models.Attendees{{Email: "bob@example.com", DisplayName: "Bob"}},
+		Reminders: models.Reminders{{
+			Actions: models.ReminderActionDisplay,
+			Trigger: models.ReminderTrigger{PointInTime: start.Add(-15 * time.Minute)},
+		}},
+		Metadata: models.NewEventMetadata("event", "https://example.com/event", "source"),
+	}
+	require.NoError(t, api.CreateEvent(ctx, event))
+
+	stored := fake.events["event-1"]
+	assert.Equal(t, "calendarsync-"+event.Metadata.SyncID, stored["uid"])
+	assert.Equal(t, "2024-03-05T09:00:00", stored["start"])
+	assert.Equal(t, "PT1H30M", stored["duration"])
+	assert.Equal(t, map[string]any{"cal-1": true}, stored["calendarIds"])
+	assert.Contains(t, stored, metadataProperty)
+
+	events, err := api.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	listed := events[0]
+	assert.Equal(t, "event-1", listed.ID)
+	assert.Equal(t, "Planning", listed.Title)
+	assert.Equal(t, "Agenda", listed.Description)
+	assert.Equal(t, "Room 1", listed.Location)
+	assert.Equal(t, event.MeetingLink, listed.MeetingLink)
+	assert.True(t, listed.StartTime.Equal(event.StartTime))
+	assert.True(t, listed.EndTime.Equal(event.EndTime))
+	assert.Equal(t, "Europe/Berlin", listed.TimeZone)
+	assert.Equal(t, event.Attendees, models.Attendees(listed.Attendees))
+	require.Len(t, listed.Reminders, 1)
+	assert.True(t, listed.Reminders[0].Trigger.PointInTime.Equal(start.Add(-15*time.Minute)))
+	assert.Equal(t, event.Metadata, listed.Metadata)
+	assert.True(t, listed.Accepted)
+
+	listed.Title = "Planning (moved)"
+	listed.Location = ""
+	require.NoError(t, api.UpdateEvent(ctx, listed))
+	assert.NotContains(t, fake.events["event-1"], "locations")
+
+	events, err = api.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "Planning (moved)", events[0].Title)
+	assert.Empty(t, events[0].Location)
+	assert.Equal(t, event.Metadata, events[0].Metadata)
+
+	require.NoError(t, api.DeleteEvent(ctx, events[0]))
+	events, err = api.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 1))
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	assert.NoError(t, api.DeleteEvent(ctx, listed))
+	assert.EqualError(t, api.UpdateEvent(ctx, listed), "already deleted")
+
+	// the attendees must never be notified
+	for _, scheduling := range fake.scheduling {
+		assert.Equal(t, false, scheduling)
+	}
+}
+
+func TestAllDayEvent(t *testing.T) {
+	fake, server := newFakeServer(t)
+	api := newTestAPI(t, server.URL, "cal-2")
+
+	ctx := context.Background()
+	start := time.Date(2024, 3, 29, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, api.CreateEvent(ctx, models.Event{
+		Title:     "Holiday",
+		StartTime: start,
+		EndTime:   start.AddDate(0, 0, 2),
+		AllDay:    true,
+		Metadata:  models.NewEventMetadata("holiday", "", "source"),
+	}))
+	assert.Equal(t, "P2D", fake.events["event-1"]["duration"])
+	assert.Equal(t, true, fake.events["event-1"]["showWithoutTime"])
+
+	events, err := api.EventsInTimeframe(ctx, start.AddDate(0, 0, -1), start.AddDate(0, 0, 3))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.True(t, events[0].AllDay)
+	assert.Equal(t, start, events[0].StartTime)
+	assert.Equal(t, start.AddDate(0, 0, 2), events[0].EndTime)
+	assert.Empty(t, events[0].TimeZone)
+}
+
+func TestChangedEvents(t *testing.T) {
+	fake, server := newFakeServer(t)
+	api := newTestAPI(t, server.URL, "Work")
+
+	ctx := context.Background()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	newEvent := func(title string, day int) models.Event {
+		eventStart := start.AddDate(0, 0, day).Add(9 * time.Hour)
+		return models.Event{Title: title, StartTime: eventStart, EndTime: eventStart.Add(time.Hour), Metadata: models.NewEventMetadata(title, "", "source")}
+	}
+	for i, title := range []string{"first", "second", "third"} {
+		require.NoError(t, api.CreateEvent(ctx, newEvent(title, i)))
+	}
+
+	changes, err := api.ChangedEventsInTimeframe(ctx, start, end, "")
+	require.NoError(t, err)
+	assert.True(t, changes.Full)
+	assert.Len(t, changes.Events, 3)
+
+	// the changes are loaded in multiple calls
+	require.NoError(t, api.CreateEvent(ctx, newEvent("fourth", 3)))
+	second := fake.events["event-2"]
+	second["title"] = "second (updated)"
+	fake.store("event-2", second, false)
+	moved := fake.events["event-3"]
+	moved["start"] = "2025-01-01T09:00:00"
+	fake.store("event-3", moved, false)
+
+	changes, err = api.ChangedEventsInTimeframe(ctx, start, end, changes.Token)
+	require.NoError(t, err)
+	assert.False(t, changes.Full)
+	require.Len(t, changes.Events, 2)
+	assert.Equal(t, "fourth", changes.Events[0].Title)
+	assert.Equal(t, "second (updated)", changes.Events[1].Title)
+	// the event moved out of the timeframe
+	require.Len(t, changes.Deleted, 1)
+	assert.Equal(t, models.NewEventMetadata("third", "", "source"), changes.Deleted[0].Metadata)
+
+	// without changes, no events are loaded
+	changes, err = api.ChangedEventsInTimeframe(ctx, start, end, changes.Token)
+	require.NoError(t, err)
+	assert.False(t, changes.Full)
+	assert.Empty(t, changes.Events)
+	assert.Empty(t, changes.Deleted)
+
+	// deleted events and changed series are synced with a full listing
+	require.NoError(t, api.DeleteEvent(ctx, models.Event{ID: "event-1"}))
+	changes, err = api.ChangedEventsInTimeframe(ctx, start, end, changes.Token)
+	require.NoError(t, err)
+	assert.True(t, changes.Full)
+	assert.Len(t, changes.Events, 2)
+
+	series := fake.events["event-2"]
+	series["recurrenceRules"] = []any{map[string]any{"@type": "RecurrenceRule", "frequency": "weekly"}}
+	fake.store("event-2", series, false)
+	changes, err = api.ChangedEventsInTimeframe(ctx, start, end, changes.Token)
+	require.NoError(t, err)
+	assert.True(t, changes.Full)
+
+	fake.firstState = fake.state
+	_, err = api.ChangedEventsInTimeframe(ctx, start, end, "1")
+	assert.ErrorIs(t, err, models.ErrSyncTokenInvalid)
+}
+
+func TestEventsPaging(t *testing.T) {
+	fake, server := newFakeServer(t)
+	api := newTestAPI(t, server.URL, "Work")
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := range pageSize + 10 {
+		id := "seeded-" + strconv.Itoa(i)
+		fake.store(id, map[string]any{
+			"id":          id,
+			"calendarIds": map[string]any{"cal-1": true},
+			"title":       id,
+			"start":       start.Add(time.Duration(i) * time.Hour).Format(localDateTime),
+			"timeZone":    "Etc/UTC",
+			"duration":    "PT30M",
+			"participants": map[string]any{
+				"alice": map[string]any{"@type": "Participant", "email": "alice@example.com", "participationStatus": "declined", "roles": map[string]any{"attendee": true}},
+			},
+		}, true)
+	}
+
+	events, err := api.EventsInTimeframe(context.Background(), start, start.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	require.Len(t, events, pageSize+10)
+	// the events were not created by CalendarSync and were declined by the user
+	assert.Equal(t, models.NewEventMetadata("seeded-0", "", api.GetCalendarHash()), events[0].Metadata)
+	assert.False(t, events[0].Accepted)
+}
+
+func TestInitialize(t *testing.T) {
+	_, server := newFakeServer(t)
+
+	tests := []struct {
+		name     string
+		calendar string
+		config   map[string]interface{}
+		err      string
+	}{
+		{"unknown calendar", "Team", map[string]interface{}{endpointKey: server.URL, tokenKey: "secret-token"}, `calendar "Team" not found, available calendars: Work, Private`},
+		{"wrong password", "Work", map[string]interface{}{endpointKey: server.URL, usernameKey: "alice", passwordKey: "wrong"}, "401 Unauthorized"},
+		{"missing credentials", "Work", map[string]interface{}{endpointKey: server.URL}, "missing config key: username or token"},
+		{"missing endpoint", "Work", map[string]interface{}{tokenKey: "secret-token"}, "missing config key: endpoint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &CalendarAPI{}
+			api.SetLogger(log.Default())
+			require.NoError(t, api.SetCalendarID(tt.calendar))
+			err := api.Initialize(context.Background(), false, tt.config)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	start := time.Date(2024, 3, 30, 12, 0, 0, 0, berlin)
+
+	tests := []struct {
+		duration string
+		expected time.Time
+	}{
+		{"PT1H30M", start.Add(90 * time.Minute)},
+		{"-PT15M", start.Add(-15 * time.Minute)},
+		// days are nominal days, the clock time stays the same over the change to daylight saving time
+		{"P1D", time.Date(2024, 3, 31, 12, 0, 0, 0, berlin)},
+		{"P1W", time.Date(2024, 4, 6, 12, 0, 0, 0, berlin)},
+		{"P1DT2H", time.Date(2024, 3, 31, 14, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.duration, func(t *testing.T) {
+			end, err := addDuration(start, tt.duration)
+			require.NoError(t, err)
+			assert.True(t, tt.expected.Equal(end), end)
+		})
+	}
+
+	for _, invalid := range []string{"", "P", "PT", "1H", "P1H"} {
+		_, err := addDuration(start, invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	assert.Equal(t, "PT1H30M", formatDuration(90*time.Minute))
+	assert.Equal(t, "-PT15M", formatDuration(-15*time.Minute))
+	assert.Equal(t, "PT0S", formatDuration(0))
+}
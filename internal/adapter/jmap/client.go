@@ -0,0 +1,192 @@
+package jmap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	coreCapability      = "urn:ietf:params:jmap:core"
+	calendarsCapability = "urn:ietf:params:jmap:calendars"
+
+	// wellKnownPath is the path of the session resource, if the endpoint is only the URL of the server
+	wellKnownPath = "/.well-known/jmap"
+)
+
+// methodError is an error response of a method call, see RFC 8620 section 3.6.2
+type methodError struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+func (e *methodError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s: %s", e.Type, e.Description)
+	}
+	return e.Type
+}
+
+// session is the session resource of the server, see RFC 8620 section 2
+type session struct {
+	Capabilities    map[string]json.RawMessage `json:"capabilities"`
+	PrimaryAccounts map[string]string          `json:"primaryAccounts"`
+	Username        string                     `json:"username"`
+	APIURL          string                     `json:"apiUrl"`
+}
+
+// invocation is a method call or a method response, it's encoded as an array of the name, the arguments and the
+// call id
+type invocation struct {
+	Name      string
+	Arguments any
+	CallID    string
+}
+
+func (i invocation) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{i.Name, i.Arguments, i.CallID})
+}
+
+func (i *invocation) UnmarshalJSON(data []byte) error {
+	var arguments json.RawMessage
+	if err := json.Unmarshal(data, &[]any{&i.Name, &arguments, &i.CallID}); err != nil {
+		return err
+	}
+	i.Arguments = arguments
+	return nil
+}
+
+type request struct {
+	Using       []string     `json:"using"`
+	MethodCalls []invocation `json:"methodCalls"`
+}
+
+type response struct {
+	MethodResponses []invocation `json:"methodResponses"`
+}
+
+// resultReference refers to the result of a previous method call of the same request
+type resultReference struct {
+	ResultOf string `json:"resultOf"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+}
+
+// discover loads the session resource and returns the session with the absolute URL of the API
+func (c *CalendarAPI) discover(ctx context.Context) (*session, error) {
+	sessionURL, err := url.Parse(c.endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if strings.Trim(sessionURL.Path, "/") == "" {
+		sessionURL.Path = wellKnownPath
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sessionURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load session: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var s session
+	if err := json.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return nil, fmt.Errorf("unable to decode session: %w", err)
+	}
+	if _, ok := s.Capabilities[calendarsCapability]; !ok {
+		return nil, fmt.Errorf("the server does not support calendars")
+	}
+	apiURL, err := sessionURL.Parse(s.APIURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid api url: %w", err)
+	}
+	s.APIURL = apiURL.String()
+	return &s, nil
+}
+
+// call sends the method calls in a single request and decodes the responses into the results, which are indexed
+// like the calls. A nil result skips the decoding of its response.
+func (c *CalendarAPI) call(ctx context.Context, calls []invocation, results ...any) error {
+	body, err := json.Marshal(request{Using: []string{coreCapability, calendarsCapability}, MethodCalls: calls})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("unable to decode response: %w", err)
+	}
+
+	// every call has exactly one response, which is identified by its call id
+	responses := make(map[string]invocation)
+	for _, methodResponse := range r.MethodResponses {
+		responses[methodResponse.CallID] = methodResponse
+	}
+	for i, call := range calls {
+		methodResponse, ok := responses[call.CallID]
+		if !ok {
+			return fmt.Errorf("missing response of %s", call.Name)
+		}
+		arguments := methodResponse.Arguments.(json.RawMessage)
+		if methodResponse.Name == "error" {
+			var methodErr methodError
+			if err := json.Unmarshal(arguments, &methodErr); err != nil {
+				return fmt.Errorf("unable to decode error of %s: %w", call.Name, err)
+			}
+			return fmt.Errorf("%s failed: %w", call.Name, &methodErr)
+		}
+		if i < len(results) && results[i] != nil {
+			if err := json.Unmarshal(arguments, results[i]); err != nil {
+				return fmt.Errorf("unable to decode response of %s: %w", call.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// do sends the authenticated request and returns the response if it was successful
+func (c *CalendarAPI) do(req *http.Request) (*http.Response, error) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	} else {
+		req.SetBasicAuth(c.username, c.password)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// isMethodError reports whether the error is a method error of the given type
+func isMethodError(err error, typ string) bool {
+	var methodErr *methodError
+	return errors.As(err, &methodErr) && methodErr.Type == typ
+}
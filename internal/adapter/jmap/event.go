@@ -0,0 +1,324 @@
+package jmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+const (
+	// metadataProperty is the vendor-specific JSCalendar property which stores the metadata of CalendarSync,
+	// see RFC 8984 section 3.3
+	metadataProperty = "inovex.de:calendarsync"
+
+	// localDateTime is the format of the start of an event in its time zone
+	localDateTime = "2006-01-02T15:04:05"
+	// utcDateTime is the format of the times of the filters
+	utcDateTime = "2006-01-02T15:04:05Z"
+)
+
+// calendarEvent is an event in the JSCalendar format, see RFC 8984
+type calendarEvent struct {
+	ID                  string                     `json:"id"`
+	UID                 string                     `json:"uid"`
+	CalendarIDs         map[string]bool            `json:"calendarIds"`
+	Title               string                     `json:"title"`
+	Description         string                     `json:"description"`
+	Start               string                     `json:"start"`
+	TimeZone            string                     `json:"timeZone"`
+	Duration            string                     `json:"duration"`
+	ShowWithoutTime     bool                       `json:"showWithoutTime"`
+	Locations           map[string]location        `json:"locations"`
+	VirtualLocations    map[string]virtualLocation `json:"virtualLocations"`
+	Participants        map[string]participant     `json:"participants"`
+	Alerts              map[string]alert           `json:"alerts"`
+	RecurrenceRules     []json.RawMessage          `json:"recurrenceRules"`
+	RecurrenceOverrides map[string]json.RawMessage `json:"recurrenceOverrides"`
+	Metadata            *metadata                  `json:"inovex.de:calendarsync"`
+}
+
+type location struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+type virtualLocation struct {
+	Type string `json:"@type"`
+	URI  string `json:"uri"`
+}
+
+type participant struct {
+	Type                string            `json:"@type"`
+	Name                string            `json:"name,omitempty"`
+	Email               string            `json:"email,omitempty"`
+	SendTo              map[string]string `json:"sendTo,omitempty"`
+	Roles               map[string]bool   `json:"roles"`
+	ParticipationStatus string            `json:"participationStatus,omitempty"`
+}
+
+type alert struct {
+	Type    string  `json:"@type"`
+	Trigger trigger `json:"trigger"`
+}
+
+// trigger is either an OffsetTrigger relative to the start of the event or an AbsoluteTrigger
+type trigger struct {
+	Type       string `json:"@type"`
+	Offset     string `json:"offset,omitempty"`
+	RelativeTo string `json:"relativeTo,omitempty"`
+	When       string `json:"when,omitempty"`
+}
+
+type metadata struct {
+	SyncID           string `json:"syncId"`
+	OriginalEventUri string `json:"originalEventUri,omitempty"`
+	SourceID         string `json:"sourceId"`
+}
+
+// isRecurring reports whether the event is a series, which is expanded to its instances by queries
+func (e calendarEvent) isRecurring() bool {
+	return len(e.RecurrenceRules) > 0 || len(e.RecurrenceOverrides) > 0
+}
+
+// times returns the start and end of the event. Events without a time zone are floating, they are interpreted in UTC.
+func (e calendarEvent) times() (time.Time, time.Time, error) {
+	location := time.UTC
+	if e.TimeZone != "" && !e.ShowWithoutTime {
+		var err error
+		location, err = time.LoadLocation(e.TimeZone)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("unknown time zone %q", e.TimeZone)
+		}
+	}
+	start, err := time.ParseInLocation(localDateTime, e.Start, location)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start %q", e.Start)
+	}
+	if e.Duration == "" {
+		return start, start, nil
+	}
+	end, err := addDuration(start, e.Duration)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return start, end, nil
+}
+
+// sortedKeys returns the keys of the map in a stable order, as the ids of the JSCalendar maps have no order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// toEvent transforms a JSCalendar event to our form of event representation. The participant with the email of the
+// user determines whether the event was accepted.
+func (e calendarEvent) toEvent(sourceID string, userEmail string) (models.Event, error) {
+	start, end, err := e.times()
+	if err != nil {
+		return models.Event{}, err
+	}
+
+	event := models.Event{
+		ICalUID:     e.UID,
+		ID:          e.ID,
+		Title:       e.Title,
+		Description: e.Description,
+		StartTime:   start,
+		EndTime:     end,
+		AllDay:      e.ShowWithoutTime,
+		Metadata:    e.ensureMetadata(sourceID),
+		Attendees:   make([]models.Attendee, 0),
+		Reminders:   make([]models.Reminder, 0),
+		Accepted:    true,
+	}
+	if !e.ShowWithoutTime && e.TimeZone != "" {
+		event.TimeZone = e.TimeZone
+	}
+
+	for _, key := range sortedKeys(e.Locations) {
+		if e.Locations[key].Name != "" {
+			event.Location = e.Locations[key].Name
+			break
+		}
+	}
+	for _, key := range sortedKeys(e.VirtualLocations) {
+		if e.VirtualLocations[key].URI != "" {
+			event.MeetingLink = e.VirtualLocations[key].URI
+			break
+		}
+	}
+
+	for _, key := range sortedKeys(e.Participants) {
+		p := e.Participants[key]
+		if userEmail != "" && strings.EqualFold(p.Email, userEmail) && p.ParticipationStatus == "declined" {
+			event.Accepted = false
+		}
+		if p.Roles["attendee"] {
+			event.Attendees = append(event.Attendees, models.Attendee{Email: p.Email, DisplayName: p.Name})
+		}
+	}
+
+	for _, key := range sortedKeys(e.Alerts) {
+		pointInTime, err := e.Alerts[key].Trigger.pointInTime(start, end)
+		if err != nil {
+			continue
+		}
+		event.Reminders = append(event.Reminders, models.Reminder{
+			Actions: models.ReminderActionDisplay,
+			Trigger: models.ReminderTrigger{PointInTime: pointInTime},
+		})
+	}
+
+	return event, nil
+}
+
+// pointInTime returns the time at which the alert is triggered
+func (t trigger) pointInTime(start, end time.Time) (time.Time, error) {
+	switch t.Type {
+	case "AbsoluteTrigger":
+		return time.Parse(time.RFC3339, t.When)
+	case "OffsetTrigger", "":
+		if t.RelativeTo == "end" {
+			return addDuration(end, t.Offset)
+		}
+		return addDuration(start, t.Offset)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported trigger %s", t.Type)
+	}
+}
+
+// ensureMetadata retrieves the metadata from the custom property or regenerates it
+func (e calendarEvent) ensureMetadata(sourceID string) *models.Metadata {
+	if e.Metadata != nil && e.Metadata.SyncID != "" && e.Metadata.SourceID != "" {
+		return &models.Metadata{
+			SyncID:           e.Metadata.SyncID,
+			OriginalEventUri: e.Metadata.OriginalEventUri,
+			SourceID:         e.Metadata.SourceID,
+		}
+	}
+	return models.NewEventMetadata(e.ID, "", sourceID)
+}
+
+// eventProperties returns the JSCalendar properties which are written for the event. Properties which are not set
+// are null, such that they are removed by updates.
+func eventProperties(e models.Event) map[string]any {
+	properties := map[string]any{
+		"title":            e.Title,
+		"description":      e.Description,
+		"showWithoutTime":  e.AllDay,
+		"locations":        nil,
+		"virtualLocations": nil,
+		"participants":     nil,
+		"alerts":           nil,
+		metadataProperty:   nil,
+	}
+
+	if e.AllDay {
+		start := e.StartTime.UTC()
+		properties["start"] = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC).Format(localDateTime)
+		properties["timeZone"] = nil
+		properties["duration"] = fmt.Sprintf("P%dD", max(1, int(e.EndTime.Sub(e.StartTime).Hours()+12)/24))
+	} else {
+		timeZone := e.TimeZone
+		location, err := time.LoadLocation(timeZone)
+		if timeZone == "" || err != nil {
+			location, timeZone = time.UTC, "Etc/UTC"
+		}
+		properties["start"] = e.StartTime.In(location).Format(localDateTime)
+		properties["timeZone"] = timeZone
+		properties["duration"] = formatDuration(e.EndTime.Sub(e.StartTime))
+	}
+
+	if e.Location != "" {
+		properties["locations"] = map[string]location{"1": {Type: "Location", Name: e.Location}}
+	}
+	if e.MeetingLink != "" {
+		properties["virtualLocations"] = map[string]virtualLocation{"1": {Type: "VirtualLocation", URI: e.MeetingLink}}
+	}
+	if len(e.Attendees) > 0 {
+		participants := make(map[string]participant)
+		for i, a := range e.Attendees {
+			participants[strconv.Itoa(i+1)] = participant{
+				Type:   "Participant",
+				Name:   a.DisplayName,
+				Email:  a.Email,
+				SendTo: map[string]string{"imip": "mailto:" + a.Email},
+				Roles:  map[string]bool{"attendee": true},
+			}
+		}
+		properties["participants"] = participants
+	}
+	if len(e.Reminders) > 0 {
+		alerts := make(map[string]alert)
+		for i, r := range e.Reminders {
+			alerts[strconv.Itoa(i+1)] = alert{
+				Type:    "Alert",
+				Trigger: trigger{Type: "OffsetTrigger", Offset: formatDuration(r.Trigger.PointInTime.Sub(e.StartTime))},
+			}
+		}
+		properties["alerts"] = alerts
+	}
+	if e.Metadata != nil {
+		properties[metadataProperty] = metadata{
+			SyncID:           e.Metadata.SyncID,
+			OriginalEventUri: e.Metadata.OriginalEventUri,
+			SourceID:         e.Metadata.SourceID,
+		}
+	}
+	return properties
+}
+
+// jmapDuration matches the durations of JSCalendar, see RFC 8984 section 1.4.6
+var jmapDuration = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)(?:\.\d+)?S)?)?$`)
+
+// addDuration adds the duration to the time. Days are nominal days, which may be longer or shorter than 24 hours.
+func addDuration(t time.Time, duration string) (time.Time, error) {
+	match := jmapDuration.FindStringSubmatch(duration)
+	if match == nil || duration == "P" || strings.HasSuffix(duration, "T") {
+		return time.Time{}, fmt.Errorf("invalid duration %q", duration)
+	}
+	values := make([]int, 5)
+	for i, value := range match[2:] {
+		values[i], _ = strconv.Atoi(value)
+	}
+	sign := 1
+	if match[1] == "-" {
+		sign = -1
+	}
+	t = t.AddDate(0, 0, sign*(7*values[0]+values[1]))
+	return t.Add(time.Duration(sign) * (time.Duration(values[2])*time.Hour + time.Duration(values[3])*time.Minute + time.Duration(values[4])*time.Second)), nil
+}
+
+// formatDuration formats the exact duration, negative durations are prefixed with a minus sign
+func formatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	d = d.Truncate(time.Second)
+	if d == 0 {
+		return "PT0S"
+	}
+	var b strings.Builder
+	b.WriteString(sign + "PT")
+	if hours := int(d / time.Hour); hours > 0 {
+		fmt.Fprintf(&b, "%dH", hours)
+	}
+	if minutes := int(d % time.Hour / time.Minute); minutes > 0 {
+		fmt.Fprintf(&b, "%dM", minutes)
+	}
+	if seconds := int(d % time.Minute / time.Second); seconds > 0 {
+		fmt.Fprintf(&b, "%dS", seconds)
+	}
+	return b.String()
+}
@@ -3,6 +3,7 @@ package outlook_http
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -17,10 +18,14 @@ import (
 const (
 	baseUrl    = "https://graph.microsoft.com/v1.0"
 	timeFormat = "2006-01-02T15:04:05.0000000"
+
+	// userKey configures the id or the principal name of the user whose calendar is synced instead of the signed-in user
+	userKey = "user"
 )
 
 type OutlookCalendarClient interface {
 	ListEvents(ctx context.Context, starttime time.Time, endtime time.Time) ([]models.Event, error)
+	ListEventChanges(ctx context.Context, starttime time.Time, endtime time.Time, deltaLink string) (models.Changes, error)
 	CreateEvent(ctx context.Context, event models.Event) error
 	UpdateEvent(ctx context.Context, event models.Event) error
 	DeleteEvent(ctx context.Context, event models.Event) error
@@ -36,10 +41,16 @@ type CalendarAPI struct {
 	oAuthUrl      string
 	oAuthToken    *oauth2.Token
 	oAuthHandler  *auth.OAuthHandler
+	// deviceFlow is set if the user is authorized with the device authorization flow instead of the oAuthHandler
+	deviceFlow *auth.DeviceFlow
+	// appTokens is set if the adapter authenticates as the application with client credentials
+	appTokens oauth2.TokenSource
+	user      string
 
 	logger *log.Logger
 
 	storage auth.Storage
+	series  bool
 }
 
 // Assert that the expected interfaces are implemented
@@ -47,6 +58,7 @@ var _ port.Configurable = &CalendarAPI{}
 var _ port.LogSetter = &CalendarAPI{}
 var _ port.CalendarIDSetter = &CalendarAPI{}
 var _ port.OAuth2Adapter = &CalendarAPI{}
+var _ port.SeriesSetter = &CalendarAPI{}
 
 func (c *CalendarAPI) SetCalendarID(calendarID string) error {
 	if calendarID == "" {
@@ -57,6 +69,10 @@ func (c *CalendarAPI) SetCalendarID(calendarID string) error {
 }
 
 func (c *CalendarAPI) SetupOauth2(ctx context.Context, credentials auth.Credentials, storage auth.Storage, bindPort uint) error {
+	if credentials.ClientCredentials {
+		return c.setupClientCredentials(ctx, credentials)
+	}
+
 	// Outlook Adapter does not need the clientKey
 	switch {
 	case credentials.Client.Id == "":
@@ -66,9 +82,10 @@ func (c *CalendarAPI) SetupOauth2(ctx context.Context, credentials auth.Credenti
 	}
 
 	endpoint := oauth2.Endpoint{
-		AuthURL:   fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/authorize", credentials.Tenant.Id),
-		TokenURL:  fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", credentials.Tenant.Id),
-		AuthStyle: oauth2.AuthStyleInParams,
+		AuthURL:       fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/authorize", credentials.Tenant.Id),
+		TokenURL:      fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", credentials.Tenant.Id),
+		DeviceAuthURL: fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/devicecode", credentials.Tenant.Id),
+		AuthStyle:     oauth2.AuthStyleInParams,
 	}
 
 	oAuthConfig := oauth2.Config{
@@ -77,12 +94,16 @@ func (c *CalendarAPI) SetupOauth2(ctx context.Context, credentials auth.Credenti
 		Scopes:   []string{"Calendars.ReadWrite", "offline_access"}, // You need to request offline_access in order to retrieve a refresh token
 	}
 
-	oAuthListener, err := auth.NewOAuthHandler(oAuthConfig, bindPort)
-	if err != nil {
-		return err
+	if credentials.DeviceFlow {
+		c.deviceFlow = auth.NewDeviceFlow(oAuthConfig)
+	} else {
+		oAuthListener, err := auth.NewOAuthHandler(oAuthConfig, bindPort)
+		if err != nil {
+			return err
+		}
+		c.oAuthHandler = oAuthListener
 	}
 
-	c.oAuthHandler = oAuthListener
 	c.storage = storage
 	c.oAuthConfig = &oAuthConfig
 
@@ -166,25 +187,45 @@ func (c *CalendarAPI) SetupOauth2(ctx context.Context, credentials auth.Credenti
 	return nil
 }
 
+// setupClientCredentials prepares the app-only authentication. The tokens of the application are requested on demand,
+// so neither the redirect listener nor the auth storage are used.
+func (c *CalendarAPI) setupClientCredentials(ctx context.Context, credentials auth.Credentials) error {
+	switch {
+	case credentials.Client.Id == "":
+		return fmt.Errorf("%s adapter oAuth2 'clientId' cannot be empty", c.Name())
+	case credentials.Tenant.Id == "":
+		return fmt.Errorf("%s adapter oAuth2 'tenantId' cannot be empty", c.Name())
+	case credentials.Client.Secret == "" && credentials.Client.CertificateFile == "":
+		return fmt.Errorf("%s adapter oAuth2 'clientKey' or 'certificateFile' cannot be empty", c.Name())
+	}
+
+	tokenURL := fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", credentials.Tenant.Id)
+	tokens, err := clientCredentialsTokenSource(ctx, credentials.Client.Id, credentials.Client.Secret, credentials.Client.CertificateFile, tokenURL)
+	if err != nil {
+		return err
+	}
+	c.appTokens = tokens
+	c.authenticated = true
+	c.logger.Debug("using client credentials")
+	return nil
+}
+
 func (c *CalendarAPI) Initialize(ctx context.Context, openBrowser bool, config map[string]interface{}) error {
-	if !c.authenticated {
-		c.oAuthUrl = c.oAuthHandler.Configuration().AuthCodeURL("state", oauth2.AccessTypeOffline)
+	if user, ok := config[userKey].(string); ok {
+		c.user = user
+	} else if c.appTokens != nil {
+		// the application has no calendar of its own
+		return fmt.Errorf("missing config key: %s", userKey)
+	}
 
-		if openBrowser {
-			c.logger.Infof("opening browser window for authentication of %s\n", c.Name())
-			err := browser.OpenURL(c.oAuthUrl)
-			if err != nil {
-				c.logger.Infof("browser did not open, please authenticate adapter %s:\n\n %s\n\n\n", c.Name(), c.oAuthUrl)
-			}
-		} else {
-			c.logger.Infof("Please authenticate adapter %s:\n\n %s\n\n\n", c.Name(), c.oAuthUrl)
-		}
-		if err := c.oAuthHandler.Listen(ctx); err != nil {
+	if !c.authenticated {
+		var err error
+		c.oAuthToken, err = c.authorize(ctx, openBrowser)
+		if err != nil {
 			return err
 		}
 
-		c.oAuthToken = c.oAuthHandler.Token()
-		_, err := c.storage.WriteCalendarAuth(auth.CalendarAuth{
+		_, err = c.storage.WriteCalendarAuth(auth.CalendarAuth{
 			CalendarID: c.calendarID,
 			OAuth2: auth.OAuth2Object{
 				AccessToken:  c.oAuthToken.AccessToken,
@@ -200,12 +241,46 @@ func (c *CalendarAPI) Initialize(ctx context.Context, openBrowser bool, config m
 		c.logger.Debug("adapter is already authenticated, loading access token")
 	}
 
-	client := c.oAuthConfig.Client(ctx, c.oAuthToken)
+	var client *http.Client
+	if c.appTokens != nil {
+		client = oauth2.NewClient(ctx, c.appTokens)
+	} else {
+		client = c.oAuthConfig.Client(ctx, c.oAuthToken)
+	}
 
-	c.outlookClient = &OutlookClient{Client: client, CalendarID: c.calendarID}
+	c.outlookClient = &OutlookClient{Client: client, CalendarID: c.calendarID, User: c.user, series: c.series, logger: c.logger}
 	return nil
 }
 
+// authorize lets the user authorize the adapter, either with the device authorization flow or in the browser
+func (c *CalendarAPI) authorize(ctx context.Context, openBrowser bool) (*oauth2.Token, error) {
+	if c.deviceFlow != nil {
+		err := c.deviceFlow.Authorize(ctx, func(userCode string, verificationURL string) {
+			c.logger.Infof("Please authenticate adapter %s with the code %s at:\n\n %s\n\n\n", c.Name(), userCode, verificationURL)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return c.deviceFlow.Token(), nil
+	}
+
+	c.oAuthUrl = c.oAuthHandler.Configuration().AuthCodeURL("state", oauth2.AccessTypeOffline)
+
+	if openBrowser {
+		c.logger.Infof("opening browser window for authentication of %s\n", c.Name())
+		err := browser.OpenURL(c.oAuthUrl)
+		if err != nil {
+			c.logger.Infof("browser did not open, please authenticate adapter %s:\n\n %s\n\n\n", c.Name(), c.oAuthUrl)
+		}
+	} else {
+		c.logger.Infof("Please authenticate adapter %s:\n\n %s\n\n\n", c.Name(), c.oAuthUrl)
+	}
+	if err := c.oAuthHandler.Listen(ctx); err != nil {
+		return nil, err
+	}
+	return c.oAuthHandler.Token(), nil
+}
+
 func (c *CalendarAPI) EventsInTimeframe(ctx context.Context, start time.Time, end time.Time) ([]models.Event, error) {
 	events, err := c.outlookClient.ListEvents(ctx, start, end)
 	if err != nil {
@@ -217,6 +292,21 @@ func (c *CalendarAPI) EventsInTimeframe(ctx context.Context, start time.Time, en
 	return events, nil
 }
 
+func (c *CalendarAPI) ChangedEventsInTimeframe(ctx context.Context, start time.Time, end time.Time, token string) (models.Changes, error) {
+	changes, err := c.outlookClient.ListEventChanges(ctx, start, end, token)
+	if err != nil {
+		return models.Changes{}, err
+	}
+
+	if changes.Full {
+		c.logger.Infof("loaded %d events between %s and %s.", len(changes.Events), start.Format(time.DateOnly), end.Format(time.DateOnly))
+	} else {
+		c.logger.Infof("loaded %d changed and %d deleted events since the last sync.", len(changes.Events), len(changes.Deleted))
+	}
+
+	return changes, nil
+}
+
 func (c *CalendarAPI) CreateEvent(ctx context.Context, e models.Event) error {
 	err := c.outlookClient.CreateEvent(ctx, e)
 	if err != nil {
@@ -261,3 +351,8 @@ func (c *CalendarAPI) Name() string {
 func (c *CalendarAPI) SetLogger(logger *log.Logger) {
 	c.logger = logger
 }
+
+// SetSeries lists series as recurring events with their modified instances instead of their instances
+func (c *CalendarAPI) SetSeries(series bool) {
+	c.series = series
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -25,9 +26,36 @@ const (
 type OutlookClient struct {
 	Client     *http.Client
 	CalendarID string
+	// User is the id or the principal name of the owner of the calendar, the signed-in user if empty
+	User string
+	// series lists series as their master event and modified instances instead of all instances
+	series bool
+	logger *log.Logger
 }
 
 func (o *OutlookClient) ListEvents(ctx context.Context, start time.Time, end time.Time) ([]models.Event, error) {
+	outlookEvents, err := o.listCalendarView(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if o.series {
+		return o.listSeries(ctx, outlookEvents, start, end)
+	}
+
+	var events []models.Event
+	for _, evt := range outlookEvents {
+		evt, err := o.outlookEventToEvent(evt, o.GetCalendarHash())
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, evt)
+	}
+
+	return events, nil
+}
+
+// listCalendarView lists the events and the instances of series in the timeframe
+func (o *OutlookClient) listCalendarView(ctx context.Context, start time.Time, end time.Time) ([]Event, error) {
 	startDate := start.Format(timeFormat)
 	endDate := end.Format(timeFormat)
 
@@ -35,7 +63,12 @@ func (o *OutlookClient) ListEvents(ctx context.Context, start time.Time, end tim
 	// Otherwise this always ends in a 500 return code, see also https://stackoverflow.com/a/62770941
 	query := "?startDateTime=" + startDate + "&endDateTime=" + endDate + "&$expand=extensions($filter=Id%20eq%20'inovex.calendarsync.meta')"
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseUrl+"/me/calendars/"+o.CalendarID+"/CalendarView"+query, nil)
+	return o.listPages(ctx, o.calendarURL()+"/CalendarView"+query)
+}
+
+// listPages loads all pages of the event list starting at the given link
+func (o *OutlookClient) listPages(ctx context.Context, link string) ([]Event, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -49,16 +82,17 @@ func (o *OutlookClient) ListEvents(ctx context.Context, start time.Time, end tim
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, err
-	}
-
 	body, _ := io.ReadAll(resp.Body)
 	err = resp.Body.Close()
 	if err != nil {
 		return nil, err
 	}
 
+	if resp.StatusCode != http.StatusOK {
+		// never return an empty list, which would delete all synced events
+		return nil, fmt.Errorf("status code at event listing was not 200, response: %v", string(body))
+	}
+
 	var eventList EventList
 	err = json.Unmarshal(body, &eventList)
 	if err != nil {
@@ -78,6 +112,10 @@ func (o *OutlookClient) ListEvents(ctx context.Context, start time.Time, end tim
 			return nil, err
 		}
 
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("status code at event listing was not 200, response: %v", string(body))
+		}
+
 		var nextList EventList
 		err = json.Unmarshal(body, &nextList)
 		if err != nil {
@@ -88,29 +126,180 @@ func (o *OutlookClient) ListEvents(ctx context.Context, start time.Time, end tim
 		nextLink = nextList.NextLink
 	}
 
-	var events []models.Event
-	for _, evt := range eventList.Events {
-		evt, err := o.outlookEventToEvent(evt, o.GetCalendarHash())
+	return eventList.Events, nil
+}
+
+// ListEventChanges lists the changes of the calendar view since the delta query which returned the given deltaLink.
+// Delta queries don't return the extensions which hold our metadata, therefore changed events are loaded again.
+// Without a deltaLink, a new delta query is started and all events are listed.
+// See https://learn.microsoft.com/en-us/graph/delta-query-events
+func (o *OutlookClient) ListEventChanges(ctx context.Context, start time.Time, end time.Time, deltaLink string) (models.Changes, error) {
+	if deltaLink == "" {
+		// Start the delta query before listing the events: changes in between are listed by both and are
+		// therefore synced again with the next run instead of being lost.
+		query := "?startDateTime=" + start.Format(timeFormat) + "&endDateTime=" + end.Format(timeFormat)
+		nextDeltaLink, _, err := o.deltaQuery(ctx, o.calendarURL()+"/calendarView/delta"+query)
 		if err != nil {
-			return nil, err
+			return models.Changes{}, err
 		}
-		events = append(events, evt)
+
+		events, err := o.ListEvents(ctx, start, end)
+		if err != nil {
+			return models.Changes{}, err
+		}
+		return models.Changes{Events: events, Token: nextDeltaLink, Full: true}, nil
 	}
 
-	return events, nil
+	nextDeltaLink, deltaEvents, err := o.deltaQuery(ctx, deltaLink)
+	if err != nil {
+		return models.Changes{}, err
+	}
+
+	changes := models.Changes{Token: nextDeltaLink}
+	for _, deltaEvent := range deltaEvents {
+		var event *models.Event
+		if deltaEvent.Removed == nil {
+			event, err = o.getEvent(ctx, deltaEvent.ID)
+			if err != nil {
+				return models.Changes{}, err
+			}
+		}
+
+		if event == nil {
+			changes.Deleted = append(changes.Deleted, models.Event{
+				ID:       deltaEvent.ID,
+				Metadata: models.NewEventMetadata(deltaEvent.ID, "", o.GetCalendarHash()),
+			})
+			continue
+		}
+		changes.Events = append(changes.Events, *event)
+	}
+
+	return changes, nil
+}
+
+// deltaQuery loads all pages of the delta query starting at the given link. It returns the deltaLink for the next
+// query and the changed events, each event is only returned once.
+func (o *OutlookClient) deltaQuery(ctx context.Context, link string) (string, []DeltaEvent, error) {
+	var events []DeltaEvent
+	index := make(map[string]int)
+
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+		if err != nil {
+			return "", nil, err
+		}
+		req.Header.Add("Prefer", "outlook.timezone=\"UTC\"")
+
+		resp, err := o.Client.Do(req)
+		if err != nil {
+			return "", nil, err
+		}
+		body, _ := io.ReadAll(resp.Body)
+		err = resp.Body.Close()
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+		case http.StatusGone:
+			return "", nil, models.ErrSyncTokenInvalid
+		default:
+			return "", nil, fmt.Errorf("status code of delta query was not 200, response: %v", string(body))
+		}
+
+		var deltaList DeltaList
+		if err := json.Unmarshal(body, &deltaList); err != nil {
+			return "", nil, fmt.Errorf("cannot unmarshal response: %w", err)
+		}
+
+		for _, event := range deltaList.Events {
+			// an event may be listed multiple times, the last occurrence is the current state
+			if i, ok := index[event.ID]; ok {
+				events[i] = event
+				continue
+			}
+			index[event.ID] = len(events)
+			events = append(events, event)
+		}
+
+		if deltaList.NextLink == "" {
+			return deltaList.DeltaLink, events, nil
+		}
+		link = deltaList.NextLink
+	}
+}
+
+// getEvent loads a single event including our metadata. It returns nil if the event does not exist anymore.
+func (o *OutlookClient) getEvent(ctx context.Context, id string) (*models.Event, error) {
+	outlookEvent, err := o.getOutlookEvent(ctx, id)
+	if err != nil || outlookEvent == nil {
+		return nil, err
+	}
+
+	event, err := o.outlookEventToEvent(*outlookEvent, o.GetCalendarHash())
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// getOutlookEvent loads a single event in the outlook format. It returns nil if the event does not exist anymore.
+func (o *OutlookClient) getOutlookEvent(ctx context.Context, id string) (*Event, error) {
+	query := "?$expand=extensions($filter=Id%20eq%20'inovex.calendarsync.meta')"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.calendarURL()+"/events/"+id+query, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Prefer", "outlook.timezone=\"UTC\"")
+
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, _ := io.ReadAll(resp.Body)
+	err = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("status code at event retrieval was not 200, response: %v", string(body))
+	}
+
+	var outlookEvent Event
+	if err := json.Unmarshal(body, &outlookEvent); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal response: %w", err)
+	}
+	return &outlookEvent, nil
 }
 
 // CreateEvent creates an event in the outlook sink
 // When an event is sent, the server sends invitations to all the attendees.
 // https://learn.microsoft.com/en-us/graph/api/user-post-events?view=graph-rest-1.0&tabs=http
 func (o *OutlookClient) CreateEvent(ctx context.Context, event models.Event) error {
+	if event.Instance != nil {
+		// the instances of a series are created together with the series, a modified instance updates it
+		return o.updateInstance(ctx, event)
+	}
+
 	outlookEvent := o.eventToOutlookEvent(event)
+	if event.Recurrence != nil {
+		if err := setRecurrence(&outlookEvent, event); err != nil {
+			return err
+		}
+	}
 	by, err := json.Marshal(outlookEvent)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseUrl+"/me/calendars/"+o.CalendarID+"/events", bytes.NewBuffer(by))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.calendarURL()+"/events", bytes.NewBuffer(by))
 	if err != nil {
 		return err
 	}
@@ -127,16 +316,25 @@ func (o *OutlookClient) CreateEvent(ctx context.Context, event models.Event) err
 		}
 	}()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
 	// TODO: we can maybe do this better
 	// the error messages are maybe standardized
 	if resp.StatusCode != http.StatusCreated {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return err
-		}
 		log.Debugf("Create Operation Response Body: %s", string(body))
 		return fmt.Errorf("status code at event creation was not 201, response: %v", string(body))
 	}
+
+	if event.Recurrence != nil && len(event.Recurrence.ExDates) > 0 {
+		var created Event
+		if err := json.Unmarshal(body, &created); err != nil {
+			return fmt.Errorf("cannot unmarshal response: %w", err)
+		}
+		return o.cancelInstances(ctx, created.ID, event)
+	}
 	return nil
 }
 
@@ -146,12 +344,17 @@ func (o *OutlookClient) UpdateEvent(ctx context.Context, event models.Event) err
 	// Normally in a patch operation we would update only the fields which changed
 	// but just update everything for simplicity
 	outlookEvent := o.eventToOutlookEvent(event)
+	if event.Recurrence != nil {
+		if err := setRecurrence(&outlookEvent, event); err != nil {
+			return err
+		}
+	}
 	by, err := json.Marshal(outlookEvent)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, baseUrl+"/me/calendars/"+o.CalendarID+"/events/"+event.ID, bytes.NewBuffer(by))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, o.calendarURL()+"/events/"+event.ID, bytes.NewBuffer(by))
 	if err != nil {
 		return err
 	}
@@ -175,12 +378,16 @@ func (o *OutlookClient) UpdateEvent(ctx context.Context, event models.Event) err
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("status code at event update was not 200, response: %v", string(body))
 	}
+
+	if event.Recurrence != nil {
+		return o.cancelInstances(ctx, event.ID, event)
+	}
 	return nil
 }
 
 func (o *OutlookClient) DeleteEvent(ctx context.Context, event models.Event) error {
 	// https://learn.microsoft.com/en-us/graph/api/event-delete?view=graph-rest-1.0&tabs=http
-	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, baseUrl+"/me/calendars/"+o.CalendarID+"/events/"+event.ID, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, o.calendarURL()+"/events/"+event.ID, nil)
 	if err != nil {
 		return err
 	}
@@ -192,6 +399,14 @@ func (o *OutlookClient) DeleteEvent(ctx context.Context, event models.Event) err
 	return nil
 }
 
+// calendarURL returns the URL of the calendar of the user
+func (o *OutlookClient) calendarURL() string {
+	if o.User != "" {
+		return baseUrl + "/users/" + url.PathEscape(o.User) + "/calendars/" + o.CalendarID
+	}
+	return baseUrl + "/me/calendars/" + o.CalendarID
+}
+
 func (o OutlookClient) GetCalendarHash() string {
 	var id []byte
 	sum := sha1.Sum([]byte(o.CalendarID))
@@ -209,6 +424,13 @@ func (o OutlookClient) eventToOutlookEvent(e models.Event) (oe Event) {
 	outlookEvent.Start.TimeZone = "UTC"
 	outlookEvent.End.DateTime = e.EndTime.UTC().Format(timeFormat)
 	outlookEvent.End.TimeZone = "UTC"
+	if e.TimeZone != "" && !e.AllDay {
+		// keep the time zone of the event, microsoft expects the windows name
+		outlookEvent.Start.DateTime = e.StartTime.In(e.TimeLocation()).Format(timeFormat)
+		outlookEvent.Start.TimeZone = models.WindowsTimeZone(e.TimeZone)
+		outlookEvent.End.DateTime = e.EndTime.In(e.TimeLocation()).Format(timeFormat)
+		outlookEvent.End.TimeZone = models.WindowsTimeZone(e.TimeZone)
+	}
 
 	outlookEvent.Subject = e.Title
 	outlookEvent.ID = e.ID
@@ -290,6 +512,12 @@ func (o OutlookClient) outlookEventToEvent(oe Event, adapterSourceID string) (e
 		hasEventAccepted = false
 	}
 
+	// the times are listed in UTC, the time zone of the event is kept separately
+	var timeZone string
+	if !oe.IsAllDay {
+		timeZone, _ = models.IANATimeZone(oe.OriginalStartTimeZone)
+	}
+
 	bufEvent = models.Event{
 		ICalUID:     oe.UID,
 		ID:          oe.ID,
@@ -298,11 +526,13 @@ func (o OutlookClient) outlookEventToEvent(oe Event, adapterSourceID string) (e
 		Location:    oe.Location.Name,
 		StartTime:   startTime,
 		EndTime:     endTime,
+		TimeZone:    timeZone,
 		Metadata:    ensureMetadata(oe, adapterSourceID),
 		Attendees:   attendees,
 		Reminders:   reminders,
 		MeetingLink: oe.OnlineMeetingUrl,
 		Accepted:    hasEventAccepted,
+		ETag:        oe.ChangeKey,
 	}
 
 	if oe.IsAllDay {
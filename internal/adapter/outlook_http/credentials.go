@@ -0,0 +1,154 @@
+package outlook_http
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	// graphScope requests the application permissions which were granted to the app
+	graphScope = "https://graph.microsoft.com/.default"
+	// clientAssertionType identifies a JWT which authenticates the client, see RFC 7523
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// certificateCredentials authenticate the application with a JWT, which is signed by the private key of the
+// certificate registered for the app
+type certificateCredentials struct {
+	clientID   string
+	tokenURL   string
+	thumbprint string
+	key        *rsa.PrivateKey
+}
+
+// loadCertificateCredentials reads the certificate and its RSA private key from the PEM file
+func loadCertificateCredentials(path string, clientID string, tokenURL string) (*certificateCredentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read certificate file: %w", err)
+	}
+
+	credentials := &certificateCredentials{clientID: clientID, tokenURL: tokenURL}
+	for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+		switch block.Type {
+		case "CERTIFICATE":
+			sum := sha1.Sum(block.Bytes)
+			credentials.thumbprint = base64.RawURLEncoding.EncodeToString(sum[:])
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid private key in %s: %w", path, err)
+			}
+			rsaKey, ok := key.(*rsa.PrivateKey)
+			if !ok {
+				return nil, fmt.Errorf("the private key in %s is not an RSA key", path)
+			}
+			credentials.key = rsaKey
+		case "RSA PRIVATE KEY":
+			credentials.key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("invalid private key in %s: %w", path, err)
+			}
+		}
+	}
+
+	switch {
+	case credentials.thumbprint == "":
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	case credentials.key == nil:
+		return nil, fmt.Errorf("no private key found in %s", path)
+	}
+	return credentials, nil
+}
+
+// assertion returns a signed JWT which is valid for a few minutes
+func (c *certificateCredentials) assertion(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "x5t": c.thumbprint})
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"aud": c.tokenURL,
+		"iss": c.clientID,
+		"sub": c.clientID,
+		"jti": base64.RawURLEncoding.EncodeToString(nonce),
+		"nbf": now.Unix(),
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// certificateTokenSource requests every token with a new assertion, as the assertions expire quickly
+type certificateTokenSource struct {
+	ctx         context.Context
+	credentials *certificateCredentials
+}
+
+func (s certificateTokenSource) Token() (*oauth2.Token, error) {
+	assertion, err := s.credentials.assertion(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	config := clientcredentials.Config{
+		ClientID: s.credentials.clientID,
+		TokenURL: s.credentials.tokenURL,
+		Scopes:   []string{graphScope},
+		EndpointParams: url.Values{
+			"client_assertion_type": {clientAssertionType},
+			"client_assertion":      {assertion},
+		},
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+	return config.Token(s.ctx)
+}
+
+// clientCredentialsTokenSource returns the tokens of the application, which authenticates with the certificate if
+// one is configured and with the client secret otherwise
+func clientCredentialsTokenSource(ctx context.Context, clientID string, secret string, certificateFile string, tokenURL string) (oauth2.TokenSource, error) {
+	if certificateFile != "" {
+		credentials, err := loadCertificateCredentials(certificateFile, clientID, tokenURL)
+		if err != nil {
+			return nil, err
+		}
+		return oauth2.ReuseTokenSource(nil, certificateTokenSource{ctx: ctx, credentials: credentials}), nil
+	}
+
+	config := clientcredentials.Config{
+		ClientID:     clientID,
+		ClientSecret: secret,
+		TokenURL:     tokenURL,
+		Scopes:       []string{graphScope},
+		AuthStyle:    oauth2.AuthStyleInParams,
+	}
+	return config.TokenSource(ctx), nil
+}
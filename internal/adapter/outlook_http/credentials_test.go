@@ -0,0 +1,128 @@
+package outlook_http
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCertificate writes a self-signed certificate and its private key into a PEM file
+func writeCertificate(t *testing.T) (string, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "calendarsync"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})...)
+	path := filepath.Join(t.TempDir(), "app.pem")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path, key
+}
+
+// newTokenServer returns a token endpoint which issues a token if the request is authenticated by check
+func newTokenServer(t *testing.T, check func(t *testing.T, r *http.Request)) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, graphScope, r.PostForm.Get("scope"))
+		check(t, r)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "app-token", "token_type": "Bearer", "expires_in": 3600})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientSecretTokenSource(t *testing.T) {
+	server := newTokenServer(t, func(t *testing.T, r *http.Request) {
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+	})
+
+	tokens, err := clientCredentialsTokenSource(context.Background(), "client", "secret", "", server.URL)
+	require.NoError(t, err)
+	token, err := tokens.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "app-token", token.AccessToken)
+}
+
+func TestCertificateTokenSource(t *testing.T) {
+	path, key := writeCertificate(t)
+	var requests int
+	server := newTokenServer(t, func(t *testing.T, r *http.Request) {
+		requests++
+		assert.Empty(t, r.PostForm.Get("client_secret"))
+		assert.Equal(t, clientAssertionType, r.PostForm.Get("client_assertion_type"))
+
+		parts := strings.Split(r.PostForm.Get("client_assertion"), ".")
+		require.Len(t, parts, 3)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+
+		var header, claims map[string]any
+		data, _ := base64.RawURLEncoding.DecodeString(parts[0])
+		require.NoError(t, json.Unmarshal(data, &header))
+		data, _ = base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, json.Unmarshal(data, &claims))
+		assert.Equal(t, "RS256", header["alg"])
+		assert.NotEmpty(t, header["x5t"])
+		assert.Equal(t, "client", claims["iss"])
+		assert.Equal(t, "client", claims["sub"])
+		assert.Equal(t, "http://"+r.Host, claims["aud"])
+	})
+
+	tokens, err := clientCredentialsTokenSource(context.Background(), "client", "", path, server.URL)
+	require.NoError(t, err)
+	for range 2 {
+		token, err := tokens.Token()
+		require.NoError(t, err)
+		assert.Equal(t, "app-token", token.AccessToken)
+	}
+	// the token is reused until it expires
+	assert.Equal(t, 1, requests)
+}
+
+func TestInvalidCertificateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.pem")
+	require.NoError(t, os.WriteFile(path, []byte("no pem"), 0o600))
+
+	_, err := clientCredentialsTokenSource(context.Background(), "client", "", path, "https://example.com/token")
+	assert.ErrorContains(t, err, "no certificate found")
+}
+
+func TestCalendarURL(t *testing.T) {
+	client := &OutlookClient{CalendarID: "calendar"}
+	assert.Equal(t, baseUrl+"/me/calendars/calendar", client.calendarURL())
+
+	client.User = "room 1@example.com"
+	assert.Equal(t, baseUrl+"/users/room%201@example.com/calendars/calendar", client.calendarURL())
+}
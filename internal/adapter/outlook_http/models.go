@@ -11,6 +11,23 @@ type EventList struct {
 	Events   []Event `json:"value"`
 }
 
+// DeltaList is a page of a calendarView delta query, see https://learn.microsoft.com/en-us/graph/delta-query-events
+type DeltaList struct {
+	NextLink  string       `json:"@odata.nextLink"`
+	DeltaLink string       `json:"@odata.deltaLink"`
+	Events    []DeltaEvent `json:"value"`
+}
+
+// DeltaEvent is an event of a delta query. Only the ID is used, as delta queries don't support expanding the extensions.
+type DeltaEvent struct {
+	ID      string   `json:"id"`
+	Removed *Removed `json:"@removed,omitempty"`
+}
+
+type Removed struct {
+	Reason string `json:"reason"`
+}
+
 type Event struct {
 	ID                         string         `json:"id"`
 	UID                        string         `json:"iCalUId"`
@@ -28,6 +45,41 @@ type Event struct {
 	IsAllDay                   bool           `json:"isAllDay"`
 	OnlineMeetingUrl           string         `json:"onlineMeetingUrl"`
 	ResponseStatus             ResponseStatus `json:"responseStatus,omitempty"`
+	// Type is one of singleInstance, occurrence, exception and seriesMaster
+	Type           string `json:"type,omitempty"`
+	SeriesMasterID string `json:"seriesMasterId,omitempty"`
+	// OriginalStart is the start time of an occurrence or exception according to the recurrence of the series
+	OriginalStart         string               `json:"originalStart,omitempty"`
+	OriginalStartTimeZone string               `json:"originalStartTimeZone,omitempty"`
+	Recurrence            *PatternedRecurrence `json:"recurrence,omitempty"`
+}
+
+// PatternedRecurrence is the recurrence of a series master, see
+// https://learn.microsoft.com/en-us/graph/api/resources/patternedrecurrence?view=graph-rest-1.0
+type PatternedRecurrence struct {
+	Pattern RecurrencePattern `json:"pattern"`
+	Range   RecurrenceRange   `json:"range"`
+}
+
+type RecurrencePattern struct {
+	// Type is one of daily, weekly, absoluteMonthly, relativeMonthly, absoluteYearly and relativeYearly
+	Type           string   `json:"type"`
+	Interval       int      `json:"interval"`
+	Month          int      `json:"month,omitempty"`
+	DayOfMonth     int      `json:"dayOfMonth,omitempty"`
+	DaysOfWeek     []string `json:"daysOfWeek,omitempty"`
+	FirstDayOfWeek string   `json:"firstDayOfWeek,omitempty"`
+	// Index is one of first, second, third, fourth and last
+	Index string `json:"index,omitempty"`
+}
+
+type RecurrenceRange struct {
+	// Type is one of endDate, noEnd and numbered
+	Type                string `json:"type"`
+	StartDate           string `json:"startDate"`
+	EndDate             string `json:"endDate,omitempty"`
+	RecurrenceTimeZone  string `json:"recurrenceTimeZone,omitempty"`
+	NumberOfOccurrences int    `json:"numberOfOccurrences,omitempty"`
 }
 
 type Extensions struct {
@@ -0,0 +1,428 @@
+package outlook_http
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+const (
+	eventTypeOccurrence = "occurrence"
+	eventTypeException  = "exception"
+)
+
+// graphWeekdays are the days of week of microsoft graph, in the order of the rrule weekdays
+var graphWeekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}
+
+// rfc5545Weekdays are the days of week of RFC5545, in the order of the rrule weekdays
+var rfc5545Weekdays = []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+// graphIndices map the week index of relative patterns to the BYSETPOS values of RFC5545
+var graphIndices = map[string]int{"first": 1, "second": 2, "third": 3, "fourth": 4, "last": -1}
+
+// listSeries converts the events of the calendar view, which lists the instances of series. The occurrences of a
+// series are replaced by its master event with the recurrence, modified instances (exceptions) are listed as
+// separate events. Microsoft doesn't list cancelled instances, they are calculated by expanding the recurrence in
+// the timeframe.
+func (o *OutlookClient) listSeries(ctx context.Context, outlookEvents []Event, start time.Time, end time.Time) ([]models.Event, error) {
+	var events []models.Event
+	var seriesIDs []string
+	instances := make(map[string][]Event)
+	for _, outlookEvent := range outlookEvents {
+		if outlookEvent.Type != eventTypeOccurrence && outlookEvent.Type != eventTypeException {
+			event, err := o.outlookEventToEvent(outlookEvent, o.GetCalendarHash())
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, event)
+			continue
+		}
+
+		if _, ok := instances[outlookEvent.SeriesMasterID]; !ok {
+			seriesIDs = append(seriesIDs, outlookEvent.SeriesMasterID)
+		}
+		instances[outlookEvent.SeriesMasterID] = append(instances[outlookEvent.SeriesMasterID], outlookEvent)
+	}
+
+	for _, seriesID := range seriesIDs {
+		master, err := o.getOutlookEvent(ctx, seriesID)
+		if err != nil {
+			return nil, err
+		}
+
+		var seriesEvents []models.Event
+		if master != nil {
+			seriesEvents, err = o.seriesToEvents(*master, instances[seriesID], start, end)
+			if err != nil {
+				o.logger.Warn("listing the instances of series with unsupported recurrence", "title", master.Subject, "error", err)
+			}
+		}
+		if master == nil || err != nil {
+			seriesEvents = nil
+			for _, instance := range instances[seriesID] {
+				event, err := o.outlookEventToEvent(instance, o.GetCalendarHash())
+				if err != nil {
+					return nil, err
+				}
+				seriesEvents = append(seriesEvents, event)
+			}
+		}
+		events = append(events, seriesEvents...)
+	}
+
+	return events, nil
+}
+
+// seriesToEvents converts the master event of a series and its instances in the timeframe
+func (o *OutlookClient) seriesToEvents(master Event, instances []Event, start time.Time, end time.Time) ([]models.Event, error) {
+	if master.Recurrence == nil {
+		return nil, fmt.Errorf("series master without recurrence")
+	}
+	event, err := o.outlookEventToEvent(master, o.GetCalendarHash())
+	if err != nil {
+		return nil, err
+	}
+	recurrence, err := patternToRecurrence(*master.Recurrence, master.OriginalStartTimeZone, event.AllDay)
+	if err != nil {
+		return nil, err
+	}
+	event.Recurrence = recurrence
+
+	events := []models.Event{event}
+	var originalStarts []time.Time
+	for _, instance := range instances {
+		originalStart, err := time.Parse(time.RFC3339, instance.OriginalStart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse originalStart of instance: %w", err)
+		}
+		originalStarts = append(originalStarts, originalStart)
+
+		if instance.Type != eventTypeException {
+			continue
+		}
+		exception, err := o.outlookEventToEvent(instance, o.GetCalendarHash())
+		if err != nil {
+			return nil, err
+		}
+		exception.Instance = &models.SeriesInstance{
+			SeriesID:      master.ID,
+			SeriesSyncID:  event.Metadata.SyncID,
+			OriginalStart: originalStart,
+		}
+		if exception.Metadata.SyncID == event.Metadata.SyncID {
+			// the instance was modified in this calendar and inherited the metadata of the series
+			continue
+		}
+		events = append(events, exception)
+	}
+
+	expected, err := recurrence.Instances(event.StartTime, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, instanceStart := range expected {
+		if !instanceStart.Before(end) {
+			continue
+		}
+		if !slices.ContainsFunc(originalStarts, func(t time.Time) bool { return sameOriginalStart(t, instanceStart, event.AllDay) }) {
+			recurrence.ExDates = append(recurrence.ExDates, instanceStart.UTC())
+		}
+	}
+
+	return events, nil
+}
+
+// updateInstance modifies the instance of a series in the sink, which turns it into an exception
+func (o *OutlookClient) updateInstance(ctx context.Context, event models.Event) error {
+	originalStart := event.Instance.OriginalStart
+	instances, err := o.listInstances(ctx, event.Instance.SeriesID, originalStart.Add(-24*time.Hour), originalStart.Add(24*time.Hour))
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		instanceStart, err := time.Parse(time.RFC3339, instance.OriginalStart)
+		if err == nil && sameOriginalStart(instanceStart, originalStart, event.AllDay) {
+			event.ID = instance.ID
+			return o.UpdateEvent(ctx, event)
+		}
+	}
+	return fmt.Errorf("instance of series %s at %s not found", event.Instance.SeriesID, originalStart.String())
+}
+
+// cancelInstances deletes the instances of the series in the sink which are cancelled in the source
+func (o *OutlookClient) cancelInstances(ctx context.Context, seriesID string, event models.Event) error {
+	exDates := slices.SortedFunc(slices.Values(event.Recurrence.ExDates), time.Time.Compare)
+	if len(exDates) == 0 {
+		return nil
+	}
+	instances, err := o.listInstances(ctx, seriesID, exDates[0].Add(-24*time.Hour), exDates[len(exDates)-1].Add(24*time.Hour))
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		instanceStart, err := time.Parse(time.RFC3339, instance.OriginalStart)
+		if err != nil {
+			continue
+		}
+		if slices.ContainsFunc(exDates, func(t time.Time) bool { return sameOriginalStart(t, instanceStart, event.AllDay) }) {
+			if err := o.DeleteEvent(ctx, models.Event{ID: instance.ID}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listInstances lists the occurrences and exceptions of a series between start and end
+func (o *OutlookClient) listInstances(ctx context.Context, seriesID string, start time.Time, end time.Time) ([]Event, error) {
+	query := "?startDateTime=" + start.UTC().Format(timeFormat) + "&endDateTime=" + end.UTC().Format(timeFormat)
+	return o.listPages(ctx, o.calendarURL()+"/events/"+seriesID+"/instances"+query)
+}
+
+// sameOriginalStart compares the original start times of instances, all-day instances only by their date
+func sameOriginalStart(a, b time.Time, allDay bool) bool {
+	if allDay {
+		return a.Sub(b).Abs() < 24*time.Hour
+	}
+	return a.Equal(b)
+}
+
+// setRecurrence sets the recurrence of the master event of a series. Microsoft expands the pattern in the time zone
+// of the start time, which is therefore set to the time zone of the recurrence.
+func setRecurrence(outlookEvent *Event, event models.Event) error {
+	location, err := event.Recurrence.Location()
+	if err != nil {
+		return err
+	}
+	if event.AllDay {
+		location = time.UTC
+	}
+	pattern, err := recurrenceToPattern(event.Recurrence, event.StartTime.In(location))
+	if err != nil {
+		return err
+	}
+	timeZone := models.WindowsTimeZone(location.String())
+	pattern.Range.RecurrenceTimeZone = timeZone
+	outlookEvent.Recurrence = pattern
+
+	outlookEvent.Start.DateTime = event.StartTime.In(location).Format(timeFormat)
+	outlookEvent.Start.TimeZone = timeZone
+	outlookEvent.End.DateTime = event.EndTime.In(location).Format(timeFormat)
+	outlookEvent.End.TimeZone = timeZone
+	return nil
+}
+
+// patternToRecurrence converts the recurrence pattern of microsoft to an RFC5545 recurrence rule
+func patternToRecurrence(recurrence PatternedRecurrence, startTimeZone string, allDay bool) (*models.Recurrence, error) {
+	timeZone := recurrence.Range.RecurrenceTimeZone
+	if timeZone == "" {
+		timeZone = startTimeZone
+	}
+	timeZone, err := models.IANATimeZone(timeZone)
+	if err != nil {
+		return nil, err
+	}
+	if allDay {
+		// all-day events are listed at midnight UTC
+		timeZone = ""
+	}
+	location := time.UTC
+	if timeZone != "" {
+		location, _ = time.LoadLocation(timeZone)
+	}
+
+	pattern := recurrence.Pattern
+	var rule []string
+	switch pattern.Type {
+	case "daily":
+		rule = append(rule, "FREQ=DAILY")
+	case "weekly":
+		rule = append(rule, "FREQ=WEEKLY")
+	case "absoluteMonthly", "relativeMonthly":
+		rule = append(rule, "FREQ=MONTHLY")
+	case "absoluteYearly", "relativeYearly":
+		rule = append(rule, "FREQ=YEARLY", fmt.Sprintf("BYMONTH=%d", pattern.Month))
+	default:
+		return nil, fmt.Errorf("recurrence pattern %s is not supported", pattern.Type)
+	}
+	if pattern.Interval > 1 {
+		rule = append(rule, fmt.Sprintf("INTERVAL=%d", pattern.Interval))
+	}
+
+	switch pattern.Type {
+	case "weekly", "relativeMonthly", "relativeYearly":
+		var days []string
+		for _, day := range pattern.DaysOfWeek {
+			i := slices.Index(graphWeekdays, day)
+			if i < 0 {
+				return nil, fmt.Errorf("unknown day of week %s", day)
+			}
+			days = append(days, rfc5545Weekdays[i])
+		}
+		rule = append(rule, "BYDAY="+strings.Join(days, ","))
+		if pattern.Type != "weekly" {
+			index, ok := graphIndices[pattern.Index]
+			if !ok {
+				return nil, fmt.Errorf("unknown week index %s", pattern.Index)
+			}
+			rule = append(rule, fmt.Sprintf("BYSETPOS=%d", index))
+		} else if pattern.FirstDayOfWeek != "" && pattern.FirstDayOfWeek != "sunday" {
+			// sunday is the default of microsoft, but not of RFC5545
+			i := slices.Index(graphWeekdays, pattern.FirstDayOfWeek)
+			if i < 0 {
+				return nil, fmt.Errorf("unknown day of week %s", pattern.FirstDayOfWeek)
+			}
+			rule = append(rule, "WKST="+rfc5545Weekdays[i])
+		} else {
+			rule = append(rule, "WKST=SU")
+		}
+	case "absoluteMonthly", "absoluteYearly":
+		rule = append(rule, fmt.Sprintf("BYMONTHDAY=%d", pattern.DayOfMonth))
+	}
+
+	switch recurrence.Range.Type {
+	case "noEnd", "":
+	case "numbered":
+		rule = append(rule, fmt.Sprintf("COUNT=%d", recurrence.Range.NumberOfOccurrences))
+	case "endDate":
+		endDate, err := time.ParseInLocation(time.DateOnly, recurrence.Range.EndDate, location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end date of recurrence: %w", err)
+		}
+		// the end date is inclusive
+		until := endDate.AddDate(0, 0, 1).Add(-time.Second)
+		rule = append(rule, "UNTIL="+until.UTC().Format(models.RFC5545DateTime))
+	default:
+		return nil, fmt.Errorf("recurrence range %s is not supported", recurrence.Range.Type)
+	}
+
+	return &models.Recurrence{Rule: strings.Join(rule, ";"), TimeZone: timeZone}, nil
+}
+
+// recurrenceToPattern converts an RFC5545 recurrence rule of a series starting at start to the recurrence pattern
+// of microsoft. Only the rules which can be represented as a pattern are supported.
+func recurrenceToPattern(recurrence *models.Recurrence, start time.Time) (*PatternedRecurrence, error) {
+	option, err := rrule.StrToROption(recurrence.Rule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule %q: %w", recurrence.Rule, err)
+	}
+	unsupported := fmt.Errorf("recurrence rule %q is not supported", recurrence.Rule)
+	if len(option.Byyearday) > 0 || len(option.Byweekno) > 0 || len(option.Byhour) > 0 ||
+		len(option.Byminute) > 0 || len(option.Bysecond) > 0 || len(option.Byeaster) > 0 ||
+		len(option.Bymonthday) > 1 || len(option.Bymonth) > 1 || len(option.Bysetpos) > 1 {
+		return nil, unsupported
+	}
+
+	pattern := RecurrencePattern{Interval: max(option.Interval, 1)}
+	var days []string
+	index := 0
+	for _, day := range option.Byweekday {
+		days = append(days, graphWeekdays[day.Day()])
+		if day.N() != 0 {
+			if index != 0 && index != day.N() {
+				return nil, unsupported
+			}
+			index = day.N()
+		}
+	}
+	if len(option.Bysetpos) == 1 {
+		if index != 0 {
+			return nil, unsupported
+		}
+		index = option.Bysetpos[0]
+	}
+	if len(days) == 0 {
+		days = []string{graphWeekdays[(int(start.Weekday())+6)%7]}
+	}
+
+	// relative returns the name of the week index, it's empty for absolute patterns
+	relative := func() (string, error) {
+		if len(option.Byweekday) == 0 {
+			return "", nil
+		}
+		if len(option.Bymonthday) > 0 {
+			return "", unsupported
+		}
+		for name, i := range graphIndices {
+			if i == index {
+				return name, nil
+			}
+		}
+		return "", unsupported
+	}
+
+	switch option.Freq {
+	case rrule.DAILY:
+		if len(option.Bymonth) > 0 || len(option.Bymonthday) > 0 || index != 0 {
+			return nil, unsupported
+		}
+		pattern.Type = "daily"
+		if len(option.Byweekday) > 0 {
+			if pattern.Interval > 1 {
+				return nil, unsupported
+			}
+			// a daily rule on some days of the week is a weekly pattern
+			pattern.Type = "weekly"
+			pattern.DaysOfWeek = days
+		}
+	case rrule.WEEKLY:
+		if len(option.Bymonth) > 0 || len(option.Bymonthday) > 0 || index != 0 {
+			return nil, unsupported
+		}
+		pattern.Type = "weekly"
+		pattern.DaysOfWeek = days
+		pattern.FirstDayOfWeek = graphWeekdays[option.Wkst.Day()]
+	case rrule.MONTHLY, rrule.YEARLY:
+		name, err := relative()
+		if err != nil {
+			return nil, err
+		}
+		kind := "Monthly"
+		if option.Freq == rrule.YEARLY {
+			kind = "Yearly"
+			pattern.Month = int(start.Month())
+			if len(option.Bymonth) == 1 {
+				pattern.Month = option.Bymonth[0]
+			}
+		} else if len(option.Bymonth) > 0 {
+			return nil, unsupported
+		}
+		if name != "" {
+			pattern.Type = "relative" + kind
+			pattern.DaysOfWeek = days
+			pattern.Index = name
+		} else {
+			if index != 0 {
+				return nil, unsupported
+			}
+			pattern.Type = "absolute" + kind
+			pattern.DayOfMonth = start.Day()
+			if len(option.Bymonthday) == 1 {
+				pattern.DayOfMonth = option.Bymonthday[0]
+			}
+			if pattern.DayOfMonth < 1 {
+				return nil, unsupported
+			}
+		}
+	default:
+		return nil, unsupported
+	}
+
+	recurrenceRange := RecurrenceRange{Type: "noEnd", StartDate: start.Format(time.DateOnly)}
+	switch {
+	case option.Count > 0:
+		recurrenceRange.Type = "numbered"
+		recurrenceRange.NumberOfOccurrences = option.Count
+	case !option.Until.IsZero():
+		recurrenceRange.Type = "endDate"
+		recurrenceRange.EndDate = option.Until.In(start.Location()).Format(time.DateOnly)
+	}
+
+	return &PatternedRecurrence{Pattern: pattern, Range: recurrenceRange}, nil
+}
@@ -18,6 +18,12 @@ type CalendarIDSetter interface {
 	SetCalendarID(calendarID string) error
 }
 
+// SeriesSetter can be implemented by a struct to list series as recurring events with their modified instances
+// instead of listing every instance as a separate event
+type SeriesSetter interface {
+	SetSeries(series bool)
+}
+
 // Configurable is an interface which defines how arbitrary configuration data can be passed
 // to a struct which implements this interface. Clients should be configurable.
 type Configurable interface {
@@ -10,7 +10,12 @@ import (
 
 	"github.com/charmbracelet/log"
 
+	"github.com/inovex/CalendarSync/internal/adapter/caldav"
+	"github.com/inovex/CalendarSync/internal/adapter/ews"
+	"github.com/inovex/CalendarSync/internal/adapter/feed"
 	"github.com/inovex/CalendarSync/internal/adapter/google"
+	"github.com/inovex/CalendarSync/internal/adapter/ics"
+	"github.com/inovex/CalendarSync/internal/adapter/jmap"
 	outlook "github.com/inovex/CalendarSync/internal/adapter/outlook_http"
 	"github.com/inovex/CalendarSync/internal/adapter/port"
 	"github.com/inovex/CalendarSync/internal/sync"
@@ -30,6 +35,16 @@ func SinkClientFactory(typ Type) (sync.Sink, error) {
 		return new(google.CalendarAPI), nil
 	case OutlookHttpCalendarType:
 		return new(outlook.CalendarAPI), nil
+	case CalDavCalendarType:
+		return new(caldav.CalendarAPI), nil
+	case ICSCalendarType:
+		return new(ics.CalendarAPI), nil
+	case FeedCalendarType:
+		return new(feed.Calendar), nil
+	case EWSCalendarType:
+		return new(ews.CalendarAPI), nil
+	case JMAPCalendarType:
+		return new(jmap.CalendarAPI), nil
 	default:
 		return nil, fmt.Errorf("unknown sink adapter client type %s", typ)
 	}
@@ -51,16 +66,29 @@ func NewSinkAdapterFromConfig(ctx context.Context, bindPort uint, openBrowser bo
 		}
 	}
 
+	if c, ok := client.(port.SeriesSetter); ok {
+		c.SetSeries(config.Series())
+	} else if config.Series() {
+		logger.Warn("adapter does not support recurring events, every instance is synced as a separate event")
+	}
+
 	if c, ok := client.(port.OAuth2Adapter); ok {
 		if err := c.SetupOauth2(ctx,
 			auth.Credentials{
 				Client: auth.Client{
-					Id:     config.Adapter().OAuth.ClientID,
-					Secret: config.Adapter().OAuth.ClientKey,
+					Id:              config.Adapter().OAuth.ClientID,
+					Secret:          config.Adapter().OAuth.ClientKey,
+					CertificateFile: config.Adapter().OAuth.CertificateFile,
 				},
 				Tenant: auth.Tenant{
 					Id: config.Adapter().OAuth.TenantID,
 				},
+				ServiceAccount: auth.ServiceAccount{
+					KeyFile: config.Adapter().OAuth.ServiceAccountKeyFile,
+					Subject: config.Adapter().OAuth.Subject,
+				},
+				DeviceFlow:        config.Adapter().OAuth.DeviceFlow(),
+				ClientCredentials: config.Adapter().OAuth.ClientCredentials(),
 			},
 			storage,
 			bindPort,
@@ -92,6 +120,11 @@ func (a SinkAdapter) CalendarID() string {
 	return a.calendarID
 }
 
+// Client returns the adapter client, e.g. to access capabilities which are not part of sync.Sink
+func (a SinkAdapter) Client() sync.Sink {
+	return a.client
+}
+
 func (a SinkAdapter) CreateEvent(ctx context.Context, e models.Event) error {
 	err := a.client.CreateEvent(ctx, e)
 	return err
@@ -105,6 +138,14 @@ func (a SinkAdapter) DeleteEvent(ctx context.Context, e models.Event) error {
 	return a.client.DeleteEvent(ctx, e)
 }
 
+// Flush writes the buffered changes if the client buffers them, see sync.BufferedSink
+func (a SinkAdapter) Flush(ctx context.Context) error {
+	if c, ok := a.client.(sync.BufferedSink); ok {
+		return c.Flush(ctx)
+	}
+	return nil
+}
+
 func (a SinkAdapter) EventsInTimeframe(ctx context.Context, start time.Time, end time.Time) ([]models.Event, error) {
 	return a.client.EventsInTimeframe(ctx, start, end)
 }
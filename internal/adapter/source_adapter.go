@@ -12,7 +12,11 @@ import (
 	outlook "github.com/inovex/CalendarSync/internal/adapter/outlook_http"
 	"github.com/inovex/CalendarSync/internal/adapter/port"
 
+	"github.com/inovex/CalendarSync/internal/adapter/caldav"
+	"github.com/inovex/CalendarSync/internal/adapter/ews"
 	"github.com/inovex/CalendarSync/internal/adapter/google"
+	"github.com/inovex/CalendarSync/internal/adapter/ics"
+	"github.com/inovex/CalendarSync/internal/adapter/jmap"
 	"github.com/inovex/CalendarSync/internal/adapter/zep"
 	"github.com/inovex/CalendarSync/internal/sync"
 )
@@ -26,6 +30,14 @@ func SourceClientFactory(typ Type) (sync.Source, error) {
 		return new(zep.CalendarAPI), nil
 	case OutlookHttpCalendarType:
 		return new(outlook.CalendarAPI), nil
+	case CalDavCalendarType:
+		return new(caldav.CalendarAPI), nil
+	case ICSCalendarType:
+		return new(ics.CalendarAPI), nil
+	case EWSCalendarType:
+		return new(ews.CalendarAPI), nil
+	case JMAPCalendarType:
+		return new(jmap.CalendarAPI), nil
 	default:
 		return nil, fmt.Errorf("unknown source adapter client type %s", typ)
 	}
@@ -55,16 +67,29 @@ func NewSourceAdapterFromConfig(ctx context.Context, bindPort uint, openBrowser
 		}
 	}
 
+	if c, ok := client.(port.SeriesSetter); ok {
+		c.SetSeries(config.Series())
+	} else if config.Series() {
+		logger.Warn("adapter does not support recurring events, every instance is synced as a separate event")
+	}
+
 	if c, ok := client.(port.OAuth2Adapter); ok {
 		if err := c.SetupOauth2(ctx,
 			auth.Credentials{
 				Client: auth.Client{
-					Id:     config.Adapter().OAuth.ClientID,
-					Secret: config.Adapter().OAuth.ClientKey,
+					Id:              config.Adapter().OAuth.ClientID,
+					Secret:          config.Adapter().OAuth.ClientKey,
+					CertificateFile: config.Adapter().OAuth.CertificateFile,
 				},
 				Tenant: auth.Tenant{
 					Id: config.Adapter().OAuth.TenantID,
 				},
+				ServiceAccount: auth.ServiceAccount{
+					KeyFile: config.Adapter().OAuth.ServiceAccountKeyFile,
+					Subject: config.Adapter().OAuth.Subject,
+				},
+				DeviceFlow:        config.Adapter().OAuth.DeviceFlow(),
+				ClientCredentials: config.Adapter().OAuth.ClientCredentials(),
 			},
 			storage,
 			bindPort,
@@ -103,3 +128,17 @@ func (a SourceAdapter) GetCalendarHash() string {
 func (a SourceAdapter) EventsInTimeframe(ctx context.Context, start time.Time, end time.Time) ([]models.Event, error) {
 	return a.client.EventsInTimeframe(ctx, start, end)
 }
+
+// ChangedEventsInTimeframe returns the changes since the listing of the given token if the client supports
+// incremental listings. Otherwise, it always returns a full listing.
+func (a SourceAdapter) ChangedEventsInTimeframe(ctx context.Context, start time.Time, end time.Time, token string) (models.Changes, error) {
+	if c, ok := a.client.(sync.IncrementalSource); ok {
+		return c.ChangedEventsInTimeframe(ctx, start, end, token)
+	}
+
+	events, err := a.client.EventsInTimeframe(ctx, start, end)
+	if err != nil {
+		return models.Changes{}, err
+	}
+	return models.Changes{Events: events, Full: true}, nil
+}
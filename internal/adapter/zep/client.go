@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/inovex/CalendarSync/internal/adapter/icalendar"
 	"github.com/inovex/CalendarSync/internal/adapter/port"
 	"github.com/inovex/CalendarSync/internal/models"
 
@@ -33,6 +34,7 @@ type CalendarAPI struct {
 
 	calendarID string
 	logger     *log.Logger
+	series     bool
 
 	principal string
 	homeSet   string
@@ -42,6 +44,7 @@ type CalendarAPI struct {
 var _ port.Configurable = &CalendarAPI{}
 var _ port.LogSetter = &CalendarAPI{}
 var _ port.CalendarIDSetter = &CalendarAPI{}
+var _ port.SeriesSetter = &CalendarAPI{}
 
 func (zep *CalendarAPI) SetCalendarID(calendarID string) error {
 	if calendarID == "" {
@@ -51,6 +54,11 @@ func (zep *CalendarAPI) SetCalendarID(calendarID string) error {
 	return nil
 }
 
+// SetSeries lists series as recurring events with their modified instances instead of their master event
+func (zep *CalendarAPI) SetSeries(series bool) {
+	zep.series = series
+}
+
 func (zep *CalendarAPI) GetCalendarHash() string {
 	var id []byte
 	components := []string{zep.username, zep.homeSet, zep.calendarID}
@@ -100,34 +108,65 @@ func (zep *CalendarAPI) Initialize(ctx context.Context, openBrowser bool, config
 }
 
 func (zep *CalendarAPI) EventsInTimeframe(ctx context.Context, start time.Time, end time.Time) ([]models.Event, error) {
-	events, err := zep.ListEvents(start, end)
+	objects, err := zep.queryCalendar(start, end)
 	if err != nil {
 		return nil, fmt.Errorf("could not get zep events %w", err)
 	}
 
 	var syncEvents []models.Event
+	for _, object := range objects {
+		if zep.series && isSeries(object.Data) {
+			events, err := zep.seriesToEvents(object, start, end)
+			if err != nil {
+				zep.logger.Warn("skipping series", "path", object.Path, "error", err)
+				continue
+			}
+			syncEvents = append(syncEvents, events...)
+			continue
+		}
 
-	zep.logger.Infof("loaded %d events between %s and %s.", len(events), start.Format(time.DateOnly), end.Format(time.DateOnly))
-
-	for _, v := range events {
-		syncEvents = append(syncEvents,
-			models.Event{
-				ICalUID:     v.ID,
-				Title:       v.Summary,
-				Description: v.Description,
-				StartTime:   v.Start,
-				EndTime:     v.End,
-				AllDay:      v.AllDay,
-				Accepted:    true,
-				Metadata:    models.NewEventMetadata(v.ID, "", zep.GetCalendarHash()),
-			})
+		for _, v := range eventsFromCalDavObject(object) {
+			syncEvents = append(syncEvents, zep.toEvent(v))
+		}
 	}
 
+	zep.logger.Infof("loaded %d events between %s and %s.", len(syncEvents), start.Format(time.DateOnly), end.Format(time.DateOnly))
+
 	return syncEvents, nil
 }
 
+// toEvent converts the ZEP event to a models.Event
+func (zep *CalendarAPI) toEvent(v Event) models.Event {
+	return models.Event{
+		ICalUID:     v.ID,
+		Title:       v.Summary,
+		Description: v.Description,
+		StartTime:   v.Start,
+		EndTime:     v.End,
+		TimeZone:    v.TimeZone,
+		AllDay:      v.AllDay,
+		Accepted:    true,
+		Metadata:    models.NewEventMetadata(v.ID, "", zep.GetCalendarHash()),
+	}
+}
+
 // ListEvents returns all events of the given calendar of a user (if it exists).
 func (zep *CalendarAPI) ListEvents(from, to time.Time) ([]Event, error) {
+	objects, err := zep.queryCalendar(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, object := range objects {
+		events = append(events, eventsFromCalDavObject(object)...)
+	}
+
+	return events, nil
+}
+
+// queryCalendar returns the calendar objects with events inside the given time range (inclusive).
+func (zep *CalendarAPI) queryCalendar(from, to time.Time) ([]caldav.CalendarObject, error) {
 	calendars, err := zep.client.FindCalendars(context.Background(), zep.homeSet)
 	if err != nil {
 		return nil, fmt.Errorf("cannot find calendars: %w", err)
@@ -168,35 +207,37 @@ func (zep *CalendarAPI) ListEvents(from, to time.Time) ([]Event, error) {
 		return nil, fmt.Errorf("unable to query calendar %s: %w", eventCalendar.Path, err)
 	}
 
+	return ret, nil
+}
+
+// eventsFromCalDavObject deconstructs the events of the calendar object if there are any
+func eventsFromCalDavObject(object caldav.CalendarObject) []Event {
 	var events []Event
 
-	// start deconstructing the events if there are any
-	for _, object := range ret {
-		if len(object.Data.Events()) > 0 {
-
-			for _, calDavEvent := range object.Data.Events() {
-				event, err := eventFromCalDavEvent(calDavEvent, object.ETag)
-				if err != nil {
-					// todo: handle properly
-					log.Error(err)
-					continue
-				}
-				events = append(events, event)
-			}
+	zones := icalendar.TimeZones(object.Data)
+
+	for _, calDavEvent := range object.Data.Events() {
+		event, err := eventFromCalDavEvent(calDavEvent, object.ETag, zones)
+		if err != nil {
+			// todo: handle properly
+			log.Error(err)
+			continue
 		}
+		events = append(events, event)
 	}
 
-	return events, nil
+	return events
 }
 
-// todo: read timezone from event, not just assume time.Local
-func eventFromCalDavEvent(event ical.Event, etag string) (Event, error) {
-	start, err := event.Props.Get("dtstart").DateTime(time.Local)
+// eventFromCalDavEvent converts the event, the times are parsed in the time zone given by their TZID.
+// Dates are parsed in UTC and times without a time zone in the local time zone.
+func eventFromCalDavEvent(event ical.Event, etag string, zones map[string]icalendar.TimeZone) (Event, error) {
+	start, timeZone, err := icalendar.ParseDateTime(event.Props.Get("dtstart"), zones)
 	if err != nil {
 		return Event{}, fmt.Errorf("unable to decode dtstart: %w", err)
 	}
 
-	end, err := event.Props.Get("dtend").DateTime(time.Local)
+	end, _, err := icalendar.ParseDateTime(event.Props.Get("dtend"), zones)
 	if err != nil {
 		return Event{}, fmt.Errorf("unable to decode dtend: %w", err)
 	}
@@ -209,6 +250,7 @@ func eventFromCalDavEvent(event ical.Event, etag string) (Event, error) {
 		ID:          event.Props.Get("uid").Value,
 		Start:       start,
 		End:         end,
+		TimeZone:    timeZone,
 		AllDay:      allDay,
 		Summary:     safeGetComponentPropValueString(event, "summary"),
 		Category:    safeGetComponentPropValueString(event, "categories"),
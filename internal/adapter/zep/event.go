@@ -14,6 +14,7 @@ type Event struct {
 	ID          string
 	Start       time.Time
 	End         time.Time
+	TimeZone    string // IANA name of the time zone of start and end, empty if unknown
 	AllDay      bool
 	Summary     string
 	Description string
@@ -0,0 +1,30 @@
+package zep
+
+import (
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav/caldav"
+
+	"github.com/inovex/CalendarSync/internal/adapter/icalendar"
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// isSeries returns true if the calendar object contains the master event of a series
+func isSeries(calendar *ical.Calendar) bool {
+	for _, event := range calendar.Events() {
+		if event.Props.Get(ical.PropRecurrenceID) == nil && event.Props.Get(ical.PropRecurrenceRule) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// seriesToEvents converts a calendar object with a series without expanding it: the series is listed as its
+// master event with the recurrence, modified instances are listed as separate events. The cancelled instances
+// within the timeframe are added to the ExDates of the master event. The SyncID of the master event is derived
+// from its UID like the SyncID of single events.
+func (zep *CalendarAPI) seriesToEvents(object caldav.CalendarObject, start time.Time, end time.Time) ([]models.Event, error) {
+	decoder := icalendar.Decoder{SourceID: zep.GetCalendarHash(), Series: true, Start: start, End: end}
+	return decoder.Decode(object.Data, "", object.ETag)
+}
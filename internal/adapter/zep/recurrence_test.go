@@ -0,0 +1,70 @@
+package zep
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav/caldav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const seriesObject = `BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//ZEP//EN
+BEGIN:VEVENT
+UID:series-1
+DTSTAMP:20240301T000000Z
+DTSTART:20240304T090000Z
+DTEND:20240304T093000Z
+SUMMARY:Daily
+RRULE:FREQ=DAILY;COUNT=10
+EXDATE:20240305T090000Z,20240401T090000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:series-1
+DTSTAMP:20240301T000000Z
+RECURRENCE-ID:20240306T090000Z
+DTSTART:20240306T100000Z
+DTEND:20240306T103000Z
+SUMMARY:Daily (moved)
+END:VEVENT
+BEGIN:VEVENT
+UID:series-1
+DTSTAMP:20240301T000000Z
+RECURRENCE-ID:20240307T090000Z
+DTSTART:20240307T090000Z
+DTEND:20240307T093000Z
+STATUS:CANCELLED
+SUMMARY:Daily
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestSeriesToEvents(t *testing.T) {
+	data, err := ical.NewDecoder(strings.NewReader(strings.ReplaceAll(seriesObject, "\n", "\r\n"))).Decode()
+	require.NoError(t, err)
+	require.True(t, isSeries(data))
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	zep := &CalendarAPI{}
+	events, err := zep.seriesToEvents(caldav.CalendarObject{Data: data, ETag: "etag"}, start, end)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	series := events[0]
+	assert.Equal(t, "Daily", series.Title)
+	assert.Equal(t, "FREQ=DAILY;COUNT=10", series.Recurrence.Rule)
+	assert.Len(t, series.Recurrence.ExDates, 2)
+	assert.True(t, series.Recurrence.ExDates[0].Equal(time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)))
+	assert.True(t, series.Recurrence.ExDates[1].Equal(time.Date(2024, 3, 7, 9, 0, 0, 0, time.UTC)))
+
+	instance := events[1]
+	assert.Equal(t, "Daily (moved)", instance.Title)
+	assert.Equal(t, series.Metadata.SyncID, instance.Instance.SeriesSyncID)
+	assert.NotEqual(t, series.Metadata.SyncID, instance.Metadata.SyncID)
+	assert.True(t, instance.Instance.OriginalStart.Equal(time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/oauth2"
+)
+
+// DeviceFlow authorizes the user with the device authorization grant (RFC 8628). The user enters a code on any device
+// with a browser, so no local redirect listener has to be reachable.
+type DeviceFlow struct {
+	config oauth2.Config
+	token  *oauth2.Token
+}
+
+func NewDeviceFlow(config oauth2.Config) *DeviceFlow {
+	return &DeviceFlow{config: config}
+}
+
+func (d *DeviceFlow) Configuration() *oauth2.Config {
+	return &d.config
+}
+
+func (d *DeviceFlow) Token() *oauth2.Token {
+	return d.token
+}
+
+// Authorize requests a device code and passes the user code and the verification URL to prompt. It then polls the
+// token endpoint until the user authorized the device, denied the access or the code expired.
+func (d *DeviceFlow) Authorize(ctx context.Context, prompt func(userCode string, verificationURL string)) error {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, http.DefaultClient)
+
+	response, err := d.config.DeviceAuth(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to request device code: %w", err)
+	}
+
+	if response.VerificationURIComplete != "" {
+		prompt(response.UserCode, response.VerificationURIComplete)
+	} else {
+		prompt(response.UserCode, response.VerificationURI)
+	}
+
+	d.token, err = d.config.DeviceAccessToken(ctx, response)
+	if err != nil {
+		return fmt.Errorf("device authorization failed: %w", err)
+	}
+	return nil
+}
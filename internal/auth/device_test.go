@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// newTokenServer returns a fake authorization server which answers the token requests with the given errors before
+// it issues the token
+func newTokenServer(t *testing.T, errors ...string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /device", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "client" || r.FormValue("scope") != "calendar" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://example.com/device",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" || r.FormValue("device_code") != "device-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if len(errors) > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": errors[0]})
+			errors = errors[1:]
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "access-token",
+			"refresh_token": "refresh-token",
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+		})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestDeviceFlow(server *httptest.Server) *DeviceFlow {
+	return NewDeviceFlow(oauth2.Config{
+		ClientID: "client",
+		Endpoint: oauth2.Endpoint{
+			DeviceAuthURL: server.URL + "/device",
+			TokenURL:      server.URL + "/token",
+			AuthStyle:     oauth2.AuthStyleInParams,
+		},
+		Scopes: []string{"calendar"},
+	})
+}
+
+func TestDeviceFlow(t *testing.T) {
+	flow := newTestDeviceFlow(newTokenServer(t, "authorization_pending"))
+
+	var userCode, verificationURL string
+	err := flow.Authorize(context.Background(), func(code string, url string) {
+		userCode, verificationURL = code, url
+	})
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if userCode != "ABCD-EFGH" || verificationURL != "https://example.com/device" {
+		t.Fatalf("got code %s at %s, expected ABCD-EFGH at https://example.com/device", userCode, verificationURL)
+	}
+	token := flow.Token()
+	if token.AccessToken != "access-token" || token.RefreshToken != "refresh-token" {
+		t.Fatalf("got access token '%s' and refresh token '%s'", token.AccessToken, token.RefreshToken)
+	}
+}
+
+func TestDeviceFlowDenied(t *testing.T) {
+	flow := newTestDeviceFlow(newTokenServer(t, "access_denied"))
+
+	err := flow.Authorize(context.Background(), func(string, string) {})
+	if err == nil || !strings.Contains(err.Error(), "access_denied") {
+		t.Fatalf("got error '%v', expected access_denied", err)
+	}
+	if flow.Token() != nil {
+		t.Fatalf("got token %v, expected none", flow.Token())
+	}
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+)
+
+// lockFile acquires an exclusive advisory lock on path, waiting until other processes released it. The lock is held
+// until the returned function is called. The lock file is never removed, as another process might wait on it.
+func lockFile(path string) (func() error, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lock(file); err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return func() error {
+		if err := unlock(file); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("failed to unlock %s: %w", path, err)
+		}
+		return file.Close()
+	}, nil
+}
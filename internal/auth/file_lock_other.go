@@ -0,0 +1,14 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package auth
+
+import "os"
+
+// lock is a no-op on platforms without advisory file locks, only the atomic writes protect the storage there
+func lock(*os.File) error {
+	return nil
+}
+
+func unlock(*os.File) error {
+	return nil
+}
@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockRange locks the first byte of the file, LockFileEx doesn't support locking the whole file
+const lockRange = 1
+
+func lock(file *os.File) error {
+	return windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, lockRange, 0, new(windows.Overlapped))
+}
+
+func unlock(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, lockRange, 0, new(windows.Overlapped))
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/zalando/go-keyring"
+
+	"github.com/inovex/CalendarSync/internal/config"
+)
+
+const (
+	defaultKeyringService = "calendarsync"
+	// keyringFallbackEnv enables the unencrypted fallback file like the fallback_path config
+	keyringFallbackEnv = "CALENDARSYNC_KEYRING_FALLBACK_PATH"
+	// keyringProbe is looked up to check whether the keyring of the OS is available
+	keyringProbe = "calendarsync-probe"
+)
+
+// secretStore stores the secrets of a service by their key
+type secretStore interface {
+	Get(service, key string) (string, error)
+	Set(service, key, secret string) error
+	Delete(service, key string) error
+}
+
+// osKeyring is the Secret Service on Linux, the Keychain on macOS and the Credential Manager on Windows
+type osKeyring struct{}
+
+func (osKeyring) Get(service, key string) (string, error) {
+	return keyring.Get(service, key)
+}
+
+func (osKeyring) Set(service, key, secret string) error {
+	return keyring.Set(service, key, secret)
+}
+
+func (osKeyring) Delete(service, key string) error {
+	return keyring.Delete(service, key)
+}
+
+// fileKeyring stores the secrets unencrypted in a file which only the user can read. It's meant for machines
+// without a keyring, e.g. CI runners without a D-Bus session.
+type fileKeyring struct {
+	path string
+}
+
+func (f *fileKeyring) read() (map[string]map[string]string, error) {
+	secrets := make(map[string]map[string]string)
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return secrets, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read keyring file: %w", err)
+	}
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal keyring file: %w", err)
+	}
+	return secrets, nil
+}
+
+func (f *fileKeyring) write(secrets map[string]map[string]string) error {
+	data, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(f.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write keyring file: %w", err)
+	}
+	return nil
+}
+
+func (f *fileKeyring) Get(service, key string) (string, error) {
+	secrets, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	secret, ok := secrets[service][key]
+	if !ok {
+		return "", keyring.ErrNotFound
+	}
+	return secret, nil
+}
+
+func (f *fileKeyring) Set(service, key, secret string) error {
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+	if secrets[service] == nil {
+		secrets[service] = make(map[string]string)
+	}
+	secrets[service][key] = secret
+	return f.write(secrets)
+}
+
+func (f *fileKeyring) Delete(service, key string) error {
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[service][key]; !ok {
+		return keyring.ErrNotFound
+	}
+	delete(secrets[service], key)
+	return f.write(secrets)
+}
+
+// KeyringStorage stores the auth of every calendar as a separate secret in the keyring of the OS, so no encryption
+// passphrase is needed
+type KeyringStorage struct {
+	// Service is the name under which the secrets are stored
+	Service string
+	secrets secretStore
+}
+
+func (k *KeyringStorage) Setup(config config.AuthStorage, encryptionPassphrase string) error {
+	k.Service = defaultKeyringService
+	if service, ok := config.Config["service"].(string); ok && service != "" {
+		k.Service = service
+	}
+
+	k.secrets = osKeyring{}
+	_, err := k.secrets.Get(k.Service, keyringProbe)
+	if err == nil || errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+
+	// the unencrypted fallback is only meant for machines without a keyring and therefore has to be enabled explicitly
+	fallbackPath, _ := config.Config["fallback_path"].(string)
+	if fallbackPath == "" {
+		fallbackPath = os.Getenv(keyringFallbackEnv)
+	}
+	if fallbackPath == "" {
+		return fmt.Errorf("the keyring of the OS is not available, set fallback_path or $%s to store the credentials unencrypted in a file instead: %w", keyringFallbackEnv, err)
+	}
+	fallbackPath, pathErr := expandHome(fallbackPath)
+	if pathErr != nil {
+		return pathErr
+	}
+	log.Warn("the keyring of the OS is not available, falling back to an unencrypted file", "path", fallbackPath, "error", err)
+	k.secrets = &fileKeyring{path: fallbackPath}
+	return nil
+}
+
+func (k *KeyringStorage) WriteCalendarAuth(newCal CalendarAuth) (bool, error) {
+	data, err := json.Marshal(newCal.OAuth2)
+	if err != nil {
+		return false, err
+	}
+	err = k.secrets.Set(k.Service, newCal.CalendarID, string(data))
+	if errors.Is(err, keyring.ErrSetDataTooBig) {
+		// the Credential Manager limits the size of secrets, without the access token it is refreshed on the next run
+		log.Debug("access token too big for the keyring, only storing the refresh token", "calendar", newCal.CalendarID)
+		withoutAccessToken := newCal.OAuth2
+		withoutAccessToken.AccessToken = ""
+		withoutAccessToken.Expiry = time.Time{}.Format(time.RFC3339)
+		data, err = json.Marshal(withoutAccessToken)
+		if err != nil {
+			return false, err
+		}
+		err = k.secrets.Set(k.Service, newCal.CalendarID, string(data))
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to store calendar auth in keyring: %w", err)
+	}
+	return true, nil
+}
+
+func (k *KeyringStorage) ReadCalendarAuth(calendarID string) (*CalendarAuth, error) {
+	data, err := k.secrets.Get(k.Service, calendarID)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read calendar auth from keyring: %w", err)
+	}
+
+	cal := &CalendarAuth{CalendarID: calendarID}
+	if err := json.Unmarshal([]byte(data), &cal.OAuth2); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal calendar auth: %w", err)
+	}
+	return cal, nil
+}
+
+func (k *KeyringStorage) RemoveCalendarAuth(calendarID string) error {
+	err := k.secrets.Delete(k.Service, calendarID)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("failed to remove calendar auth from keyring: %w", err)
+	}
+	return nil
+}
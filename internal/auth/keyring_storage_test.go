@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+
+	"github.com/inovex/CalendarSync/internal/config"
+)
+
+// tooBigKeyring rejects secrets which contain an access token, like the size limit of the Credential Manager
+type tooBigKeyring struct {
+	fileKeyring
+}
+
+func (t *tooBigKeyring) Set(service, key, secret string) error {
+	if strings.Contains(secret, "access-token") {
+		return keyring.ErrSetDataTooBig
+	}
+	return t.fileKeyring.Set(service, key, secret)
+}
+
+func testCalendarAuth() CalendarAuth {
+	return CalendarAuth{
+		CalendarID: "primary",
+		OAuth2: OAuth2Object{
+			AccessToken:  "access-token",
+			RefreshToken: "refresh-token",
+			Expiry:       "2024-03-01T10:00:00Z",
+			TokenType:    "Bearer",
+		},
+	}
+}
+
+func testKeyringStorage(t *testing.T, secrets secretStore) {
+	storage := &KeyringStorage{Service: "calendarsync-test", secrets: secrets}
+
+	cal, err := storage.ReadCalendarAuth("primary")
+	if err != nil || cal != nil {
+		t.Fatalf("got auth %v and error '%v', expected none", cal, err)
+	}
+
+	if _, err := storage.WriteCalendarAuth(testCalendarAuth()); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	cal, err = storage.ReadCalendarAuth("primary")
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if *cal != testCalendarAuth() {
+		t.Fatalf("got auth %v, expected %v", *cal, testCalendarAuth())
+	}
+
+	if err := storage.RemoveCalendarAuth("primary"); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if cal, err = storage.ReadCalendarAuth("primary"); err != nil || cal != nil {
+		t.Fatalf("got auth %v and error '%v', expected none", cal, err)
+	}
+	// removing the auth again is no error
+	if err := storage.RemoveCalendarAuth("primary"); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+}
+
+func TestKeyringStorage(t *testing.T) {
+	keyring.MockInit()
+	testKeyringStorage(t, osKeyring{})
+}
+
+func TestFileKeyringStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	testKeyringStorage(t, &fileKeyring{path: path})
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("got permissions %v, expected 0600", info.Mode().Perm())
+	}
+}
+
+func TestKeyringStorageFallback(t *testing.T) {
+	keyring.MockInitWithError(os.ErrPermission)
+	defer keyring.MockInit()
+
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	storage, err := StorageFactory("keyring")
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if err := storage.Setup(config.AuthStorage{StorageMode: "keyring", Config: config.CustomMap{"fallback_path": path}}, ""); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if _, err := storage.WriteCalendarAuth(testCalendarAuth()); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("got error '%v', expected the fallback file", err)
+	}
+}
+
+func TestKeyringStorageWithoutFallback(t *testing.T) {
+	keyring.MockInitWithError(os.ErrPermission)
+	defer keyring.MockInit()
+	t.Setenv(keyringFallbackEnv, "")
+
+	storage := &KeyringStorage{}
+	err := storage.Setup(config.AuthStorage{StorageMode: "keyring", Config: config.CustomMap{}}, "")
+	if !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("got error '%v', expected the keyring error", err)
+	}
+}
+
+func TestKeyringStorageFallbackFromEnv(t *testing.T) {
+	keyring.MockInitWithError(os.ErrPermission)
+	defer keyring.MockInit()
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	t.Setenv(keyringFallbackEnv, path)
+
+	storage := &KeyringStorage{}
+	if err := storage.Setup(config.AuthStorage{StorageMode: "keyring", Config: config.CustomMap{}}, ""); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if _, err := storage.WriteCalendarAuth(testCalendarAuth()); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("got error '%v', expected the fallback file", err)
+	}
+}
+
+func TestKeyringStorageWithoutAccessToken(t *testing.T) {
+	storage := &KeyringStorage{Service: "calendarsync-test", secrets: &tooBigKeyring{fileKeyring{path: filepath.Join(t.TempDir(), "keyring.json")}}}
+
+	if _, err := storage.WriteCalendarAuth(testCalendarAuth()); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	cal, err := storage.ReadCalendarAuth("primary")
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if cal.OAuth2.AccessToken != "" || cal.OAuth2.RefreshToken != "refresh-token" || cal.OAuth2.Expiry != "0001-01-01T00:00:00Z" {
+		t.Fatalf("got auth %v, expected only the refresh token", cal.OAuth2)
+	}
+}
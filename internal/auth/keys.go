@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"golang.org/x/crypto/ssh"
+)
+
+// parseRecipients parses age X25519 recipients and SSH public keys
+func parseRecipients(values []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, value := range values {
+		var recipient age.Recipient
+		var err error
+		if strings.HasPrefix(value, "ssh-") {
+			recipient, err = agessh.ParseRecipient(value)
+		} else {
+			recipient, err = age.ParseX25519Recipient(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", value, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, nil
+}
+
+// readIdentities reads an age identity file or an SSH private key, which must not be protected by a passphrase
+func readIdentities(path string) ([]age.Identity, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read identity file: %w", err)
+	}
+
+	if bytes.Contains(data, []byte("PRIVATE KEY-----")) {
+		identity, err := agessh.ParseIdentity(data)
+		var missingPassphrase *ssh.PassphraseMissingError
+		if errors.As(err, &missingPassphrase) {
+			return nil, fmt.Errorf("the SSH key %s is protected by a passphrase, which is not supported", path)
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid SSH key %s: %w", path, err)
+		}
+		return []age.Identity{identity}, nil
+	}
+
+	identities, err := age.ParseIdentities(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity file %s: %w", path, err)
+	}
+	return identities, nil
+}
+
+// identityRecipients returns the recipients of the identities, such that the storage can be encrypted for the
+// identities which decrypt it
+func identityRecipients(identities []age.Identity) []age.Recipient {
+	var recipients []age.Recipient
+	for _, identity := range identities {
+		switch i := identity.(type) {
+		case *age.X25519Identity:
+			recipients = append(recipients, i.Recipient())
+		case *agessh.Ed25519Identity:
+			recipients = append(recipients, i.Recipient())
+		case *agessh.RSAIdentity:
+			recipients = append(recipients, i.Recipient())
+		}
+	}
+	return recipients
+}
@@ -1,15 +1,28 @@
 package auth
 
 type Credentials struct {
-	Client Client
-	Tenant Tenant
+	Client         Client
+	Tenant         Tenant
+	ServiceAccount ServiceAccount
+	// DeviceFlow authorizes the user with the device authorization flow instead of the browser redirect
+	DeviceFlow bool
+	// ClientCredentials authenticates as the application itself, without a user
+	ClientCredentials bool
 }
 
 type Client struct {
 	Id     string
 	Secret string
+	// CertificateFile is the path of the certificate and the private key, which can be used instead of the Secret
+	CertificateFile string
 }
 
 type Tenant struct {
 	Id string
 }
+
+// ServiceAccount authenticates without user interaction, the Subject is the impersonated user
+type ServiceAccount struct {
+	KeyFile string
+	Subject string
+}
@@ -30,6 +30,8 @@ func StorageFactory(typ string) (Storage, error) {
 	switch typ {
 	case "yaml":
 		return new(YamlStorage), nil
+	case "keyring":
+		return new(KeyringStorage), nil
 	default:
 		return nil, fmt.Errorf("unknown storage mode %s", typ)
 	}
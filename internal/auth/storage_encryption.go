@@ -3,6 +3,7 @@ package auth
 import (
 	"fmt"
 	"io"
+	"slices"
 
 	"filippo.io/age"
 )
@@ -10,10 +11,12 @@ import (
 // Ensure EncryptedFile implements the io.ReadWriteCloser interface
 var _ io.ReadWriteCloser = &EncryptedFile{}
 
-// EncryptedFile offers
+// EncryptedFile offers transparent age encryption, either with a passphrase or for public key recipients
 type EncryptedFile struct {
 	upstream   io.ReadWriter
 	passphrase string
+	recipients []age.Recipient
+	identities []age.Identity
 	decryptor  io.Reader
 	encryptor  io.WriteCloser
 }
@@ -27,15 +30,30 @@ func NewEncryptedFile(file io.ReadWriter, passphrase string) *EncryptedFile {
 	}
 }
 
+// NewRecipientsEncryptedFile sets up an EncryptedFile which is encrypted for the recipients and decrypted with the
+// identities. If a passphrase is given, files which are still encrypted with the passphrase can be decrypted as well.
+func NewRecipientsEncryptedFile(file io.ReadWriter, recipients []age.Recipient, identities []age.Identity, passphrase string) *EncryptedFile {
+	return &EncryptedFile{
+		upstream:   file,
+		passphrase: passphrase,
+		recipients: recipients,
+		identities: identities,
+	}
+}
+
 // Read implement the io.Reader interface.
 // On first call, this sets up the age decryption infrastructure
 func (e *EncryptedFile) Read(p []byte) (n int, err error) {
 	if e.decryptor == nil {
-		identity, err := age.NewScryptIdentity(e.passphrase)
-		if err != nil {
-			return 0, fmt.Errorf("failed to create age identity: %w", err)
+		identities := e.identities
+		if e.passphrase != "" || len(identities) == 0 {
+			identity, err := age.NewScryptIdentity(e.passphrase)
+			if err != nil {
+				return 0, fmt.Errorf("failed to create age identity: %w", err)
+			}
+			identities = slices.Concat(identities, []age.Identity{identity})
 		}
-		d, err := age.Decrypt(e.upstream, identity)
+		d, err := age.Decrypt(e.upstream, identities...)
 		if err != nil {
 			return 0, fmt.Errorf("failed to setup data decryption: %w", err)
 		}
@@ -48,11 +66,15 @@ func (e *EncryptedFile) Read(p []byte) (n int, err error) {
 // On first call, this sets up the age encryption infrastructure
 func (e *EncryptedFile) Write(p []byte) (n int, err error) {
 	if e.encryptor == nil {
-		recipent, err := age.NewScryptRecipient(e.passphrase)
-		if err != nil {
-			return 0, fmt.Errorf("failed to create age recipient: %w", err)
+		recipients := e.recipients
+		if len(recipients) == 0 {
+			recipent, err := age.NewScryptRecipient(e.passphrase)
+			if err != nil {
+				return 0, fmt.Errorf("failed to create age recipient: %w", err)
+			}
+			recipients = []age.Recipient{recipent}
 		}
-		encryptor, err := age.Encrypt(e.upstream, recipent)
+		encryptor, err := age.Encrypt(e.upstream, recipients...)
 		if err != nil {
 			return 0, fmt.Errorf("failed to setup encryption: %w", err)
 		}
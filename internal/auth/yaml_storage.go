@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"filippo.io/age"
 	"github.com/charmbracelet/log"
 	"github.com/inovex/CalendarSync/internal/config"
 	"gopkg.in/yaml.v3"
@@ -18,43 +19,158 @@ import (
 type YamlStorage struct {
 	StoragePath          string
 	StorageEncryptionKey string
+	// Recipients the storage is encrypted for instead of the StorageEncryptionKey
+	Recipients []age.Recipient
+	// Identities decrypt the storage which is encrypted for the Recipients
+	Identities []age.Identity
 	// Holds the decrypted CalendarAuth Config in memory, so the file does not have to be read multiple times
 	CachedAuth []CalendarAuth
 }
 
 func (y *YamlStorage) Setup(config config.AuthStorage, encryptionPassphrase string) error {
 	y.StorageEncryptionKey = encryptionPassphrase
-	y.StoragePath = config.Config["path"].(string)
-	if strings.HasPrefix(y.StoragePath, "~"+string(filepath.Separator)) {
-		homeDir, err := os.UserHomeDir()
+	var err error
+	y.StoragePath, err = expandHome(config.Config["path"].(string))
+	if err != nil {
+		return err
+	}
+
+	if path, ok := config.Config["identity_file"].(string); ok && path != "" {
+		path, err = expandHome(path)
+		if err != nil {
+			return err
+		}
+		y.Identities, err = readIdentities(path)
+		if err != nil {
+			return err
+		}
+	}
+	if values, ok := config.Config["recipients"].([]interface{}); ok {
+		var recipients []string
+		for _, value := range values {
+			recipient, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("invalid recipient %v", value)
+			}
+			recipients = append(recipients, recipient)
+		}
+		y.Recipients, err = parseRecipients(recipients)
 		if err != nil {
 			return err
 		}
-		y.StoragePath = filepath.Join(homeDir, y.StoragePath[2:])
+	}
+	if len(y.Recipients) == 0 {
+		y.Recipients = identityRecipients(y.Identities)
+	}
+
+	switch {
+	case len(y.Recipients) > 0 && len(y.Identities) == 0:
+		return fmt.Errorf("the recipients of the auth storage require an identity_file to decrypt it")
+	case len(y.Recipients) == 0 && y.StorageEncryptionKey == "":
+		return fmt.Errorf("storage encryption key needs to be set")
 	}
 	return nil
 }
 
-func (y *YamlStorage) WriteCalendarAuth(newCal CalendarAuth) (bool, error) {
+// encrypted returns true if the storage is encrypted, either for the recipients or with the passphrase
+func (y *YamlStorage) encrypted() bool {
+	return len(y.Recipients) > 0 || y.StorageEncryptionKey != ""
+}
+
+func (y *YamlStorage) newEncryptedFile(file io.ReadWriter) *EncryptedFile {
+	if len(y.Recipients) > 0 {
+		return NewRecipientsEncryptedFile(file, y.Recipients, y.Identities, y.StorageEncryptionKey)
+	}
+	return NewEncryptedFile(file, y.StorageEncryptionKey)
+}
+
+// Rekey encrypts the storage file for the configured recipients. The file is decrypted with the identities or, if it
+// is still encrypted with a passphrase, with the StorageEncryptionKey. It returns the number of stored calendars.
+func (y *YamlStorage) Rekey() (int, error) {
+	if len(y.Recipients) == 0 {
+		return 0, fmt.Errorf("no recipients configured for the auth storage")
+	}
+	unlock, err := y.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlockStorage(unlock)
+
+	file, err := y.readAndParseFile()
+	if err != nil {
+		return 0, err
+	}
+	if err := y.writeFile(file.Calendars); err != nil {
+		return 0, err
+	}
+	y.CachedAuth = file.Calendars
+	return len(file.Calendars), nil
+}
+
+// lock acquires the lock of the storage file, so concurrent runs sharing the file don't overwrite each others changes
+func (y *YamlStorage) lock() (func() error, error) {
+	return lockFile(y.StoragePath + ".lock")
+}
+
+func unlockStorage(unlock func() error) {
+	if err := unlock(); err != nil {
+		log.Warn("failed to unlock the auth storage", "error", err)
+	}
+}
+
+// update reads the calendars from the storage file, applies the change and writes them back while holding the lock.
+// As the file is read again instead of using the cache, the change is merged with the changes of concurrent runs.
+func (y *YamlStorage) update(change func(cals []CalendarAuth) []CalendarAuth) ([]CalendarAuth, error) {
+	unlock, err := y.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlockStorage(unlock)
+
 	file, err := y.readAndParseFile()
 	err = ignoreNoFile(err)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	cals := []CalendarAuth{}
 	if file != nil {
+		cals = file.Calendars
+	}
+	cals = change(cals)
+	if err := y.writeFile(cals); err != nil {
+		return nil, err
+	}
+	return cals, nil
+}
+
+// expandHome replaces the unix abbreviation ~ of the home directory at the beginning of the path
+func expandHome(path string) (string, error) {
+	if strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(homeDir, path[2:]), nil
+	}
+	return path, nil
+}
+
+func (y *YamlStorage) WriteCalendarAuth(newCal CalendarAuth) (bool, error) {
+	cals, err := y.update(func(stored []CalendarAuth) []CalendarAuth {
+		cals := []CalendarAuth{}
 		// Iterate through all stored calendar auth objects and only append those with a different ID to the new slice
 		// Calendar ID is supposed to be unique and old entries will be "overwritten"
-		for _, cal := range file.Calendars {
+		for _, cal := range stored {
 			if cal.CalendarID != newCal.CalendarID {
 				cals = append(cals, cal)
+			} else if newCal.OAuth2.RefreshToken == "" {
+				// a token refresh doesn't always return a new refresh token, the stored one stays valid then
+				newCal.OAuth2.RefreshToken = cal.OAuth2.RefreshToken
 			}
 		}
-	}
-
-	cals = append(cals, newCal)
-	err = y.writeFile(cals)
+		return append(cals, newCal)
+	})
 	if err != nil {
 		return false, err
 	}
@@ -75,7 +191,12 @@ func (y *YamlStorage) ReadCalendarAuth(calendarID string) (*CalendarAuth, error)
 		calendars = y.CachedAuth
 	} else {
 		log.Debug("Loading Auth Data from file")
+		unlock, err := y.lock()
+		if err != nil {
+			return nil, err
+		}
 		file, err := y.readAndParseFile()
+		unlockStorage(unlock)
 		if err != nil {
 			return nil, ignoreNoFile(err)
 		}
@@ -95,70 +216,96 @@ func (y *YamlStorage) ReadCalendarAuth(calendarID string) (*CalendarAuth, error)
 }
 
 func (y *YamlStorage) RemoveCalendarAuth(calendarID string) error {
-	file, err := y.readAndParseFile()
-	err = ignoreNoFile(err)
-	if err != nil {
-		return err
-	}
-
-	cals := []CalendarAuth{}
-	if file != nil {
+	cals, err := y.update(func(stored []CalendarAuth) []CalendarAuth {
+		cals := []CalendarAuth{}
 		// Iterate through all stored calendar auth objects and only append those with a different ID to the new slice
 		// The provided ID will be removed
-		for _, cal := range file.Calendars {
+		for _, cal := range stored {
 			if cal.CalendarID != calendarID {
 				cals = append(cals, cal)
 			}
 		}
-	}
-
-	err = y.writeFile(cals)
+		return cals
+	})
 	if err != nil {
 		return err
 	}
+	y.CachedAuth = cals
 
 	return nil
 }
 
+// writeFile writes the calendars to a temporary file next to the storage file and renames it afterwards, so the
+// storage file is replaced atomically and never left half written. The caller needs to hold the lock.
 func (y *YamlStorage) writeFile(cals []CalendarAuth) error {
-	var writer io.Writer
-	file, err := os.OpenFile(y.StoragePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	file, err := os.CreateTemp(filepath.Dir(y.StoragePath), filepath.Base(y.StoragePath)+".*.tmp")
 	if err != nil {
-		return fmt.Errorf("failed to open storage file: %w", err)
+		return fmt.Errorf("failed to create temporary storage file: %w", err)
 	}
-	defer func() {
-		err = file.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
+	// fails if the file was renamed already
+	defer os.Remove(file.Name())
 
-	writer = file
+	if err := y.encode(file, cals); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to sync storage file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close storage file: %w", err)
+	}
+	if err := os.Rename(file.Name(), y.StoragePath); err != nil {
+		return fmt.Errorf("failed to replace storage file: %w", err)
+	}
+	return nil
+}
 
+func (y *YamlStorage) encode(file io.ReadWriter, cals []CalendarAuth) error {
+	var writer io.Writer = file
+	var eFile *EncryptedFile
 	// if encryption is enabled encrypt data
-	if y.StorageEncryptionKey != "" {
-		eFile := NewEncryptedFile(file, y.StorageEncryptionKey)
-		defer func() {
-			err = eFile.Close()
-			if err != nil {
-				log.Fatal(err)
-			}
-		}()
+	if y.encrypted() {
+		eFile = y.newEncryptedFile(file)
 		writer = eFile
 	}
 
-	err = yaml.NewEncoder(writer).Encode(storageFile{Calendars: cals})
+	err := yaml.NewEncoder(writer).Encode(storageFile{Calendars: cals})
 	if err != nil {
 		return fmt.Errorf("cannot write calendar auth data: %w", err)
 	}
-
+	if eFile != nil {
+		if err := eFile.Close(); err != nil {
+			return fmt.Errorf("cannot encrypt calendar auth data: %w", err)
+		}
+	}
 	return nil
 }
 
 func (y *YamlStorage) readAndParseFile() (*storageFile, error) {
+	data, storageIsEncrypted, err := y.parseFile()
+	if err != nil {
+		return nil, err
+	}
+
+	// write encrypt storage if encryption key is provided first time.
+	// The storage file is closed already, an open file can't be replaced on Windows.
+	if !storageIsEncrypted && y.encrypted() {
+		err := y.writeFile(data.Calendars)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// parseFile decodes the storage file and returns whether it is encrypted
+func (y *YamlStorage) parseFile() (*storageFile, bool, error) {
 	file, err := os.Open(y.StoragePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open storage path: %w", err)
+		return nil, false, fmt.Errorf("failed to open storage path: %w", err)
 	}
 	defer func() {
 		err = file.Close()
@@ -172,33 +319,25 @@ func (y *YamlStorage) readAndParseFile() (*storageFile, error) {
 
 	storageIsEncrypted, err := isEncrypted(file)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	if y.StorageEncryptionKey == "" && storageIsEncrypted {
-		return nil, fmt.Errorf("no encryption key provided, but auth storage is encrypted")
+	if !y.encrypted() && storageIsEncrypted {
+		return nil, false, fmt.Errorf("no encryption key provided, but auth storage is encrypted")
 	}
 
 	// if encryption is enabled decrypt data
-	if y.StorageEncryptionKey != "" && storageIsEncrypted {
-		reader = NewEncryptedFile(file, y.StorageEncryptionKey)
+	if y.encrypted() && storageIsEncrypted {
+		reader = y.newEncryptedFile(file)
 	}
 
 	var data = &storageFile{}
 	err = yaml.NewDecoder(reader).Decode(data)
 	if err != nil {
-		return nil, fmt.Errorf("cannot unmarshal config file: %w", err)
+		return nil, false, fmt.Errorf("cannot unmarshal config file: %w", err)
 	}
 
-	// write encrypt storage if encryption key is provided first time
-	if !storageIsEncrypted && y.StorageEncryptionKey != "" {
-		err := y.writeFile(data.Calendars)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return data, nil
+	return data, storageIsEncrypted, nil
 }
 
 func isEncrypted(reader io.ReaderAt) (bool, error) {
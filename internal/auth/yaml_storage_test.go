@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/inovex/CalendarSync/internal/config"
+)
+
+// writeIdentity writes a new age identity file and returns its path and recipient
+func writeIdentity(t *testing.T) (string, string) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	path := filepath.Join(t.TempDir(), "identity.txt")
+	if err := os.WriteFile(path, []byte("# calendarsync\n"+identity.String()+"\n"), 0o600); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	return path, identity.Recipient().String()
+}
+
+func setupYamlStorage(t *testing.T, storageConfig config.CustomMap, passphrase string) *YamlStorage {
+	storage := &YamlStorage{}
+	if err := storage.Setup(config.AuthStorage{StorageMode: "yaml", Config: storageConfig}, passphrase); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	return storage
+}
+
+func assertStoredAuth(t *testing.T, storage *YamlStorage) {
+	cal, err := storage.ReadCalendarAuth("primary")
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if cal == nil || *cal != testCalendarAuth() {
+		t.Fatalf("got auth %v, expected %v", cal, testCalendarAuth())
+	}
+}
+
+func TestYamlStorageRecipients(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth-storage.yaml")
+	identityFile, recipient := writeIdentity(t)
+	_, otherRecipient := writeIdentity(t)
+	storageConfig := config.CustomMap{"path": path, "identity_file": identityFile, "recipients": []interface{}{recipient, otherRecipient}}
+
+	storage := setupYamlStorage(t, storageConfig, "")
+	if _, err := storage.WriteCalendarAuth(testCalendarAuth()); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if !strings.Contains(string(data), "X25519") || strings.Contains(string(data), "refresh-token") {
+		t.Fatalf("expected the storage to be encrypted for the recipients, got %q", data)
+	}
+
+	// without the recipients, the identity file is sufficient
+	assertStoredAuth(t, setupYamlStorage(t, config.CustomMap{"path": path, "identity_file": identityFile}, ""))
+}
+
+func TestYamlStorageSSHIdentity(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	identityFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(identityFile, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+
+	storageConfig := config.CustomMap{"path": filepath.Join(t.TempDir(), "auth-storage.yaml"), "identity_file": identityFile}
+	storage := setupYamlStorage(t, storageConfig, "")
+	if _, err := storage.WriteCalendarAuth(testCalendarAuth()); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	assertStoredAuth(t, setupYamlStorage(t, storageConfig, ""))
+}
+
+func TestYamlStorageRekey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth-storage.yaml")
+	passphrase := "I like calendarsync"
+	storage := setupYamlStorage(t, config.CustomMap{"path": path}, passphrase)
+	if _, err := storage.WriteCalendarAuth(testCalendarAuth()); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+
+	identityFile, recipient := writeIdentity(t)
+	storageConfig := config.CustomMap{"path": path, "identity_file": identityFile, "recipients": []interface{}{recipient}}
+	// the passphrase is required to decrypt the file
+	if _, err := setupYamlStorage(t, storageConfig, "").Rekey(); err == nil {
+		t.Fatalf("got no error, expected the rekey to fail without the passphrase")
+	}
+
+	calendars, err := setupYamlStorage(t, storageConfig, passphrase).Rekey()
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if calendars != 1 {
+		t.Fatalf("got %d calendars, expected 1", calendars)
+	}
+	assertStoredAuth(t, setupYamlStorage(t, storageConfig, ""))
+}
+
+func TestYamlStorageEncryptsUnencryptedStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth-storage.yaml")
+	// a storage written by an older version without encryption
+	if err := (&YamlStorage{StoragePath: path}).writeFile([]CalendarAuth{testCalendarAuth()}); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+
+	// reading the storage with a passphrase the first time encrypts it
+	assertStoredAuth(t, setupYamlStorage(t, config.CustomMap{"path": path}, "I like calendarsync"))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if !strings.HasPrefix(string(data), "age-encryption.org") {
+		t.Fatalf("expected the storage to be encrypted, got %q", data)
+	}
+	assertStoredAuth(t, setupYamlStorage(t, config.CustomMap{"path": path}, "I like calendarsync"))
+}
+
+func TestYamlStorageSetupErrors(t *testing.T) {
+	_, recipient := writeIdentity(t)
+	path := filepath.Join(t.TempDir(), "auth-storage.yaml")
+
+	tests := map[string]config.CustomMap{
+		"storage encryption key needs to be set": {"path": path},
+		"require an identity_file":               {"path": path, "recipients": []interface{}{recipient}},
+		"invalid recipient":                      {"path": path, "recipients": []interface{}{"age1invalid"}},
+		"failed to read identity file":           {"path": path, "identity_file": filepath.Join(t.TempDir(), "missing.txt")},
+	}
+	for expected, storageConfig := range tests {
+		err := new(YamlStorage).Setup(config.AuthStorage{StorageMode: "yaml", Config: storageConfig}, "")
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("got error '%v', expected '%s'", err, expected)
+		}
+	}
+}
+
+func TestYamlStorageParallelWriters(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "auth-storage.yaml")
+	identityFile, _ := writeIdentity(t)
+	storageConfig := config.CustomMap{"path": path, "identity_file": identityFile}
+
+	const writers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every writer has its own storage, like concurrent runs of calendarsync sharing the file
+			cal := testCalendarAuth()
+			cal.CalendarID = fmt.Sprintf("calendar-%d", i)
+			_, err := setupYamlStorage(t, storageConfig, "").WriteCalendarAuth(cal)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("got error '%v', expected nil", err)
+		}
+	}
+
+	storage := setupYamlStorage(t, storageConfig, "")
+	for i := range writers {
+		cal, err := storage.ReadCalendarAuth(fmt.Sprintf("calendar-%d", i))
+		if err != nil {
+			t.Fatalf("got error '%v', expected nil", err)
+		}
+		if cal == nil {
+			t.Fatalf("calendar-%d is missing, expected the writes to be merged", i)
+		}
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".tmp") {
+			t.Fatalf("got temporary file %s, expected it to be renamed or removed", file.Name())
+		}
+	}
+}
+
+func TestYamlStorageKeepsRefreshToken(t *testing.T) {
+	identityFile, _ := writeIdentity(t)
+	storageConfig := config.CustomMap{"path": filepath.Join(t.TempDir(), "auth-storage.yaml"), "identity_file": identityFile}
+	if _, err := setupYamlStorage(t, storageConfig, "").WriteCalendarAuth(testCalendarAuth()); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+
+	refreshed := testCalendarAuth()
+	refreshed.OAuth2.AccessToken = "new-access-token"
+	refreshed.OAuth2.RefreshToken = ""
+	if _, err := setupYamlStorage(t, storageConfig, "").WriteCalendarAuth(refreshed); err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+
+	cal, err := setupYamlStorage(t, storageConfig, "").ReadCalendarAuth("primary")
+	if err != nil {
+		t.Fatalf("got error '%v', expected nil", err)
+	}
+	if cal.OAuth2.AccessToken != "new-access-token" || cal.OAuth2.RefreshToken != "refresh-token" {
+		t.Fatalf("got auth %v, expected the new access token and the stored refresh token", cal.OAuth2)
+	}
+}
@@ -1,15 +1,28 @@
 package config
 
 type AdapterConfig struct {
-	config Adapter
+	config     Adapter
+	recurrence string
 }
 
 func NewAdapterConfig(config Adapter) AdapterConfig {
 	return AdapterConfig{
-		config: config,
+		config:     config,
+		recurrence: RecurrenceInstances,
 	}
 }
 
+// WithRecurrence returns the config for an adapter which lists series according to the given recurrence mode
+func (cfg AdapterConfig) WithRecurrence(recurrence string) AdapterConfig {
+	cfg.recurrence = recurrence
+	return cfg
+}
+
 func (cfg AdapterConfig) Adapter() Adapter {
 	return cfg.config
 }
+
+// Series returns true if series are synchronised as recurring events
+func (cfg AdapterConfig) Series() bool {
+	return cfg.recurrence == RecurrenceSeries
+}
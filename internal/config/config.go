@@ -3,19 +3,79 @@ package config
 import (
 	"fmt"
 	"os"
+	"sort"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	// ModeOneWay synchronises the events of the sources into the sinks
+	ModeOneWay = "oneway"
+	// ModeBidirectional synchronises the events of one source and one sink into each other
+	ModeBidirectional = "bidirectional"
+
+	// RecurrenceInstances synchronises every instance of a series as a separate event
+	RecurrenceInstances = "instances"
+	// RecurrenceSeries synchronises series as recurring events
+	RecurrenceSeries = "series"
+
+	// OAuthFlowBrowser authorizes adapters in the browser, which is redirected to a local listener
+	OAuthFlowBrowser = "browser"
+	// OAuthFlowDevice authorizes adapters with a code which is entered on another device, see RFC 8628
+	OAuthFlowDevice = "device"
+	// OAuthFlowClientCredentials authenticates adapters as an application without a user, see RFC 6749 section 4.4
+	OAuthFlowClientCredentials = "clientCredentials"
+
+	// DefaultJobName is the name of the job configured by the top-level settings if no jobs are configured
+	DefaultJobName = "default"
+
+	// DefaultDeleteLimitPercent is the delete limit of jobs without a configured one, it aborts the sync of a sink
+	// if more than half of its events would be deleted
+	DefaultDeleteLimitPercent = 50
+
+	// DefaultDeleteLimitMinCount is the number of deletions up to which DefaultDeleteLimitPercent does not apply,
+	// so that a sink with only a few events can still be emptied
+	DefaultDeleteLimitMinCount = 5
+)
+
 type File struct {
-	Path              string
-	Auth              AuthStorage
+	Path string
+	Auth AuthStorage
+	// State configures where the state of the synchronisation is kept between runs
+	State StateStorage `yaml:"state"`
+	// Job contains the top-level settings. If no jobs are configured, these settings make up the default job.
+	// Otherwise, they're used as defaults for all configured jobs.
+	Job `yaml:",inline"`
+	// Jobs are independent syncs which share the auth storage
+	Jobs map[string]Job `yaml:"jobs,omitempty"`
+}
+
+// Job configures a single sync of the sources into the sinks
+type Job struct {
 	Source            Source        `yaml:"source"`
+	Sources           []Source      `yaml:"sources,omitempty"`
 	Sink              Sink          `yaml:"sink"`
+	Sinks             []Sink        `yaml:"sinks,omitempty"`
 	Filters           []Filter      `yaml:"filters,omitempty"`
 	Transformations   []Transformer `yaml:"transformations,omitempty"`
 	Sync              Sync          `yaml:"sync"`
 	UpdateConcurrency int           `yaml:"updateConcurrency,omitempty"`
+	// Mode is either ModeOneWay (default) or ModeBidirectional
+	Mode string `yaml:"mode,omitempty"`
+	// Schedule is only used by the daemon
+	Schedule Schedule `yaml:"schedule,omitempty"`
+	// Incremental enables syncing only the changes since the last run, if the sources support it
+	Incremental bool `yaml:"incremental,omitempty"`
+	// Compare configures which differences between a source event and its synced copy cause an update
+	Compare Compare `yaml:"compare,omitempty"`
+	// DeleteLimit aborts the sync of a sink if too many events would be deleted
+	DeleteLimit DeleteLimit `yaml:"deleteLimit,omitempty"`
+	// Recurrence is either RecurrenceInstances (default) or RecurrenceSeries
+	Recurrence string `yaml:"recurrence,omitempty"`
+	// TimeZone is the IANA time zone of the sync window and the hour-based filters.
+	// The sync window defaults to the local time zone, the filters to UTC.
+	TimeZone string `yaml:"timezone,omitempty"`
 }
 
 func NewFromFile(path string) (*File, error) {
@@ -32,27 +92,278 @@ func NewFromFile(path string) (*File, error) {
 				"path": "./auth-storage.yaml",
 			},
 		},
+		State: StateStorage{
+			Path: "./calendarsync-state.json",
+		},
 	}
 
 	if err := yaml.Unmarshal(yamlFile, &config); err != nil {
 		return nil, fmt.Errorf("cannot unmarshal config file: %w", err)
 	}
 
+	if len(config.Jobs) == 0 {
+		if err := config.Job.normalize(); err != nil {
+			return nil, err
+		}
+		config.Jobs = map[string]Job{DefaultJobName: config.Job}
+		return &config, nil
+	}
+
+	for name, job := range config.Jobs {
+		job.applyDefaults(config.Job)
+		if err := job.normalize(); err != nil {
+			return nil, fmt.Errorf("job %s: %w", name, err)
+		}
+		config.Jobs[name] = job
+	}
+
 	return &config, nil
 }
 
+// JobNames returns the names of all configured jobs in alphabetical order
+func (f *File) JobNames() []string {
+	names := make([]string, 0, len(f.Jobs))
+	for name := range f.Jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyDefaults sets all settings which are not configured for the job to the given defaults
+func (j *Job) applyDefaults(defaults Job) {
+	if j.Source.Adapter.Type == "" && len(j.Sources) == 0 {
+		j.Source = defaults.Source
+		j.Sources = append([]Source(nil), defaults.Sources...)
+	}
+	if j.Sink.Adapter.Type == "" && len(j.Sinks) == 0 {
+		j.Sink = defaults.Sink
+		// copy the sinks, as normalize modifies them per job
+		j.Sinks = append([]Sink(nil), defaults.Sinks...)
+	}
+	if j.Filters == nil {
+		j.Filters = defaults.Filters
+	}
+	if j.Transformations == nil {
+		j.Transformations = defaults.Transformations
+	}
+	if j.Sync == (Sync{}) {
+		j.Sync = defaults.Sync
+	}
+	if j.UpdateConcurrency == 0 {
+		j.UpdateConcurrency = defaults.UpdateConcurrency
+	}
+	if j.Mode == "" {
+		j.Mode = defaults.Mode
+	}
+	if j.Schedule == (Schedule{}) {
+		j.Schedule = defaults.Schedule
+	}
+	if !j.Incremental {
+		j.Incremental = defaults.Incremental
+	}
+	if j.Compare.Ignore == nil && j.Compare.DefaultReminders == nil {
+		j.Compare = defaults.Compare
+	}
+	if j.DeleteLimit == (DeleteLimit{}) {
+		j.DeleteLimit = defaults.DeleteLimit
+	}
+	if j.Recurrence == "" {
+		j.Recurrence = defaults.Recurrence
+	}
+	if j.TimeZone == "" {
+		j.TimeZone = defaults.TimeZone
+	}
+}
+
+// normalize merges the single 'source' and 'sink' blocks, which are kept for backwards compatibility,
+// into the 'sources' and 'sinks' lists and applies the global transformations and filters to every sink
+// which does not configure its own.
+func (j *Job) normalize() error {
+	if j.Source.Adapter.Type != "" {
+		if len(j.Sources) > 0 {
+			return fmt.Errorf("only one of 'source' and 'sources' can be configured")
+		}
+		j.Sources = []Source{j.Source}
+	}
+
+	if j.Sink.Adapter.Type != "" {
+		if len(j.Sinks) > 0 {
+			return fmt.Errorf("only one of 'sink' and 'sinks' can be configured")
+		}
+		j.Sinks = []Sink{j.Sink}
+	}
+
+	switch j.Mode {
+	case "":
+		j.Mode = ModeOneWay
+	case ModeOneWay:
+	case ModeBidirectional:
+		if len(j.Sources) != 1 || len(j.Sinks) != 1 {
+			return fmt.Errorf("mode %s requires exactly one source and one sink", ModeBidirectional)
+		}
+		if j.Incremental {
+			return fmt.Errorf("mode %s does not support incremental syncs", ModeBidirectional)
+		}
+	default:
+		return fmt.Errorf("unknown mode %s", j.Mode)
+	}
+
+	switch j.Recurrence {
+	case "":
+		j.Recurrence = RecurrenceInstances
+	case RecurrenceInstances:
+	case RecurrenceSeries:
+		if j.Incremental {
+			return fmt.Errorf("recurrence %s does not support incremental syncs", RecurrenceSeries)
+		}
+	default:
+		return fmt.Errorf("unknown recurrence %s", j.Recurrence)
+	}
+
+	for _, adapter := range j.adapters() {
+		switch adapter.OAuth.Flow {
+		case "", OAuthFlowBrowser, OAuthFlowDevice, OAuthFlowClientCredentials:
+		default:
+			return fmt.Errorf("unknown oAuth flow %s of adapter %s", adapter.OAuth.Flow, adapter.Type)
+		}
+	}
+
+	if _, err := j.Location(); err != nil {
+		return err
+	}
+	if j.Sync != (Sync{}) {
+		if _, _, err := j.SyncWindow(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if j.Schedule.Interval != 0 && j.Schedule.Cron != "" {
+		return fmt.Errorf("only one of 'schedule.interval' and 'schedule.cron' can be configured")
+	}
+
+	if j.DeleteLimit == (DeleteLimit{}) {
+		j.DeleteLimit = DeleteLimit{Percent: DefaultDeleteLimitPercent, MinCount: DefaultDeleteLimitMinCount}
+	}
+	if j.DeleteLimit.Count < 0 {
+		return fmt.Errorf("'deleteLimit.count' must not be negative")
+	}
+	if j.DeleteLimit.MinCount < 0 {
+		return fmt.Errorf("'deleteLimit.minCount' must not be negative")
+	}
+	if j.DeleteLimit.Percent < 0 || j.DeleteLimit.Percent > 100 {
+		return fmt.Errorf("'deleteLimit.percent' must be between 0 and 100")
+	}
+
+	for i := range j.Sources {
+		if j.Mode != ModeBidirectional {
+			if j.Sources[i].Transformations != nil || j.Sources[i].Filters != nil {
+				return fmt.Errorf("the transformations and filters of a source require mode %s", ModeBidirectional)
+			}
+			continue
+		}
+		if j.Sources[i].Transformations == nil {
+			j.Sources[i].Transformations = j.Transformations
+		}
+		if j.Sources[i].Filters == nil {
+			j.Sources[i].Filters = j.Filters
+		}
+	}
+
+	for i := range j.Sinks {
+		if j.Sinks[i].Transformations == nil {
+			j.Sinks[i].Transformations = j.Transformations
+		}
+		if j.Sinks[i].Filters == nil {
+			j.Sinks[i].Filters = j.Filters
+		}
+	}
+
+	return nil
+}
+
+// StateStorage configures the file which keeps the state of the synchronisation
+type StateStorage struct {
+	// Path of the state file
+	Path string `yaml:"path"`
+}
+
+// adapters returns the adapters of the sources and the sinks
+func (j *Job) adapters() []Adapter {
+	var adapters []Adapter
+	for _, source := range j.Sources {
+		adapters = append(adapters, source.Adapter)
+	}
+	for _, sink := range j.Sinks {
+		adapters = append(adapters, sink.Adapter)
+	}
+	return adapters
+}
+
+// Location returns the configured time zone, nil if none is configured
+func (j *Job) Location() (*time.Location, error) {
+	if j.TimeZone == "" {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(j.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %s: %w", j.TimeZone, err)
+	}
+	return location, nil
+}
+
+// SyncWindow returns the start and end of the sync window relative to now in the configured time zone,
+// or in the local time zone if none is configured
+func (j *Job) SyncWindow(now time.Time) (time.Time, time.Time, error) {
+	location, err := j.Location()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if location == nil {
+		location = time.Local
+	}
+	return j.Sync.Window(now.In(location))
+}
+
 type AuthStorage struct {
 	StorageMode string `yaml:"storage_mode"`
 	// Any kind of parameter which can be passed to the StorageMode
 	Config CustomMap `yaml:"config"`
 }
 
+type Compare struct {
+	// Ignore lists the fields which are not compared, see models.DiffFields
+	Ignore []string `yaml:"ignore,omitempty"`
+	// DefaultReminders is the number of reminders which the sink calendar adds to new events by default.
+	// These are not treated as a change if the source event has no reminders. Defaults to 1.
+	DefaultReminders *int `yaml:"defaultReminders,omitempty"`
+}
+
+// DeleteLimit is the maximum number of deletions per sink, either as an absolute count or as a percentage
+// of the events in the sink. The percentage only applies if more than MinCount events would be deleted. A zero value
+// disables the respective limit, if none is configured DefaultDeleteLimitPercent and DefaultDeleteLimitMinCount are
+// used. A percentage of 100 disables the limit.
+type DeleteLimit struct {
+	Count    int     `yaml:"count,omitempty"`
+	Percent  float64 `yaml:"percent,omitempty"`
+	MinCount int     `yaml:"minCount,omitempty"`
+}
+
 type Source struct {
 	Adapter Adapter `yaml:"adapter"`
+	// Transformations applied to the events synchronised into this source in bidirectional mode.
+	// Defaults to the global transformations.
+	Transformations []Transformer `yaml:"transformations,omitempty"`
+	// Filters applied to the events synchronised into this source in bidirectional mode. Defaults to the global filters.
+	Filters []Filter `yaml:"filters,omitempty"`
 }
 
 type Sink struct {
 	Adapter Adapter `yaml:"adapter"`
+	// Transformations applied to the events of this sink. Defaults to the global transformations.
+	Transformations []Transformer `yaml:"transformations,omitempty"`
+	// Filters applied to the events of this sink. Defaults to the global filters.
+	Filters []Filter `yaml:"filters,omitempty"`
 }
 
 type Adapter struct {
@@ -70,6 +381,26 @@ type OAuth struct {
 	ClientID  string `yaml:"clientId,omitempty"`
 	ClientKey string `yaml:"clientKey,omitempty"`
 	TenantID  string `yaml:"tenantId,omitempty"`
+	// Flow is either OAuthFlowBrowser (default), OAuthFlowDevice or OAuthFlowClientCredentials
+	Flow string `yaml:"flow,omitempty"`
+	// CertificateFile is the path of a PEM file with the certificate and the private key of the application, which
+	// is used instead of the ClientKey by the client credentials flow
+	CertificateFile string `yaml:"certificateFile,omitempty"`
+	// ServiceAccountKeyFile is the path of the JSON key of a Google service account, which is used instead of the
+	// interactive authentication
+	ServiceAccountKeyFile string `yaml:"serviceAccountKeyFile,omitempty"`
+	// Subject is the user which is impersonated by the service account using domain-wide delegation
+	Subject string `yaml:"subject,omitempty"`
+}
+
+// DeviceFlow returns true if the adapter is authorized with the device authorization flow
+func (o OAuth) DeviceFlow() bool {
+	return o.Flow == OAuthFlowDevice
+}
+
+// ClientCredentials returns true if the adapter authenticates as an application without a user
+func (o OAuth) ClientCredentials() bool {
+	return o.Flow == OAuthFlowClientCredentials
 }
 
 // CustomMap is meant to provide custom parameters to different adapters/transformers.
@@ -94,9 +425,34 @@ type Filter struct {
 type Sync struct {
 	StartTime SyncTime `yaml:"start"`
 	EndTime   SyncTime `yaml:"end"`
+	// FirstWeekday is the first day of the week for the WeekStart and WeekEnd identifiers, defaults to monday
+	FirstWeekday string `yaml:"firstWeekday,omitempty"`
+}
+
+// Schedule configures when the daemon runs a job
+type Schedule struct {
+	// Interval between the end of a run and the start of the next one, e.g. 15m
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Cron expression with five fields, e.g. "*/15 8-18 * * 1-5"
+	Cron string `yaml:"cron,omitempty"`
+	// Jitter is the maximum random delay added to every run
+	Jitter time.Duration `yaml:"jitter,omitempty"`
 }
 
 type SyncTime struct {
 	Identifier string `yaml:"identifier"`
 	Offset     int    `yaml:"offset,omitempty"`
 }
+
+// ID identifies the configured sync window independent of the point in time it is resolved at, e.g. "MonthStart/MonthEnd"
+func (s Sync) ID() string {
+	return s.StartTime.String() + "/" + s.EndTime.String()
+}
+
+// String returns the identifier with its offset, e.g. "MonthStart+1"
+func (t SyncTime) String() string {
+	if t.Offset == 0 {
+		return t.Identifier
+	}
+	return fmt.Sprintf("%s%+d", t.Identifier, t.Offset)
+}
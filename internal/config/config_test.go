@@ -2,6 +2,7 @@ package config_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/inovex/CalendarSync/internal/config"
 	"github.com/inovex/CalendarSync/internal/sync"
@@ -76,3 +77,242 @@ func (suite *ConfigTestSuite) TestCustomAuthStorageFromFile() {
 	assert.Equal(suite.T(), "custom", sut.Auth.StorageMode)
 	assert.Equal(suite.T(), "./auth-storage.custom", sut.Auth.Config["path"])
 }
+
+func (suite *ConfigTestSuite) TestSingleSourceFromFile() {
+	sut, err := config.NewFromFile("../../testdata/testconfig.yaml")
+
+	assert.Nil(suite.T(), err)
+	require.Len(suite.T(), sut.Sources, 1)
+	assert.Equal(suite.T(), "outlook_http", sut.Sources[0].Adapter.Type)
+}
+
+func (suite *ConfigTestSuite) TestMultipleSourcesFromFile() {
+	sut, err := config.NewFromFile("../../testdata/multiple_sources.yaml")
+
+	assert.Nil(suite.T(), err)
+	require.Len(suite.T(), sut.Sources, 2)
+	assert.Equal(suite.T(), "outlook_http", sut.Sources[0].Adapter.Type)
+	assert.Equal(suite.T(), "zep", sut.Sources[1].Adapter.Type)
+	assert.Equal(suite.T(), "absences", sut.Sources[1].Adapter.Calendar)
+}
+
+func (suite *ConfigTestSuite) TestSourceAndSourcesFromFile() {
+	_, err := config.NewFromFile("../../testdata/source_and_sources.yaml")
+
+	assert.Error(suite.T(), err)
+}
+
+func (suite *ConfigTestSuite) TestSingleSinkFromFile() {
+	sut, err := config.NewFromFile("../../testdata/testconfig.yaml")
+
+	assert.Nil(suite.T(), err)
+	require.Len(suite.T(), sut.Sinks, 1)
+	assert.Equal(suite.T(), "google", sut.Sinks[0].Adapter.Type)
+	assert.Equal(suite.T(), sut.Transformations, sut.Sinks[0].Transformations)
+}
+
+func (suite *ConfigTestSuite) TestMultipleSinksFromFile() {
+	sut, err := config.NewFromFile("../../testdata/multiple_sinks.yaml")
+
+	assert.Nil(suite.T(), err)
+	require.Len(suite.T(), sut.Sinks, 2)
+
+	personal := sut.Sinks[0]
+	assert.Equal(suite.T(), "personal@gmail.com", personal.Adapter.Calendar)
+	assert.Equal(suite.T(), sut.Transformations, personal.Transformations)
+	assert.Equal(suite.T(), sut.Filters, personal.Filters)
+
+	team := sut.Sinks[1]
+	assert.Equal(suite.T(), "team@group.calendar.google.com", team.Adapter.Calendar)
+	require.Len(suite.T(), team.Transformations, 1)
+	assert.Equal(suite.T(), "ReplaceTitle", team.Transformations[0].Name)
+	require.Len(suite.T(), team.Filters, 2)
+	assert.Equal(suite.T(), "AllDayEvents", team.Filters[1].Name)
+}
+
+func (suite *ConfigTestSuite) TestModeDefaultsToOneWay() {
+	sut, err := config.NewFromFile("../../testdata/testconfig.yaml")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), config.ModeOneWay, sut.Mode)
+}
+
+func (suite *ConfigTestSuite) TestBidirectionalModeFromFile() {
+	sut, err := config.NewFromFile("../../testdata/bidirectional.yaml")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), config.ModeBidirectional, sut.Mode)
+	assert.Equal(suite.T(), "./calendarsync-state.json", sut.State.Path)
+	require.Len(suite.T(), sut.Sources[0].Transformations, 2)
+	assert.Equal(suite.T(), "PrefixTitle", sut.Sources[0].Transformations[1].Name)
+}
+
+func (suite *ConfigTestSuite) TestSourceTransformationsRequireBidirectionalMode() {
+	_, err := config.NewFromFile("../../testdata/oneway_source_transformations.yaml")
+
+	assert.ErrorContains(suite.T(), err, "require mode bidirectional")
+}
+
+func (suite *ConfigTestSuite) TestBidirectionalModeRequiresOneSink() {
+	_, err := config.NewFromFile("../../testdata/bidirectional_multiple_sinks.yaml")
+
+	assert.ErrorContains(suite.T(), err, "exactly one source and one sink")
+}
+
+func (suite *ConfigTestSuite) TestDefaultJobFromFile() {
+	sut, err := config.NewFromFile("../../testdata/testconfig.yaml")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{config.DefaultJobName}, sut.JobNames())
+	assert.Equal(suite.T(), sut.Job, sut.Jobs[config.DefaultJobName])
+}
+
+func (suite *ConfigTestSuite) TestJobsFromFile() {
+	sut, err := config.NewFromFile("../../testdata/jobs.yaml")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []string{"team", "work"}, sut.JobNames())
+
+	work := sut.Jobs["work"]
+	require.Len(suite.T(), work.Sources, 1)
+	assert.Equal(suite.T(), "outlook_http", work.Sources[0].Adapter.Type)
+	require.Len(suite.T(), work.Sinks, 1)
+	assert.Equal(suite.T(), "personal@gmail.com", work.Sinks[0].Adapter.Calendar)
+	assert.Equal(suite.T(), "MonthStart", work.Sync.StartTime.Identifier)
+	assert.Equal(suite.T(), 2, work.UpdateConcurrency)
+	assert.Equal(suite.T(), config.ModeOneWay, work.Mode)
+	require.Len(suite.T(), work.Sinks[0].Transformations, 1)
+	assert.Equal(suite.T(), "KeepTitle", work.Sinks[0].Transformations[0].Name)
+	require.Len(suite.T(), work.Sinks[0].Filters, 1)
+	assert.Equal(suite.T(), "DeclinedEvents", work.Sinks[0].Filters[0].Name)
+
+	team := sut.Jobs["team"]
+	require.Len(suite.T(), team.Sinks, 1)
+	assert.Equal(suite.T(), "team-copy@group.calendar.google.com", team.Sinks[0].Adapter.Calendar)
+	assert.Equal(suite.T(), config.SyncTime{Identifier: "MonthStart"}, team.Sync.StartTime)
+	require.Len(suite.T(), team.Sinks[0].Transformations, 1)
+	assert.Equal(suite.T(), "ReplaceTitle", team.Sinks[0].Transformations[0].Name)
+	assert.Empty(suite.T(), team.Sinks[0].Filters)
+}
+
+func (suite *ConfigTestSuite) TestScheduleFromFile() {
+	sut, err := config.NewFromFile("../../testdata/schedule.yaml")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), config.Schedule{Interval: 15 * time.Minute, Jitter: 30 * time.Second}, sut.Jobs["frequent"].Schedule)
+	assert.Equal(suite.T(), config.Schedule{Cron: "*/5 8-18 * * 1-5"}, sut.Jobs["office-hours"].Schedule)
+}
+
+func (suite *ConfigTestSuite) TestIncrementalFromFile() {
+	sut, err := config.NewFromFile("../../testdata/incremental.yaml")
+
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), sut.Jobs[config.DefaultJobName].Incremental)
+	assert.Equal(suite.T(), "~/.calendarsync/state.json", sut.State.Path)
+}
+
+func (suite *ConfigTestSuite) TestStateDefaultsFromFile() {
+	sut, err := config.NewFromFile("../../testdata/testconfig.yaml")
+
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), sut.Incremental)
+	assert.Equal(suite.T(), "./calendarsync-state.json", sut.State.Path)
+}
+
+func (suite *ConfigTestSuite) TestSyncID() {
+	sync := config.Sync{
+		StartTime: config.SyncTime{Identifier: "MonthStart", Offset: -1},
+		EndTime:   config.SyncTime{Identifier: "MonthEnd"},
+	}
+
+	assert.Equal(suite.T(), "MonthStart-1/MonthEnd", sync.ID())
+}
+
+func (suite *ConfigTestSuite) TestCompareFromFile() {
+	sut, err := config.NewFromFile("../../testdata/compare.yaml")
+
+	assert.Nil(suite.T(), err)
+	compare := sut.Jobs[config.DefaultJobName].Compare
+	assert.Equal(suite.T(), []string{"description"}, compare.Ignore)
+	if assert.NotNil(suite.T(), compare.DefaultReminders) {
+		assert.Equal(suite.T(), 0, *compare.DefaultReminders)
+	}
+}
+
+func (suite *ConfigTestSuite) TestDeleteLimitFromFile() {
+	sut, err := config.NewFromFile("../../testdata/delete_limit.yaml")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), config.DeleteLimit{Count: 20, Percent: 30, MinCount: 10}, sut.Jobs["work"].DeleteLimit)
+	assert.Equal(suite.T(), config.DeleteLimit{Percent: 100}, sut.Jobs["team"].DeleteLimit)
+}
+
+func (suite *ConfigTestSuite) TestDefaultDeleteLimit() {
+	sut, err := config.NewFromFile("../../testdata/empty_testconfig.yaml")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), config.DeleteLimit{Percent: config.DefaultDeleteLimitPercent, MinCount: config.DefaultDeleteLimitMinCount}, sut.Jobs[config.DefaultJobName].DeleteLimit)
+}
+
+func (suite *ConfigTestSuite) TestRecurrenceFromFile() {
+	sut, err := config.NewFromFile("../../testdata/recurrence.yaml")
+
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), config.RecurrenceSeries, sut.Jobs["work"].Recurrence)
+	assert.Equal(suite.T(), config.RecurrenceInstances, sut.Jobs["team"].Recurrence)
+
+	sut, err = config.NewFromFile("../../testdata/testconfig.yaml")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), config.RecurrenceInstances, sut.Jobs[config.DefaultJobName].Recurrence)
+}
+
+func (suite *ConfigTestSuite) TestRecurrenceSeriesNotIncremental() {
+	_, err := config.NewFromFile("../../testdata/recurrence_incremental.yaml")
+
+	assert.ErrorContains(suite.T(), err, "incremental")
+}
+
+func (suite *ConfigTestSuite) TestTimeZoneFromFile() {
+	sut, err := config.NewFromFile("../../testdata/timezone.yaml")
+
+	assert.Nil(suite.T(), err)
+	work := sut.Jobs["work"]
+	location, err := work.Location()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Europe/Berlin", location.String())
+	assert.Equal(suite.T(), "America/New_York", sut.Jobs["travel"].TimeZone)
+
+	sut, err = config.NewFromFile("../../testdata/testconfig.yaml")
+	assert.Nil(suite.T(), err)
+	defaultJob := sut.Jobs[config.DefaultJobName]
+	location, err = defaultJob.Location()
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), location)
+}
+
+func (suite *ConfigTestSuite) TestInvalidTimeZone() {
+	_, err := config.NewFromFile("../../testdata/invalid_timezone.yaml")
+
+	assert.ErrorContains(suite.T(), err, "Middle/Earth")
+}
+
+func (suite *ConfigTestSuite) TestInvalidSyncWindow() {
+	_, err := config.NewFromFile("../../testdata/invalid_sync_window.yaml")
+
+	assert.ErrorContains(suite.T(), err, "after the sync end")
+}
+
+func (suite *ConfigTestSuite) TestOAuthFlowFromFile() {
+	sut, err := config.NewFromFile("../../testdata/oauth_flow.yaml")
+
+	assert.Nil(suite.T(), err)
+	work := sut.Jobs["work"]
+	assert.True(suite.T(), work.Sources[0].Adapter.OAuth.DeviceFlow())
+	assert.False(suite.T(), work.Sinks[0].Adapter.OAuth.DeviceFlow())
+}
+
+func (suite *ConfigTestSuite) TestInvalidOAuthFlow() {
+	_, err := config.NewFromFile("../../testdata/invalid_oauth_flow.yaml")
+
+	assert.ErrorContains(suite.T(), err, "carrier-pigeon")
+}
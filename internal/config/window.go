@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The identifiers of a SyncTime. The offset of a SyncTime is counted in the unit of its identifier, e.g. in weeks
+// for IdentifierWeekStart. The ...End identifiers refer to the beginning of the last day of the period.
+const (
+	IdentifierNow        = "Now"
+	IdentifierDayStart   = "DayStart"
+	IdentifierWeekStart  = "WeekStart"
+	IdentifierWeekEnd    = "WeekEnd"
+	IdentifierMonthStart = "MonthStart"
+	IdentifierMonthEnd   = "MonthEnd"
+	IdentifierYearStart  = "YearStart"
+	IdentifierYearEnd    = "YearEnd"
+)
+
+// isoDuration matches ISO-8601 durations like P14D, -P1M or PT12H, the sign is an extension of ISO-8601
+var isoDuration = regexp.MustCompile(`^([+-])?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// Window returns the start and end of the sync window relative to now, whose location is used for the
+// identifiers and dates without a time zone
+func (s Sync) Window(now time.Time) (time.Time, time.Time, error) {
+	firstWeekday, err := s.firstWeekday()
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start, err := s.StartTime.Time(now, firstWeekday)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid sync start: %w", err)
+	}
+	end, err := s.EndTime.Time(now, firstWeekday)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid sync end: %w", err)
+	}
+	if start.After(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("sync start %s is after the sync end %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return start, end, nil
+}
+
+func (s Sync) firstWeekday() (time.Weekday, error) {
+	if s.FirstWeekday == "" {
+		return time.Monday, nil
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), s.FirstWeekday) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown firstWeekday %s", s.FirstWeekday)
+}
+
+// Time returns the point in time of the SyncTime relative to now. The identifier is either one of the
+// Identifier... constants, an ISO-8601 duration which is added to now or an RFC3339 date-time or date.
+func (t SyncTime) Time(now time.Time, firstWeekday time.Weekday) (time.Time, error) {
+	year, month, day := now.Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+
+	switch t.Identifier {
+	case IdentifierNow:
+		return now.AddDate(0, 0, t.Offset), nil
+	case IdentifierDayStart:
+		return today.AddDate(0, 0, t.Offset), nil
+	case IdentifierWeekStart, IdentifierWeekEnd:
+		weekStart := today.AddDate(0, 0, -((int(today.Weekday()) - int(firstWeekday) + 7) % 7))
+		if t.Identifier == IdentifierWeekEnd {
+			return weekStart.AddDate(0, 0, 7*t.Offset+6), nil
+		}
+		return weekStart.AddDate(0, 0, 7*t.Offset), nil
+	case IdentifierMonthStart:
+		return time.Date(year, month, 1, 0, 0, 0, 0, now.Location()).AddDate(0, t.Offset, 0), nil
+	case IdentifierMonthEnd:
+		firstOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		return firstOfMonth.AddDate(0, 1, -1).AddDate(0, t.Offset, 0), nil
+	case IdentifierYearStart:
+		return time.Date(year+t.Offset, time.January, 1, 0, 0, 0, 0, now.Location()), nil
+	case IdentifierYearEnd:
+		return time.Date(year+t.Offset, time.December, 31, 0, 0, 0, 0, now.Location()), nil
+	}
+
+	if t.Offset != 0 {
+		return time.Time{}, fmt.Errorf("offset is not supported for %s", t.Identifier)
+	}
+	if match := isoDuration.FindStringSubmatch(t.Identifier); match != nil && strings.ContainsAny(t.Identifier, "YMWDHS") {
+		values := make([]int, len(match))
+		for i, value := range match[2:] {
+			if value != "" {
+				values[i+2], _ = strconv.Atoi(value)
+			}
+		}
+		sign := 1
+		if match[1] == "-" {
+			sign = -1
+		}
+		duration := time.Duration(values[6])*time.Hour + time.Duration(values[7])*time.Minute + time.Duration(values[8])*time.Second
+		return now.AddDate(sign*values[2], sign*values[3], sign*(7*values[4]+values[5])).Add(time.Duration(sign) * duration), nil
+	}
+	if date, err := time.Parse(time.RFC3339, t.Identifier); err == nil {
+		return date, nil
+	}
+	if date, err := time.ParseInLocation(time.DateOnly, t.Identifier, now.Location()); err == nil {
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("unknown identifier %q", t.Identifier)
+}
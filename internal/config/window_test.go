@@ -0,0 +1,142 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/inovex/CalendarSync/internal/config"
+)
+
+func TestSyncTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	// Wednesday
+	now := time.Date(2024, 3, 13, 15, 30, 0, 0, berlin)
+
+	tests := []struct {
+		name         string
+		syncTime     config.SyncTime
+		firstWeekday time.Weekday
+		expected     time.Time
+		expectedErr  bool
+	}{
+		{
+			name:     "now",
+			syncTime: config.SyncTime{Identifier: config.IdentifierNow},
+			expected: now,
+		},
+		{
+			name:     "day start with offset",
+			syncTime: config.SyncTime{Identifier: config.IdentifierDayStart, Offset: -7},
+			expected: time.Date(2024, 3, 6, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:         "week start on monday",
+			syncTime:     config.SyncTime{Identifier: config.IdentifierWeekStart},
+			firstWeekday: time.Monday,
+			expected:     time.Date(2024, 3, 11, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:         "next week end on sunday",
+			syncTime:     config.SyncTime{Identifier: config.IdentifierWeekEnd, Offset: 1},
+			firstWeekday: time.Sunday,
+			expected:     time.Date(2024, 3, 23, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:         "week start on the first weekday",
+			syncTime:     config.SyncTime{Identifier: config.IdentifierWeekStart},
+			firstWeekday: time.Wednesday,
+			expected:     time.Date(2024, 3, 13, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:     "month start",
+			syncTime: config.SyncTime{Identifier: config.IdentifierMonthStart, Offset: -1},
+			expected: time.Date(2024, 2, 1, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:     "month end",
+			syncTime: config.SyncTime{Identifier: config.IdentifierMonthEnd},
+			expected: time.Date(2024, 3, 31, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:     "year start and end",
+			syncTime: config.SyncTime{Identifier: config.IdentifierYearEnd, Offset: 1},
+			expected: time.Date(2025, 12, 31, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:     "negative duration",
+			syncTime: config.SyncTime{Identifier: "-P14D"},
+			expected: time.Date(2024, 2, 28, 15, 30, 0, 0, berlin),
+		},
+		{
+			name:     "duration with time",
+			syncTime: config.SyncTime{Identifier: "P1M1WT12H"},
+			expected: time.Date(2024, 4, 21, 3, 30, 0, 0, berlin),
+		},
+		{
+			name:     "RFC3339 date-time",
+			syncTime: config.SyncTime{Identifier: "2024-01-01T08:00:00Z"},
+			expected: time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "date",
+			syncTime: config.SyncTime{Identifier: "2024-01-01"},
+			expected: time.Date(2024, 1, 1, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:        "empty duration",
+			syncTime:    config.SyncTime{Identifier: "PT"},
+			expectedErr: true,
+		},
+		{
+			name:        "offset of a date",
+			syncTime:    config.SyncTime{Identifier: "2024-01-01", Offset: 1},
+			expectedErr: true,
+		},
+		{
+			name:        "unknown identifier",
+			syncTime:    config.SyncTime{Identifier: "Tomorrow"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.syncTime.Time(now, tt.firstWeekday)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.True(t, tt.expected.Equal(result), "expected %s, got %s", tt.expected, result)
+		})
+	}
+}
+
+func TestSyncWindow(t *testing.T) {
+	now := time.Date(2024, 3, 13, 15, 30, 0, 0, time.UTC)
+
+	start, end, err := config.Sync{
+		StartTime:    config.SyncTime{Identifier: config.IdentifierWeekStart},
+		EndTime:      config.SyncTime{Identifier: "P90D"},
+		FirstWeekday: "sunday",
+	}.Window(now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 6, 11, 15, 30, 0, 0, time.UTC), end)
+
+	_, _, err = config.Sync{
+		StartTime: config.SyncTime{Identifier: config.IdentifierNow},
+		EndTime:   config.SyncTime{Identifier: "-P1D"},
+	}.Window(now)
+	assert.ErrorContains(t, err, "after")
+
+	_, _, err = config.Sync{
+		StartTime:    config.SyncTime{Identifier: config.IdentifierNow},
+		EndTime:      config.SyncTime{Identifier: config.IdentifierNow},
+		FirstWeekday: "someday",
+	}.Window(now)
+	assert.ErrorContains(t, err, "firstWeekday")
+}
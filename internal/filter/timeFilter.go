@@ -1,12 +1,22 @@
 package filter
 
 import (
+	"time"
+
 	"github.com/inovex/CalendarSync/internal/models"
 )
 
 type TimeFilterEvents struct {
 	HourStart int
 	HourEnd   int
+
+	// location in which the hours are evaluated, UTC if not set
+	location *time.Location
+}
+
+// SetLocation sets the time zone in which the hours are evaluated
+func (a *TimeFilterEvents) SetLocation(location *time.Location) {
+	a.location = location
 }
 
 func (a TimeFilterEvents) Name() string {
@@ -20,10 +30,16 @@ func (a TimeFilterEvents) Filter(event models.Event) bool {
 		return true
 	}
 
+	location := a.location
+	if location == nil {
+		location = time.UTC
+	}
+	startTime, endTime := event.StartTime.In(location), event.EndTime.In(location)
+
 	// if start time and end time are inside the timeframe: exclude
 	// example: event from 12:15-12:45, timeframe is 12-13
 	// starttime and endtime are inside the timeframe
-	if event.StartTime.Hour() >= a.HourStart && event.EndTime.Hour() <= a.HourEnd {
+	if startTime.Hour() >= a.HourStart && endTime.Hour() <= a.HourEnd {
 		return false
 	}
 
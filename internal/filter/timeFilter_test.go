@@ -101,3 +101,29 @@ func TestTimeFilterEventsFilter(t *testing.T) {
 	}
 	checkEventFilter(t, eventFilter, sourceEvents, expectedSinkEvents)
 }
+
+// The hours are evaluated in the configured time zone
+func TestTimeFilterEventsLocation(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Error(err)
+	}
+
+	// 11:15-11:45 in UTC, 12:15-12:45 in Berlin
+	sourceEvents := []models.Event{
+		{
+			ID:        "lunch",
+			StartTime: time.Date(2024, 1, 1, 11, 15, 0, 0, time.UTC),
+			EndTime:   time.Date(2024, 1, 1, 11, 45, 0, 0, time.UTC),
+		},
+	}
+
+	eventFilter := &filter.TimeFilterEvents{
+		HourStart: 12,
+		HourEnd:   13,
+	}
+	checkEventFilter(t, eventFilter, sourceEvents, sourceEvents)
+
+	eventFilter.SetLocation(berlin)
+	checkEventFilter(t, eventFilter, sourceEvents, nil)
+}
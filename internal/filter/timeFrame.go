@@ -1,12 +1,22 @@
 package filter
 
 import (
+	"time"
+
 	"github.com/inovex/CalendarSync/internal/models"
 )
 
 type TimeFrameEvents struct {
 	HourStart int
 	HourEnd   int
+
+	// location in which the hours are evaluated, UTC if not set
+	location *time.Location
+}
+
+// SetLocation sets the time zone in which the hours are evaluated
+func (a *TimeFrameEvents) SetLocation(location *time.Location) {
+	a.location = location
 }
 
 func (a TimeFrameEvents) Name() string {
@@ -20,24 +30,30 @@ func (a TimeFrameEvents) Filter(event models.Event) bool {
 		return true
 	}
 
+	location := a.location
+	if location == nil {
+		location = time.UTC
+	}
+	startTime, endTime := event.StartTime.In(location), event.EndTime.In(location)
+
 	// if start time is inside the timeframe
 	// example: event from 10-12, timeframe is 8-18
 	// starttime and endtime are inside the timeframe
-	if event.StartTime.Hour() >= a.HourStart && event.StartTime.Hour() <= a.HourEnd {
+	if startTime.Hour() >= a.HourStart && startTime.Hour() <= a.HourEnd {
 		return true
 	}
 
 	// if the endtime is inside the timeframe
 	// example: event from 7-9, timeframe is 8-18
 	// then the endtime of the event is inside the timeframe and therefore should be kept
-	if event.EndTime.Hour() <= a.HourEnd && event.EndTime.Hour() >= a.HourStart {
+	if endTime.Hour() <= a.HourEnd && endTime.Hour() >= a.HourStart {
 		return true
 	}
 
 	// if the starttime is inside the timeframe
 	// example: event from 17-19 timeframe is 8-18
 	// then the starttime of the event is inside the timeframe and therefore should be kept
-	if event.StartTime.Hour() >= a.HourStart && event.StartTime.Hour() <= a.HourEnd {
+	if startTime.Hour() >= a.HourStart && startTime.Hour() <= a.HourEnd {
 		return true
 	}
 
@@ -86,3 +86,33 @@ func TestTimeFrameEventsFilter(t *testing.T) {
 	}
 	checkEventFilter(t, eventFilter, sourceEvents, expectedSinkEvents)
 }
+
+// The hours are evaluated in the configured time zone
+func TestTimeFrameEventsFilterLocation(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Error(err)
+	}
+
+	sourceEvents := []models.Event{
+		// 07:30-08:00 in UTC, 08:30-09:00 in Berlin
+		{
+			ID:        "inside",
+			StartTime: time.Date(2024, 1, 1, 7, 30, 0, 0, time.UTC),
+			EndTime:   time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC),
+		},
+		// 17:00-17:30 in UTC, 18:00-18:30 in Berlin
+		{
+			ID:        "outside",
+			StartTime: time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2024, 1, 1, 17, 30, 0, 0, time.UTC),
+		},
+	}
+
+	eventFilter := &filter.TimeFrameEvents{
+		HourStart: 8,
+		HourEnd:   17,
+	}
+	eventFilter.SetLocation(berlin)
+	checkEventFilter(t, eventFilter, sourceEvents, []models.Event{sourceEvents[0]})
+}
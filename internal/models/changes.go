@@ -0,0 +1,20 @@
+package models
+
+import "errors"
+
+// ErrSyncTokenInvalid is returned by incremental sources if the sync token of a previous listing expired or was
+// invalidated by the calendar provider. A full listing is required to obtain a new token.
+var ErrSyncTokenInvalid = errors.New("sync token is no longer valid")
+
+// Changes are the events which changed in a calendar since a previous listing
+type Changes struct {
+	// Events contains the created and updated events. For a full listing, it contains all events.
+	Events []Event
+	// Deleted contains the events which were deleted or moved out of the timeframe. Only their ID and Metadata are set.
+	Deleted []Event
+	// Token is passed to the next listing to only get the changes after this one. It's empty if the calendar
+	// doesn't support incremental listings.
+	Token string
+	// Full is set if the Changes are a full listing of the timeframe instead of the changes since the previous listing
+	Full bool
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The fields which are compared by DiffEvents
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldLocation    = "location"
+	FieldStart       = "start"
+	FieldEnd         = "end"
+	FieldAllDay      = "allDay"
+	FieldTimeZone    = "timeZone"
+	FieldReminders   = "reminders"
+	FieldAttendees   = "attendees"
+	FieldRecurrence  = "recurrence"
+)
+
+// DiffFields are all fields which are compared by DiffEvents, in the order in which they are compared
+var DiffFields = []string{
+	FieldTitle,
+	FieldDescription,
+	FieldLocation,
+	FieldStart,
+	FieldEnd,
+	FieldAllDay,
+	FieldTimeZone,
+	FieldReminders,
+	FieldAttendees,
+	FieldRecurrence,
+}
+
+// FieldDiff is the change of a single event field. Old and New are the formatted values of the field.
+type FieldDiff struct {
+	Field string `json:"field" yaml:"field"`
+	Old   string `json:"old" yaml:"old"`
+	New   string `json:"new" yaml:"new"`
+}
+
+// EventDiff lists the fields which differ between two events
+type EventDiff []FieldDiff
+
+// Changed returns true if any field differs
+func (d EventDiff) Changed() bool {
+	return len(d) > 0
+}
+
+// Fields returns the names of the changed fields
+func (d EventDiff) Fields() []string {
+	fields := make([]string, 0, len(d))
+	for _, field := range d {
+		fields = append(fields, field.Field)
+	}
+	return fields
+}
+
+// DiffOptions configure which differences between two events are reported by DiffEvents
+type DiffOptions struct {
+	// Ignore are fields which are not compared
+	Ignore []string
+	// DefaultReminders is the number of reminders which the sink calendar adds to events by default.
+	// If the new event has no reminders, up to this many reminders of the old event are not reported as a change.
+	DefaultReminders int
+}
+
+// DefaultDiffOptions compare all fields and ignore a single reminder which was added by the sink calendar
+func DefaultDiffOptions() DiffOptions {
+	return DiffOptions{DefaultReminders: 1}
+}
+
+// DiffEvents compares all fields of the events using the DefaultDiffOptions, see DiffOptions.DiffEvents
+func DiffEvents(old, new Event) EventDiff {
+	return DefaultDiffOptions().DiffEvents(old, new)
+}
+
+// DiffEvents returns the fields which differ between the old event (e.g. in the sink) and the new event
+// (e.g. the transformed source event).
+// This implementation evaluates the differences after event transformation rather than comparing the content versions.
+func (o DiffOptions) DiffEvents(old, new Event) EventDiff {
+	var diff EventDiff
+	add := func(field, oldValue, newValue string) {
+		if !slices.Contains(o.Ignore, field) {
+			diff = append(diff, FieldDiff{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+
+	if old.Title != new.Title {
+		add(FieldTitle, old.Title, new.Title)
+	}
+	if old.Description != new.Description {
+		add(FieldDescription, old.Description, new.Description)
+	}
+	if old.Location != new.Location {
+		add(FieldLocation, old.Location, new.Location)
+	}
+
+	if old.AllDay && new.AllDay {
+		// only compare dates
+		if !sameDay(old.StartTime, new.StartTime) {
+			add(FieldStart, formatEventTime(old, old.StartTime), formatEventTime(new, new.StartTime))
+		}
+		if !sameDay(old.EndTime, new.EndTime) {
+			add(FieldEnd, formatEventTime(old, old.EndTime), formatEventTime(new, new.EndTime))
+		}
+	} else {
+		if !old.StartTime.Equal(new.StartTime) {
+			add(FieldStart, formatEventTime(old, old.StartTime), formatEventTime(new, new.StartTime))
+		}
+		if !old.EndTime.Equal(new.EndTime) {
+			add(FieldEnd, formatEventTime(old, old.EndTime), formatEventTime(new, new.EndTime))
+		}
+	}
+	if old.AllDay != new.AllDay {
+		add(FieldAllDay, fmt.Sprint(old.AllDay), fmt.Sprint(new.AllDay))
+	}
+	// all-day events have no time zone, and if the time zone of the new event is unknown the sink keeps its own
+	if !old.AllDay && !new.AllDay && new.TimeZone != "" && old.TimeZone != new.TimeZone {
+		add(FieldTimeZone, old.TimeZone, new.TimeZone)
+	}
+
+	if !o.sameReminders(old.Reminders, new.Reminders) {
+		add(FieldReminders, formatReminders(old.Reminders), formatReminders(new.Reminders))
+	}
+
+	// Comparing the display name could be a problem because those are optional
+	if !slices.Equal(sortedAttendees(old.Attendees), sortedAttendees(new.Attendees)) {
+		add(FieldAttendees, formatAttendees(old.Attendees), formatAttendees(new.Attendees))
+	}
+
+	if !SameRecurrence(old, new) {
+		add(FieldRecurrence, old.Recurrence.String(), new.Recurrence.String())
+	}
+
+	return diff
+}
+
+func (o DiffOptions) sameReminders(old, new Reminders) bool {
+	if len(new) == 0 && len(old) <= o.DefaultReminders {
+		// the reminders were added by a default of the sink calendar
+		return true
+	}
+	if len(old) != len(new) {
+		return false
+	}
+
+	oldTriggers, newTriggers := reminderTriggers(old), reminderTriggers(new)
+	for i := range oldTriggers {
+		if !oldTriggers[i].Equal(newTriggers[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+// formatEventTime formats the start or end time of the event, all-day events only show the date
+func formatEventTime(event Event, t time.Time) string {
+	switch {
+	case t.IsZero():
+		return ""
+	case event.AllDay:
+		return t.Format(time.DateOnly)
+	default:
+		return t.UTC().Format(time.RFC3339)
+	}
+}
+
+// reminderTriggers returns the sorted points in time of the reminders
+func reminderTriggers(reminders Reminders) []time.Time {
+	triggers := make([]time.Time, 0, len(reminders))
+	for _, reminder := range reminders {
+		triggers = append(triggers, reminder.Trigger.PointInTime)
+	}
+	slices.SortFunc(triggers, time.Time.Compare)
+	return triggers
+}
+
+func formatReminders(reminders Reminders) string {
+	var triggers []string
+	for _, trigger := range reminderTriggers(reminders) {
+		triggers = append(triggers, trigger.UTC().Format(time.RFC3339))
+	}
+	return strings.Join(triggers, ", ")
+}
+
+// sortedAttendees returns a sorted copy of the attendees, such that the order of the attendees is not compared
+func sortedAttendees(attendees Attendees) Attendees {
+	sorted := slices.Clone(attendees)
+	slices.SortFunc(sorted, func(a, b Attendee) int {
+		return strings.Compare(a.Email, b.Email)
+	})
+	return sorted
+}
+
+func formatAttendees(attendees Attendees) string {
+	var formatted []string
+	for _, attendee := range sortedAttendees(attendees) {
+		if attendee.DisplayName != "" {
+			formatted = append(formatted, fmt.Sprintf("%s <%s>", attendee.DisplayName, attendee.Email))
+		} else {
+			formatted = append(formatted, attendee.Email)
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffEvents(t *testing.T) {
+	startTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(time.Hour)
+	reminder := func(before time.Duration) Reminder {
+		return Reminder{Actions: ReminderActionDisplay, Trigger: ReminderTrigger{PointInTime: startTime.Add(-before)}}
+	}
+	event := Event{
+		Title:     "Title",
+		StartTime: startTime,
+		EndTime:   endTime,
+		Attendees: Attendees{{Email: "a@example.com"}, {Email: "b@example.com"}},
+		Reminders: Reminders{reminder(10 * time.Minute)},
+	}
+
+	tests := []struct {
+		name     string
+		options  DiffOptions
+		old      Event
+		new      func(Event) Event
+		expected EventDiff
+	}{
+		{
+			name:    "same event",
+			options: DefaultDiffOptions(),
+			old:     event,
+			new:     func(e Event) Event { return e },
+		},
+		{
+			name:    "changed title and start",
+			options: DefaultDiffOptions(),
+			old:     event,
+			new: func(e Event) Event {
+				e.Title = "Other"
+				e.StartTime = startTime.Add(-time.Hour)
+				return e
+			},
+			expected: EventDiff{
+				{Field: FieldTitle, Old: "Title", New: "Other"},
+				{Field: FieldStart, Old: "2024-03-01T10:00:00Z", New: "2024-03-01T09:00:00Z"},
+			},
+		},
+		{
+			name:    "all-day events only compare dates",
+			options: DefaultDiffOptions(),
+			old: Event{
+				AllDay:    true,
+				StartTime: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				EndTime:   time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+			},
+			new: func(e Event) Event {
+				e.StartTime = e.StartTime.Add(time.Hour)
+				e.EndTime = e.EndTime.Add(24 * time.Hour)
+				return e
+			},
+			expected: EventDiff{
+				{Field: FieldEnd, Old: "2024-03-02", New: "2024-03-03"},
+			},
+		},
+		{
+			name:    "order of attendees and reminders is ignored",
+			options: DefaultDiffOptions(),
+			old: func() Event {
+				e := event
+				e.Reminders = Reminders{reminder(time.Hour), reminder(time.Minute)}
+				return e
+			}(),
+			new: func(e Event) Event {
+				e.Attendees = Attendees{e.Attendees[1], e.Attendees[0]}
+				e.Reminders = Reminders{reminder(time.Minute), reminder(time.Hour)}
+				return e
+			},
+		},
+		{
+			name:    "changed attendee display name",
+			options: DefaultDiffOptions(),
+			old:     event,
+			new: func(e Event) Event {
+				e.Attendees = Attendees{{Email: "a@example.com", DisplayName: "A"}, {Email: "b@example.com"}}
+				return e
+			},
+			expected: EventDiff{
+				{Field: FieldAttendees, Old: "a@example.com, b@example.com", New: "A <a@example.com>, b@example.com"},
+			},
+		},
+		{
+			name:    "reminder added by the sink default",
+			options: DefaultDiffOptions(),
+			old:     event,
+			new: func(e Event) Event {
+				e.Reminders = nil
+				return e
+			},
+		},
+		{
+			name:    "reminders added by the sink default without default reminders",
+			options: DiffOptions{},
+			old:     event,
+			new: func(e Event) Event {
+				e.Reminders = nil
+				return e
+			},
+			expected: EventDiff{
+				{Field: FieldReminders, Old: "2024-03-01T09:50:00Z"},
+			},
+		},
+		{
+			name:    "changed recurrence",
+			options: DefaultDiffOptions(),
+			old: func() Event {
+				e := event
+				e.Recurrence = &Recurrence{Rule: "FREQ=WEEKLY;INTERVAL=1"}
+				return e
+			}(),
+			new: func(e Event) Event {
+				e.Recurrence = &Recurrence{Rule: "FREQ=WEEKLY", ExDates: []time.Time{startTime.AddDate(0, 0, 7)}}
+				return e
+			},
+			expected: EventDiff{
+				{Field: FieldRecurrence, Old: "RRULE:FREQ=WEEKLY;INTERVAL=1", New: "RRULE:FREQ=WEEKLY\nEXDATE:20240308T100000Z"},
+			},
+		},
+		{
+			name:    "changed time zone",
+			options: DefaultDiffOptions(),
+			old: func() Event {
+				e := event
+				e.TimeZone = "Europe/Berlin"
+				return e
+			}(),
+			new: func(e Event) Event {
+				e.TimeZone = "America/New_York"
+				return e
+			},
+			expected: EventDiff{
+				{Field: FieldTimeZone, Old: "Europe/Berlin", New: "America/New_York"},
+			},
+		},
+		{
+			name:    "time zone of an event synced without it",
+			options: DefaultDiffOptions(),
+			old:     event,
+			new: func(e Event) Event {
+				e.TimeZone = "Europe/Berlin"
+				return e
+			},
+			expected: EventDiff{
+				{Field: FieldTimeZone, New: "Europe/Berlin"},
+			},
+		},
+		{
+			name:    "unknown time zone of the new event",
+			options: DefaultDiffOptions(),
+			old: func() Event {
+				e := event
+				e.TimeZone = "Europe/Berlin"
+				return e
+			}(),
+			new: func(e Event) Event {
+				e.TimeZone = ""
+				return e
+			},
+		},
+		{
+			name:    "ignored fields",
+			options: DiffOptions{Ignore: []string{FieldTitle, FieldReminders}},
+			old:     event,
+			new: func(e Event) Event {
+				e.Title = "Other"
+				e.Reminders = Reminders{reminder(time.Hour), reminder(time.Minute)}
+				e.Location = "Room"
+				return e
+			},
+			expected: EventDiff{
+				{Field: FieldLocation, New: "Room"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := tt.options.DiffEvents(tt.old, tt.new(tt.old))
+
+			assert.Equal(t, tt.expected, diff)
+			assert.Equal(t, tt.expected.Changed(), diff.Changed())
+		})
+	}
+}
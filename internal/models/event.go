@@ -1,10 +1,7 @@
 package models
 
 import (
-	"sort"
 	"time"
-
-	"github.com/charmbracelet/log"
 )
 
 // Event describes a calendar event which can be processed in a Controller via Transformer funcs.
@@ -22,12 +19,16 @@ type Event struct {
 	Location    string
 	StartTime   time.Time
 	EndTime     time.Time
+	TimeZone    string // IANA time zone of the start and end time in the calendar of the event. Empty if unknown
 	AllDay      bool
 	Metadata    *Metadata
 	Attendees   Attendees
 	Reminders   Reminders
 	MeetingLink string
 	Accepted    bool
+	ETag        string          // version of the event in its calendar, changes with every modification. Empty if the calendar has no versions
+	Recurrence  *Recurrence     // set if the event is the master event of a series
+	Instance    *SeriesInstance // set if the event is a modified instance of a series
 }
 
 type Reminders []Reminder
@@ -73,13 +74,16 @@ type ReminderTrigger struct {
 // It can be aggregated by Transformers with additional data if desired.
 func NewSyncEvent(origin Event) Event {
 	return Event{
-		ICalUID:   origin.ICalUID,
-		ID:        origin.ID,
-		StartTime: origin.StartTime,
-		EndTime:   origin.EndTime,
-		AllDay:    origin.AllDay,
-		Title:     "CalendarSync Event",
-		Metadata:  origin.Metadata,
+		ICalUID:    origin.ICalUID,
+		ID:         origin.ID,
+		StartTime:  origin.StartTime,
+		EndTime:    origin.EndTime,
+		TimeZone:   origin.TimeZone,
+		AllDay:     origin.AllDay,
+		Title:      "CalendarSync Event",
+		Metadata:   origin.Metadata,
+		Recurrence: origin.Recurrence,
+		Instance:   origin.Instance,
 	}
 }
 
@@ -91,6 +95,18 @@ func (e *Event) SyncID() string {
 	return e.Metadata.SyncID
 }
 
+// TimeLocation returns the time zone of the event, UTC if the time zone is unknown
+func (e *Event) TimeLocation() *time.Location {
+	if e.TimeZone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
 // ShortTitle returns the title with a capped max length of maxLen.
 // If the title is too long, the string is cut to maxLen and '...' is added
 func (e *Event) ShortTitle() string {
@@ -107,111 +123,19 @@ func (e *Event) Overwrite(source Event) Event {
 	e.Description = source.Description
 	e.StartTime = source.StartTime
 	e.EndTime = source.EndTime
+	e.TimeZone = source.TimeZone
 	e.AllDay = source.AllDay
 	e.Metadata = source.Metadata
 	e.Attendees = source.Attendees
 	e.Location = source.Location
 	e.Reminders = source.Reminders
 	e.MeetingLink = source.MeetingLink
+	// the instance is kept, as it refers to the series in the calendar of e
+	e.Recurrence = source.Recurrence
 
 	return *e
 }
 
-// This implementation evalutes the differences after event transformation rather than comparing the content versions
-func IsSameEvent(a, b Event) bool {
-
-	// TODO can be done better
-	if a.Title != b.Title {
-		log.Debugf("Title of Source Event %s at %s changed, Sink Title is %s", a.Title, a.StartTime, b.Title)
-		return false
-	}
-
-	if a.Description != b.Description {
-		log.Debugf("Description of Source Event %s at %s changed", a.Title, a.StartTime)
-		log.Debugf("Description in Source: '%s'", a.Description)
-		log.Debugf("Description in Sink: '%s'", b.Description)
-		return false
-	}
-
-	if a.AllDay != b.AllDay {
-		log.Debugf("AllDay of Source Event %s at %s changed", a.Title, a.StartTime)
-		return false
-	}
-
-	if a.AllDay && b.AllDay {
-		// only compare dates
-		if a.StartTime.Year() != b.StartTime.Year() || a.StartTime.YearDay() != b.StartTime.YearDay() {
-			log.Debugf("StartTime of all-day event %s changed, sourceTime: %s, sinkTime: %s", a.Title, a.StartTime.Format(time.DateOnly), b.StartTime.Format(time.DateOnly))
-			return false
-		}
-		if a.EndTime.Year() != b.EndTime.Year() || a.EndTime.YearDay() != b.EndTime.YearDay() {
-			log.Debugf("EndTime of all-day event %s changed, sourceTime: %s, sinkTime: %s", a.Title, a.EndTime.Format(time.DateOnly), b.EndTime.Format(time.DateOnly))
-			return false
-		}
-	} else {
-		if !a.StartTime.Equal(b.StartTime) {
-			log.Debugf("StartTime of Source Event %s changed, sourceTime: %s, sinkTime: %s ", a.Title, a.StartTime, b.StartTime)
-			return false
-		}
-		if !a.EndTime.Equal(b.EndTime) {
-			log.Debugf("EndTime of Source Event %s changed, sourceTime: %s, sinkTime: %s ", a.Title, a.StartTime, b.StartTime)
-			return false
-		}
-	}
-
-	if a.AllDay != b.AllDay {
-		log.Debugf("AllDay State of Source Event %s at %s changed", a.Title, a.StartTime)
-		return false
-	}
-
-	if a.Location != b.Location {
-		log.Debugf("Location of Source Event %s at %s changed", a.Title, a.StartTime)
-		return false
-	}
-
-	// Check if the reminders have changed
-	// when the length does not match, we need to sync anyways
-	if len(a.Reminders) != len(b.Reminders) {
-		if len(a.Reminders) == 0 && len(b.Reminders) == 1 {
-			log.Debugf("Count of Reminders in Source is 0 and in Sink is 1. Most of the time, this is due to the fact that the sink calendar has some reminder default configured. we're skipping here.")
-		} else {
-			log.Debugf("Count of Reminders of Source Event %s at %s changed", a.Title, a.StartTime)
-			log.Debugf("Count of Reminders in Source: %d - in Sink: %d", len(a.Reminders), len(b.Reminders))
-			return false
-		}
-	} else {
-		sort.Sort(a.Reminders)
-		sort.Sort(b.Reminders)
-
-		// if the length does match, we need to check if the content was modified
-		for i := 0; i < len(a.Reminders); i++ {
-			if !a.Reminders[i].Equal(b.Reminders[i]) {
-				log.Debugf("Reminder in Source is scheduled for %s, in the sink we have: %s", a.Reminders[i].Trigger.PointInTime.String(), b.Reminders[i].Trigger.PointInTime.String())
-				return false
-			}
-		}
-	}
-
-	// Comparing the display name could be a problem because those are optional
-	sort.Sort(a.Attendees)
-	sort.Sort(b.Attendees)
-
-	if len(a.Attendees) != len(b.Attendees) {
-		log.Debugf("Amount of Attendees differ. Source: %d, Sink: %d", len(a.Attendees), len(b.Attendees))
-		return false
-	}
-
-	for j := 0; j < len(a.Attendees); j++ {
-		if !a.Attendees[j].Equal(b.Attendees[j]) {
-			log.Debugf("Attendee in Source has Email: %s, in Sink the Email is: %s", a.Attendees[j].Email, b.Attendees[j].Email)
-			log.Debugf("Attendee in Source has DisplayName: %s, in Sink the DisplayName is: %s", a.Attendees[j].DisplayName, b.Attendees[j].DisplayName)
-			return false
-		}
-	}
-
-	return true
-}
-
 type Calendar struct {
 	ID          string
 	Title       string
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+// Recurrence describes the recurrence of the master event of a series. The start and end time of the master
+// event are the times of its first instance.
+type Recurrence struct {
+	// Rule is the RFC5545 recurrence rule without the "RRULE:" prefix, e.g. "FREQ=WEEKLY;BYDAY=MO,WE"
+	Rule string
+	// ExDates are the original start times of the cancelled instances. Calendars only list the cancelled
+	// instances within the synced timeframe.
+	ExDates []time.Time
+	// TimeZone is the IANA time zone in which the rule is expanded, the rule is expanded in UTC if it's empty
+	TimeZone string
+}
+
+// SeriesInstance identifies a modified instance (exception) of a series
+type SeriesInstance struct {
+	// SeriesID is the ID of the master event of the series in the calendar of the instance
+	SeriesID string
+	// SeriesSyncID is the SyncID of the master event of the series
+	SeriesSyncID string
+	// OriginalStart is the start time of the instance according to the recurrence rule
+	OriginalStart time.Time
+}
+
+// Location returns the location in which the rule is expanded
+func (r *Recurrence) Location() (*time.Location, error) {
+	if r.TimeZone == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone of recurrence: %w", err)
+	}
+	return location, nil
+}
+
+// String formats the recurrence like the RRULE and EXDATE properties of RFC5545
+func (r *Recurrence) String() string {
+	if r == nil {
+		return ""
+	}
+	lines := []string{"RRULE:" + r.Rule}
+	for _, exDate := range r.sortedExDates() {
+		lines = append(lines, "EXDATE:"+exDate.UTC().Format(RFC5545DateTime))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Instances returns the start times of the instances between from and to (inclusive) of a series whose first
+// instance starts at dtstart. Cancelled instances are not excluded.
+func (r *Recurrence) Instances(dtstart, from, to time.Time) ([]time.Time, error) {
+	location, err := r.Location()
+	if err != nil {
+		return nil, err
+	}
+	option, err := rrule.StrToROption(r.Rule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule %q: %w", r.Rule, err)
+	}
+	option.Dtstart = dtstart.In(location)
+	rule, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule %q: %w", r.Rule, err)
+	}
+	return rule.Between(from, to, true), nil
+}
+
+// Canonical returns the rule of a series whose first instance starts at dtstart in a canonical form, as calendars
+// write equivalent rules differently: default values and the values which are implied by dtstart are omitted,
+// UNTIL is reduced to its date and a single BYSETPOS is merged into BYDAY. Rules which cannot be parsed are
+// returned unchanged.
+func (r *Recurrence) Canonical(dtstart time.Time) string {
+	option, err := rrule.StrToROption(r.Rule)
+	if err != nil {
+		return r.Rule
+	}
+	if location, err := r.Location(); err == nil {
+		dtstart = dtstart.In(location)
+	}
+
+	if option.Interval == 1 {
+		option.Interval = 0
+	}
+	if option.Freq != rrule.WEEKLY || option.Interval <= 1 {
+		// the week start only changes the instances of weekly rules with an interval
+		option.Wkst = rrule.MO
+	}
+	if !option.Until.IsZero() {
+		until := option.Until.UTC()
+		option.Until = time.Date(until.Year(), until.Month(), until.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	if len(option.Bysetpos) == 1 && len(option.Byweekday) == 1 && option.Byweekday[0].N() == 0 {
+		option.Byweekday = []rrule.Weekday{option.Byweekday[0].Nth(option.Bysetpos[0])}
+		option.Bysetpos = nil
+	}
+
+	switch option.Freq {
+	case rrule.WEEKLY:
+		if len(option.Byweekday) == 1 && option.Byweekday[0] == weekday(dtstart) {
+			option.Byweekday = nil
+		}
+	case rrule.MONTHLY, rrule.YEARLY:
+		if slices.Equal(option.Bymonthday, []int{dtstart.Day()}) {
+			option.Bymonthday = nil
+		}
+		if option.Freq == rrule.YEARLY && slices.Equal(option.Bymonth, []int{int(dtstart.Month())}) {
+			option.Bymonth = nil
+		}
+	}
+	return option.RRuleString()
+}
+
+// SameRecurrence returns true if both events have the same recurrence, see Recurrence.Canonical
+func SameRecurrence(a, b Event) bool {
+	if a.Recurrence == nil || b.Recurrence == nil {
+		return a.Recurrence == b.Recurrence
+	}
+	return a.Recurrence.Canonical(a.StartTime) == b.Recurrence.Canonical(b.StartTime) &&
+		slices.EqualFunc(a.Recurrence.sortedExDates(), b.Recurrence.sortedExDates(), time.Time.Equal)
+}
+
+// weekday returns the rrule weekday of the time
+func weekday(t time.Time) rrule.Weekday {
+	// rrule weekdays start on monday
+	return []rrule.Weekday{rrule.SU, rrule.MO, rrule.TU, rrule.WE, rrule.TH, rrule.FR, rrule.SA}[t.Weekday()]
+}
+
+func (r *Recurrence) sortedExDates() []time.Time {
+	return UniqueDates(r.ExDates)
+}
+
+// UniqueDates returns the sorted dates without duplicates, e.g. an instance which is cancelled twice
+func UniqueDates(dates []time.Time) []time.Time {
+	dates = slices.Clone(dates)
+	slices.SortFunc(dates, time.Time.Compare)
+	return slices.CompactFunc(dates, time.Time.Equal)
+}
+
+// RFC5545DateTime is the UTC date-time format of RFC5545, e.g. used for EXDATE
+const RFC5545DateTime = "20060102T150405Z"
+
+// RFC5545Date is the date format of RFC5545, e.g. used for EXDATE of all-day events
+const RFC5545Date = "20060102"
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecurrenceCanonical(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	// Monday, 4th of March 2024, 00:30 in Berlin is still Sunday in UTC
+	dtstart := time.Date(2024, 3, 4, 0, 30, 0, 0, berlin)
+
+	tests := []struct {
+		name       string
+		recurrence Recurrence
+		expected   string
+	}{
+		{
+			name:       "default interval and week start are omitted",
+			recurrence: Recurrence{Rule: "FREQ=DAILY;INTERVAL=1;WKST=SU", TimeZone: "Europe/Berlin"},
+			expected:   "FREQ=DAILY",
+		},
+		{
+			name:       "week start of weekly rules with an interval is kept",
+			recurrence: Recurrence{Rule: "FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=MO,WE", TimeZone: "Europe/Berlin"},
+			expected:   "FREQ=WEEKLY;INTERVAL=2;WKST=SU;BYDAY=MO,WE",
+		},
+		{
+			name:       "day implied by the start in the time zone of the rule is omitted",
+			recurrence: Recurrence{Rule: "FREQ=WEEKLY;BYDAY=MO", TimeZone: "Europe/Berlin"},
+			expected:   "FREQ=WEEKLY",
+		},
+		{
+			name:       "day of the start in UTC is not implied",
+			recurrence: Recurrence{Rule: "FREQ=WEEKLY;BYDAY=MO"},
+			expected:   "FREQ=WEEKLY;BYDAY=MO",
+		},
+		{
+			name:       "until is reduced to its date",
+			recurrence: Recurrence{Rule: "FREQ=MONTHLY;BYMONTHDAY=4;UNTIL=20241231T235959Z", TimeZone: "Europe/Berlin"},
+			expected:   "FREQ=MONTHLY;UNTIL=20241231T000000Z",
+		},
+		{
+			name:       "single set position is merged into the day",
+			recurrence: Recurrence{Rule: "FREQ=MONTHLY;BYDAY=MO;BYSETPOS=1", TimeZone: "Europe/Berlin"},
+			expected:   "FREQ=MONTHLY;BYDAY=+1MO",
+		},
+		{
+			name:       "invalid rules are returned unchanged",
+			recurrence: Recurrence{Rule: "FREQ=SOMETIMES"},
+			expected:   "FREQ=SOMETIMES",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.recurrence.Canonical(dtstart))
+		})
+	}
+}
+
+func TestSameRecurrence(t *testing.T) {
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	exDate := start.AddDate(0, 0, 7)
+	series := func(rule string, exDates ...time.Time) Event {
+		return Event{StartTime: start, Recurrence: &Recurrence{Rule: rule, ExDates: exDates}}
+	}
+
+	assert.True(t, SameRecurrence(Event{}, Event{}))
+	assert.False(t, SameRecurrence(Event{}, series("FREQ=WEEKLY")))
+	assert.True(t, SameRecurrence(series("FREQ=WEEKLY;BYDAY=MO;INTERVAL=1"), series("FREQ=WEEKLY")))
+	assert.True(t, SameRecurrence(
+		series("FREQ=WEEKLY", exDate, exDate.AddDate(0, 0, 7)),
+		series("FREQ=WEEKLY", exDate.AddDate(0, 0, 7), exDate),
+	))
+	assert.False(t, SameRecurrence(series("FREQ=WEEKLY", exDate), series("FREQ=WEEKLY")))
+	assert.False(t, SameRecurrence(series("FREQ=WEEKLY"), series("FREQ=DAILY")))
+}
+
+func TestRecurrenceInstances(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	// the daylight saving time starts on the 31st of March 2024 in Berlin
+	dtstart := time.Date(2024, 3, 25, 8, 0, 0, 0, time.UTC)
+	recurrence := Recurrence{Rule: "FREQ=WEEKLY;COUNT=3", TimeZone: "Europe/Berlin"}
+
+	instances, err := recurrence.Instances(dtstart, dtstart, dtstart.AddDate(0, 1, 0))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 3, 25, 9, 0, 0, 0, berlin),
+		time.Date(2024, 4, 1, 9, 0, 0, 0, berlin),
+		time.Date(2024, 4, 8, 9, 0, 0, 0, berlin),
+	}, instances)
+
+	_, err = (&Recurrence{Rule: "FREQ=WEEKLY", TimeZone: "Nowhere/Special"}).Instances(dtstart, dtstart, dtstart)
+	assert.Error(t, err)
+}
+
+func TestRecurrenceString(t *testing.T) {
+	recurrence := &Recurrence{
+		Rule: "FREQ=DAILY",
+		ExDates: []time.Time{
+			time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC),
+		},
+	}
+
+	assert.Equal(t, "RRULE:FREQ=DAILY\nEXDATE:20240305T090000Z\nEXDATE:20240306T090000Z", recurrence.String())
+	assert.Equal(t, "", (*Recurrence)(nil).String())
+}
+
+func TestSameRecurrenceDuplicateExDates(t *testing.T) {
+	start := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+	exDate := start.AddDate(0, 0, 7)
+	a := Event{StartTime: start, Recurrence: &Recurrence{Rule: "FREQ=WEEKLY", ExDates: []time.Time{exDate}}}
+	b := Event{StartTime: start, Recurrence: &Recurrence{Rule: "FREQ=WEEKLY", ExDates: []time.Time{exDate, exDate}}}
+
+	assert.True(t, SameRecurrence(a, b))
+}
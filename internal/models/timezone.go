@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IANATimeZone returns the IANA name of a time zone, which is given by its IANA or its windows name.
+// Microsoft mostly uses the windows names.
+func IANATimeZone(name string) (string, error) {
+	if iana, ok := windowsTimeZones[name]; ok {
+		return iana, nil
+	}
+	if name == "" || name == "Local" {
+		return "", fmt.Errorf("unknown time zone %q", name)
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return "", fmt.Errorf("unknown time zone %q: %w", name, err)
+	}
+	return name, nil
+}
+
+// WindowsTimeZone returns the windows name of an IANA time zone or the IANA name if it's unknown
+func WindowsTimeZone(iana string) string {
+	for windows, name := range windowsTimeZones {
+		if name == iana && !strings.HasPrefix(windows, "tzone://") {
+			return windows
+		}
+	}
+	return iana
+}
+
+// windowsTimeZones maps the windows time zones to IANA time zones, see
+// https://github.com/unicode-org/cldr/blob/main/common/supplemental/windowsZones.xml
+var windowsTimeZones = map[string]string{
+	"tzone://Microsoft/Utc":           "UTC",
+	"UTC":                             "UTC",
+	"Dateline Standard Time":          "Etc/GMT+12",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"Mountain Standard Time":          "America/Denver",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Central Standard Time":           "America/Chicago",
+	"Central America Standard Time":   "America/Guatemala",
+	"Canada Central Standard Time":    "America/Regina",
+	"Central Standard Time (Mexico)":  "America/Mexico_City",
+	"Eastern Standard Time":           "America/New_York",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"Atlantic Standard Time":          "America/Halifax",
+	"Newfoundland Standard Time":      "America/St_Johns",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"Argentina Standard Time":         "America/Buenos_Aires",
+	"Pacific SA Standard Time":        "America/Santiago",
+	"Azores Standard Time":            "Atlantic/Azores",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"Romance Standard Time":           "Europe/Paris",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"FLE Standard Time":               "Europe/Kiev",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Russian Standard Time":           "Europe/Moscow",
+	"Arab Standard Time":              "Asia/Riyadh",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Iran Standard Time":              "Asia/Tehran",
+	"Pakistan Standard Time":          "Asia/Karachi",
+	"India Standard Time":             "Asia/Calcutta",
+	"Nepal Standard Time":             "Asia/Katmandu",
+	"Bangladesh Standard Time":        "Asia/Dhaka",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"China Standard Time":             "Asia/Shanghai",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"Taipei Standard Time":            "Asia/Taipei",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Korea Standard Time":             "Asia/Seoul",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"E. Australia Standard Time":      "Australia/Brisbane",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIANATimeZone(t *testing.T) {
+	name, err := IANATimeZone("W. Europe Standard Time")
+	assert.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", name)
+
+	name, err = IANATimeZone("America/New_York")
+	assert.NoError(t, err)
+	assert.Equal(t, "America/New_York", name)
+
+	_, err = IANATimeZone("Middle Earth Standard Time")
+	assert.Error(t, err)
+	_, err = IANATimeZone("")
+	assert.Error(t, err)
+}
+
+func TestWindowsTimeZone(t *testing.T) {
+	assert.Equal(t, "W. Europe Standard Time", WindowsTimeZone("Europe/Berlin"))
+	assert.Equal(t, "UTC", WindowsTimeZone("UTC"))
+	assert.Equal(t, "Europe/Vaduz", WindowsTimeZone("Europe/Vaduz"))
+}
+
+func TestEventTimeLocation(t *testing.T) {
+	event := Event{TimeZone: "Europe/Berlin"}
+	assert.Equal(t, "Europe/Berlin", event.TimeLocation().String())
+
+	event = Event{TimeZone: "Nowhere/Special"}
+	assert.Equal(t, time.UTC, event.TimeLocation())
+	assert.Equal(t, time.UTC, (&Event{}).TimeLocation())
+}
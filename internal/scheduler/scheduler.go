@@ -0,0 +1,131 @@
+// Package scheduler runs jobs repeatedly on an interval or cron schedule.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule returns the next time a job should run after the given time.
+type Schedule interface {
+	Next(time.Time) time.Time
+}
+
+type interval time.Duration
+
+func (i interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// Every returns a Schedule which runs a job in the given interval, measured from the end of the previous run.
+func Every(d time.Duration) (Schedule, error) {
+	if d <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", d)
+	}
+	return interval(d), nil
+}
+
+// ParseCron parses a standard cron expression with five fields (minute, hour, day of month, month, day of week)
+// or a descriptor like @hourly.
+func ParseCron(expression string) (Schedule, error) {
+	schedule, err := cron.ParseStandard(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", expression, err)
+	}
+	return schedule, nil
+}
+
+// JobFunc is executed on every run of a job
+type JobFunc func(ctx context.Context) error
+
+type job struct {
+	name     string
+	schedule Schedule
+	// jitter is the maximum random delay which is added to every scheduled run
+	jitter time.Duration
+	run    JobFunc
+	// running is shared between all jobs with the same name
+	running *sync.Mutex
+}
+
+// Scheduler runs the added jobs on their schedules until it is stopped
+type Scheduler struct {
+	logger *log.Logger
+	jobs   []job
+	locks  map[string]*sync.Mutex
+}
+
+// New constructs a new Scheduler.
+func New(logger *log.Logger) *Scheduler {
+	return &Scheduler{
+		logger: logger,
+		locks:  make(map[string]*sync.Mutex),
+	}
+}
+
+// Add adds a job to the Scheduler. Jobs with the same name never run at the same time, a run which is due while
+// another run of the same name is still in progress is skipped.
+func (s *Scheduler) Add(name string, schedule Schedule, jitter time.Duration, run JobFunc) {
+	if _, ok := s.locks[name]; !ok {
+		s.locks[name] = &sync.Mutex{}
+	}
+	s.jobs = append(s.jobs, job{
+		name:     name,
+		schedule: schedule,
+		jitter:   jitter,
+		run:      run,
+		running:  s.locks[name],
+	})
+}
+
+// Run runs every job once right away and afterwards on its schedule. It blocks until the context is cancelled.
+// Runs which are in progress at that time are not interrupted, Run waits for them to finish before returning.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, j := range s.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, j)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	for ctx.Err() == nil {
+		s.execute(ctx, j)
+
+		next := j.schedule.Next(time.Now())
+		if j.jitter > 0 {
+			next = next.Add(rand.N(j.jitter))
+		}
+		s.logger.Info("scheduled next run", "job", j.name, "at", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, j job) {
+	if !j.running.TryLock() {
+		s.logger.Warn("skipping run, the previous run is still in progress", "job", j.name)
+		return
+	}
+	defer j.running.Unlock()
+
+	// A shutdown must not abort a sync halfway, so the run does not inherit the cancellation of the scheduler.
+	if err := j.run(context.WithoutCancel(ctx)); err != nil {
+		s.logger.Error("run failed", "job", j.name, "error", err)
+	}
+}
@@ -0,0 +1,101 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEveryRejectsNonPositiveInterval(t *testing.T) {
+	_, err := Every(0)
+	assert.Error(t, err)
+}
+
+func TestParseCron(t *testing.T) {
+	schedule, err := ParseCron("30 9 * * 1-5")
+	require.NoError(t, err)
+
+	// Friday evening, the next run is on Monday morning
+	now := time.Date(2024, 3, 8, 18, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 3, 11, 9, 30, 0, 0, time.UTC), schedule.Next(now))
+
+	_, err = ParseCron("every monday")
+	assert.Error(t, err)
+}
+
+func TestRunRepeatsJobUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	schedule, err := Every(time.Millisecond)
+	require.NoError(t, err)
+
+	var runs atomic.Int32
+	s := New(log.Default())
+	s.Add("job", schedule, 0, func(ctx context.Context) error {
+		if runs.Add(1) == 3 {
+			cancel()
+		}
+		return errors.New("failures do not stop the job")
+	})
+
+	s.Run(ctx)
+
+	assert.Equal(t, int32(3), runs.Load())
+}
+
+func TestRunFinishesRunInProgress(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	schedule, err := Every(time.Hour)
+	require.NoError(t, err)
+
+	var finished atomic.Bool
+	s := New(log.Default())
+	s.Add("job", schedule, 0, func(runCtx context.Context) error {
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+		// the run must not be cancelled with the scheduler
+		assert.NoError(t, runCtx.Err())
+		finished.Store(true)
+		return nil
+	})
+
+	s.Run(ctx)
+
+	assert.True(t, finished.Load())
+}
+
+func TestRunNeverOverlapsJobsWithTheSameName(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	schedule, err := Every(time.Millisecond)
+	require.NoError(t, err)
+
+	var running, maxRunning, runs atomic.Int32
+	run := func(ctx context.Context) error {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			highest := maxRunning.Load()
+			if current <= highest || maxRunning.CompareAndSwap(highest, current) {
+				break
+			}
+		}
+		runs.Add(1)
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+
+	s := New(log.Default())
+	s.Add("job", schedule, time.Millisecond, run)
+	s.Add("job", schedule, time.Millisecond, run)
+
+	s.Run(ctx)
+
+	assert.Greater(t, runs.Load(), int32(1))
+	assert.Equal(t, int32(1), maxRunning.Load())
+}
@@ -0,0 +1,184 @@
+// Package serve publishes calendars as iCalendar feeds via HTTP.
+package serve
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// Feed is a published calendar
+type Feed interface {
+	// FeedName is the name of the feed, which is used in its path
+	FeedName() string
+	// Token is the secret part of the path of the feed, it's empty if the feed is public
+	Token() string
+	// Render returns the encoded feed and its ETag
+	Render() ([]byte, string, error)
+}
+
+// RefreshFunc synchronises the events of the feeds of a job
+type RefreshFunc func(ctx context.Context) error
+
+// job refreshes its feeds at most once per cache duration
+type job struct {
+	name    string
+	refresh RefreshFunc
+	mu      sync.Mutex
+	// refreshed is the time of the last successful refresh
+	refreshed time.Time
+}
+
+type route struct {
+	feed Feed
+	job  *job
+}
+
+// Server serves the feeds of the added jobs. The feeds are available at /<token>/<name>.ics or at /<name>.ics if
+// they have no token. A job is refreshed on a request for one of its feeds if it was last refreshed longer than the
+// cache duration ago, with a cache duration of 0 it is refreshed on every request.
+type Server struct {
+	logger *log.Logger
+	cache  time.Duration
+	routes map[string]route
+	now    func() time.Time
+}
+
+// New constructs a new Server.
+func New(logger *log.Logger, cache time.Duration) *Server {
+	return &Server{
+		logger: logger,
+		cache:  cache,
+		routes: make(map[string]route),
+		now:    time.Now,
+	}
+}
+
+// Add adds the feeds of a job to the server. The names of all feeds must be unique.
+func (s *Server) Add(name string, refresh RefreshFunc, feeds ...Feed) error {
+	j := &job{name: name, refresh: refresh}
+	for _, feed := range feeds {
+		if _, ok := s.routes[feed.FeedName()]; ok {
+			return fmt.Errorf("feed %s is configured multiple times", feed.FeedName())
+		}
+		if feed.Token() == "" {
+			s.logger.Warn("feed is not protected by a token", "job", name, "feed", feed.FeedName())
+		}
+		s.routes[feed.FeedName()] = route{feed: feed, job: j}
+	}
+	return nil
+}
+
+// Paths returns the paths of the feeds, the tokens are replaced by a placeholder
+func (s *Server) Paths() []string {
+	var paths []string
+	for _, name := range slices.Sorted(maps.Keys(s.routes)) {
+		if s.routes[name].feed.Token() != "" {
+			paths = append(paths, "/<token>/"+name+".ics")
+		} else {
+			paths = append(paths, "/"+name+".ics")
+		}
+	}
+	return paths
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	rt, ok := s.match(r.URL.Path)
+	if !ok {
+		// unknown feeds and wrong tokens are not distinguished, such that tokens cannot be guessed
+		http.NotFound(w, r)
+		return
+	}
+	logger := s.logger.With("job", rt.job.name, "feed", rt.feed.FeedName())
+
+	// the sync is not cancelled if the client disconnects, as other requests may wait for it
+	if available, err := s.refresh(context.WithoutCancel(r.Context()), rt.job); err != nil {
+		if !available {
+			logger.Error("failed to sync the feed", "error", err)
+			http.Error(w, "the feed is not available", http.StatusServiceUnavailable)
+			return
+		}
+		logger.Error("failed to sync the feed, serving the previous events", "error", err)
+	}
+
+	content, etag, err := rt.feed.Render()
+	if err != nil {
+		logger.Error("failed to render the feed", "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(s.cache.Seconds())))
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(content); err != nil {
+		logger.Debug("failed to write the feed", "error", err)
+	}
+}
+
+// match returns the route of the path, if the path contains the token of the feed
+func (s *Server) match(path string) (route, bool) {
+	token, file, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if file == "" {
+		token, file = "", token
+	}
+	name, ok := strings.CutSuffix(file, ".ics")
+	if !ok {
+		return route{}, false
+	}
+	rt, ok := s.routes[name]
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(rt.feed.Token())) != 1 {
+		return route{}, false
+	}
+	return rt, true
+}
+
+// refresh synchronises the job, unless it was refreshed within the cache duration. Concurrent requests wait for
+// a running refresh instead of starting another one. It returns false if the job was never refreshed successfully.
+func (s *Server) refresh(ctx context.Context, j *job) (bool, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.refreshed.IsZero() && s.now().Sub(j.refreshed) < s.cache {
+		return true, nil
+	}
+	if err := j.refresh(ctx); err != nil {
+		return !j.refreshed.IsZero(), err
+	}
+	j.refreshed = s.now()
+	return true, nil
+}
+
+// matchesETag checks if the If-None-Match header contains the ETag
+func matchesETag(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
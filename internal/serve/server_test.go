@@ -0,0 +1,104 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testFeed struct {
+	name    string
+	token   string
+	content string
+}
+
+func (f *testFeed) FeedName() string { return f.name }
+func (f *testFeed) Token() string    { return f.token }
+func (f *testFeed) Render() ([]byte, string, error) {
+	return []byte(f.content), `"` + f.content + `"`, nil
+}
+
+func get(server *Server, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestServeFeed(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	server := New(log.Default(), 5*time.Minute)
+	server.now = func() time.Time { return now }
+
+	feed := &testFeed{name: "team", token: "secret", content: "v1"}
+	public := &testFeed{name: "holidays", content: "public"}
+	var refreshes int
+	require.NoError(t, server.Add("job", func(ctx context.Context) error {
+		refreshes++
+		return nil
+	}, feed, public))
+	assert.Equal(t, []string{"/holidays.ics", "/<token>/team.ics"}, server.Paths())
+
+	rec := get(server, "/secret/team.ics", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "v1", rec.Body.String())
+	assert.Equal(t, `"v1"`, rec.Header().Get("ETag"))
+	assert.Equal(t, "text/calendar; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "private, max-age=300", rec.Header().Get("Cache-Control"))
+
+	// the job is only refreshed again after the cache duration
+	rec = get(server, "/secret/team.ics", http.Header{"If-None-Match": {`"v0", "v1"`}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	assert.Equal(t, http.StatusOK, get(server, "/holidays.ics", nil).Code)
+	assert.Equal(t, 1, refreshes)
+
+	now = now.Add(5 * time.Minute)
+	feed.content = "v2"
+	rec = get(server, "/secret/team.ics", http.Header{"If-None-Match": {`"v1"`}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "v2", rec.Body.String())
+	assert.Equal(t, 2, refreshes)
+
+	for _, path := range []string{"/team.ics", "/wrong/team.ics", "/secret/team", "/secret/other.ics", "/secret/holidays.ics", "/"} {
+		assert.Equal(t, http.StatusNotFound, get(server, path, nil).Code, path)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/secret/team.ics", nil)
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	assert.ErrorContains(t, server.Add("other", nil, &testFeed{name: "team"}), "configured multiple times")
+}
+
+func TestServeFailedRefresh(t *testing.T) {
+	server := New(log.Default(), 0)
+	feed := &testFeed{name: "team", token: "secret", content: "v1"}
+	refreshErr := errors.New("source not available")
+	require.NoError(t, server.Add("job", func(ctx context.Context) error {
+		return refreshErr
+	}, feed))
+
+	// without a successful sync, there are no events to serve
+	assert.Equal(t, http.StatusServiceUnavailable, get(server, "/secret/team.ics", nil).Code)
+
+	refreshErr = nil
+	assert.Equal(t, http.StatusOK, get(server, "/secret/team.ics", nil).Code)
+
+	// afterwards, the previous events are served
+	refreshErr = errors.New("source not available")
+	rec := get(server, "/secret/team.ics", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "v1", rec.Body.String())
+}
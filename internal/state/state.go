@@ -0,0 +1,153 @@
+// Package state persists the state of the synchronisation between runs.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// Entry is the state of the synchronisation of two calendars
+type Entry struct {
+	// Token is the sync token of the last incremental sync
+	Token string `json:"token,omitempty"`
+	// Window identifies the configured sync window the token was obtained for, e.g. "MonthStart/MonthEnd"
+	Window string `json:"window,omitempty"`
+	// Start and End are the timeframe of the full listing which returned the first token
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Events are the events which were in sync between both calendars of a bidirectional sync, by their SyncID
+	Events map[string]models.Event `json:"events,omitempty"`
+}
+
+// FileStore keeps the state in a json file. It is safe for concurrent use.
+type FileStore struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+// NewFileStore loads the state from the given path. A missing file is created on the first write.
+func NewFileStore(path string) (*FileStore, error) {
+	if strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(homeDir, path[2:])
+	}
+
+	store := &FileStore{
+		path:    path,
+		entries: make(map[string]Entry),
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("cannot read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &store.entries); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal state file %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// Token returns the token stored for the key and the timeframe of its full listing, if it was obtained for the same
+// sync window. The timeframe of a relative window moves between runs, so it's not compared.
+func (s *FileStore) Token(key string, window string) (string, time.Time, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || entry.Window != window {
+		return "", time.Time{}, time.Time{}
+	}
+	return entry.Token, entry.Start, entry.End
+}
+
+// SetToken stores the token for the key and writes the state file
+func (s *FileStore) SetToken(key string, window string, token string, start time.Time, end time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entries[key]
+	entry.Token, entry.Window, entry.Start, entry.End = token, window, start, end
+	s.entries[key] = entry
+	return s.write()
+}
+
+// SyncedEvents returns the events which were stored for the key
+func (s *FileStore) SyncedEvents(key string) map[string]models.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.entries[key].Events
+}
+
+// SetSyncedEvents stores the events for the key and writes the state file
+func (s *FileStore) SetSyncedEvents(key string, events map[string]models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entries[key]
+	entry.Events = events
+	s.entries[key] = entry
+	return s.write()
+}
+
+// write writes all entries to the state file, the caller needs to hold the lock
+func (s *FileStore) write() error {
+	content, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, such that an interrupted write does not corrupt the state
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return fmt.Errorf("cannot write state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("cannot write state file: %w", err)
+	}
+	return nil
+}
+
+// Scoped returns a view of the store in which all keys are prefixed with the given scope,
+// e.g. the name of a job
+func (s *FileStore) Scoped(scope string) *ScopedStore {
+	return &ScopedStore{store: s, prefix: scope + "/"}
+}
+
+// ScopedStore prefixes all keys of the underlying FileStore
+type ScopedStore struct {
+	store  *FileStore
+	prefix string
+}
+
+func (s *ScopedStore) Token(key string, window string) (string, time.Time, time.Time) {
+	return s.store.Token(s.prefix+key, window)
+}
+
+func (s *ScopedStore) SetToken(key string, window string, token string, start time.Time, end time.Time) error {
+	return s.store.SetToken(s.prefix+key, window, token, start, end)
+}
+
+func (s *ScopedStore) SyncedEvents(key string) map[string]models.Event {
+	return s.store.SyncedEvents(s.prefix + key)
+}
+
+func (s *ScopedStore) SetSyncedEvents(key string, events map[string]models.Event) error {
+	return s.store.SetSyncedEvents(s.prefix+key, events)
+}
@@ -0,0 +1,82 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+func TestFileStorePersistsTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+	token, _, _ := store.Token("key", "MonthStart/MonthEnd")
+	assert.Empty(t, token)
+
+	require.NoError(t, store.SetToken("key", "MonthStart/MonthEnd", "token", start, end))
+
+	reloaded, err := NewFileStore(path)
+	require.NoError(t, err)
+	token, tokenStart, tokenEnd := reloaded.Token("key", "MonthStart/MonthEnd")
+	assert.Equal(t, "token", token)
+	assert.Equal(t, start, tokenStart.UTC())
+	assert.Equal(t, end, tokenEnd.UTC())
+	// the token is only valid for the sync window it was obtained for
+	token, _, _ = reloaded.Token("key", "Now/P14D")
+	assert.Empty(t, token)
+}
+
+func TestScopedStoresDoNotShareTokens(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+	start := time.Now()
+	end := start.Add(time.Hour)
+
+	require.NoError(t, store.Scoped("work").SetToken("key", "Now/P14D", "work-token", start, end))
+	require.NoError(t, store.Scoped("team").SetToken("key", "Now/P14D", "team-token", start, end))
+
+	token, _, _ := store.Scoped("work").Token("key", "Now/P14D")
+	assert.Equal(t, "work-token", token)
+	token, _, _ = store.Scoped("team").Token("key", "Now/P14D")
+	assert.Equal(t, "team-token", token)
+}
+
+func TestFileStorePersistsSyncedEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	start := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	events := map[string]models.Event{
+		"1": {ID: "copy", Title: "Planning", StartTime: start, EndTime: start.Add(time.Hour), Metadata: models.NewEventMetadata("1", "", "calendarA")},
+	}
+
+	store, err := NewFileStore(path)
+	require.NoError(t, err)
+	assert.Empty(t, store.SyncedEvents("key"))
+
+	require.NoError(t, store.SetSyncedEvents("key", events))
+
+	reloaded, err := NewFileStore(path)
+	require.NoError(t, err)
+	synced := reloaded.SyncedEvents("key")
+	require.Len(t, synced, 1)
+	assert.Equal(t, "Planning", synced["1"].Title)
+	assert.True(t, start.Equal(synced["1"].StartTime))
+	assert.Equal(t, events["1"].Metadata, synced["1"].Metadata)
+	assert.Empty(t, reloaded.SyncedEvents("other"))
+}
+
+func TestNewFileStoreRejectsInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0600))
+
+	_, err := NewFileStore(path)
+	assert.Error(t, err)
+}
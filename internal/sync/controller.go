@@ -2,7 +2,10 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/inovex/CalendarSync/internal/models"
@@ -16,26 +19,105 @@ var (
 	}
 )
 
-// A Controller can synchronise the events from the sink via the given transformers into the sink
+// A Controller can synchronise the events from the sources via the given transformers into the sinks
 type Controller struct {
-	source Source
+	// sources are synchronised into the same sinks, each one owns the events it created there
+	sources []Source
+	// sinks are loaded and updated independently of each other
+	sinks       []sinkTarget
+	concurrency int
+	// bidirectional synchronises the only source and the only sink into each other
+	bidirectional bool
+	// reverse holds the transformers and filters of the events synchronised into the source in bidirectional mode
+	reverse sinkTarget
+	// state keeps the sync tokens of incremental syncs and the events which are in sync in bidirectional mode
+	state StateStore
+	// window identifies the configured sync window, the tokens in the state are stored per window
+	window string
+	// plan records the operations if set
+	plan *Plan
+	// diff configures which differences between a source event and its copy in the sink cause an update
+	diff        models.DiffOptions
+	deleteLimit DeleteLimit
+	logger      *log.Logger
+}
+
+// sinkTarget is a sink together with the transformers and filters which are applied to the events written to it
+type sinkTarget struct {
+	sink Sink
 	// transformers are applied in order
 	transformers []Transformer
 	filters      []Filter
-	sink         Sink
-	concurrency  int
-	logger       *log.Logger
+}
+
+// sourceEvents holds the events loaded from a single source
+type sourceEvents struct {
+	source Source
+	events []models.Event
+}
+
+// sourceChanges holds the changes loaded from a single incremental source for a single sink
+type sourceChanges struct {
+	source  IncrementalSource
+	changes models.Changes
+	// covered is the timeframe of the full listing which returned the first token, the token only lists the
+	// changes of the events in it
+	covered timeframe
+	// listed are the parts of the sync timeframe outside of covered, all events in them are part of the changes
+	listed []timeframe
+}
+
+type timeframe struct {
+	start time.Time
+	end   time.Time
+}
+
+// contains returns true if the event starts within the timeframe
+func (t timeframe) contains(event models.Event) bool {
+	return !event.StartTime.Before(t.start) && event.StartTime.Before(t.end)
+}
+
+// uncovered returns the parts of the timeframe from start to end which are outside of covered
+func (t timeframe) uncovered(start, end time.Time) []timeframe {
+	if !t.start.Before(t.end) {
+		return []timeframe{{start, end}}
+	}
+	var result []timeframe
+	if start.Before(t.start) {
+		result = append(result, timeframe{start, minTime(t.start, end)})
+	}
+	if end.After(t.end) {
+		result = append(result, timeframe{maxTime(t.end, start), end})
+	}
+	return result
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
 }
 
 // NewController constructs a new Controller.
-func NewController(logger *log.Logger, source Source, sink Sink, transformer []Transformer, filters []Filter) Controller {
+func NewController(logger *log.Logger, sources []Source, sink Sink, transformer []Transformer, filters []Filter) Controller {
 	return Controller{
-		concurrency:  1,
-		source:       source,
-		transformers: transformer,
-		filters:      filters,
-		sink:         sink,
-		logger:       logger,
+		concurrency: 1,
+		diff:        models.DefaultDiffOptions(),
+		sources:     sources,
+		sinks: []sinkTarget{{
+			sink:         sink,
+			transformers: transformer,
+			filters:      filters,
+		}},
+		logger: logger,
 	}
 }
 
@@ -43,70 +125,361 @@ func (p *Controller) SetConcurrency(concurrency int) {
 	p.concurrency = concurrency
 }
 
-// loadEvents will load source and sink events in the given timeframe and return them
-func (p Controller) loadEvents(ctx context.Context, start, end time.Time) (source []models.Event, sink []models.Event, err error) {
-	source, err = p.source.EventsInTimeframe(ctx, start, end)
+// SetDiffOptions configures which differences between a source event and its copy in a sink cause an update.
+// Defaults to models.DefaultDiffOptions.
+func (p *Controller) SetDiffOptions(options models.DiffOptions) {
+	p.diff = options
+}
+
+// SetBidirectional enables the bidirectional mode. In this mode, the Controller synchronises exactly one source
+// and one sink into each other. The source must be writable and therefore implement the Sink interface as well.
+func (p *Controller) SetBidirectional(bidirectional bool) {
+	p.bidirectional = bidirectional
+}
+
+// SetReverseTransformation sets the transformers and filters which are applied to the events synchronised from the
+// sink into the source in bidirectional mode. The transformers and filters of the sink are only applied to the events
+// synchronised into the sink.
+func (p *Controller) SetReverseTransformation(transformers []Transformer, filters []Filter) {
+	p.reverse = sinkTarget{transformers: transformers, filters: filters}
+}
+
+// SetStateStore sets the store which keeps the state between runs. It enables incremental syncs: IncrementalSources
+// only list the changes since the previous run, which are tracked by sync tokens in the given store. The sinks are only
+// loaded if there are any changes. The tokens are stored per window, which identifies the configured sync window: a
+// relative window moves with every run, the events which moved into it are listed in addition to the changes.
+// Incremental syncs are not supported in bidirectional mode, which requires the store to tell whether a copy was
+// deleted or edited since the last run.
+func (p *Controller) SetStateStore(state StateStore, window string) {
+	p.state = state
+	p.window = window
+}
+
+// AddSink adds another sink to the Controller. The events of all sources are synchronised into every sink,
+// each sink applies its own transformers and filters.
+func (p *Controller) AddSink(sink Sink, transformers []Transformer, filters []Filter) {
+	p.sinks = append(p.sinks, sinkTarget{
+		sink:         sink,
+		transformers: transformers,
+		filters:      filters,
+	})
+}
+
+// loadSourceEvents will load the events of the given source in the given timeframe and return them
+func (p Controller) loadSourceEvents(ctx context.Context, source Source, start, end time.Time) ([]models.Event, error) {
+	events, err := source.EventsInTimeframe(ctx, start, end)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get events in timeframe from source %s: %v", p.source.Name(), err)
+		return nil, fmt.Errorf("failed to get events in timeframe from source %s: %v", source.Name(), err)
 	}
 
 	if p.logger.GetLevel() == log.DebugLevel {
-		for _, event := range source {
+		for _, event := range events {
 			p.logger.Debug("source event loaded", logFields(event)...)
 		}
 	}
 
-	sink, err = p.sink.EventsInTimeframe(ctx, start, end)
+	return events, nil
+}
+
+// loadSinkEvents will load the events of the given sink in the given timeframe and return them
+func (p Controller) loadSinkEvents(ctx context.Context, sink Sink, start, end time.Time) ([]models.Event, error) {
+	events, err := sink.EventsInTimeframe(ctx, start, end)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get events in timeframe from sink %s: %v", p.sink.Name(), err)
+		return nil, fmt.Errorf("failed to get events in timeframe from sink %s: %v", sink.Name(), err)
 	}
 
 	if p.logger.GetLevel() == log.DebugLevel {
-		for _, event := range sink {
+		for _, event := range events {
 			p.logger.Debug("sink event loaded", logFields(event)...)
 		}
 	}
 
-	return source, sink, err
+	return events, nil
 }
 
-// SynchroniseTimeframe synchronises all events in the given timeframe
-func (p Controller) SynchroniseTimeframe(ctx context.Context, start time.Time, end time.Time, dryRun bool) error {
-	eventsInSource, eventsInSink, err := p.loadEvents(ctx, start, end)
-	if err != nil {
-		return err
+// SynchroniseTimeframe synchronises all events of all sources in the given timeframe into every sink.
+// The sources are only loaded once, the diff and the resulting changes are computed per sink.
+// An error in one sink does not stop the synchronisation of the other sinks.
+// The returned Summary counts the events which were changed successfully.
+func (p Controller) SynchroniseTimeframe(ctx context.Context, start time.Time, end time.Time, dryRun bool) (Summary, error) {
+	if p.plan != nil {
+		p.plan.Start, p.plan.End = start, end
 	}
 
-	filteredEventsInSource := []models.Event{}
+	if p.bidirectional {
+		return p.synchroniseBidirectional(ctx, start, end, dryRun)
+	}
 
-	for _, filter := range p.filters {
-		p.logger.Debug("loaded filter", "name", filter.Name())
+	var errs []error
+	var counter summaryCounter
+
+	var loaded []sourceEvents
+	for _, source := range p.sources {
+		if p.isIncremental(source) {
+			// the changes of incremental sources are loaded per sink
+			continue
+		}
+		// A source which cannot be loaded is skipped entirely. Its events in the sinks stay untouched,
+		// the remaining sources are still synchronised.
+		events, err := p.loadSourceEvents(ctx, source, start, end)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		loaded = append(loaded, sourceEvents{source: source, events: events})
 	}
 
-	for _, event := range eventsInSource {
-		if FilterEvent(event, p.filters...) {
-			filteredEventsInSource = append(filteredEventsInSource, event)
-		} else {
-			p.logger.Debug("filter rejects event", logFields(event)...)
+	for _, target := range p.sinks {
+		if err := p.synchroniseSink(ctx, target, loaded, start, end, dryRun, &counter); err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", target.sink.Name(), err))
 		}
 	}
 
-	// Transform source events before comparing them to the sink events
-	transformedEventsInSource := []models.Event{}
+	if dryRun {
+		p.logger.Warn("we're running in dry run mode, no changes will be executed")
+	}
+
+	return counter.summary(), errors.Join(errs...)
+}
+
+// synchroniseSink applies the filters and transformers of the given sink to the loaded source events and
+// synchronises the result into the sink.
+func (p Controller) synchroniseSink(ctx context.Context, target sinkTarget, loaded []sourceEvents, start, end time.Time, dryRun bool, counter *summaryCounter) error {
+	for _, filter := range target.filters {
+		p.logger.Debug("loaded filter", "name", filter.Name())
+	}
 
 	// Output which transformers were loaded
-	for _, trans := range p.transformers {
+	for _, trans := range target.transformers {
 		p.logger.Debug("loaded transformer", "name", trans.Name())
 	}
 
-	for _, event := range filteredEventsInSource {
-		transformedEventsInSource = append(transformedEventsInSource, TransformEvent(event, p.transformers...))
+	changes, errs := p.loadChanges(ctx, target.sink, start, end)
+	if len(loaded) == 0 && !hasChanges(changes) {
+		p.logger.Info("no changes since the last sync, skipping sink")
+		if !dryRun {
+			errs = append(errs, p.storeTokens(target.sink, changes, start, end))
+		}
+		return errors.Join(errs...)
+	}
+
+	eventsInSink, err := p.loadSinkEvents(ctx, target.sink, start, end)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	var tasks []taskFunc
+	var deletions int
+	for _, src := range loaded {
+		toCreate, toUpdate, toDelete := p.diffEvents(src.source, target.sink, target.prepare(p.logger, src.events), eventsInSink)
+		p.logger.Infof("found %d new, %d changed, and %d deleted events", len(toCreate), len(toUpdate), len(toDelete))
+		p.recordPlan(target.sink, toCreate, toUpdate, toDelete, eventsInSink)
+		counter.countFields(p.diff, toUpdate, eventsInSink)
+		deletions += len(toDelete)
+
+		tasks = append(tasks, p.syncTasks(ctx, target.sink, toCreate, toUpdate, toDelete, counter, dryRun)...)
+	}
+
+	for _, src := range changes {
+		toCreate, toUpdate, toDelete := p.diffChanges(src, target, eventsInSink)
+		p.logger.Infof("found %d new, %d changed, and %d deleted events", len(toCreate), len(toUpdate), len(toDelete))
+		p.recordPlan(target.sink, toCreate, toUpdate, toDelete, eventsInSink)
+		counter.countFields(p.diff, toUpdate, eventsInSink)
+		deletions += len(toDelete)
+
+		tasks = append(tasks, p.syncTasks(ctx, target.sink, toCreate, toUpdate, toDelete, counter, dryRun)...)
+	}
+
+	if err := p.checkDeleteLimit(target.sink, deletions, len(eventsInSink), dryRun); err != nil {
+		return errors.Join(append(errs, err)...)
 	}
 
-	toCreate, toUpdate, toDelete := p.diffEvents(transformedEventsInSource, eventsInSink)
-	log.Infof("found %d new, %d changed, and %d deleted events", len(toCreate), len(toUpdate), len(toDelete))
 	if dryRun {
-		p.logger.Warn("we're running in dry run mode, no changes will be executed")
+		return errors.Join(errs...)
+	}
+
+	if err := errors.Join(parallel(ctx, p.concurrency, tasks), flush(ctx, target.sink)); err != nil {
+		// Keep the previous tokens, such that the failed changes are retried with the next run
+		return errors.Join(append(errs, err)...)
+	}
+
+	errs = append(errs, p.storeTokens(target.sink, changes, start, end))
+	return errors.Join(errs...)
+}
+
+// isIncremental returns true if the changes of the source can be loaded incrementally
+func (p Controller) isIncremental(source Source) bool {
+	_, ok := source.(IncrementalSource)
+	return ok && p.state != nil
+}
+
+// loadChanges loads the changes of all incremental sources since the last sync into the given sink.
+// If the stored token of a source is no longer valid, a full listing is loaded instead.
+// A source which cannot be loaded is skipped and its error is returned.
+func (p Controller) loadChanges(ctx context.Context, sink Sink, start, end time.Time) ([]sourceChanges, []error) {
+	var result []sourceChanges
+	var errs []error
+	for _, source := range p.sources {
+		if !p.isIncremental(source) {
+			continue
+		}
+		incremental := source.(IncrementalSource)
+
+		src, err := p.loadSourceChanges(ctx, incremental, sink, start, end)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get changed events from source %s: %v", source.Name(), err))
+			continue
+		}
+		result = append(result, src)
+	}
+	return result, errs
+}
+
+// loadSourceChanges loads the changes of the source since the stored token. As the token only lists the changes
+// of the events in the timeframe of its full listing, all events in the rest of the timeframe are listed as well.
+// If that's more than half of the timeframe, a full listing is loaded instead.
+func (p Controller) loadSourceChanges(ctx context.Context, source IncrementalSource, sink Sink, start, end time.Time) (sourceChanges, error) {
+	token, coveredStart, coveredEnd := p.state.Token(stateKey(source, sink), p.window)
+	covered := timeframe{coveredStart, coveredEnd}
+	var uncovered time.Duration
+	for _, part := range covered.uncovered(start, end) {
+		uncovered += part.end.Sub(part.start)
+	}
+	switch {
+	case token == "":
+		p.logger.Info("no sync token for the sync window, loading all events", "source", source.Name(), "window", p.window)
+	case uncovered > end.Sub(start)/2:
+		p.logger.Info("the sync window moved too far since the last full sync, loading all events", "source", source.Name(), "window", p.window,
+			"previousStart", coveredStart, "previousEnd", coveredEnd)
+		token = ""
+	}
+
+	changes, err := source.ChangedEventsInTimeframe(ctx, start, end, token)
+	if errors.Is(err, models.ErrSyncTokenInvalid) {
+		p.logger.Info("sync token is no longer valid, falling back to a full sync", "source", source.Name())
+		changes, err = source.ChangedEventsInTimeframe(ctx, start, end, "")
+	}
+	if err != nil {
+		return sourceChanges{}, err
+	}
+	if changes.Full {
+		return sourceChanges{source: source, changes: changes, covered: timeframe{start, end}}, nil
+	}
+
+	result := sourceChanges{source: source, covered: covered, changes: models.Changes{Deleted: changes.Deleted, Token: changes.Token}}
+	current := timeframe{start, end}
+	listed := make(map[string]bool)
+	for _, event := range changes.Events {
+		// the token lists the changes of events which moved out of the sync window
+		if current.contains(event) {
+			result.changes.Events = append(result.changes.Events, event)
+			listed[event.ID] = true
+		}
+	}
+	for _, part := range covered.uncovered(start, end) {
+		p.logger.Debug("loading the events which moved into the sync window", "source", source.Name(), "start", part.start, "end", part.end)
+		events, err := source.EventsInTimeframe(ctx, part.start, part.end)
+		if err != nil {
+			return sourceChanges{}, err
+		}
+		for _, event := range events {
+			if !listed[event.ID] {
+				result.changes.Events = append(result.changes.Events, event)
+				listed[event.ID] = true
+			}
+		}
+		result.listed = append(result.listed, part)
+	}
+	return result, nil
+}
+
+// storeTokens stores the tokens of the given changes, such that the next sync only loads the changes after them
+func (p Controller) storeTokens(sink Sink, changes []sourceChanges, start, end time.Time) error {
+	var errs []error
+	for _, src := range changes {
+		if err := p.state.SetToken(stateKey(src.source, sink), p.window, src.changes.Token, src.covered.start, src.covered.end); err != nil {
+			errs = append(errs, fmt.Errorf("failed to store sync token of source %s: %w", src.source.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// resetChanges returns empty changes without a token for all incremental sources, storing them resets the sync state
+func (p Controller) resetChanges() []sourceChanges {
+	var result []sourceChanges
+	for _, source := range p.sources {
+		if p.isIncremental(source) {
+			result = append(result, sourceChanges{source: source.(IncrementalSource)})
+		}
+	}
+	return result
+}
+
+// stateKey identifies the state of the synchronisation of a source into a sink
+func stateKey(source Source, sink Sink) string {
+	return source.GetCalendarHash() + ":" + sink.GetCalendarHash()
+}
+
+func hasChanges(changes []sourceChanges) bool {
+	for _, src := range changes {
+		if src.changes.Full || len(src.listed) > 0 || len(src.changes.Events) > 0 || len(src.changes.Deleted) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// diffChanges computes the changes of the sink for the changes of an incremental source.
+// Events which are not part of the changes are unknown and therefore left untouched in the sink.
+func (p Controller) diffChanges(src sourceChanges, target sinkTarget, sinkEvents []models.Event) ([]models.Event, []models.Event, []models.Event) {
+	changes := src.changes
+	prepared := target.prepare(p.logger, changes.Events)
+	if changes.Full {
+		return p.diffEvents(src.source, target.sink, prepared, sinkEvents)
+	}
+
+	// Only compare the sink events which belong to a changed or deleted event. The copies of changed events
+	// which are rejected by the filters are not part of the prepared events and are therefore deleted by
+	// diffEvents, just like the copies of deleted events.
+	affected := make(map[string]bool)
+	for _, event := range append(changes.Events, changes.Deleted...) {
+		if event.Metadata != nil {
+			affected[event.Metadata.SyncID] = true
+		}
+	}
+
+	var affectedSinkEvents []models.Event
+	for _, event := range sinkEvents {
+		// all events of the listed timeframes are part of the changes, like in a full listing
+		listed := slices.ContainsFunc(src.listed, func(t timeframe) bool { return t.contains(event) })
+		if event.Metadata != nil && (affected[event.Metadata.SyncID] || listed) {
+			affectedSinkEvents = append(affectedSinkEvents, event)
+		}
+	}
+
+	return p.diffEvents(src.source, target.sink, prepared, affectedSinkEvents)
+}
+
+// prepare filters the given source events and transforms the remaining ones before they are compared to the sink events
+func (t sinkTarget) prepare(logger *log.Logger, events []models.Event) []models.Event {
+	transformed := []models.Event{}
+	for _, event := range events {
+		if !FilterEvent(event, t.filters...) {
+			logger.Debug("filter rejects event", logFields(event)...)
+			continue
+		}
+		transformed = append(transformed, TransformEvent(event, t.transformers...))
+	}
+	return transformed
+}
+
+// syncTasks returns the tasks which apply the given changes to the sink. Every successful change is counted in the
+// given counter. In dry run mode, the changes are counted right away, as the tasks are not executed.
+func (p Controller) syncTasks(ctx context.Context, sink Sink, toCreate, toUpdate, toDelete []models.Event, counter *summaryCounter, dryRun bool) []taskFunc {
+	if dryRun {
+		counter.created.Add(int64(len(toCreate)))
+		counter.updated.Add(int64(len(toUpdate)))
+		counter.deleted.Add(int64(len(toDelete)))
 		return nil
 	}
 
@@ -115,9 +488,10 @@ func (p Controller) SynchroniseTimeframe(ctx context.Context, start time.Time, e
 		// redefine to let the closure capture individual variables
 		event := event
 		tasks = append(tasks, func() error {
-			if err := p.sink.DeleteEvent(ctx, event); err != nil {
-				return fmt.Errorf("failed to delete event %s in sink %s: %w", event.ShortTitle(), p.sink.Name(), err)
+			if err := sink.DeleteEvent(ctx, event); err != nil {
+				return fmt.Errorf("failed to delete event %s in sink %s: %w", event.ShortTitle(), sink.Name(), err)
 			}
+			counter.deleted.Add(1)
 			return nil
 		})
 	}
@@ -126,9 +500,10 @@ func (p Controller) SynchroniseTimeframe(ctx context.Context, start time.Time, e
 		// redefine to let the closure capture individual variables
 		event := event
 		tasks = append(tasks, func() error {
-			if err := p.sink.CreateEvent(ctx, event); err != nil {
-				return fmt.Errorf("failed to create event %s in sink %s: %w", event.ShortTitle(), p.sink.Name(), err)
+			if err := sink.CreateEvent(ctx, event); err != nil {
+				return fmt.Errorf("failed to create event %s in sink %s: %w", event.ShortTitle(), sink.Name(), err)
 			}
+			counter.created.Add(1)
 			return nil
 		})
 	}
@@ -137,45 +512,280 @@ func (p Controller) SynchroniseTimeframe(ctx context.Context, start time.Time, e
 		// redefine to let the closure capture individual variables
 		event := event
 		tasks = append(tasks, func() error {
-			if err := p.sink.UpdateEvent(ctx, event); err != nil {
-				return fmt.Errorf("unable to update event %s: %s at %s in sink %s: %v", event.ShortTitle(), event.ShortTitle(), event.StartTime.Format(time.RFC1123), p.sink.Name(), err)
+			if err := sink.UpdateEvent(ctx, event); err != nil {
+				return fmt.Errorf("unable to update event %s: %s at %s in sink %s: %v", event.ShortTitle(), event.ShortTitle(), event.StartTime.Format(time.RFC1123), sink.Name(), err)
 			}
+			counter.updated.Add(1)
 			return nil
 		})
 	}
 
-	return parallel(ctx, p.concurrency, tasks)
+	return tasks
 }
 
-func (p Controller) CleanUp(ctx context.Context, start time.Time, end time.Time) error {
-	_, eventsInSink, err := p.loadEvents(ctx, start, end)
-	if err != nil {
-		return err
+// CleanUp removes all events from the sinks which were synced by any of the sources.
+// In bidirectional mode, the events synced into the source are removed as well. The copies which were in sync are
+// forgotten, otherwise the next run would take the removed copies for deleted ones and delete their originals.
+func (p Controller) CleanUp(ctx context.Context, start time.Time, end time.Time) (Summary, error) {
+	var counter summaryCounter
+
+	if p.bidirectional {
+		a, b, err := p.bidirectionalPair()
+		if err != nil {
+			return Summary{}, err
+		}
+		errs := []error{
+			p.cleanUpSink(ctx, b.sink, []Source{a}, start, end, &counter),
+			p.cleanUpSink(ctx, a, []Source{b.sink}, start, end, &counter),
+		}
+		if p.state != nil {
+			// the state is reset even if a cleanup failed, as some of the copies may have been removed
+			errs = append(errs, p.state.SetSyncedEvents(stateKey(a, b.sink), nil))
+		}
+		return counter.summary(), errors.Join(errs...)
 	}
 
-	sink := maps(eventsInSink)
+	var errs []error
+	for _, target := range p.sinks {
+		if err := p.cleanUpSink(ctx, target.sink, p.sources, start, end, &counter); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if p.state != nil {
+			// the next incremental sync has to start over with a full sync to restore the removed events
+			errs = append(errs, p.storeTokens(target.sink, p.resetChanges(), start, end))
+		}
+	}
 
-	var tasks []taskFunc
+	return counter.summary(), errors.Join(errs...)
+}
+
+// cleanUpSink removes all events from the sink which were synced by any of the given sources
+func (p Controller) cleanUpSink(ctx context.Context, sink Sink, sources []Source, start time.Time, end time.Time, counter *summaryCounter) error {
+	sourceIDs := make(map[string]bool)
+	for _, source := range sources {
+		sourceIDs[source.GetCalendarHash()] = true
+	}
+
+	eventsInSink, err := p.loadSinkEvents(ctx, sink, start, end)
+	if err != nil {
+		return err
+	}
 
-	for _, event := range sink {
+	var toDelete []models.Event
+	for _, event := range maps(eventsInSink) {
 		// Check if the sink event was synced by us, if there's no metadata the event may
 		// be there because we were invited or because it is not managed by us
-		if event.Metadata.SourceID == p.source.GetCalendarHash() {
-			// redefine to let the closure capture individual variables
-			event := event
-			tasks = append(tasks, func() error {
-				if err := p.sink.DeleteEvent(ctx, event); err != nil {
-					return fmt.Errorf("failed to delete event %s in sink %s: %w", event.ShortTitle(), p.sink.Name(), err)
-				}
-				return nil
-			})
+		if sourceIDs[event.Metadata.SourceID] {
+			toDelete = append(toDelete, event)
 		}
 	}
 
-	return parallel(ctx, p.concurrency, tasks)
+	return errors.Join(parallel(ctx, p.concurrency, p.syncTasks(ctx, sink, nil, nil, toDelete, counter, false)), flush(ctx, sink))
+}
+
+// flush writes the changes of the sinks which buffer them, see BufferedSink. It's called after all changes were
+// executed, also if some of them failed, as the buffered changes were applied.
+func flush(ctx context.Context, sinks ...Sink) error {
+	var errs []error
+	for _, sink := range sinks {
+		if buffered, ok := sink.(BufferedSink); ok {
+			if err := buffered.Flush(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("failed to write the changes to %s: %w", sink.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// bidirectionalPair returns the two calendars which are synchronised into each other in bidirectional mode
+func (p Controller) bidirectionalPair() (Sink, sinkTarget, error) {
+	if len(p.sources) != 1 || len(p.sinks) != 1 {
+		return nil, sinkTarget{}, fmt.Errorf("bidirectional mode requires exactly one source and one sink, got %d sources and %d sinks", len(p.sources), len(p.sinks))
+	}
+	a, ok := p.sources[0].(Sink)
+	if !ok {
+		return nil, sinkTarget{}, fmt.Errorf("bidirectional mode requires a writable source, %s can only be read from", p.sources[0].Name())
+	}
+	return a, p.sinks[0], nil
 }
 
-func (p Controller) diffEvents(sourceEvents []models.Event, sinkEvents []models.Event) ([]models.Event, []models.Event, []models.Event) {
+// synchroniseBidirectional synchronises the source and the sink into each other.
+//
+// Both calendars are loaded once. Every event is routed by its origin (Metadata.SourceID): events which originate
+// from one calendar are synchronised into the other one, copies which were synced by us are never synchronised
+// back into their origin as new events. Creating, updating or deleting an original event is propagated to its copy.
+// The copies which are in sync after a run are kept in the state store, with them the next run tells whether a copy
+// was deleted or edited since: deleting a copy deletes its original event, the edits of a copy are written to its
+// original event (see copyEdits). A copy which was moved out of the timeframe counts as deleted. The transformers
+// and filters of the sink are applied to the events synchronised into the sink, those set by
+// SetReverseTransformation to the events synchronised into the source.
+// Both diffs are computed before any change is applied, so the changes of this run do not influence each other.
+func (p Controller) synchroniseBidirectional(ctx context.Context, start time.Time, end time.Time, dryRun bool) (Summary, error) {
+	a, target, err := p.bidirectionalPair()
+	if err != nil {
+		return Summary{}, err
+	}
+	if p.state == nil {
+		return Summary{}, fmt.Errorf("bidirectional mode requires a state store")
+	}
+	b := target.sink
+	reverse := p.reverse
+	reverse.sink = a
+
+	eventsInA, err := p.loadSinkEvents(ctx, a, start, end)
+	if err != nil {
+		return Summary{}, err
+	}
+	eventsInB, err := p.loadSinkEvents(ctx, b, start, end)
+	if err != nil {
+		return Summary{}, err
+	}
+
+	key := stateKey(a, b)
+	synced := p.state.SyncedEvents(key)
+
+	originalsA, editedInA, deletedInA := p.reconcileCopies(a, eventsInA, eventsInB, synced)
+	originalsB, editedInB, deletedInB := p.reconcileCopies(b, eventsInB, eventsInA, synced)
+
+	createInB, updateInB, deleteInB := p.diffEvents(a, b, target.prepare(p.logger, originalsA), eventsInB)
+	createInA, updateInA, deleteInA := p.diffEvents(b, a, reverse.prepare(p.logger, originalsB), eventsInA)
+
+	// the copies which are in sync once the changes are applied
+	inSync := make(map[string]models.Event)
+	syncedCopies(inSync, a, eventsInB, createInB, updateInB, deleteInB)
+	syncedCopies(inSync, b, eventsInA, createInA, updateInA, deleteInA)
+
+	updateInB, deleteInB = append(updateInB, editedInB...), append(deleteInB, deletedInB...)
+	updateInA, deleteInA = append(updateInA, editedInA...), append(deleteInA, deletedInA...)
+	p.logger.Infof("found %d new, %d changed, and %d deleted events for %s", len(createInB), len(updateInB), len(deleteInB), b.Name())
+	p.recordPlan(b, createInB, updateInB, deleteInB, eventsInB)
+	p.logger.Infof("found %d new, %d changed, and %d deleted events for %s", len(createInA), len(updateInA), len(deleteInA), a.Name())
+	p.recordPlan(a, createInA, updateInA, deleteInA, eventsInA)
+
+	var counter summaryCounter
+	counter.countFields(p.diff, updateInB, eventsInB)
+	counter.countFields(p.diff, updateInA, eventsInA)
+	if err := p.checkDeleteLimit(b, len(deleteInB), len(eventsInB), dryRun); err != nil {
+		return Summary{}, err
+	}
+	if err := p.checkDeleteLimit(a, len(deleteInA), len(eventsInA), dryRun); err != nil {
+		return Summary{}, err
+	}
+
+	tasks := p.syncTasks(ctx, b, createInB, updateInB, deleteInB, &counter, dryRun)
+	tasks = append(tasks, p.syncTasks(ctx, a, createInA, updateInA, deleteInA, &counter, dryRun)...)
+
+	if dryRun {
+		p.logger.Warn("we're running in dry run mode, no changes will be executed")
+		return counter.summary(), nil
+	}
+
+	if err := errors.Join(parallel(ctx, p.concurrency, tasks), flush(ctx, b, a)); err != nil {
+		// the state of the last run is kept, as it's unknown which changes were applied
+		return counter.summary(), err
+	}
+	return counter.summary(), p.state.SetSyncedEvents(key, inSync)
+}
+
+// reconcileCopies compares the copies of the events which originate from the calendar with the copies which were
+// in sync after the last run. It returns the original events which are synchronised into the other calendar, with
+// the edits of their copies applied, the original events which are updated because their copy was edited and the
+// original events which are deleted because their copy was deleted.
+func (p Controller) reconcileCopies(calendar Sink, events []models.Event, otherEvents []models.Event, synced map[string]models.Event) ([]models.Event, []models.Event, []models.Event) {
+	var originals, toUpdate, toDelete []models.Event
+	copies := maps(otherEvents)
+	for _, event := range originatingFrom(calendar, events) {
+		last, wasSynced := synced[event.Metadata.SyncID]
+		eventCopy, exists := copies[event.Metadata.SyncID]
+
+		switch {
+		case !wasSynced:
+			originals = append(originals, event)
+
+		case !exists:
+			p.logger.Info("the copy of the event was deleted, marked for removal", logFields(event)...)
+			toDelete = append(toDelete, event)
+
+		default:
+			edited, fields := copyEdits(event, last, eventCopy)
+			if len(fields) > 0 {
+				p.logger.Info("the copy of the event was edited, needs sync", append(logFields(event), "fields", strings.Join(fields, ","))...)
+				toUpdate = append(toUpdate, edited)
+			}
+			originals = append(originals, edited)
+		}
+	}
+	return originals, toUpdate, toDelete
+}
+
+// copyEdits applies the fields of the copy which were edited since it was last in sync to the original event and
+// returns the names of the edited fields. Only the title, the description, the location and the time are taken over,
+// the other fields of a copy are usually changed by the transformers or by the calendar of the copy. If a field was
+// changed in both calendars, the edit of the copy wins.
+func copyEdits(original, last, eventCopy models.Event) (models.Event, []string) {
+	var fields []string
+	if eventCopy.Title != last.Title {
+		original.Title = eventCopy.Title
+		fields = append(fields, "title")
+	}
+	if eventCopy.Description != last.Description {
+		original.Description = eventCopy.Description
+		fields = append(fields, "description")
+	}
+	if eventCopy.Location != last.Location {
+		original.Location = eventCopy.Location
+		fields = append(fields, "location")
+	}
+	if !sameTime(eventCopy, last) {
+		original.StartTime, original.EndTime, original.AllDay = eventCopy.StartTime, eventCopy.EndTime, eventCopy.AllDay
+		fields = append(fields, "time")
+	}
+	return original, fields
+}
+
+// sameTime returns true if the events take place at the same time, all-day events only compare the dates
+func sameTime(a, b models.Event) bool {
+	if a.AllDay != b.AllDay {
+		return false
+	}
+	if a.AllDay {
+		return a.StartTime.Format(time.DateOnly) == b.StartTime.Format(time.DateOnly) &&
+			a.EndTime.Format(time.DateOnly) == b.EndTime.Format(time.DateOnly)
+	}
+	return a.StartTime.Equal(b.StartTime) && a.EndTime.Equal(b.EndTime)
+}
+
+// syncedCopies adds the copies of the events originating from the given calendar to inSync, as they are once the
+// changes computed by diffEvents are applied
+func syncedCopies(inSync map[string]models.Event, origin Source, copies, toCreate, toUpdate, toDelete []models.Event) {
+	for _, event := range copies {
+		if event.Metadata != nil && event.Metadata.SourceID == origin.GetCalendarHash() {
+			inSync[event.Metadata.SyncID] = event
+		}
+	}
+	for _, event := range toDelete {
+		delete(inSync, event.Metadata.SyncID)
+	}
+	for _, event := range append(toCreate, toUpdate...) {
+		inSync[event.Metadata.SyncID] = event
+	}
+}
+
+// originatingFrom returns the events which originate from the given calendar and were not synced into it
+func originatingFrom(calendar Source, events []models.Event) []models.Event {
+	var result []models.Event
+	for _, event := range events {
+		if event.Metadata != nil && event.Metadata.SourceID == calendar.GetCalendarHash() {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// diffEvents compares the (transformed) events of the given source with the sink events.
+// Only sink events which were synced by this source are updated or deleted.
+func (p Controller) diffEvents(src Source, snk Sink, sourceEvents []models.Event, sinkEvents []models.Event) ([]models.Event, []models.Event, []models.Event) {
 	var (
 		createEvents = make([]models.Event, 0)
 		updateEvents = make([]models.Event, 0)
@@ -202,23 +812,39 @@ func (p Controller) diffEvents(sourceEvents []models.Event, sinkEvents []models.
 			// - Run sync from calendar B to calendar A. This will copy (and thereby resurrect) the event.
 			//
 			// Solution: Ignore events that originate from the sink, but no longer exist there.
-			if event.Metadata.SourceID == p.sink.GetCalendarHash() {
+			if event.Metadata.SourceID == snk.GetCalendarHash() {
 				p.logger.Info("skipping event as it originates from the sink, but no longer exists there", logFields(event)...)
 				continue
 			}
+			if event.Instance != nil {
+				// a modified instance is created by modifying the instance of the series in the sink
+				series, ok := sink[event.Instance.SeriesSyncID]
+				if !ok {
+					p.logger.Info("series of modified instance is not synced yet, syncing with the next run", logFields(event)...)
+					continue
+				}
+				instance := *event.Instance
+				instance.SeriesID = series.ID
+				event.Instance = &instance
+			}
 			p.logger.Info("new event, needs sync", logFields(event)...)
 			createEvents = append(createEvents, event)
 
-		case sinkEvent.Metadata.SourceID != p.source.GetCalendarHash():
+		case sinkEvent.Metadata.SourceID != src.GetCalendarHash():
 			p.logger.Info("event was not synced by this source adapter, skipping", logFields(event)...)
 
+		default:
 			// Only update the event if the event differs AND we synced it prior and set the correct metadata
-		case !models.IsSameEvent(event, sinkEvent) && sinkEvent.Metadata.SourceID == p.source.GetCalendarHash():
-			p.logger.Info("event content changed, needs sync", logFields(event)...)
+			diff := p.diff.DiffEvents(sinkEvent, event)
+			if !diff.Changed() {
+				p.logger.Debug("event in sync", logFields(event)...)
+				continue
+			}
+			p.logger.Info("event content changed, needs sync", append(logFields(event), "fields", strings.Join(diff.Fields(), ","))...)
+			for _, field := range diff {
+				p.logger.Debug("field changed", append(logFields(event), "field", field.Field, "sink", field.Old, "source", field.New)...)
+			}
 			updateEvents = append(updateEvents, sinkEvent.Overwrite(event))
-
-		default:
-			p.logger.Debug("event in sync", logFields(event)...)
 		}
 	}
 
@@ -232,7 +858,7 @@ func (p Controller) diffEvents(sourceEvents []models.Event, sinkEvents []models.
 		case exists:
 			// Nothing to do
 
-		case event.Metadata.SourceID == p.source.GetCalendarHash():
+		case event.Metadata.SourceID == src.GetCalendarHash():
 			p.logger.Info("sinkEvent is not (anymore) in sourceEvents, marked for removal", logFields(event)...)
 			deleteEvents = append(deleteEvents, event)
 
@@ -243,7 +869,23 @@ func (p Controller) diffEvents(sourceEvents []models.Event, sinkEvents []models.
 		}
 	}
 
-	return createEvents, updateEvents, deleteEvents
+	return createEvents, updateEvents, withoutDeletedInstances(deleteEvents)
+}
+
+// withoutDeletedInstances removes the modified instances of series which are deleted as well,
+// as they are deleted together with their series
+func withoutDeletedInstances(deleteEvents []models.Event) []models.Event {
+	deleted := maps(deleteEvents)
+	result := make([]models.Event, 0, len(deleteEvents))
+	for _, event := range deleteEvents {
+		if event.Instance != nil {
+			if _, ok := deleted[event.Instance.SeriesSyncID]; ok {
+				continue
+			}
+		}
+		result = append(result, event)
+	}
+	return result
 }
 
 func maps(events []models.Event) map[string]models.Event {
@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -36,8 +37,8 @@ func (suite *ControllerTestSuite) SetupTest() {
 	})
 	filters := FilterFactory([]config.Filter{
 		{Name: "DeclinedEvents"},
-	})
-	suite.controller = NewController(log.Default(), suite.source, suite.sink, transformers, filters)
+	}, nil)
+	suite.controller = NewController(log.Default(), []Source{suite.source}, suite.sink, transformers, filters)
 }
 
 // TestDryRun tests that no acutal adapter func is called
@@ -102,7 +103,7 @@ func (suite *ControllerTestSuite) TestDryRun() {
 	suite.sink.On("GetCalendarHash").Return("sinkID")
 	suite.source.On("GetCalendarHash").Return("sourceID")
 
-	err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, true)
+	_, err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, true)
 	assert.NoError(suite.T(), err)
 
 	suite.sink.AssertNotCalled(suite.T(), "CreateEvent", ctx, mock.AnythingOfType("models.Event"))
@@ -179,8 +180,9 @@ func (suite *ControllerTestSuite) TestCleanUp() {
 	suite.sink.On("GetCalendarHash").Return("sinkID")
 	suite.source.On("GetCalendarHash").Return("sourceID")
 
-	err := suite.controller.CleanUp(ctx, startTime, endTime)
+	summary, err := suite.controller.CleanUp(ctx, startTime, endTime)
 	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), Summary{Deleted: expectedDelete}, summary)
 
 	suite.sink.AssertNotCalled(suite.T(), "CreateEvent", ctx, mock.AnythingOfType("models.Event"))
 	suite.sink.AssertNotCalled(suite.T(), "UpdateEvent", ctx, mock.AnythingOfType("models.Event"))
@@ -224,8 +226,9 @@ func (suite *ControllerTestSuite) TestCreateEventsEmptySink() {
 	suite.sink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
 	suite.sink.On("GetCalendarHash").Return("sinkID")
 
-	err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	summary, err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
 	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), Summary{Created: len(eventsToCreate)}, summary)
 
 	suite.source.AssertCalled(suite.T(), "EventsInTimeframe", ctx, startTime, endTime)
 	suite.sink.AssertCalled(suite.T(), "EventsInTimeframe", ctx, startTime, endTime)
@@ -310,7 +313,7 @@ func (suite *ControllerTestSuite) TestDeleteEventsNotInSink() {
 	suite.sink.On("GetCalendarHash").Return("sinkID")
 	suite.source.On("GetCalendarHash").Return("sourceID")
 
-	err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	_, err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
 	assert.NoError(suite.T(), err)
 
 	suite.sink.AssertNotCalled(suite.T(), "CreateEvent", ctx, mock.AnythingOfType("models.Event"))
@@ -347,7 +350,7 @@ func (suite *ControllerTestSuite) TestDoNotResurrectEvents() {
 	suite.sink.On("GetCalendarHash").Return("sinkID")
 	suite.source.On("GetCalendarHash").Return("sourceID")
 
-	err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	_, err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
 	assert.NoError(suite.T(), err)
 
 	suite.sink.AssertNotCalled(suite.T(), "CreateEvent", ctx, mock.AnythingOfType("models.Event"))
@@ -481,7 +484,7 @@ func (suite *ControllerTestSuite) TestUpdateEventsPrefilledSink() {
 	suite.sink.On("GetCalendarHash").Return("sinkID")
 	suite.source.On("GetCalendarHash").Return("sourceID")
 
-	err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	_, err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
 	assert.NoError(suite.T(), err)
 
 	suite.source.AssertCalled(suite.T(), "EventsInTimeframe", ctx, startTime, endTime)
@@ -527,7 +530,7 @@ func (suite *ControllerTestSuite) TestCreateEventsDeclined() {
 	suite.sink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
 	suite.sink.On("GetCalendarHash").Return("sinkID")
 
-	err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	_, err := suite.controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
 	assert.NoError(suite.T(), err)
 
 	suite.source.AssertCalled(suite.T(), "EventsInTimeframe", ctx, startTime, endTime)
@@ -537,6 +540,795 @@ func (suite *ControllerTestSuite) TestCreateEventsDeclined() {
 	suite.sink.AssertNotCalled(suite.T(), "DeleteEvent", ctx, mock.AnythingOfType("models.Event"))
 }
 
+// TestMultipleSources asserts that multiple sources are synchronised into the same sink
+// without deleting each other's events.
+func (suite *ControllerTestSuite) TestMultipleSources() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	sourceB := &mocks.Source{}
+
+	sourceAEvents := []models.Event{
+		{
+			ID:        "a1",
+			Title:     "Event A1",
+			StartTime: startTime,
+			EndTime:   endTime,
+			Metadata:  models.NewEventMetadata("a1", "uri", "sourceA"),
+			Accepted:  true,
+		},
+		{
+			ID:        "a2",
+			Title:     "Event A2",
+			StartTime: startTime,
+			EndTime:   endTime,
+			Metadata:  models.NewEventMetadata("a2", "uri", "sourceA"),
+			Accepted:  true,
+		},
+	}
+	sourceBEvents := []models.Event{
+		{
+			ID:        "b1",
+			Title:     "Event B1",
+			StartTime: startTime,
+			EndTime:   endTime,
+			Metadata:  models.NewEventMetadata("b1", "uri", "sourceB"),
+			Accepted:  true,
+		},
+	}
+	sinkEvents := []models.Event{
+		// synced from source A and still in sync
+		{
+			ID:        "sinkA1",
+			Title:     "Event A1",
+			StartTime: startTime,
+			EndTime:   endTime,
+			Metadata:  models.NewEventMetadata("a1", "uri", "sourceA"),
+		},
+		// synced from source B and still in sync
+		{
+			ID:        "sinkB1",
+			Title:     "Event B1",
+			StartTime: startTime,
+			EndTime:   endTime,
+			Metadata:  models.NewEventMetadata("b1", "uri", "sourceB"),
+		},
+		// synced from source B, but no longer there
+		{
+			ID:        "sinkB2",
+			Title:     "Event B2",
+			StartTime: startTime,
+			EndTime:   endTime,
+			Metadata:  models.NewEventMetadata("b2", "uri", "sourceB"),
+		},
+	}
+
+	suite.source.On("EventsInTimeframe", ctx, startTime, endTime).Return(sourceAEvents, nil)
+	suite.source.On("GetCalendarHash").Return("sourceA")
+	sourceB.On("EventsInTimeframe", ctx, startTime, endTime).Return(sourceBEvents, nil)
+	sourceB.On("GetCalendarHash").Return("sourceB")
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(sinkEvents, nil)
+	suite.sink.On("GetCalendarHash").Return("sinkID")
+	suite.sink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	suite.sink.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	transformers := TransformerFactory([]config.Transformer{{Name: "KeepTitle"}})
+	controller := NewController(log.Default(), []Source{suite.source, sourceB}, suite.sink, transformers, nil)
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+
+	// the sink is only loaded once for all sources
+	suite.sink.AssertNumberOfCalls(suite.T(), "EventsInTimeframe", 1)
+	suite.sink.AssertNumberOfCalls(suite.T(), "CreateEvent", 1)
+	suite.sink.AssertCalled(suite.T(), "CreateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.Title == "Event A2" }))
+	suite.sink.AssertNumberOfCalls(suite.T(), "DeleteEvent", 1)
+	suite.sink.AssertCalled(suite.T(), "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "sinkB2" }))
+	suite.sink.AssertNotCalled(suite.T(), "UpdateEvent", ctx, mock.AnythingOfType("models.Event"))
+}
+
+// TestMultipleSinks asserts that the source is loaded once and synchronised into every sink
+// with the transformers and filters of that sink. A failing sink does not affect the other sinks.
+func (suite *ControllerTestSuite) TestMultipleSinks() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	teamSink := &mocks.Sink{}
+	brokenSink := &mocks.Sink{}
+
+	sourceEvents := []models.Event{
+		{
+			ID:        "1",
+			Title:     "Customer Meeting",
+			StartTime: startTime,
+			EndTime:   endTime,
+			Metadata:  models.NewEventMetadata("1", "uri", "sourceID"),
+			Accepted:  true,
+		},
+		{
+			ID:        "2",
+			Title:     "Declined Meeting",
+			StartTime: startTime,
+			EndTime:   endTime,
+			Metadata:  models.NewEventMetadata("2", "uri", "sourceID"),
+			Accepted:  false,
+		},
+	}
+
+	suite.source.On("EventsInTimeframe", ctx, startTime, endTime).Return(sourceEvents, nil)
+	suite.source.On("GetCalendarHash").Return("sourceID")
+
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(nil, nil)
+	suite.sink.On("GetCalendarHash").Return("personalSinkID")
+	suite.sink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	teamSink.On("EventsInTimeframe", ctx, startTime, endTime).Return(nil, nil)
+	teamSink.On("GetCalendarHash").Return("teamSinkID")
+	teamSink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	brokenSink.On("EventsInTimeframe", ctx, startTime, endTime).Return(nil, errors.New("unauthorized"))
+	brokenSink.On("Name").Return("broken")
+
+	controller := NewController(log.Default(), []Source{suite.source}, suite.sink,
+		TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}),
+		nil,
+	)
+	controller.AddSink(brokenSink, nil, nil)
+	controller.AddSink(teamSink,
+		TransformerFactory([]config.Transformer{{Name: "ReplaceTitle", Config: config.CustomMap{"NewTitle": "Busy"}}}),
+		FilterFactory([]config.Filter{{Name: "DeclinedEvents"}}, nil),
+	)
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.ErrorContains(suite.T(), err, "sink broken")
+	assert.ErrorContains(suite.T(), err, "unauthorized")
+
+	suite.source.AssertNumberOfCalls(suite.T(), "EventsInTimeframe", 1)
+
+	suite.sink.AssertNumberOfCalls(suite.T(), "CreateEvent", 2)
+	suite.sink.AssertCalled(suite.T(), "CreateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.Title == "Customer Meeting" }))
+	suite.sink.AssertCalled(suite.T(), "CreateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.Title == "Declined Meeting" }))
+
+	teamSink.AssertNumberOfCalls(suite.T(), "CreateEvent", 1)
+	teamSink.AssertCalled(suite.T(), "CreateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.Title == "Busy" }))
+}
+
+// TestBufferedSink asserts that the changes of a buffered sink are flushed once after all of them were executed
+func (suite *ControllerTestSuite) TestBufferedSink() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	fileSink := &mocks.BufferedSink{}
+
+	sourceEvents := []models.Event{
+		{ID: "1", Title: "Meeting 1", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("1", "uri", "sourceID"), Accepted: true},
+		{ID: "2", Title: "Meeting 2", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("2", "uri", "sourceID"), Accepted: true},
+	}
+
+	suite.source.On("EventsInTimeframe", ctx, startTime, endTime).Return(sourceEvents, nil)
+	suite.source.On("GetCalendarHash").Return("sourceID")
+
+	var created int
+	fileSink.On("EventsInTimeframe", ctx, startTime, endTime).Return(nil, nil)
+	fileSink.On("GetCalendarHash").Return("fileSinkID")
+	fileSink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Run(func(args mock.Arguments) { created++ }).Return(nil)
+	fileSink.On("Flush", ctx).Run(func(args mock.Arguments) {
+		assert.Equal(suite.T(), 2, created, "the sink must be flushed after all changes")
+	}).Return(nil)
+
+	controller := NewController(log.Default(), []Source{suite.source}, fileSink, TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}), nil)
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+	fileSink.AssertNumberOfCalls(suite.T(), "CreateEvent", 2)
+	fileSink.AssertNumberOfCalls(suite.T(), "Flush", 1)
+
+	// nothing is written in dry run mode
+	_, err = controller.SynchroniseTimeframe(ctx, startTime, endTime, true)
+	assert.NoError(suite.T(), err)
+	fileSink.AssertNumberOfCalls(suite.T(), "Flush", 1)
+}
+
+// TestBidirectional asserts that two calendars are synchronised into each other in one run. Copies are never
+// synchronised back into the calendar they originate from.
+func (suite *ControllerTestSuite) TestBidirectional() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	calendarA := &mocks.Sink{}
+
+	event := func(id, title, syncSeed, origin string) models.Event {
+		return models.Event{
+			ID:        id,
+			Title:     title,
+			StartTime: startTime,
+			EndTime:   endTime,
+			Metadata:  models.NewEventMetadata(syncSeed, "uri", origin),
+			Accepted:  true,
+		}
+	}
+
+	eventsInA := []models.Event{
+		// native event, not yet in B
+		event("a-new", "A new", "a-new", "calendarA"),
+		// native event, changed since the last sync into B
+		event("a-changed", "A changed", "a-changed", "calendarA"),
+		// native event, in sync with B
+		event("a-synced", "A synced", "a-synced", "calendarA"),
+		// copy of a native event of B, in sync
+		event("copy-of-b-synced", "B synced", "b-synced", "calendarB"),
+		// copy of a native event of B which was deleted in B
+		event("copy-of-b-deleted", "B deleted", "b-deleted", "calendarB"),
+		// copy of an event of a third calendar, not managed by this sync
+		event("copy-of-c", "C", "c", "calendarC"),
+	}
+	eventsInB := []models.Event{
+		// native event, not yet in A
+		event("b-new", "B new", "b-new", "calendarB"),
+		// native event, in sync with A
+		event("b-synced", "B synced", "b-synced", "calendarB"),
+		// copy of a native event of A, outdated
+		event("copy-of-a-changed", "A changed (old)", "a-changed", "calendarA"),
+		// copy of a native event of A, in sync
+		event("copy-of-a-synced", "A synced", "a-synced", "calendarA"),
+		// copy of a native event of A which was deleted in A
+		event("copy-of-a-deleted", "A deleted", "a-deleted", "calendarA"),
+	}
+
+	calendarA.On("EventsInTimeframe", ctx, startTime, endTime).Return(eventsInA, nil)
+	calendarA.On("GetCalendarHash").Return("calendarA")
+	calendarA.On("Name").Return("A")
+	calendarA.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	calendarA.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(eventsInB, nil)
+	suite.sink.On("GetCalendarHash").Return("calendarB")
+	suite.sink.On("Name").Return("B")
+	suite.sink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	suite.sink.On("UpdateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	suite.sink.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	// there's no state of a previous run
+	var inSync map[string]models.Event
+	state := &mocks.StateStore{}
+	state.On("SyncedEvents", "calendarA:calendarB").Return(nil)
+	state.On("SetSyncedEvents", "calendarA:calendarB", mock.Anything).Run(func(args mock.Arguments) {
+		inSync = args.Get(1).(map[string]models.Event)
+	}).Return(nil)
+
+	controller := NewController(log.Default(), []Source{calendarA}, suite.sink, TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}), nil)
+	controller.SetBidirectional(true)
+	controller.SetReverseTransformation(TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}), nil)
+	controller.SetStateStore(state, "")
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+
+	calendarA.AssertNumberOfCalls(suite.T(), "EventsInTimeframe", 1)
+	suite.sink.AssertNumberOfCalls(suite.T(), "EventsInTimeframe", 1)
+
+	suite.sink.AssertNumberOfCalls(suite.T(), "CreateEvent", 1)
+	suite.sink.AssertCalled(suite.T(), "CreateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.Title == "A new" }))
+	suite.sink.AssertNumberOfCalls(suite.T(), "UpdateEvent", 1)
+	suite.sink.AssertCalled(suite.T(), "UpdateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "copy-of-a-changed" && e.Title == "A changed" }))
+	suite.sink.AssertNumberOfCalls(suite.T(), "DeleteEvent", 1)
+	suite.sink.AssertCalled(suite.T(), "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "copy-of-a-deleted" }))
+
+	calendarA.AssertNumberOfCalls(suite.T(), "CreateEvent", 1)
+	calendarA.AssertCalled(suite.T(), "CreateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.Title == "B new" }))
+	calendarA.AssertNotCalled(suite.T(), "UpdateEvent", ctx, mock.AnythingOfType("models.Event"))
+	calendarA.AssertNumberOfCalls(suite.T(), "DeleteEvent", 1)
+	calendarA.AssertCalled(suite.T(), "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "copy-of-b-deleted" }))
+
+	// the copies of a-new, a-changed, a-synced, b-new and b-synced are in sync
+	assert.Len(suite.T(), inSync, 5)
+
+	// a second run with the resulting state must not change anything, there's no ping-pong between the calendars
+	calendarA2 := &mocks.Sink{}
+	calendarB2 := &mocks.Sink{}
+	inSyncA := []models.Event{
+		event("a-synced", "A synced", "a-synced", "calendarA"),
+		event("copy-of-b-synced", "B synced", "b-synced", "calendarB"),
+	}
+	inSyncB := []models.Event{
+		event("b-synced", "B synced", "b-synced", "calendarB"),
+		event("copy-of-a-synced", "A synced", "a-synced", "calendarA"),
+	}
+	calendarA2.On("EventsInTimeframe", ctx, startTime, endTime).Return(inSyncA, nil)
+	calendarA2.On("GetCalendarHash").Return("calendarA")
+	calendarA2.On("Name").Return("A")
+	calendarB2.On("EventsInTimeframe", ctx, startTime, endTime).Return(inSyncB, nil)
+	calendarB2.On("GetCalendarHash").Return("calendarB")
+	calendarB2.On("Name").Return("B")
+	state2 := &mocks.StateStore{}
+	state2.On("SyncedEvents", "calendarA:calendarB").Return(inSync)
+	state2.On("SetSyncedEvents", "calendarA:calendarB", mock.Anything).Return(nil)
+
+	controller = NewController(log.Default(), []Source{calendarA2}, calendarB2, TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}), nil)
+	controller.SetBidirectional(true)
+	controller.SetReverseTransformation(TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}), nil)
+	controller.SetStateStore(state2, "")
+
+	_, err = controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+
+	for _, calendar := range []*mocks.Sink{calendarA2, calendarB2} {
+		calendar.AssertNotCalled(suite.T(), "CreateEvent", ctx, mock.AnythingOfType("models.Event"))
+		calendar.AssertNotCalled(suite.T(), "UpdateEvent", ctx, mock.AnythingOfType("models.Event"))
+		calendar.AssertNotCalled(suite.T(), "DeleteEvent", ctx, mock.AnythingOfType("models.Event"))
+	}
+}
+
+// TestBidirectionalRequiresWritableSource asserts that the bidirectional mode is refused for read-only sources
+func (suite *ControllerTestSuite) TestBidirectionalRequiresWritableSource() {
+	suite.source.On("Name").Return("read-only")
+
+	controller := NewController(log.Default(), []Source{suite.source}, suite.sink, nil, nil)
+	controller.SetBidirectional(true)
+	controller.SetStateStore(&mocks.StateStore{}, "")
+
+	_, err := controller.SynchroniseTimeframe(context.Background(), time.Now(), time.Now().Add(time.Hour), false)
+	assert.ErrorContains(suite.T(), err, "writable source")
+}
+
+// TestBidirectionalDeletedCopy asserts that deleting a copy deletes its original event instead of recreating the copy
+func (suite *ControllerTestSuite) TestBidirectionalDeletedCopy() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	calendarA := &mocks.Sink{}
+	original := models.Event{
+		ID:        "a1",
+		Title:     "Meeting",
+		StartTime: startTime,
+		EndTime:   endTime,
+		Metadata:  models.NewEventMetadata("a1", "uri", "calendarA"),
+		Accepted:  true,
+	}
+	// the copy was in sync after the last run, but was deleted in B since
+	lastCopy := original
+	lastCopy.ID = "copy-of-a1"
+
+	calendarA.On("EventsInTimeframe", ctx, startTime, endTime).Return([]models.Event{original}, nil)
+	calendarA.On("GetCalendarHash").Return("calendarA")
+	calendarA.On("Name").Return("A")
+	calendarA.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(nil, nil)
+	suite.sink.On("GetCalendarHash").Return("calendarB")
+	suite.sink.On("Name").Return("B")
+
+	state := &mocks.StateStore{}
+	state.On("SyncedEvents", "calendarA:calendarB").Return(map[string]models.Event{lastCopy.Metadata.SyncID: lastCopy})
+	state.On("SetSyncedEvents", "calendarA:calendarB", map[string]models.Event{}).Return(nil)
+
+	controller := NewController(log.Default(), []Source{calendarA}, suite.sink, TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}), nil)
+	controller.SetBidirectional(true)
+	controller.SetStateStore(state, "")
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+
+	calendarA.AssertNumberOfCalls(suite.T(), "DeleteEvent", 1)
+	calendarA.AssertCalled(suite.T(), "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "a1" }))
+	suite.sink.AssertNotCalled(suite.T(), "CreateEvent", ctx, mock.AnythingOfType("models.Event"))
+	state.AssertExpectations(suite.T())
+}
+
+// TestBidirectionalEditedCopy asserts that the edits of a copy are written to its original event instead of being
+// overwritten by it
+func (suite *ControllerTestSuite) TestBidirectionalEditedCopy() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	calendarA := &mocks.Sink{}
+	original := models.Event{
+		ID:          "a1",
+		Title:       "Meeting",
+		Description: "Agenda",
+		StartTime:   startTime,
+		EndTime:     endTime,
+		Metadata:    models.NewEventMetadata("a1", "uri", "calendarA"),
+		Accepted:    true,
+	}
+	lastCopy := models.Event{
+		ID:        "copy-of-a1",
+		Title:     "Meeting",
+		StartTime: startTime,
+		EndTime:   endTime,
+		Metadata:  original.Metadata,
+	}
+	// the copy was renamed and moved by an hour in B
+	editedCopy := lastCopy
+	editedCopy.Title = "Meeting (moved)"
+	editedCopy.StartTime = startTime.Add(time.Hour)
+	editedCopy.EndTime = endTime.Add(time.Hour)
+
+	calendarA.On("EventsInTimeframe", ctx, startTime, endTime).Return([]models.Event{original}, nil)
+	calendarA.On("GetCalendarHash").Return("calendarA")
+	calendarA.On("Name").Return("A")
+	calendarA.On("UpdateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return([]models.Event{editedCopy}, nil)
+	suite.sink.On("GetCalendarHash").Return("calendarB")
+	suite.sink.On("Name").Return("B")
+
+	state := &mocks.StateStore{}
+	state.On("SyncedEvents", "calendarA:calendarB").Return(map[string]models.Event{lastCopy.Metadata.SyncID: lastCopy})
+	state.On("SetSyncedEvents", "calendarA:calendarB", map[string]models.Event{editedCopy.Metadata.SyncID: editedCopy}).Return(nil)
+
+	controller := NewController(log.Default(), []Source{calendarA}, suite.sink, TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}), nil)
+	controller.SetBidirectional(true)
+	controller.SetStateStore(state, "")
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+
+	calendarA.AssertNumberOfCalls(suite.T(), "UpdateEvent", 1)
+	calendarA.AssertCalled(suite.T(), "UpdateEvent", ctx, mock.MatchedBy(func(e models.Event) bool {
+		// the fields which were not edited in the copy are kept
+		return e.ID == "a1" && e.Title == "Meeting (moved)" && e.Description == "Agenda" &&
+			e.StartTime.Equal(editedCopy.StartTime) && e.EndTime.Equal(editedCopy.EndTime)
+	}))
+	suite.sink.AssertNotCalled(suite.T(), "UpdateEvent", ctx, mock.AnythingOfType("models.Event"))
+	state.AssertExpectations(suite.T())
+}
+
+// TestBidirectionalTransformationsPerDirection asserts that the transformers of the sink are applied to the events
+// synchronised into the sink and the reverse transformers to the events synchronised into the source
+func (suite *ControllerTestSuite) TestBidirectionalTransformationsPerDirection() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	calendarA := &mocks.Sink{}
+	calendarA.On("EventsInTimeframe", ctx, startTime, endTime).Return([]models.Event{{
+		ID:        "a1",
+		Title:     "Customer Meeting",
+		StartTime: startTime,
+		EndTime:   endTime,
+		Metadata:  models.NewEventMetadata("a1", "uri", "calendarA"),
+		Accepted:  true,
+	}}, nil)
+	calendarA.On("GetCalendarHash").Return("calendarA")
+	calendarA.On("Name").Return("A")
+	calendarA.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return([]models.Event{{
+		ID:        "b1",
+		Title:     "Lunch",
+		StartTime: startTime,
+		EndTime:   endTime,
+		Metadata:  models.NewEventMetadata("b1", "uri", "calendarB"),
+		Accepted:  true,
+	}}, nil)
+	suite.sink.On("GetCalendarHash").Return("calendarB")
+	suite.sink.On("Name").Return("B")
+	suite.sink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	state := &mocks.StateStore{}
+	state.On("SyncedEvents", "calendarA:calendarB").Return(nil)
+	state.On("SetSyncedEvents", "calendarA:calendarB", mock.Anything).Return(nil)
+
+	controller := NewController(log.Default(), []Source{calendarA}, suite.sink,
+		TransformerFactory([]config.Transformer{{Name: "ReplaceTitle", Config: config.CustomMap{"NewTitle": "Busy"}}}),
+		nil,
+	)
+	controller.SetBidirectional(true)
+	controller.SetReverseTransformation(TransformerFactory([]config.Transformer{
+		{Name: "KeepTitle"},
+		{Name: "PrefixTitle", Config: config.CustomMap{"Prefix": "[B] "}},
+	}), nil)
+	controller.SetStateStore(state, "")
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+
+	suite.sink.AssertNumberOfCalls(suite.T(), "CreateEvent", 1)
+	suite.sink.AssertCalled(suite.T(), "CreateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.Title == "Busy" }))
+	calendarA.AssertNumberOfCalls(suite.T(), "CreateEvent", 1)
+	calendarA.AssertCalled(suite.T(), "CreateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.Title == "[B] Lunch" }))
+}
+
+// TestBidirectionalCleanUp asserts that the copies are removed from both calendars and the copies which were in sync
+// are forgotten, so that the next run does not delete the originals of the removed copies
+func (suite *ControllerTestSuite) TestBidirectionalCleanUp() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	calendarA := &mocks.Sink{}
+	originalA := models.Event{ID: "a1", Title: "Meeting", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("a1", "uri", "calendarA")}
+	copyOfB := models.Event{ID: "copy-of-b1", Title: "Lunch", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("b1", "uri", "calendarB")}
+	originalB := models.Event{ID: "b1", Title: "Lunch", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("b1", "uri", "calendarB")}
+	copyOfA := models.Event{ID: "copy-of-a1", Title: "Meeting", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("a1", "uri", "calendarA")}
+
+	calendarA.On("EventsInTimeframe", ctx, startTime, endTime).Return([]models.Event{originalA, copyOfB}, nil)
+	calendarA.On("GetCalendarHash").Return("calendarA")
+	calendarA.On("Name").Return("A")
+	calendarA.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return([]models.Event{originalB, copyOfA}, nil)
+	suite.sink.On("GetCalendarHash").Return("calendarB")
+	suite.sink.On("Name").Return("B")
+	suite.sink.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	state := &mocks.StateStore{}
+	state.On("SetSyncedEvents", "calendarA:calendarB", map[string]models.Event(nil)).Return(nil)
+
+	controller := NewController(log.Default(), []Source{calendarA}, suite.sink, nil, nil)
+	controller.SetBidirectional(true)
+	controller.SetStateStore(state, "")
+
+	_, err := controller.CleanUp(ctx, startTime, endTime)
+	assert.NoError(suite.T(), err)
+
+	calendarA.AssertNumberOfCalls(suite.T(), "DeleteEvent", 1)
+	calendarA.AssertCalled(suite.T(), "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "copy-of-b1" }))
+	suite.sink.AssertNumberOfCalls(suite.T(), "DeleteEvent", 1)
+	suite.sink.AssertCalled(suite.T(), "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "copy-of-a1" }))
+	state.AssertExpectations(suite.T())
+}
+
+// TestBidirectionalRequiresStateStore asserts that the bidirectional mode is refused without a state store
+func (suite *ControllerTestSuite) TestBidirectionalRequiresStateStore() {
+	calendarA := &mocks.Sink{}
+
+	controller := NewController(log.Default(), []Source{calendarA}, suite.sink, nil, nil)
+	controller.SetBidirectional(true)
+
+	_, err := controller.SynchroniseTimeframe(context.Background(), time.Now(), time.Now().Add(time.Hour), false)
+	assert.ErrorContains(suite.T(), err, "state store")
+}
+
+// TestIncrementalSync asserts that only the changes of an incremental source are applied to the sink.
+// Sink events which are not affected by the changes are left untouched.
+func (suite *ControllerTestSuite) TestIncrementalSync() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	source := &mocks.IncrementalSource{}
+	state := &mocks.StateStore{}
+
+	changes := models.Changes{
+		Events: []models.Event{
+			{ID: "changed", Title: "New Title", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("changed", "uri", "sourceID"), Accepted: true},
+			{ID: "new", Title: "New Event", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("new", "uri", "sourceID"), Accepted: true},
+			// the event is now declined and therefore rejected by the filter
+			{ID: "declined", Title: "Declined", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("declined", "uri", "sourceID"), Accepted: false},
+		},
+		Deleted: []models.Event{
+			{ID: "deleted", Metadata: models.NewEventMetadata("deleted", "", "sourceID")},
+		},
+		Token: "newToken",
+	}
+	sinkEvents := []models.Event{
+		{ID: "sinkChanged", Title: "Old Title", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("changed", "uri", "sourceID")},
+		{ID: "sinkDeleted", Title: "Deleted", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("deleted", "uri", "sourceID")},
+		{ID: "sinkDeclined", Title: "Declined", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("declined", "uri", "sourceID")},
+		// not part of the changes, so it is unchanged in the source
+		{ID: "sinkUnchanged", Title: "Unchanged", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("unchanged", "uri", "sourceID")},
+	}
+
+	source.On("GetCalendarHash").Return("sourceID")
+	source.On("ChangedEventsInTimeframe", ctx, startTime, endTime, "oldToken").Return(changes, nil)
+	suite.sink.On("GetCalendarHash").Return("sinkID")
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(sinkEvents, nil)
+	suite.sink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	suite.sink.On("UpdateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	suite.sink.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	state.On("Token", "sourceID:sinkID", "MonthStart/MonthEnd").Return("oldToken", startTime, endTime)
+	state.On("SetToken", "sourceID:sinkID", "MonthStart/MonthEnd", "newToken", startTime, endTime).Return(nil)
+
+	controller := NewController(log.Default(), []Source{source}, suite.sink,
+		TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}),
+		FilterFactory([]config.Filter{{Name: "DeclinedEvents"}}, nil),
+	)
+	controller.SetStateStore(state, "MonthStart/MonthEnd")
+
+	summary, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), Summary{Created: 1, Updated: 1, Deleted: 2, Fields: map[string]int{"title": 1}}, summary)
+
+	source.AssertNotCalled(suite.T(), "EventsInTimeframe", ctx, startTime, endTime)
+	suite.sink.AssertCalled(suite.T(), "CreateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.Title == "New Event" }))
+	suite.sink.AssertCalled(suite.T(), "UpdateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "sinkChanged" && e.Title == "New Title" }))
+	suite.sink.AssertCalled(suite.T(), "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "sinkDeleted" }))
+	suite.sink.AssertCalled(suite.T(), "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "sinkDeclined" }))
+	state.AssertExpectations(suite.T())
+}
+
+// TestIncrementalSyncWithoutChanges asserts that the sink is not loaded if nothing changed in the sources
+func (suite *ControllerTestSuite) TestIncrementalSyncWithoutChanges() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	source := &mocks.IncrementalSource{}
+	state := &mocks.StateStore{}
+
+	source.On("GetCalendarHash").Return("sourceID")
+	source.On("ChangedEventsInTimeframe", ctx, startTime, endTime, "oldToken").Return(models.Changes{Token: "newToken"}, nil)
+	suite.sink.On("GetCalendarHash").Return("sinkID")
+	state.On("Token", "sourceID:sinkID", "MonthStart/MonthEnd").Return("oldToken", startTime, endTime)
+	state.On("SetToken", "sourceID:sinkID", "MonthStart/MonthEnd", "newToken", startTime, endTime).Return(nil)
+
+	controller := NewController(log.Default(), []Source{source}, suite.sink, nil, nil)
+	controller.SetStateStore(state, "MonthStart/MonthEnd")
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+
+	suite.sink.AssertNotCalled(suite.T(), "EventsInTimeframe", ctx, startTime, endTime)
+	state.AssertExpectations(suite.T())
+}
+
+// TestIncrementalSyncFallsBackToFullSync asserts that an invalid token results in a full sync, which also removes
+// the events which are not in the source anymore.
+func (suite *ControllerTestSuite) TestIncrementalSyncFallsBackToFullSync() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	source := &mocks.IncrementalSource{}
+	state := &mocks.StateStore{}
+
+	full := models.Changes{
+		Events: []models.Event{
+			{ID: "1", Title: "Event", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("1", "uri", "sourceID"), Accepted: true},
+		},
+		Token: "newToken",
+		Full:  true,
+	}
+	sinkEvents := []models.Event{
+		{ID: "sink1", Title: "Event", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("1", "uri", "sourceID")},
+		{ID: "sink2", Title: "Gone", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("2", "uri", "sourceID")},
+	}
+
+	source.On("GetCalendarHash").Return("sourceID")
+	source.On("Name").Return("source")
+	source.On("ChangedEventsInTimeframe", ctx, startTime, endTime, "expiredToken").Return(models.Changes{}, models.ErrSyncTokenInvalid)
+	source.On("ChangedEventsInTimeframe", ctx, startTime, endTime, "").Return(full, nil)
+	suite.sink.On("GetCalendarHash").Return("sinkID")
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(sinkEvents, nil)
+	suite.sink.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	state.On("Token", "sourceID:sinkID", "MonthStart/MonthEnd").Return("expiredToken", startTime, endTime)
+	state.On("SetToken", "sourceID:sinkID", "MonthStart/MonthEnd", "newToken", startTime, endTime).Return(nil)
+
+	controller := NewController(log.Default(), []Source{source}, suite.sink, TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}), nil)
+	controller.SetStateStore(state, "MonthStart/MonthEnd")
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+
+	suite.sink.AssertNumberOfCalls(suite.T(), "DeleteEvent", 1)
+	suite.sink.AssertCalled(suite.T(), "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "sink2" }))
+	state.AssertExpectations(suite.T())
+}
+
+// TestIncrementalSyncKeepsTokenOnError asserts that the token is not advanced if the changes could not be applied,
+// such that they are retried with the next run.
+func (suite *ControllerTestSuite) TestIncrementalSyncKeepsTokenOnError() {
+	ctx := context.Background()
+	startTime := time.Now()
+	endTime := startTime.Add(2 * time.Hour)
+
+	source := &mocks.IncrementalSource{}
+	state := &mocks.StateStore{}
+
+	changes := models.Changes{
+		Events: []models.Event{
+			{ID: "1", Title: "Event", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("1", "uri", "sourceID"), Accepted: true},
+		},
+		Token: "newToken",
+	}
+
+	source.On("GetCalendarHash").Return("sourceID")
+	source.On("ChangedEventsInTimeframe", ctx, startTime, endTime, "oldToken").Return(changes, nil)
+	suite.sink.On("GetCalendarHash").Return("sinkID")
+	suite.sink.On("Name").Return("sink")
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(nil, nil)
+	suite.sink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(errors.New("quota exceeded"))
+	state.On("Token", "sourceID:sinkID", "MonthStart/MonthEnd").Return("oldToken", startTime, endTime)
+
+	controller := NewController(log.Default(), []Source{source}, suite.sink, nil, nil)
+	controller.SetStateStore(state, "MonthStart/MonthEnd")
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.ErrorContains(suite.T(), err, "quota exceeded")
+
+	state.AssertNotCalled(suite.T(), "SetToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestIncrementalSyncMovedWindow asserts that the token is kept when a relative sync window moves. The events which
+// moved into the window are listed in addition to the changes, as the token doesn't list them.
+func (suite *ControllerTestSuite) TestIncrementalSyncMovedWindow() {
+	ctx := context.Background()
+	startTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(4 * time.Hour)
+	// the previous run synced the window an hour earlier
+	coveredStart, coveredEnd := startTime.Add(-time.Hour), endTime.Add(-time.Hour)
+	moved := endTime.Add(-30 * time.Minute)
+
+	source := &mocks.IncrementalSource{}
+	state := &mocks.StateStore{}
+
+	changes := models.Changes{
+		Events: []models.Event{
+			{ID: "changed", Title: "Changed", StartTime: startTime, EndTime: startTime.Add(time.Hour), Metadata: models.NewEventMetadata("changed", "uri", "sourceID")},
+			// the event moved out of the window, it must not be created in the sink
+			{ID: "past", Title: "Past", StartTime: coveredStart, EndTime: coveredStart.Add(30 * time.Minute), Metadata: models.NewEventMetadata("past", "uri", "sourceID")},
+		},
+		Token: "newToken",
+	}
+	sinkEvents := []models.Event{
+		{ID: "sinkChanged", Title: "Old", StartTime: startTime, EndTime: startTime.Add(time.Hour), Metadata: models.NewEventMetadata("changed", "uri", "sourceID")},
+		// deleted in the source before it moved into the window
+		{ID: "sinkGone", Title: "Gone", StartTime: moved, EndTime: endTime, Metadata: models.NewEventMetadata("gone", "uri", "sourceID")},
+	}
+
+	source.On("GetCalendarHash").Return("sourceID")
+	source.On("Name").Return("source")
+	source.On("ChangedEventsInTimeframe", ctx, startTime, endTime, "oldToken").Return(changes, nil)
+	source.On("EventsInTimeframe", ctx, coveredEnd, endTime).Return([]models.Event{
+		{ID: "entered", Title: "Entered", StartTime: moved, EndTime: endTime, Metadata: models.NewEventMetadata("entered", "uri", "sourceID")},
+	}, nil)
+	suite.sink.On("GetCalendarHash").Return("sinkID")
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(sinkEvents, nil)
+	suite.sink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	suite.sink.On("UpdateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	suite.sink.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	state.On("Token", "sourceID:sinkID", "MonthStart/MonthEnd").Return("oldToken", coveredStart, coveredEnd)
+	// the token still only lists the changes in the timeframe of its full listing
+	state.On("SetToken", "sourceID:sinkID", "MonthStart/MonthEnd", "newToken", coveredStart, coveredEnd).Return(nil)
+
+	controller := NewController(log.Default(), []Source{source}, suite.sink, TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}), nil)
+	controller.SetStateStore(state, "MonthStart/MonthEnd")
+
+	summary, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), Summary{Created: 1, Updated: 1, Deleted: 1, Fields: map[string]int{"title": 1}}, summary)
+
+	suite.sink.AssertCalled(suite.T(), "CreateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.Title == "Entered" }))
+	suite.sink.AssertCalled(suite.T(), "UpdateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "sinkChanged" }))
+	suite.sink.AssertCalled(suite.T(), "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "sinkGone" }))
+	state.AssertExpectations(suite.T())
+}
+
+// TestIncrementalSyncWindowMovedTooFar asserts that a full sync is loaded if most of the window is not covered by the token
+func (suite *ControllerTestSuite) TestIncrementalSyncWindowMovedTooFar() {
+	ctx := context.Background()
+	startTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(4 * time.Hour)
+
+	source := &mocks.IncrementalSource{}
+	state := &mocks.StateStore{}
+
+	source.On("GetCalendarHash").Return("sourceID")
+	source.On("Name").Return("source")
+	source.On("ChangedEventsInTimeframe", ctx, startTime, endTime, "").Return(models.Changes{Token: "newToken", Full: true}, nil)
+	suite.sink.On("GetCalendarHash").Return("sinkID")
+	suite.sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(nil, nil)
+	state.On("Token", "sourceID:sinkID", "MonthStart/MonthEnd").Return("oldToken", startTime.Add(-3*time.Hour), endTime.Add(-3*time.Hour))
+	state.On("SetToken", "sourceID:sinkID", "MonthStart/MonthEnd", "newToken", startTime, endTime).Return(nil)
+
+	controller := NewController(log.Default(), []Source{source}, suite.sink, nil, nil)
+	controller.SetStateStore(state, "MonthStart/MonthEnd")
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, false)
+	assert.NoError(suite.T(), err)
+
+	source.AssertNotCalled(suite.T(), "ChangedEventsInTimeframe", ctx, startTime, endTime, "oldToken")
+	state.AssertExpectations(suite.T())
+}
+
 func TestControllerTestSuite(t *testing.T) {
 	suite.Run(t, new(ControllerTestSuite))
 }
@@ -764,6 +1556,83 @@ func TestController_diffEvents(t *testing.T) {
 			expectedUpdateEvents: []models.Event{},
 			expectedDeleteEvents: []models.Event{},
 		},
+		{
+			name: "should create modified instance in the synced series of the sink",
+			source: []models.Event{
+				{
+					ID: "sourceSeries",
+					Metadata: &models.Metadata{
+						SyncID:   "series",
+						SourceID: "sourceID",
+					},
+				},
+				{
+					Metadata: &models.Metadata{
+						SyncID:   "instance",
+						SourceID: "sourceID",
+					},
+					Title:    "Foo",
+					Instance: &models.SeriesInstance{SeriesID: "sourceSeries", SeriesSyncID: "series"},
+				},
+			},
+			sink: []models.Event{{
+				ID: "sinkSeries",
+				Metadata: &models.Metadata{
+					SyncID:   "series",
+					SourceID: "sourceID",
+				},
+			}},
+			expectedCreateEvents: []models.Event{{
+				Metadata: &models.Metadata{
+					SyncID:   "instance",
+					SourceID: "sourceID",
+				},
+				Title:    "Foo",
+				Instance: &models.SeriesInstance{SeriesID: "sinkSeries", SeriesSyncID: "series"},
+			}},
+			expectedUpdateEvents: []models.Event{},
+			expectedDeleteEvents: []models.Event{},
+		},
+		{
+			name: "should skip modified instance until its series is synced",
+			source: []models.Event{{
+				Metadata: &models.Metadata{
+					SyncID:   "instance",
+					SourceID: "sourceID",
+				},
+				Title:    "Foo",
+				Instance: &models.SeriesInstance{SeriesID: "sourceSeries", SeriesSyncID: "series"},
+			}},
+			expectedCreateEvents: []models.Event{},
+			expectedUpdateEvents: []models.Event{},
+			expectedDeleteEvents: []models.Event{},
+		},
+		{
+			name: "should not delete modified instances of deleted series",
+			sink: []models.Event{
+				{
+					Metadata: &models.Metadata{
+						SyncID:   "series",
+						SourceID: "sourceID",
+					},
+				},
+				{
+					Metadata: &models.Metadata{
+						SyncID:   "instance",
+						SourceID: "sourceID",
+					},
+					Instance: &models.SeriesInstance{SeriesID: "sinkSeries", SeriesSyncID: "series"},
+				},
+			},
+			expectedCreateEvents: []models.Event{},
+			expectedUpdateEvents: []models.Event{},
+			expectedDeleteEvents: []models.Event{{
+				Metadata: &models.Metadata{
+					SyncID:   "series",
+					SourceID: "sourceID",
+				},
+			}},
+		},
 	}
 
 	for _, tc := range tt {
@@ -775,12 +1644,10 @@ func TestController_diffEvents(t *testing.T) {
 			sink.On("GetCalendarHash").Return("sinkID")
 
 			var controller = Controller{
-				source: &source,
-				sink:   &sink,
 				logger: log.Default(),
 			}
 
-			creates, updates, deletes := controller.diffEvents(tc.source, tc.sink)
+			creates, updates, deletes := controller.diffEvents(&source, &sink, tc.source, tc.sink)
 
 			assert.Equal(t, tc.expectedCreateEvents, creates)
 			assert.Equal(t, tc.expectedUpdateEvents, updates)
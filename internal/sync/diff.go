@@ -0,0 +1,26 @@
+package sync
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/inovex/CalendarSync/internal/config"
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// DiffOptionsFactory builds the options for comparing the events from the config file
+func DiffOptionsFactory(cfg config.Compare) (models.DiffOptions, error) {
+	for _, field := range cfg.Ignore {
+		if !slices.Contains(models.DiffFields, field) {
+			return models.DiffOptions{}, fmt.Errorf("unknown field %s in 'compare.ignore', available fields: %s", field, strings.Join(models.DiffFields, ", "))
+		}
+	}
+
+	options := models.DefaultDiffOptions()
+	options.Ignore = cfg.Ignore
+	if cfg.DefaultReminders != nil {
+		options.DefaultReminders = *cfg.DefaultReminders
+	}
+	return options, nil
+}
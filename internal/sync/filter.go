@@ -1,7 +1,9 @@
 package sync
 
 import (
+	"reflect"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 
@@ -46,7 +48,14 @@ var (
 	}
 )
 
-func FilterFactory(configuredFilters []config.Filter) (loadedFilters []Filter) {
+// LocationSetter is implemented by filters which evaluate the time of day of events
+type LocationSetter interface {
+	SetLocation(location *time.Location)
+}
+
+// FilterFactory loads the configured filters. The filters which evaluate the time of day use the given location,
+// or UTC if it's nil.
+func FilterFactory(configuredFilters []config.Filter, location *time.Location) (loadedFilters []Filter) {
 	for _, configuredFilter := range configuredFilters {
 		if _, nameExists := filterConfigMapping[configuredFilter.Name]; !nameExists {
 			log.Warnf("unknown filter: %s, skipping...", configuredFilter.Name)
@@ -54,7 +63,11 @@ func FilterFactory(configuredFilters []config.Filter) (loadedFilters []Filter) {
 		}
 		// load the default Transformer for the configured name and initialize it based on the config
 		filterDefault := filterConfigMapping[configuredFilter.Name]
-		loadedFilters = append(loadedFilters, filterFromConfig(filterDefault, configuredFilter.Config))
+		loadedFilter := filterFromConfig(filterDefault, configuredFilter.Config)
+		if setter, ok := loadedFilter.(LocationSetter); ok && location != nil {
+			setter.SetLocation(location)
+		}
+		loadedFilters = append(loadedFilters, loadedFilter)
 	}
 
 	var sortedAndLoadedFilter []Filter
@@ -70,6 +83,8 @@ func FilterFactory(configuredFilters []config.Filter) (loadedFilters []Filter) {
 }
 
 func filterFromConfig(filter Filter, config config.CustomMap) Filter {
+	// configure a copy, the defaults are shared between all sinks and jobs
+	filter = reflect.New(reflect.TypeOf(filter).Elem()).Interface().(Filter)
 	autoConfigure(filter, config)
 	return filter
 }
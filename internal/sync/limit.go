@@ -0,0 +1,54 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDeleteLimitExceeded is returned if more events would be deleted from a sink than allowed by the DeleteLimit
+var ErrDeleteLimitExceeded = errors.New("delete limit exceeded")
+
+// DeleteLimit protects the sinks from mass deletions, e.g. if a source returns an empty list of events
+// after a failed login. The sync of a sink is aborted if the deletions exceed the absolute Count or the
+// Percent of the events in the sink. A zero value disables the respective limit. The Percent only applies
+// if more than MinCount events would be deleted, so that small calendars can still be emptied.
+type DeleteLimit struct {
+	Count    int
+	Percent  float64
+	MinCount int
+}
+
+// SetDeleteLimit sets the limit of deletions per sink for SynchroniseTimeframe. It's disabled by default.
+func (p *Controller) SetDeleteLimit(limit DeleteLimit) {
+	p.deleteLimit = limit
+}
+
+// exceeded returns true if deleting toDelete of the total events in the sink exceeds the limit
+func (l DeleteLimit) exceeded(toDelete, total int) bool {
+	if toDelete == 0 {
+		return false
+	}
+	if l.Count > 0 && toDelete > l.Count {
+		return true
+	}
+	return l.Percent > 0 && toDelete > l.MinCount && total > 0 && float64(toDelete)*100/float64(total) > l.Percent
+}
+
+// checkDeleteLimit returns ErrDeleteLimitExceeded if the deletions in the sink exceed the limit.
+// In dry run mode, the explanation is only logged as no event is deleted anyway.
+func (p Controller) checkDeleteLimit(sink Sink, toDelete, total int, dryRun bool) error {
+	if !p.deleteLimit.exceeded(toDelete, total) {
+		return nil
+	}
+
+	explanation := fmt.Sprintf("%d of %d events would be deleted from %s, which exceeds the delete limit (count: %d, percent: %g, minCount: %d). "+
+		"This usually means that a source returned an incomplete list of events, e.g. because its login failed. "+
+		"Please check the sources and run again with --force if the deletions are intended.",
+		toDelete, total, sink.Name(), p.deleteLimit.Count, p.deleteLimit.Percent, p.deleteLimit.MinCount)
+	if dryRun {
+		p.logger.Warn(explanation)
+		return nil
+	}
+	p.logger.Error("aborting the sync of the sink, no events were changed. " + explanation)
+	return fmt.Errorf("%w: %d of %d events would be deleted from %s", ErrDeleteLimitExceeded, toDelete, total, sink.Name())
+}
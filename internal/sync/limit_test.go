@@ -0,0 +1,95 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/inovex/CalendarSync/internal/models"
+	"github.com/inovex/CalendarSync/internal/sync/mocks"
+)
+
+func TestDeleteLimitExceeded(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    DeleteLimit
+		toDelete int
+		total    int
+		expected bool
+	}{
+		{name: "disabled", limit: DeleteLimit{}, toDelete: 10, total: 10, expected: false},
+		{name: "no deletions", limit: DeleteLimit{Count: 1, Percent: 1}, toDelete: 0, total: 10, expected: false},
+		{name: "count reached", limit: DeleteLimit{Count: 5}, toDelete: 5, total: 10, expected: false},
+		{name: "count exceeded", limit: DeleteLimit{Count: 5}, toDelete: 6, total: 10, expected: true},
+		{name: "percent reached", limit: DeleteLimit{Percent: 50}, toDelete: 5, total: 10, expected: false},
+		{name: "percent exceeded", limit: DeleteLimit{Percent: 50}, toDelete: 6, total: 10, expected: true},
+		{name: "percent exceeded within min count", limit: DeleteLimit{Percent: 50, MinCount: 5}, toDelete: 1, total: 1, expected: false},
+		{name: "percent exceeded above min count", limit: DeleteLimit{Percent: 50, MinCount: 5}, toDelete: 6, total: 10, expected: true},
+		{name: "percent exceeded within count", limit: DeleteLimit{Count: 10, Percent: 50}, toDelete: 6, total: 10, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.limit.exceeded(tt.toDelete, tt.total))
+		})
+	}
+}
+
+// TestDeleteLimit asserts that no event is changed if a source returns no events and the delete limit is exceeded
+func TestDeleteLimit(t *testing.T) {
+	ctx := context.Background()
+	startTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(time.Hour)
+
+	sinkEvents := []models.Event{
+		{ID: "1", Title: "1", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("1", "uri", "sourceID")},
+		{ID: "2", Title: "2", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("2", "uri", "sourceID")},
+		{ID: "3", Title: "3", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("3", "uri", "sourceID")},
+	}
+
+	tests := []struct {
+		name          string
+		dryRun        bool
+		limit         DeleteLimit
+		expectedError error
+		deleted       int
+	}{
+		{name: "exceeded", limit: DeleteLimit{Percent: 50}, expectedError: ErrDeleteLimitExceeded},
+		{name: "exceeded in dry run", limit: DeleteLimit{Count: 2}, dryRun: true, deleted: 3},
+		{name: "within limit", limit: DeleteLimit{Count: 3}, deleted: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &mocks.Source{}
+			sink := &mocks.Sink{}
+
+			source.On("EventsInTimeframe", ctx, startTime, endTime).Return([]models.Event{}, nil)
+			source.On("GetCalendarHash").Return("sourceID")
+			sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(sinkEvents, nil)
+			sink.On("GetCalendarHash").Return("sinkID")
+			sink.On("Name").Return("Google Calendar")
+			sink.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+			controller := NewController(log.Default(), []Source{source}, sink, nil, nil)
+			controller.SetDeleteLimit(tt.limit)
+
+			summary, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, tt.dryRun)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.deleted, summary.Deleted)
+			if tt.dryRun || tt.expectedError != nil {
+				sink.AssertNotCalled(t, "DeleteEvent", ctx, mock.Anything)
+			} else {
+				sink.AssertNumberOfCalls(t, "DeleteEvent", tt.deleted)
+			}
+		})
+	}
+}
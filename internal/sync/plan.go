@@ -0,0 +1,221 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// Plan lists the operations of a synchronisation. It is meant to be reviewed before the synchronisation is executed
+// with Controller.ApplyPlan.
+type Plan struct {
+	Job string `json:"job" yaml:"job"`
+	// Start and End are the timeframe of the synchronisation
+	Start      time.Time   `json:"start" yaml:"start"`
+	End        time.Time   `json:"end" yaml:"end"`
+	Operations []Operation `json:"operations" yaml:"operations"`
+}
+
+// Operation is a single change in a sink
+type Operation struct {
+	// Operation is one of OperationCreate, OperationUpdate and OperationDelete
+	Operation string `json:"operation" yaml:"operation"`
+	// Sink is the name of the sink adapter
+	Sink string `json:"sink" yaml:"sink"`
+	// SinkID is the calendar hash of the sink
+	SinkID string `json:"sinkId" yaml:"sinkId"`
+	SyncID string `json:"syncId" yaml:"syncId"`
+	// EventID is the ID of the event in the sink, it's empty for created events
+	EventID string    `json:"eventId,omitempty" yaml:"eventId,omitempty"`
+	Title   string    `json:"title" yaml:"title"`
+	Start   time.Time `json:"start" yaml:"start"`
+	End     time.Time `json:"end" yaml:"end"`
+	// Changes are the fields which are set by a create or changed by an update
+	Changes models.EventDiff `json:"changes,omitempty" yaml:"changes,omitempty"`
+	// ETag is the version of the sink event the plan was made for, it's empty for created events
+	ETag string `json:"etag,omitempty" yaml:"etag,omitempty"`
+	// Previous is the sink event the plan was made for, if the sink doesn't provide an ETag. An update or delete is
+	// stale if the sink event differs from it.
+	Previous *models.Event `json:"previous,omitempty" yaml:"previous,omitempty"`
+	// Event is written to the sink by a create or an update
+	Event *models.Event `json:"event,omitempty" yaml:"event,omitempty"`
+}
+
+// SetPlan records all operations of the following synchronisations in the given plan
+func (p *Controller) SetPlan(plan *Plan) {
+	p.plan = plan
+}
+
+// recordPlan adds the given changes of the sink to the plan, if one is set.
+// The sink events are needed to compute the field changes of the updated events.
+func (p Controller) recordPlan(sink Sink, toCreate, toUpdate, toDelete, sinkEvents []models.Event) {
+	if p.plan == nil {
+		return
+	}
+
+	operation := func(typ string, event models.Event) Operation {
+		op := Operation{
+			Operation: typ,
+			Sink:      sink.Name(),
+			SinkID:    sink.GetCalendarHash(),
+			Title:     event.Title,
+			Start:     event.StartTime,
+			End:       event.EndTime,
+		}
+		if event.Metadata != nil {
+			op.SyncID = event.Metadata.SyncID
+		}
+		if typ != OperationCreate {
+			op.EventID = event.ID
+			op.ETag = event.ETag
+		}
+		if typ != OperationDelete {
+			op.Event = &event
+		}
+		return op
+	}
+
+	for _, event := range toCreate {
+		op := operation(OperationCreate, event)
+		op.Changes = p.diff.DiffEvents(models.Event{}, event)
+		p.plan.Operations = append(p.plan.Operations, op)
+	}
+
+	existing := maps(sinkEvents)
+	for _, event := range toUpdate {
+		op := operation(OperationUpdate, event)
+		previous := existing[event.Metadata.SyncID]
+		op.Changes = p.diff.DiffEvents(previous, event)
+		if op.ETag == "" {
+			op.Previous = &previous
+		}
+		p.plan.Operations = append(p.plan.Operations, op)
+	}
+
+	for _, event := range toDelete {
+		op := operation(OperationDelete, event)
+		if op.ETag == "" {
+			op.Previous = &event
+		}
+		p.plan.Operations = append(p.plan.Operations, op)
+	}
+}
+
+// ApplyPlan executes the operations of the given plan. Before the operations are executed, the sinks are loaded
+// again: operations whose sink event changed since the plan was made are skipped and returned as stale.
+// A create is stale if the event was synced in the meantime, an update or delete is stale if the sink event
+// was modified or deleted. The sync of a sink is aborted if the deletions exceed the DeleteLimit.
+func (p Controller) ApplyPlan(ctx context.Context, plan Plan) (Summary, []Operation, error) {
+	sinks := make(map[string]Sink)
+	for _, target := range p.sinks {
+		sinks[target.sink.GetCalendarHash()] = target.sink
+	}
+	if p.bidirectional {
+		// in bidirectional mode, the source is written to as well
+		if a, _, err := p.bidirectionalPair(); err == nil {
+			sinks[a.GetCalendarHash()] = a
+		}
+	}
+
+	operations := make(map[string][]Operation)
+	var sinkIDs []string
+	for _, op := range plan.Operations {
+		if _, ok := sinks[op.SinkID]; !ok {
+			return Summary{}, nil, fmt.Errorf("the plan contains the sink %s (%s) which is not configured for job %s", op.Sink, op.SinkID, plan.Job)
+		}
+		if _, ok := operations[op.SinkID]; !ok {
+			sinkIDs = append(sinkIDs, op.SinkID)
+		}
+		operations[op.SinkID] = append(operations[op.SinkID], op)
+	}
+
+	var counter summaryCounter
+	var stale []Operation
+	var errs []error
+	for _, sinkID := range sinkIDs {
+		sink := sinks[sinkID]
+		toCreate, toUpdate, toDelete, staleInSink, err := p.checkOperations(ctx, sink, operations[sinkID], plan.Start, plan.End)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", sink.Name(), err))
+			continue
+		}
+		stale = append(stale, staleInSink...)
+
+		tasks := p.syncTasks(ctx, sink, toCreate, toUpdate, toDelete, &counter, false)
+		if err := errors.Join(parallel(ctx, p.concurrency, tasks), flush(ctx, sink)); err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", sink.Name(), err))
+		}
+	}
+
+	return counter.summary(), stale, errors.Join(errs...)
+}
+
+// checkOperations compares the operations with the current events of the sink and returns the events to create,
+// update and delete as well as the stale operations
+func (p Controller) checkOperations(ctx context.Context, sink Sink, operations []Operation, start, end time.Time) ([]models.Event, []models.Event, []models.Event, []Operation, error) {
+	eventsInSink, err := p.loadSinkEvents(ctx, sink, start, end)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	byID := make(map[string]models.Event)
+	for _, event := range eventsInSink {
+		byID[event.ID] = event
+	}
+	bySyncID := maps(eventsInSink)
+
+	var toCreate, toUpdate, toDelete []models.Event
+	var stale []Operation
+	for _, op := range operations {
+		current, exists := byID[op.EventID]
+		switch op.Operation {
+		case OperationCreate:
+			if _, synced := bySyncID[op.SyncID]; synced || op.Event == nil {
+				stale = append(stale, op)
+				continue
+			}
+			toCreate = append(toCreate, *op.Event)
+		case OperationUpdate:
+			if !exists || changedSince(op, current) || op.Event == nil {
+				stale = append(stale, op)
+				continue
+			}
+			toUpdate = append(toUpdate, *op.Event)
+		case OperationDelete:
+			if !exists || changedSince(op, current) {
+				stale = append(stale, op)
+				continue
+			}
+			toDelete = append(toDelete, current)
+		default:
+			return nil, nil, nil, nil, fmt.Errorf("unknown operation %s", op.Operation)
+		}
+	}
+
+	if err := p.checkDeleteLimit(sink, len(toDelete), len(eventsInSink), false); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	return toCreate, toUpdate, toDelete, stale, nil
+}
+
+// changedSince returns true if the current sink event changed since the operation was planned. Sinks without
+// ETags are compared field by field with the planned event, an operation without either is always stale.
+func changedSince(op Operation, current models.Event) bool {
+	if op.ETag != "" || current.ETag != "" {
+		return current.ETag != op.ETag
+	}
+	if op.Previous == nil {
+		return true
+	}
+	return models.DiffOptions{}.DiffEvents(*op.Previous, current).Changed()
+}
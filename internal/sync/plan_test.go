@@ -0,0 +1,255 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inovex/CalendarSync/internal/config"
+	"github.com/inovex/CalendarSync/internal/models"
+	"github.com/inovex/CalendarSync/internal/sync/mocks"
+)
+
+// TestPlan asserts that a dry run records all operations with their field changes in the plan
+func TestPlan(t *testing.T) {
+	ctx := context.Background()
+	startTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(time.Hour)
+
+	source := &mocks.Source{}
+	sink := &mocks.Sink{}
+
+	sourceEvents := []models.Event{
+		{ID: "new", Title: "New", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("new", "uri", "sourceID")},
+		{ID: "moved", Title: "Moved", StartTime: startTime.Add(time.Hour), EndTime: endTime.Add(time.Hour), Metadata: models.NewEventMetadata("moved", "uri", "sourceID")},
+	}
+	sinkEvents := []models.Event{
+		{ID: "sinkMoved", Title: "Moved", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("moved", "uri", "sourceID"), ETag: "v1"},
+		{ID: "sinkDeleted", Title: "Deleted", StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata("deleted", "uri", "sourceID"), ETag: "v2"},
+	}
+
+	source.On("EventsInTimeframe", ctx, startTime, endTime).Return(sourceEvents, nil)
+	source.On("GetCalendarHash").Return("sourceID")
+	sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(sinkEvents, nil)
+	sink.On("GetCalendarHash").Return("sinkID")
+	sink.On("Name").Return("Google Calendar")
+
+	plan := &Plan{Job: "default"}
+	controller := NewController(log.Default(), []Source{source}, sink, TransformerFactory([]config.Transformer{{Name: "KeepTitle"}}), nil)
+	controller.SetPlan(plan)
+
+	_, err := controller.SynchroniseTimeframe(ctx, startTime, endTime, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, startTime, plan.Start)
+	assert.Equal(t, endTime, plan.End)
+
+	// the planned events are written by ApplyPlan
+	require.Len(t, plan.Operations, 3)
+	require.NotNil(t, plan.Operations[0].Event)
+	assert.Equal(t, "New", plan.Operations[0].Event.Title)
+	require.NotNil(t, plan.Operations[1].Event)
+	assert.Equal(t, "sinkMoved", plan.Operations[1].Event.ID)
+	assert.Equal(t, startTime.Add(time.Hour), plan.Operations[1].Event.StartTime)
+	assert.Nil(t, plan.Operations[2].Event)
+	for i := range plan.Operations {
+		plan.Operations[i].Event = nil
+	}
+
+	assert.Equal(t, []Operation{
+		{
+			Operation: OperationCreate,
+			Sink:      "Google Calendar",
+			SinkID:    "sinkID",
+			SyncID:    models.NewEventID("new"),
+			Title:     "New",
+			Start:     startTime,
+			End:       endTime,
+			Changes: models.EventDiff{
+				{Field: "title", New: "New"},
+				{Field: "start", New: "2024-03-01T10:00:00Z"},
+				{Field: "end", New: "2024-03-01T11:00:00Z"},
+			},
+		},
+		{
+			Operation: OperationUpdate,
+			Sink:      "Google Calendar",
+			SinkID:    "sinkID",
+			SyncID:    models.NewEventID("moved"),
+			EventID:   "sinkMoved",
+			ETag:      "v1",
+			Title:     "Moved",
+			Start:     startTime.Add(time.Hour),
+			End:       endTime.Add(time.Hour),
+			Changes: models.EventDiff{
+				{Field: "start", Old: "2024-03-01T10:00:00Z", New: "2024-03-01T11:00:00Z"},
+				{Field: "end", Old: "2024-03-01T11:00:00Z", New: "2024-03-01T12:00:00Z"},
+			},
+		},
+		{
+			Operation: OperationDelete,
+			Sink:      "Google Calendar",
+			SinkID:    "sinkID",
+			SyncID:    models.NewEventID("deleted"),
+			EventID:   "sinkDeleted",
+			ETag:      "v2",
+			Title:     "Deleted",
+			Start:     startTime,
+			End:       endTime,
+		},
+	}, plan.Operations)
+}
+
+// TestApplyPlan asserts that the operations of a plan are executed unless the sink changed since the plan was made
+func TestApplyPlan(t *testing.T) {
+	ctx := context.Background()
+	startTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(time.Hour)
+
+	source := &mocks.Source{}
+	sink := &mocks.Sink{}
+
+	event := func(id, syncID, etag string) models.Event {
+		return models.Event{ID: id, Title: id, StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata(syncID, "uri", "sourceID"), ETag: etag}
+	}
+	operation := func(typ string, e models.Event) Operation {
+		op := Operation{Operation: typ, SinkID: "sinkID", SyncID: e.Metadata.SyncID, EventID: e.ID, ETag: e.ETag}
+		if typ != OperationDelete {
+			op.Event = &e
+		}
+		return op
+	}
+
+	sinkEvents := []models.Event{
+		event("synced", "synced", "v1"),
+		event("unchanged", "unchanged", "v1"),
+		event("modified", "modified", "v2"),
+		event("toDelete", "toDelete", "v1"),
+	}
+	plan := Plan{
+		Job:   "default",
+		Start: startTime,
+		End:   endTime,
+		Operations: []Operation{
+			operation(OperationCreate, event("", "new", "")),
+			// synced by another run after the plan was made
+			operation(OperationCreate, event("", "synced", "")),
+			operation(OperationUpdate, event("unchanged", "unchanged", "v1")),
+			// modified in the sink after the plan was made
+			operation(OperationUpdate, event("modified", "modified", "v1")),
+			operation(OperationDelete, event("toDelete", "toDelete", "v1")),
+			// deleted in the sink after the plan was made
+			operation(OperationDelete, event("gone", "gone", "v1")),
+		},
+	}
+
+	sink.On("GetCalendarHash").Return("sinkID")
+	sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(sinkEvents, nil)
+	sink.On("CreateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	sink.On("UpdateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	sink.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+
+	controller := NewController(log.Default(), []Source{source}, sink, nil, nil)
+
+	summary, stale, err := controller.ApplyPlan(ctx, plan)
+	require.NoError(t, err)
+
+	assert.Equal(t, Summary{Created: 1, Updated: 1, Deleted: 1}, summary)
+	assert.Equal(t, []Operation{plan.Operations[1], plan.Operations[3], plan.Operations[5]}, stale)
+	sink.AssertCalled(t, "CreateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.Metadata.SyncID == models.NewEventID("new") }))
+	sink.AssertCalled(t, "UpdateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "unchanged" }))
+	sink.AssertCalled(t, "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "toDelete" }))
+}
+
+// TestApplyPlanRejectsUnknownSink asserts that a plan can only be applied with the config it was made with
+func TestApplyPlanRejectsUnknownSink(t *testing.T) {
+	sink := &mocks.Sink{}
+	sink.On("GetCalendarHash").Return("sinkID")
+
+	controller := NewController(log.Default(), []Source{&mocks.Source{}}, sink, nil, nil)
+
+	_, _, err := controller.ApplyPlan(context.Background(), Plan{
+		Job:        "default",
+		Operations: []Operation{{Operation: OperationDelete, Sink: "Outlook", SinkID: "otherSinkID"}},
+	})
+	assert.ErrorContains(t, err, "not configured")
+}
+
+// TestApplyPlanWithoutETags asserts that the sink events of sinks without ETags are compared with the planned events
+func TestApplyPlanWithoutETags(t *testing.T) {
+	ctx := context.Background()
+	startTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(time.Hour)
+
+	source := &mocks.Source{}
+	sink := &mocks.Sink{}
+
+	event := func(id, title string) models.Event {
+		return models.Event{ID: id, Title: title, StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata(id, "uri", "sourceID")}
+	}
+
+	sink.On("GetCalendarHash").Return("sinkID")
+	sink.On("Name").Return("ICS")
+	sink.On("EventsInTimeframe", ctx, startTime, endTime).Return([]models.Event{
+		event("unchanged", "Unchanged"),
+		event("modified", "Modified"),
+		event("toDelete", "Delete"),
+		event("deleteModified", "Modified"),
+	}, nil).Once()
+
+	plan := &Plan{Job: "default"}
+	controller := NewController(log.Default(), []Source{source}, sink, nil, nil)
+	controller.SetPlan(plan)
+	controller.recordPlan(sink, nil,
+		[]models.Event{event("unchanged", "New title"), event("modified", "New title")},
+		[]models.Event{event("toDelete", "Delete"), event("deleteModified", "Delete")},
+		[]models.Event{event("unchanged", "Unchanged"), event("modified", "Planned"), event("toDelete", "Delete"), event("deleteModified", "Delete")},
+	)
+	require.Len(t, plan.Operations, 4)
+	require.NotNil(t, plan.Operations[0].Previous)
+
+	sink.On("UpdateEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	sink.On("DeleteEvent", ctx, mock.AnythingOfType("models.Event")).Return(nil)
+	plan.Start, plan.End = startTime, endTime
+
+	summary, stale, err := controller.ApplyPlan(ctx, *plan)
+	require.NoError(t, err)
+
+	assert.Equal(t, Summary{Updated: 1, Deleted: 1}, summary)
+	assert.Equal(t, []Operation{plan.Operations[1], plan.Operations[3]}, stale)
+	sink.AssertCalled(t, "UpdateEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "unchanged" }))
+	sink.AssertCalled(t, "DeleteEvent", ctx, mock.MatchedBy(func(e models.Event) bool { return e.ID == "toDelete" }))
+}
+
+// TestApplyPlanDeleteLimit asserts that a plan with more deletions than allowed is not applied
+func TestApplyPlanDeleteLimit(t *testing.T) {
+	ctx := context.Background()
+	startTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(time.Hour)
+
+	sink := &mocks.Sink{}
+	var sinkEvents []models.Event
+	var operations []Operation
+	for _, id := range []string{"a", "b", "c"} {
+		e := models.Event{ID: id, StartTime: startTime, EndTime: endTime, Metadata: models.NewEventMetadata(id, "uri", "sourceID"), ETag: "v1"}
+		sinkEvents = append(sinkEvents, e)
+		operations = append(operations, Operation{Operation: OperationDelete, SinkID: "sinkID", SyncID: e.Metadata.SyncID, EventID: id, ETag: "v1"})
+	}
+	sink.On("GetCalendarHash").Return("sinkID")
+	sink.On("Name").Return("Google Calendar")
+	sink.On("EventsInTimeframe", ctx, startTime, endTime).Return(sinkEvents, nil)
+
+	controller := NewController(log.Default(), []Source{&mocks.Source{}}, sink, nil, nil)
+	controller.SetDeleteLimit(DeleteLimit{Count: 2})
+
+	summary, _, err := controller.ApplyPlan(ctx, Plan{Job: "default", Start: startTime, End: endTime, Operations: operations})
+
+	assert.ErrorIs(t, err, ErrDeleteLimitExceeded)
+	assert.Equal(t, Summary{}, summary)
+	sink.AssertNotCalled(t, "DeleteEvent", mock.Anything, mock.Anything)
+}
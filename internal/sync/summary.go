@@ -0,0 +1,71 @@
+package sync
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	gosync "sync"
+	"sync/atomic"
+
+	"github.com/inovex/CalendarSync/internal/models"
+)
+
+// Summary counts the events which were created, updated and deleted in the sinks.
+// In dry run mode, it counts the changes which would have been executed.
+type Summary struct {
+	Created int
+	Updated int
+	Deleted int
+	// Fields counts for every field how many of the events to update changed it
+	Fields map[string]int
+}
+
+// FieldsReport lists the changed fields with their counts, e.g. "start=2 title=1"
+func (s Summary) FieldsReport() string {
+	var fields []string
+	for field, count := range s.Fields {
+		fields = append(fields, fmt.Sprintf("%s=%d", field, count))
+	}
+	slices.Sort(fields)
+	return strings.Join(fields, " ")
+}
+
+// summaryCounter collects a Summary from concurrently running tasks
+type summaryCounter struct {
+	created atomic.Int64
+	updated atomic.Int64
+	deleted atomic.Int64
+
+	mu     gosync.Mutex
+	fields map[string]int
+}
+
+// countFields counts the fields which differ between the events to update and their current version in the sink
+func (c *summaryCounter) countFields(options models.DiffOptions, toUpdate, sinkEvents []models.Event) {
+	if len(toUpdate) == 0 {
+		return
+	}
+	existing := maps(sinkEvents)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fields == nil {
+		c.fields = make(map[string]int)
+	}
+	for _, event := range toUpdate {
+		for _, field := range options.DiffEvents(existing[event.Metadata.SyncID], event).Fields() {
+			c.fields[field]++
+		}
+	}
+}
+
+func (c *summaryCounter) summary() Summary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Summary{
+		Created: int(c.created.Load()),
+		Updated: int(c.updated.Load()),
+		Deleted: int(c.deleted.Load()),
+		Fields:  c.fields,
+	}
+}
@@ -38,3 +38,34 @@ type Sink interface {
 	DeleteEvent(ctx context.Context, e models.Event) error
 	GetCalendarHash() string
 }
+
+// BufferedSink is a Sink which buffers the changes, e.g. to write a file only once per sync instead of once per
+// change. The changes are only written with Flush.
+type BufferedSink interface {
+	Sink
+	// Flush writes the buffered changes
+	Flush(ctx context.Context) error
+}
+
+// IncrementalSource is a Source which can list only the events which changed since a previous listing.
+type IncrementalSource interface {
+	Source
+	// ChangedEventsInTimeframe returns the changes in the timeframe since the listing which returned the given token.
+	// Without a token, a full listing of the timeframe is returned. If the token is no longer valid,
+	// models.ErrSyncTokenInvalid is returned.
+	ChangedEventsInTimeframe(ctx context.Context, start time.Time, end time.Time, token string) (models.Changes, error)
+}
+
+// StateStore persists the state of the synchronisation between runs
+type StateStore interface {
+	// Token returns the token stored for the key together with the timeframe of the full listing it was obtained by.
+	// The token is empty if there is none or it was stored for a different sync window.
+	Token(key string, window string) (string, time.Time, time.Time)
+	// SetToken stores the token for the key, the sync window and the timeframe of the full listing it was obtained by
+	SetToken(key string, window string, token string, start time.Time, end time.Time) error
+	// SyncedEvents returns the events which were in sync between both calendars of a bidirectional sync after the
+	// last run for the key, by their SyncID
+	SyncedEvents(key string) map[string]models.Event
+	// SetSyncedEvents stores the events which are in sync after this run for the key
+	SetSyncedEvents(key string, events map[string]models.Event) error
+}